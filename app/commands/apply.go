@@ -1,10 +1,12 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/opencredo/venafi-vault-wizard/app/config"
 	"github.com/opencredo/venafi-vault-wizard/app/downloader"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
 	"github.com/opencredo/venafi-vault-wizard/app/reporter/pretty"
 	"github.com/opencredo/venafi-vault-wizard/app/tasks"
 	"github.com/opencredo/venafi-vault-wizard/app/tasks/checks"
@@ -13,6 +15,19 @@ import (
 func Apply(configuration *config.Config) {
 	report := pretty.NewReport()
 
+	// Check the plugins' external services first, so that bad credentials or zones fail fast before Vault is changed
+	for _, plugin := range configuration.Plugins {
+		preflightPlugin, ok := plugin.Impl.(plugins.PreflightPlugin)
+		if !ok {
+			continue
+		}
+
+		err := preflightPlugin.Preflight(report)
+		if err != nil {
+			return
+		}
+	}
+
 	sshClients, vaultClient, closeFunc, err := tasks.GetClients(&configuration.Vault, report)
 	if err != nil {
 		return
@@ -21,21 +36,60 @@ func Apply(configuration *config.Config) {
 
 	pluginDownloader := downloader.NewPluginDownloader()
 
-	// TODO: try to ascertain whether we have SSH connections to every replica
+	err = tasks.VerifySSHCoverage(&tasks.VerifySSHCoverageInput{
+		VaultClient:  vaultClient,
+		SSHConfig:    configuration.Vault.SSHConfig,
+		Reporter:     report,
+		AllowMissing: configuration.Vault.AllowMissingSSH,
+	})
+	if err != nil {
+		return
+	}
+
 	checkConfigSection := report.AddSection("Checking Vault server config")
-	pluginDir, err := checks.GetPluginDir(checkConfigSection, vaultClient)
+	serverConfigFallback := &checks.VaultServerConfigFallback{
+		PluginDirectory: configuration.Vault.PluginDirectory,
+		DisableMlock:    configuration.Vault.DisableMlock,
+		SSHClients:      sshClients,
+		VaultConfigPath: configuration.Vault.VaultConfigPath,
+	}
+	pluginDir, err := checks.GetPluginDir(checkConfigSection, vaultClient, serverConfigFallback)
 	if err != nil {
 		return
 	}
 
-	mlockDisabled, err := checks.IsMlockDisabled(checkConfigSection, vaultClient)
+	mlockDisabled, err := checks.IsMlockDisabled(checkConfigSection, vaultClient, serverConfigFallback)
 	if err != nil {
 		return
 	}
 
+	if configuration.Vault.VersionedPluginCatalog {
+		err = checks.VerifyVaultVersion(checkConfigSection, vaultClient, "1.12.0", "the versioned plugin catalog")
+		if err != nil {
+			return
+		}
+	}
+
 	checkConfigSection.Info(fmt.Sprintf("The Vault server plugin directory is configured as %s\n", pluginDir))
 
+	tasks.VerifyVaultServerConfigs(&tasks.VerifyVaultServerConfigsInput{
+		SSHClients:      sshClients,
+		Reporter:        report,
+		VaultConfigPath: configuration.Vault.VaultConfigPath,
+		PluginDir:       pluginDir,
+		MlockDisabled:   mlockDisabled,
+	})
+
 	for _, plugin := range configuration.Plugins {
+		plugin.LegacyCatalogName, err = tasks.ResolveCatalogName(&tasks.ResolveCatalogNameInput{
+			VaultClient: vaultClient,
+			Reporter:    report,
+			Plugin:      plugin,
+		})
+		if err != nil {
+			return
+		}
+
 		err = tasks.ResolveBuildArch(&tasks.ResolveBuildArchInput{
 			SSHClients:      sshClients,
 			PluginBuildArch: plugin.BuildArch,
@@ -66,13 +120,28 @@ func Apply(configuration *config.Config) {
 			return
 		}
 
+		// Anything replaced while upgrading the plugin is recorded, so that the upgrade can be rolled back if the new
+		// version fails to configure or check
+		upgrade := new(tasks.PluginUpgrade)
+		rollback := func(cause error) {
+			_ = tasks.RollbackPlugin(&tasks.RollbackPluginInput{
+				VaultClient: vaultClient,
+				Reporter:    report,
+				Plugin:      plugin,
+				Upgrade:     upgrade,
+				Cause:       cause,
+			})
+		}
+
 		err = tasks.EnablePlugin(&tasks.EnablePluginInput{
 			VaultClient: vaultClient,
 			Reporter:    report,
 			Plugin:      plugin,
 			SHA:         sha,
+			Upgrade:     upgrade,
 		})
 		if err != nil {
+			rollback(err)
 			return
 		}
 
@@ -80,18 +149,27 @@ func Apply(configuration *config.Config) {
 			VaultClient: vaultClient,
 			Reporter:    report,
 			Plugin:      plugin,
+			Upgrade:     upgrade,
 		})
 		if err != nil {
+			rollback(err)
 			return
 		}
 
 		err = plugin.Impl.Configure(report, vaultClient)
+		if errors.Is(err, plugins.ErrPendingApproval) {
+			// Nothing has failed, so there's nothing to roll back, but the plugin can't be checked until apply is run
+			// again once approved
+			continue
+		}
 		if err != nil {
+			rollback(err)
 			return
 		}
 
 		err = plugin.Impl.Check(report, vaultClient)
 		if err != nil {
+			rollback(err)
 			return
 		}
 	}
@@ -12,10 +12,12 @@ import (
 	"github.com/opencredo/venafi-vault-wizard/app/commands"
 	"github.com/opencredo/venafi-vault-wizard/app/config"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/generic"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
 	pki_backend "github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/pki-backend"
 	pki_monitor "github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/pki-monitor"
 	"github.com/opencredo/venafi-vault-wizard/app/questions"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 	mocks "github.com/opencredo/venafi-vault-wizard/mocks/app/questions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -26,6 +28,31 @@ func TestGenerateConfig(t *testing.T) {
 		questionsCSVFilename string
 		expectedConfig       *config.Config
 	}{
+		"container generic": {
+			questionsCSVFilename: "test_fixtures/container_generic.csv",
+			expectedConfig: &config.Config{
+				Vault: config.VaultConfig{
+					VaultAddress: "http://localhost:8200",
+					VaultToken:   "root",
+				},
+				Plugins: []plugins.PluginConfig{
+					{
+						Type:      "generic",
+						Version:   "v1.2.0",
+						MountPath: "custom",
+						Kind:      api.PluginKindAuth,
+						Name:      "vault-plugin-auth-custom",
+						Impl: &generic.GenericPluginConfig{
+							MountPath:  "custom",
+							Version:    "v1.2.0",
+							Repository: "example/vault-plugin-auth-custom",
+							Asset:      "custom_{{.Version}}_{{.OS}}_{{.Arch}}.zip",
+							Kind:       "auth",
+						},
+					},
+				},
+			},
+		},
 		"one VM pki-backend": {
 			questionsCSVFilename: "test_fixtures/one_vm_pki-backend.csv",
 			expectedConfig: &config.Config{
@@ -46,6 +73,7 @@ func TestGenerateConfig(t *testing.T) {
 						Type:      "venafi-pki-backend",
 						Version:   "v0.9.0",
 						MountPath: "pki",
+						Kind:      api.PluginKindSecret,
 						Impl: &pki_backend.VenafiPKIBackendConfig{
 							MountPath: "pki",
 							Version:   "v0.9.0",
@@ -94,6 +122,7 @@ func TestGenerateConfig(t *testing.T) {
 						Type:      "venafi-pki-backend",
 						Version:   "v0.9.0",
 						MountPath: "pki",
+						Kind:      api.PluginKindSecret,
 						Impl: &pki_backend.VenafiPKIBackendConfig{
 							MountPath: "pki",
 							Version:   "v0.9.0",
@@ -128,6 +157,7 @@ func TestGenerateConfig(t *testing.T) {
 						Type:      "venafi-pki-backend",
 						Version:   "v0.9.0",
 						MountPath: "pki",
+						Kind:      api.PluginKindSecret,
 						Impl: &pki_backend.VenafiPKIBackendConfig{
 							MountPath: "pki",
 							Version:   "v0.9.0",
@@ -163,6 +193,7 @@ func TestGenerateConfig(t *testing.T) {
 						Version:   "v0.9.0",
 						MountPath: "pki",
 						BuildArch: "linux86",
+						Kind:      api.PluginKindSecret,
 						Impl: &pki_backend.VenafiPKIBackendConfig{
 							MountPath: "pki",
 							Version:   "v0.9.0",
@@ -186,6 +217,59 @@ func TestGenerateConfig(t *testing.T) {
 				},
 			},
 		},
+		"container pki-backend optional": {
+			questionsCSVFilename: "test_fixtures/container_pki-backend_optional.csv",
+			expectedConfig: &config.Config{
+				Vault: config.VaultConfig{
+					VaultAddress: "http://localhost:8200",
+					VaultToken:   "root",
+				},
+				Plugins: []plugins.PluginConfig{
+					{
+						Type:      "venafi-pki-backend",
+						Version:   "v0.9.0",
+						MountPath: "pki",
+						Kind:      api.PluginKindSecret,
+						Impl: &pki_backend.VenafiPKIBackendConfig{
+							MountPath: "pki",
+							Version:   "v0.9.0",
+							Roles: []pki_backend.Role{
+								{
+									Name: "web",
+									Secret: pki_backend.ZonedSecret{
+										Name: "vaas",
+										Zone: "projectzoneID",
+										VenafiSecret: venafi.VenafiSecret{
+											VaaS: &venafi.VenafiVaaSConnection{
+												APIKey: "venafiAPIKey",
+											},
+										},
+									},
+									OptionalConfig: &pki_backend.OptionalConfig{
+										StoreBy:         "serial",
+										StorePrivateKey: true,
+										MinCertTimeLeft: "720h",
+										KeyType:         "ec",
+										KeyCurve:        "P384",
+										ChainOption:     "first",
+										AllowedDomains:  []string{"example.com", "example.org"},
+										AllowSubdomains: true,
+										ServerTimeout:   60,
+										CustomFields:    []string{"team=platform"},
+										IssuerHint:      "digicert",
+										OptionalConfig: venafi.OptionalConfig{
+											AllowAnyName: true,
+											TTL:          "1h",
+											MaxTTL:       "2h",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		"container pki-monitor": {
 			questionsCSVFilename: "test_fixtures/container_pki-monitor.csv",
 			expectedConfig: &config.Config{
@@ -198,26 +282,12 @@ func TestGenerateConfig(t *testing.T) {
 						Type:      "venafi-pki-monitor",
 						Version:   "v0.9.0",
 						MountPath: "pki",
+						Kind:      api.PluginKindSecret,
 						Impl: &pki_monitor.VenafiPKIMonitorConfig{
 							MountPath: "pki",
 							Version:   "v0.9.0",
-							Role: pki_monitor.Role{
-								Name: "web",
-								Secret: pki_monitor.UnZonedSecret{
-									Name: "vaas",
-									VenafiSecret: venafi.VenafiSecret{
-										VaaS: &venafi.VenafiVaaSConnection{
-											APIKey: "venafiAPIKey",
-										},
-									},
-								},
-								EnforcementPolicy: &pki_monitor.Policy{
-									Zone: "policy folder\\\\policy",
-								},
-								ImportPolicy: &pki_monitor.Policy{
-									Zone: "policy folder\\\\policy",
-								},
-								IntermediateCert: &pki_monitor.IntermediateCertRequest{
+							CA: &pki_monitor.CA{
+								VenafiIntermediate: &pki_monitor.IntermediateCertRequest{
 									Zone: "policy folder\\\\policy",
 									CertificateRequest: venafi.CertificateRequest{
 										CommonName:   "cn",
@@ -230,6 +300,71 @@ func TestGenerateConfig(t *testing.T) {
 									},
 								},
 							},
+							Roles: []pki_monitor.Role{
+								{
+									Name: "web",
+									Secret: pki_monitor.UnZonedSecret{
+										Name: "vaas",
+										VenafiSecret: venafi.VenafiSecret{
+											VaaS: &venafi.VenafiVaaSConnection{
+												APIKey: "venafiAPIKey",
+											},
+										},
+									},
+									EnforcementPolicy: &pki_monitor.Policy{
+										Zone: "policy folder\\\\policy",
+									},
+									ImportPolicy: &pki_monitor.Policy{
+										Zone: "policy folder\\\\policy",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"container pki-monitor importing CA": {
+			questionsCSVFilename: "test_fixtures/container_pki-monitor_import_ca.csv",
+			expectedConfig: &config.Config{
+				Vault: config.VaultConfig{
+					VaultAddress: "http://localhost:8200",
+					VaultToken:   "root",
+				},
+				Plugins: []plugins.PluginConfig{
+					{
+						Type:      "venafi-pki-monitor",
+						Version:   "v0.9.0",
+						MountPath: "pki",
+						Kind:      api.PluginKindSecret,
+						Impl: &pki_monitor.VenafiPKIMonitorConfig{
+							MountPath: "pki",
+							Version:   "v0.9.0",
+							CA: &pki_monitor.CA{
+								Import: &pki_monitor.ImportedCA{
+									Certificate: readFixture("test_fixtures/import_ca.pem"),
+									PrivateKey:  readFixture("test_fixtures/import_ca.key"),
+								},
+							},
+							Roles: []pki_monitor.Role{
+								{
+									Name: "web",
+									Secret: pki_monitor.UnZonedSecret{
+										Name: "vaas",
+										VenafiSecret: venafi.VenafiSecret{
+											VaaS: &venafi.VenafiVaaSConnection{
+												APIKey: "venafiAPIKey",
+											},
+										},
+									},
+									EnforcementPolicy: &pki_monitor.Policy{
+										Zone: "policy folder\\\\policy",
+									},
+									ImportPolicy: &pki_monitor.Policy{
+										Zone: "policy folder\\\\policy",
+									},
+								},
+							},
 						},
 					},
 				},
@@ -248,29 +383,13 @@ func TestGenerateConfig(t *testing.T) {
 						Version:   "v0.9.0",
 						MountPath: "pki",
 						BuildArch: "darwin",
+						Kind:      api.PluginKindSecret,
 						Impl: &pki_monitor.VenafiPKIMonitorConfig{
 							MountPath: "pki",
 							Version:   "v0.9.0",
 							BuildArch: "darwin",
-							Role: pki_monitor.Role{
-								Name: "web",
-								Secret: pki_monitor.UnZonedSecret{
-									Name: "tpp",
-									VenafiSecret: venafi.VenafiSecret{
-										TPP: &venafi.VenafiTPPConnection{
-											URL:      "tpp.com",
-											Username: "admin",
-											Password: "password",
-										},
-									},
-								},
-								EnforcementPolicy: &pki_monitor.Policy{
-									Zone: "policy folder\\\\policy",
-								},
-								ImportPolicy: &pki_monitor.Policy{
-									Zone: "policy folder\\\\policy",
-								},
-								IntermediateCert: &pki_monitor.IntermediateCertRequest{
+							CA: &pki_monitor.CA{
+								VenafiIntermediate: &pki_monitor.IntermediateCertRequest{
 									Zone: "policy folder\\\\policy",
 									CertificateRequest: venafi.CertificateRequest{
 										CommonName:   "cn",
@@ -283,6 +402,27 @@ func TestGenerateConfig(t *testing.T) {
 									},
 								},
 							},
+							Roles: []pki_monitor.Role{
+								{
+									Name: "web",
+									Secret: pki_monitor.UnZonedSecret{
+										Name: "tpp",
+										VenafiSecret: venafi.VenafiSecret{
+											TPP: &venafi.VenafiTPPConnection{
+												URL:      "tpp.com",
+												Username: "admin",
+												Password: "password",
+											},
+										},
+									},
+									EnforcementPolicy: &pki_monitor.Policy{
+										Zone: "policy folder\\\\policy",
+									},
+									ImportPolicy: &pki_monitor.Policy{
+										Zone: "policy folder\\\\policy",
+									},
+								},
+							},
 						},
 					},
 				},
@@ -300,28 +440,12 @@ func TestGenerateConfig(t *testing.T) {
 						Type:      "venafi-pki-monitor",
 						Version:   "v0.9.0",
 						MountPath: "pki",
+						Kind:      api.PluginKindSecret,
 						Impl: &pki_monitor.VenafiPKIMonitorConfig{
 							MountPath: "pki",
 							Version:   "v0.9.0",
-							Role: pki_monitor.Role{
-								Name: "web",
-								Secret: pki_monitor.UnZonedSecret{
-									Name: "tpp",
-									VenafiSecret: venafi.VenafiSecret{
-										TPP: &venafi.VenafiTPPConnection{
-											URL:      "tpp.com",
-											Username: "admin",
-											Password: "password",
-										},
-									},
-								},
-								EnforcementPolicy: &pki_monitor.Policy{
-									Zone: "policy folder\\\\policy",
-								},
-								ImportPolicy: &pki_monitor.Policy{
-									Zone: "policy folder\\\\policy",
-								},
-								IntermediateCert: &pki_monitor.IntermediateCertRequest{
+							CA: &pki_monitor.CA{
+								VenafiIntermediate: &pki_monitor.IntermediateCertRequest{
 									Zone: "policy folder\\\\policy",
 									CertificateRequest: venafi.CertificateRequest{
 										CommonName:   "cn",
@@ -333,11 +457,34 @@ func TestGenerateConfig(t *testing.T) {
 										TTL:          "3h",
 									},
 								},
-								OptionalConfig: &venafi.OptionalConfig{
-									GenerateLease: true,
-									AllowAnyName:  true,
-									TTL:           "2h",
-									MaxTTL:        "4h",
+							},
+							Roles: []pki_monitor.Role{
+								{
+									Name: "web",
+									Secret: pki_monitor.UnZonedSecret{
+										Name: "tpp",
+										VenafiSecret: venafi.VenafiSecret{
+											TPP: &venafi.VenafiTPPConnection{
+												URL:      "tpp.com",
+												Username: "admin",
+												Password: "password",
+											},
+										},
+									},
+									EnforcementPolicy: &pki_monitor.Policy{
+										Zone: "policy folder\\\\policy",
+									},
+									ImportPolicy: &pki_monitor.Policy{
+										Zone: "policy folder\\\\policy",
+									},
+									OptionalConfig: &pki_monitor.OptionalConfig{
+										OptionalConfig: venafi.OptionalConfig{
+											GenerateLease: true,
+											AllowAnyName:  true,
+											TTL:           "2h",
+											MaxTTL:        "4h",
+										},
+									},
 								},
 							},
 						},
@@ -369,7 +516,12 @@ func TestGenerateConfig(t *testing.T) {
 				tc.expectedConfig,
 				actualConfig,
 				cmp.FilterPath(func(path cmp.Path) bool {
-					return path.String() == "Plugins.Config"
+					switch path.String() {
+					case "Plugins.Config", "Plugins.VaultAddress", "Plugins.ConfigDir",
+						"Plugins.Impl.CA.VenafiIntermediate.VaultAddress", "Plugins.Impl.CA.VenafiIntermediate.ConfigDir":
+						return true
+					}
+					return false
 				}, cmp.Ignore()),
 			)
 			if diff != "" {
@@ -379,6 +531,16 @@ func TestGenerateConfig(t *testing.T) {
 	}
 }
 
+// readFixture returns the contents of a test fixture, or panics if it can't be read
+func readFixture(filename string) string {
+	contents, err := questionsFiles.ReadFile(filename)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(contents)
+}
+
 type QuestionType int
 
 const (
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"errors"
+	"time"
+
+	"github.com/opencredo/venafi-vault-wizard/app/config"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	pki_monitor "github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/pki-monitor"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter/pretty"
+	"github.com/opencredo/venafi-vault-wizard/app/tasks"
+)
+
+// RenewCA renews the intermediate CA certificates, signed by Venafi, of the venafi-pki-monitor plugins in
+// configuration that expire within threshold, or the renewal_window of their ca block if threshold is nil
+func RenewCA(configuration *config.Config, threshold *time.Duration) {
+	report := pretty.NewReport()
+
+	vaultClient, err := tasks.GetVaultAPIClient(&configuration.Vault, report)
+	if err != nil {
+		return
+	}
+
+	intermediatesFound := false
+	for _, plugin := range configuration.Plugins {
+		monitorConfig, ok := plugin.Impl.(*pki_monitor.VenafiPKIMonitorConfig)
+		if !ok || !monitorConfig.HasVenafiIntermediate() {
+			continue
+		}
+		intermediatesFound = true
+
+		err = monitorConfig.RenewIntermediateCA(report, vaultClient, threshold)
+		if errors.Is(err, plugins.ErrPendingApproval) {
+			// The pickup ID has been saved, so the renewal is finished by running renew-ca again once approved
+			continue
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if !intermediatesFound {
+		report.AddSection("Renewing intermediate CA certificates").Info(
+			"No venafi-pki-monitor plugins with a venafi_intermediate CA are configured, so there is nothing to renew",
+		)
+	}
+}
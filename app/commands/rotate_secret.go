@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/opencredo/venafi-vault-wizard/app/config"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter/pretty"
+	"github.com/opencredo/venafi-vault-wizard/app/tasks"
+)
+
+// RotateSecret rewrites the Venafi secret called secretName, or every Venafi secret if secretName is blank, of the
+// plugins in configuration with fresh credentials, without reinstalling or reconfiguring the rest of their mounts
+func RotateSecret(configuration *config.Config, secretName string) {
+	report := pretty.NewReport()
+
+	vaultClient, err := tasks.GetVaultAPIClient(&configuration.Vault, report)
+	if err != nil {
+		return
+	}
+
+	secretsFound := false
+	for _, plugin := range configuration.Plugins {
+		rotatingPlugin, ok := plugin.Impl.(plugins.SecretRotatingPlugin)
+		if !ok {
+			continue
+		}
+
+		rotated, err := rotatingPlugin.RotateSecrets(report, vaultClient, secretName)
+		if rotated {
+			secretsFound = true
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if !secretsFound {
+		message := "No plugins with Venafi secrets are configured, so there is nothing to rotate"
+		if secretName != "" {
+			message = fmt.Sprintf("No plugins use a Venafi secret called %s, so there is nothing to rotate", secretName)
+		}
+		report.AddSection("Rotating Venafi secrets").Info(message)
+	}
+}
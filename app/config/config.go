@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsimple"
@@ -47,6 +48,23 @@ func NewConfig(filename string, src []byte) (*Config, error) {
 					return cty.StringVal(env), nil
 				},
 			}),
+			"file": function.New(&function.Spec{
+				Params: []function.Parameter{
+					{
+						Name: "path",
+						Type: cty.String,
+					},
+				},
+				Type: function.StaticReturnType(cty.String),
+				Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+					contents, err := ioutil.ReadFile(args[0].AsString())
+					if err != nil {
+						return cty.NullVal(cty.String), err
+					}
+
+					return cty.StringVal(string(contents)), nil
+				},
+			}),
 		},
 	}
 
@@ -60,6 +78,12 @@ func NewConfig(filename string, src []byte) (*Config, error) {
 		return nil, err
 	}
 
+	// Set before ParseConfig, so that plugins saving state between runs can keep it next to the config file
+	for i := range config.Plugins {
+		config.Plugins[i].VaultAddress = config.Vault.VaultAddress
+		config.Plugins[i].ConfigDir = filepath.Dir(filename)
+	}
+
 	for i, plugin := range config.Plugins {
 		pluginImpl, err := lookup.GetPlugin(plugin.Type)
 		if err != nil {
@@ -77,6 +101,11 @@ func NewConfig(filename string, src []byte) (*Config, error) {
 		}
 
 		config.Plugins[i].Impl = pluginImpl
+		config.Plugins[i].Kind = pluginImpl.GetKind()
+		if namedPlugin, ok := pluginImpl.(plugins.NamedPlugin); ok {
+			config.Plugins[i].Name = namedPlugin.GetName()
+		}
+		config.Plugins[i].VersionedCatalog = config.Vault.VersionedPluginCatalog
 	}
 
 	return config, nil
@@ -102,5 +131,14 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if c.Vault.VersionedPluginCatalog {
+		for _, plugin := range c.Plugins {
+			_, err := plugin.GetSemanticVersion()
+			if err != nil {
+				return fmt.Errorf("error with plugin %s, versioned_plugin_catalog requires it: %w", plugin.GetPerMountCatalogName(), err)
+			}
+		}
+	}
+
 	return nil
 }
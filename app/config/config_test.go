@@ -6,9 +6,11 @@ import (
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/generic"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
 	pki_backend "github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/pki-backend"
 	pki_monitor "github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/pki-monitor"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -27,6 +29,15 @@ func TestNewConfig(t *testing.T) {
 			want:    validPKIBackendTPPConfigResult,
 			wantErr: false,
 		},
+		"valid venafi-pki-backend with role parameters": {
+			config:  validPKIBackendRoleParametersConfig,
+			want:    validPKIBackendRoleParametersConfigResult,
+			wantErr: false,
+		},
+		"invalid venafi-pki-backend with incorrect role parameters": {
+			config:  invalidPKIBackendConfigRoleParameters,
+			wantErr: true,
+		},
 		"invalid venafi-pki-backend without role": {
 			config:  invalidPKIBackendConfigNoRole,
 			wantErr: true,
@@ -44,6 +55,48 @@ func TestNewConfig(t *testing.T) {
 			want:    validPKIMonitorConfigResult,
 			wantErr: false,
 		},
+		"valid venafi-pki-monitor with multiple roles": {
+			config:  validPKIMonitorMultipleRolesConfig,
+			want:    validPKIMonitorMultipleRolesConfigResult,
+			wantErr: false,
+		},
+		"valid venafi-pki-monitor with role and policy parameters": {
+			config:  validPKIMonitorPoliciesConfig,
+			want:    validPKIMonitorPoliciesConfigResult,
+			wantErr: false,
+		},
+		"invalid venafi-pki-monitor with policy for undefined role": {
+			config:  invalidPKIMonitorConfigPolicyUndefinedRole,
+			wantErr: true,
+		},
+		"invalid venafi-pki-monitor with multiple CAs": {
+			config:  invalidPKIMonitorConfigMultipleCAs,
+			wantErr: true,
+		},
+		"invalid venafi-pki-monitor with multiple blocks in ca": {
+			config:  invalidPKIMonitorConfigMultipleCABlocks,
+			wantErr: true,
+		},
+		"invalid venafi-pki-monitor with missing imported CA file": {
+			config:  invalidPKIMonitorConfigMissingImportFile,
+			wantErr: true,
+		},
+		"invalid venafi-pki-monitor with incorrect ca renewal window": {
+			config:  invalidPKIMonitorConfigRenewalWindow,
+			wantErr: true,
+		},
+		"invalid venafi-pki-monitor with incorrect intermediate retrieve timeout": {
+			config:  invalidPKIMonitorConfigRetrieveTimeout,
+			wantErr: true,
+		},
+		"invalid venafi-pki-monitor with intermediate secret of no role": {
+			config:  invalidPKIMonitorConfigIntermediateSecret,
+			wantErr: true,
+		},
+		"invalid venafi-pki-monitor with duplicate role names": {
+			config:  invalidPKIMonitorConfigDuplicateRoles,
+			wantErr: true,
+		},
 		"invalid venafi-pki-monitor with blank intermediate certificate zone": {
 			config:  invalidPKIMonitorConfigBlankIntermediateCertificateZone,
 			wantErr: true,
@@ -74,6 +127,27 @@ func TestNewConfig(t *testing.T) {
 			config:  invalidPKIMonitorBuildArchConfig,
 			wantErr: true,
 		},
+		"invalid venafi-pki-backend with unknown test certificate cleanup": {
+			config:  invalidPKIBackendTestCertificateCleanupConfig,
+			wantErr: true,
+		},
+		"invalid venafi-pki-monitor with csr_file in intermediate certificate": {
+			config:  invalidPKIMonitorIntermediateCSRFileConfig,
+			wantErr: true,
+		},
+		"valid generic plugin": {
+			config:  validGenericConfig,
+			want:    validGenericConfigResult,
+			wantErr: false,
+		},
+		"invalid generic plugin with incorrect kind": {
+			config:  invalidGenericKindConfig,
+			wantErr: true,
+		},
+		"invalid versioned plugin catalog with non-semantic plugin version": {
+			config:  invalidVersionedCatalogConfig,
+			wantErr: true,
+		},
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -88,6 +162,15 @@ func TestNewConfig(t *testing.T) {
 				return
 			}
 
+			for _, plugin := range got.Plugins {
+				if plugin.VaultAddress != got.Vault.VaultAddress || plugin.ConfigDir != "." {
+					t.Errorf(
+						"NewConfig() plugin %s has VaultAddress %q and ConfigDir %q, want %q and \".\"",
+						plugin.MountPath, plugin.VaultAddress, plugin.ConfigDir, got.Vault.VaultAddress,
+					)
+				}
+			}
+
 			deletePluginsUncheckedFields(got)
 
 			if diff := cmp.Diff(tt.want, got); diff != "" {
@@ -152,6 +235,7 @@ var validPKIBackendVaaSConfigResult = &Config{
 			MountPath: "venafi-pki",
 			Version:   "v0.9.0",
 			Config:    nil,
+			Kind:      api.PluginKindSecret,
 			Impl: &pki_backend.VenafiPKIBackendConfig{
 				MountPath: "venafi-pki",
 				Version:   "v0.9.0",
@@ -185,6 +269,116 @@ var validPKIBackendVaaSConfigResult = &Config{
 	},
 }
 
+const validPKIBackendRoleParametersConfig = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-backend" "venafi-pki" {
+  version = "v0.9.0"
+  role "vaas" {
+    secret "vaas" {
+      zone = "zone1"
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    optional_config {
+      ttl = "1h"
+      max_ttl = "2h"
+      store_by = "serial"
+      store_pkey = true
+      key_type = "ec"
+      key_curve = "P384"
+      chain_option = "first"
+      allowed_domains = ["example.com", "example.org"]
+      allow_subdomains = true
+      server_timeout = 60
+      custom_fields = ["team=platform"]
+      min_cert_time_left = "720h"
+      issuer_hint = "digicert"
+    }
+  }
+}
+`
+
+var validPKIBackendRoleParametersConfigResult = &Config{
+	Vault: VaultConfig{
+		VaultAddress: "http://localhost:8200",
+		VaultToken:   "root",
+	},
+	Plugins: []plugins.PluginConfig{
+		{
+			Type:      "venafi-pki-backend",
+			MountPath: "venafi-pki",
+			Version:   "v0.9.0",
+			Config:    nil,
+			Kind:      api.PluginKindSecret,
+			Impl: &pki_backend.VenafiPKIBackendConfig{
+				MountPath: "venafi-pki",
+				Version:   "v0.9.0",
+				Roles: []pki_backend.Role{
+					{
+						Name: "vaas",
+						Secret: pki_backend.ZonedSecret{
+							Name: "vaas",
+							Zone: "zone1",
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: "apikey",
+								},
+							},
+						},
+						OptionalConfig: &pki_backend.OptionalConfig{
+							StoreBy:         "serial",
+							StorePrivateKey: true,
+							KeyType:         "ec",
+							KeyCurve:        "P384",
+							ChainOption:     "first",
+							AllowedDomains:  []string{"example.com", "example.org"},
+							AllowSubdomains: true,
+							ServerTimeout:   60,
+							CustomFields:    []string{"team=platform"},
+							MinCertTimeLeft: "720h",
+							IssuerHint:      "digicert",
+							OptionalConfig: venafi.OptionalConfig{
+								TTL:    "1h",
+								MaxTTL: "2h",
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+const invalidPKIBackendConfigRoleParameters = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-backend" "venafi-pki" {
+  version = "v0.9.0"
+  role "vaas" {
+    secret "vaas" {
+      zone = "zone1"
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    optional_config {
+      key_type = "ec"
+      key_bits = 4096
+    }
+  }
+}
+`
+
 const validPKIBackendTPPConfig = `
 vault {
   api_address = "http://localhost:8200"
@@ -241,6 +435,7 @@ var validPKIBackendTPPConfigResult = &Config{
 			MountPath: "venafi-pki",
 			Version:   "v0.9.0",
 			Config:    nil,
+			Kind:      api.PluginKindSecret,
 			Impl: &pki_backend.VenafiPKIBackendConfig{
 				MountPath: "venafi-pki",
 				Version:   "v0.9.0",
@@ -355,18 +550,12 @@ var validPKIMonitorConfigResult = &Config{
 			MountPath: "venafi-pki",
 			Version:   "v0.9.0",
 			Config:    nil,
+			Kind:      api.PluginKindSecret,
 			Impl: &pki_monitor.VenafiPKIMonitorConfig{
 				MountPath: "venafi-pki",
 				Version:   "v0.9.0",
-				Role: pki_monitor.Role{
-					Name: "web_server",
-					EnforcementPolicy: &pki_monitor.Policy{
-						Zone: "zone",
-					},
-					ImportPolicy: &pki_monitor.Policy{
-						Zone: "zone2",
-					},
-					IntermediateCert: &pki_monitor.IntermediateCertRequest{
+				CA: &pki_monitor.CA{
+					VenafiIntermediate: &pki_monitor.IntermediateCertRequest{
 						Zone: "zone3",
 						CertificateRequest: venafi.CertificateRequest{
 							CommonName:   "Vault SubCA",
@@ -378,29 +567,42 @@ var validPKIMonitorConfigResult = &Config{
 							TTL:          "1h",
 						},
 					},
-					Secret: pki_monitor.UnZonedSecret{
-						Name: "vaas",
-						VenafiSecret: venafi.VenafiSecret{
-							VaaS: &venafi.VenafiVaaSConnection{
-								APIKey: "apikey",
+				},
+				Roles: []pki_monitor.Role{
+					{
+						Name: "web_server",
+						EnforcementPolicy: &pki_monitor.Policy{
+							Zone: "zone",
+						},
+						ImportPolicy: &pki_monitor.Policy{
+							Zone: "zone2",
+						},
+						Secret: pki_monitor.UnZonedSecret{
+							Name: "vaas",
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: "apikey",
+								},
 							},
 						},
-					},
-					TestCerts: []venafi.CertificateRequest{
-						{
-							CommonName:   "vvw-example.test",
-							OU:           "VVW",
-							Organisation: "VVW",
-							Locality:     "London",
-							Province:     "London",
-							Country:      "GB",
-							TTL:          "1h",
+						TestCerts: []venafi.CertificateRequest{
+							{
+								CommonName:   "vvw-example.test",
+								OU:           "VVW",
+								Organisation: "VVW",
+								Locality:     "London",
+								Province:     "London",
+								Country:      "GB",
+								TTL:          "1h",
+							},
+						},
+						OptionalConfig: &pki_monitor.OptionalConfig{
+							OptionalConfig: venafi.OptionalConfig{
+								AllowAnyName: true,
+								TTL:          "1h",
+								MaxTTL:       "2h",
+							},
 						},
-					},
-					OptionalConfig: &venafi.OptionalConfig{
-						AllowAnyName: true,
-						TTL:          "1h",
-						MaxTTL:       "2h",
 					},
 				},
 			},
@@ -454,6 +656,7 @@ var validPKIBackendBuildArchConfigResult = &Config{
 			Version:   "v0.9.0",
 			BuildArch: "linux86",
 			Config:    nil,
+			Kind:      api.PluginKindSecret,
 			Impl: &pki_backend.VenafiPKIBackendConfig{
 				MountPath: "venafi-pki",
 				Version:   "v0.9.0",
@@ -542,19 +745,13 @@ var validPKIMonitorBuildArchConfigResult = &Config{
 			Version:   "v0.9.0",
 			BuildArch: "linux86",
 			Config:    nil,
+			Kind:      api.PluginKindSecret,
 			Impl: &pki_monitor.VenafiPKIMonitorConfig{
 				MountPath: "venafi-pki",
 				Version:   "v0.9.0",
 				BuildArch: "linux86",
-				Role: pki_monitor.Role{
-					Name: "web_server",
-					EnforcementPolicy: &pki_monitor.Policy{
-						Zone: "zone",
-					},
-					ImportPolicy: &pki_monitor.Policy{
-						Zone: "zone2",
-					},
-					IntermediateCert: &pki_monitor.IntermediateCertRequest{
+				CA: &pki_monitor.CA{
+					VenafiIntermediate: &pki_monitor.IntermediateCertRequest{
 						Zone: "zone3",
 						CertificateRequest: venafi.CertificateRequest{
 							CommonName:   "Vault SubCA",
@@ -566,11 +763,22 @@ var validPKIMonitorBuildArchConfigResult = &Config{
 							TTL:          "1h",
 						},
 					},
-					Secret: pki_monitor.UnZonedSecret{
-						Name: "vaas",
-						VenafiSecret: venafi.VenafiSecret{
-							VaaS: &venafi.VenafiVaaSConnection{
-								APIKey: "apikey",
+				},
+				Roles: []pki_monitor.Role{
+					{
+						Name: "web_server",
+						EnforcementPolicy: &pki_monitor.Policy{
+							Zone: "zone",
+						},
+						ImportPolicy: &pki_monitor.Policy{
+							Zone: "zone2",
+						},
+						Secret: pki_monitor.UnZonedSecret{
+							Name: "vaas",
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: "apikey",
+								},
 							},
 						},
 					},
@@ -647,69 +855,26 @@ plugin "venafi-pki-backend" "venafi-pki" {
   }
 }`
 
-const invalidPKIMonitorConfigBlankIntermediateCertificateZone = `
+const validPKIMonitorMultipleRolesConfig = `
 vault {
   api_address = "http://localhost:8200"
   token = "root"
-
-  ssh {
-    hostname = "localhost"
-    username = "vagrant"
-    password = "vagrant"
-    port = 22
-  }
 }
 
 plugin "venafi-pki-monitor" "venafi-pki" {
   version = "v0.9.0"
 
-  role "web_server" {
-    secret "vaas" {
-      venafi_vaas {
-        apikey = "apikey"
-      }
-    }
-
-	enforcement_policy {
-	  zone = "zone"
-	}
-
-    import_policy {
-      zone = "zone2"
-    }
-
-	intermediate_certificate {
-      zone = ""
-	  common_name = "Vault SubCA"
-	  ou = "VVW"
-	  organisation = "VVW"
+  ca {
+    root {
+      common_name = "Vault Root"
+      ou = "VVW"
+      organisation = "VVW"
       locality = "London"
       province = "London"
       country = "GB"
       ttl = "1h"
-	}
-
-    allow_any_name = true
-    ttl = "1h"
-    max_ttl = "2h"
-  }
-}`
-
-const invalidPKIMonitorConfigBlankEnforcementPolicyZone = `
-vault {
-  api_address = "http://localhost:8200"
-  token = "root"
-
-  ssh {
-    hostname = "localhost"
-    username = "vagrant"
-    password = "vagrant"
-    port = 22
+    }
   }
-}
-
-plugin "venafi-pki-monitor" "venafi-pki" {
-  version = "v0.9.0"
 
   role "web_server" {
     secret "vaas" {
@@ -718,62 +883,641 @@ plugin "venafi-pki-monitor" "venafi-pki" {
       }
     }
 
-	enforcement_policy {
-	  zone = ""
-	}
-
-    import_policy {
-      zone = "zone2"
+    enforcement_policy {
+      zone = "zone"
     }
-
-	intermediate_certificate {
-      zone = "zone3"
-	  common_name = "Vault SubCA"
-	  ou = "VVW"
-	  organisation = "VVW"
-      locality = "London"
-      province = "London"
-      country = "GB"
-      ttl = "1h"
-	}
-
-    allow_any_name = true
-    ttl = "1h"
-    max_ttl = "2h"
-  }
-}`
-
-const invalidPKIMonitorConfigBlankImportPolicyZone = `
-vault {
-  api_address = "http://localhost:8200"
-  token = "root"
-
-  ssh {
-    hostname = "localhost"
-    username = "vagrant"
-    password = "vagrant"
-    port = 22
   }
-}
-
-plugin "venafi-pki-monitor" "venafi-pki" {
-  version = "v0.9.0"
 
-  role "web_server" {
+  role "database" {
     secret "vaas" {
       venafi_vaas {
         apikey = "apikey"
       }
     }
 
-	enforcement_policy {
-	  zone = "zone"
-	}
-
     import_policy {
-      zone = ""
+      zone = "zone2"
     }
-
+  }
+}`
+
+var validPKIMonitorMultipleRolesConfigResult = &Config{
+	Vault: VaultConfig{
+		VaultAddress: "http://localhost:8200",
+		VaultToken:   "root",
+	},
+	Plugins: []plugins.PluginConfig{
+		{
+			Type:      "venafi-pki-monitor",
+			MountPath: "venafi-pki",
+			Version:   "v0.9.0",
+			Config:    nil,
+			Kind:      api.PluginKindSecret,
+			Impl: &pki_monitor.VenafiPKIMonitorConfig{
+				MountPath: "venafi-pki",
+				Version:   "v0.9.0",
+				CA: &pki_monitor.CA{
+					Root: &venafi.CertificateRequest{
+						CommonName:   "Vault Root",
+						OU:           "VVW",
+						Organisation: "VVW",
+						Locality:     "London",
+						Province:     "London",
+						Country:      "GB",
+						TTL:          "1h",
+					},
+				},
+				Roles: []pki_monitor.Role{
+					{
+						Name: "web_server",
+						EnforcementPolicy: &pki_monitor.Policy{
+							Zone: "zone",
+						},
+						Secret: pki_monitor.UnZonedSecret{
+							Name: "vaas",
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: "apikey",
+								},
+							},
+						},
+					},
+					{
+						Name: "database",
+						ImportPolicy: &pki_monitor.Policy{
+							Zone: "zone2",
+						},
+						Secret: pki_monitor.UnZonedSecret{
+							Name: "vaas",
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: "apikey",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+const validPKIMonitorPoliciesConfig = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    root {
+      common_name = "Vault Root"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      name = "web"
+      zone = "zone"
+      auto_refresh_interval = 300
+    }
+
+    optional_config {
+      allowed_domains = ["example.com"]
+      allow_subdomains = true
+      allow_ip_sans = false
+      key_type = "ec"
+      key_bits = 256
+      ext_key_usage = ["ServerAuth"]
+    }
+  }
+
+  policy "shared_import" {
+    venafi_secret = "vaas"
+    import_roles = ["web_server"]
+    zone = "zone2"
+    import_timeout = 30
+    import_workers = 5
+  }
+}`
+
+var validPKIMonitorPoliciesConfigResult = &Config{
+	Vault: VaultConfig{
+		VaultAddress: "http://localhost:8200",
+		VaultToken:   "root",
+	},
+	Plugins: []plugins.PluginConfig{
+		{
+			Type:      "venafi-pki-monitor",
+			MountPath: "venafi-pki",
+			Version:   "v0.9.0",
+			Config:    nil,
+			Kind:      api.PluginKindSecret,
+			Impl: &pki_monitor.VenafiPKIMonitorConfig{
+				MountPath: "venafi-pki",
+				Version:   "v0.9.0",
+				CA: &pki_monitor.CA{
+					Root: &venafi.CertificateRequest{
+						CommonName:   "Vault Root",
+						OU:           "VVW",
+						Organisation: "VVW",
+						Locality:     "London",
+						Province:     "London",
+						Country:      "GB",
+						TTL:          "1h",
+					},
+				},
+				Roles: []pki_monitor.Role{
+					{
+						Name: "web_server",
+						EnforcementPolicy: &pki_monitor.Policy{
+							Name:                "web",
+							Zone:                "zone",
+							AutoRefreshInterval: 300,
+						},
+						Secret: pki_monitor.UnZonedSecret{
+							Name: "vaas",
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: "apikey",
+								},
+							},
+						},
+						OptionalConfig: &pki_monitor.OptionalConfig{
+							AllowedDomains:  []string{"example.com"},
+							AllowSubdomains: true,
+							AllowIPSANs:     &falseValue,
+							KeyType:         "ec",
+							KeyBits:         256,
+							ExtKeyUsage:     []string{"ServerAuth"},
+						},
+					},
+				},
+				Policies: []pki_monitor.MountPolicy{
+					{
+						Name:         "shared_import",
+						VenafiSecret: "vaas",
+						ImportRoles:  []string{"web_server"},
+						Policy: pki_monitor.Policy{
+							Zone:          "zone2",
+							ImportTimeout: 30,
+							ImportWorkers: 5,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+var falseValue = false
+
+const invalidPKIMonitorConfigPolicyUndefinedRole = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    root {
+      common_name = "Vault Root"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+  }
+
+  policy "shared_import" {
+    venafi_secret = "vaas"
+    import_roles = ["database"]
+    zone = "zone2"
+  }
+}`
+
+const invalidPKIMonitorConfigMultipleCAs = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    root {
+      common_name = "Vault Root"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+
+    intermediate_certificate {
+      zone = "zone3"
+      common_name = "Vault SubCA"
+      ttl = "1h"
+    }
+  }
+}`
+
+const invalidPKIMonitorConfigMultipleCABlocks = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    root {
+      common_name = "Vault Root"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+    }
+
+    import {
+      certificate = "certificate"
+      private_key = "private key"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+  }
+}`
+
+const invalidPKIMonitorConfigMissingImportFile = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    import {
+      certificate = file("does-not-exist.pem")
+      private_key = file("does-not-exist.key")
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+  }
+}`
+
+const invalidPKIMonitorConfigRenewalWindow = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    renewal_window = "30 days"
+
+    root {
+      common_name = "Vault Root"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+  }
+}`
+
+const invalidPKIMonitorConfigRetrieveTimeout = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    venafi_intermediate {
+      zone = "zone"
+      retrieve_timeout = "2 hours"
+      pending_approval = true
+
+      common_name = "Vault Intermediate"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+  }
+}`
+
+const invalidPKIMonitorConfigIntermediateSecret = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    venafi_intermediate {
+      zone = "zone"
+      venafi_secret = "tpp"
+
+      common_name = "Vault Intermediate"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+  }
+}`
+
+const invalidPKIMonitorConfigDuplicateRoles = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  ca {
+    root {
+      common_name = "Vault Root"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+  }
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    import_policy {
+      zone = "zone2"
+    }
+  }
+}`
+
+const invalidPKIMonitorConfigBlankIntermediateCertificateZone = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+
+  ssh {
+    hostname = "localhost"
+    username = "vagrant"
+    password = "vagrant"
+    port = 22
+  }
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+	enforcement_policy {
+	  zone = "zone"
+	}
+
+    import_policy {
+      zone = "zone2"
+    }
+
+	intermediate_certificate {
+      zone = ""
+	  common_name = "Vault SubCA"
+	  ou = "VVW"
+	  organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+	}
+
+    allow_any_name = true
+    ttl = "1h"
+    max_ttl = "2h"
+  }
+}`
+
+const invalidPKIMonitorConfigBlankEnforcementPolicyZone = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+
+  ssh {
+    hostname = "localhost"
+    username = "vagrant"
+    password = "vagrant"
+    port = 22
+  }
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+	enforcement_policy {
+	  zone = ""
+	}
+
+    import_policy {
+      zone = "zone2"
+    }
+
+	intermediate_certificate {
+      zone = "zone3"
+	  common_name = "Vault SubCA"
+	  ou = "VVW"
+	  organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+	}
+
+    allow_any_name = true
+    ttl = "1h"
+    max_ttl = "2h"
+  }
+}`
+
+const invalidPKIMonitorConfigBlankImportPolicyZone = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+
+  ssh {
+    hostname = "localhost"
+    username = "vagrant"
+    password = "vagrant"
+    port = 22
+  }
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+	enforcement_policy {
+	  zone = "zone"
+	}
+
+    import_policy {
+      zone = ""
+    }
+
 	intermediate_certificate {
       zone = "zone3"
 	  common_name = "Vault SubCA"
@@ -817,6 +1561,41 @@ plugin "venafi-pki-backend" "venafi-pki" {
   }
 }`
 
+const invalidPKIBackendTestCertificateCleanupConfig = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+
+  ssh {
+    hostname = "localhost"
+    username = "vagrant"
+    password = "vagrant"
+    port = 22
+  }
+}
+
+plugin "venafi-pki-backend" "venafi-pki" {
+  version = "v0.9.0"
+  role "vaas" {
+    secret "vaas" {
+      zone = "zone1"
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+    test_certificate {
+      common_name = "test.example.com"
+      ou = "VVW"
+      organisation = "OpenCredo"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+      cleanup = "delete"
+    }
+  }
+}`
+
 const invalidPKIMonitorBuildArchConfig = `
 vault {
   api_address = "http://localhost:8200"
@@ -862,8 +1641,148 @@ plugin "venafi-pki-monitor" "venafi-pki" {
   }
 }`
 
+const invalidPKIMonitorIntermediateCSRFileConfig = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+
+  ssh {
+    hostname = "localhost"
+    username = "vagrant"
+    password = "vagrant"
+    port = 22
+  }
+}
+
+plugin "venafi-pki-monitor" "venafi-pki" {
+  version = "v0.9.0"
+
+  role "web_server" {
+    secret "vaas" {
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+
+    enforcement_policy {
+      zone = "zone"
+    }
+
+    intermediate_certificate {
+      zone = "zone3"
+      common_name = "Vault SubCA"
+      ou = "VVW"
+      organisation = "VVW"
+      locality = "London"
+      province = "London"
+      country = "GB"
+      ttl = "1h"
+      csr_file = "subca.csr"
+    }
+  }
+}`
+
 func deletePluginsUncheckedFields(config *Config) {
 	for i := 0; i < len(config.Plugins); i++ {
 		config.Plugins[i].Config = nil
+		config.Plugins[i].VaultAddress = ""
+		config.Plugins[i].ConfigDir = ""
+
+		if monitorPlugin, ok := config.Plugins[i].Impl.(*pki_monitor.VenafiPKIMonitorConfig); ok {
+			if intermediate := monitorPlugin.CA.VenafiIntermediate; intermediate != nil {
+				intermediate.VaultAddress = ""
+				intermediate.ConfigDir = ""
+			}
+		}
+
+		if genericPlugin, ok := config.Plugins[i].Impl.(*generic.GenericPluginConfig); ok {
+			for j := range genericPlugin.ConfigPaths {
+				genericPlugin.ConfigPaths[j].Body = nil
+			}
+		}
 	}
 }
+
+const invalidVersionedCatalogConfig = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+  versioned_plugin_catalog = true
+}
+
+plugin "venafi-pki-backend" "venafi-pki" {
+  version = "latest"
+  role "vaas" {
+    secret "vaas" {
+      zone = "zone1"
+      venafi_vaas {
+        apikey = "apikey"
+      }
+    }
+  }
+}`
+
+const validGenericConfig = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "generic" "kv-custom" {
+  version = "v1.2.0"
+  repository = "example/vault-plugin-secrets-custom"
+  asset = "custom_{{.VersionNumber}}_{{.OS}}_{{.Arch}}.zip"
+  kind = "secret"
+
+  config "config" {
+    url = "https://example.com"
+    retries = 3
+    allowed = ["a", "b"]
+  }
+}`
+
+var validGenericConfigResult = &Config{
+	Vault: VaultConfig{
+		VaultAddress: "http://localhost:8200",
+		VaultToken:   "root",
+	},
+	Plugins: []plugins.PluginConfig{
+		{
+			Type:      "generic",
+			MountPath: "kv-custom",
+			Version:   "v1.2.0",
+			Kind:      api.PluginKindSecret,
+			Name:      "vault-plugin-secrets-custom",
+			Impl: &generic.GenericPluginConfig{
+				MountPath:  "kv-custom",
+				Version:    "v1.2.0",
+				Repository: "example/vault-plugin-secrets-custom",
+				Asset:      "custom_{{.VersionNumber}}_{{.OS}}_{{.Arch}}.zip",
+				Kind:       "secret",
+				ConfigPaths: []generic.ConfigPath{
+					{
+						Path: "config",
+						Fields: map[string]interface{}{
+							"url":     "https://example.com",
+							"retries": float64(3),
+							"allowed": []interface{}{"a", "b"},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+const invalidGenericKindConfig = `
+vault {
+  api_address = "http://localhost:8200"
+  token = "root"
+}
+
+plugin "generic" "kv-custom" {
+  version = "v1.2.0"
+  repository = "example/vault-plugin-secrets-custom"
+  asset = "custom_{{.Version}}.zip"
+  kind = "unknown"
+}`
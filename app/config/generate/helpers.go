@@ -1,6 +1,7 @@
 package generate
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -10,18 +11,23 @@ import (
 
 func WriteStringAttributeToHCL(attributeName, input string, body *hclwrite.Body) {
 	if strings.HasPrefix(input, "$") {
-		body.SetAttributeRaw(attributeName, envFunctionTokens(input[1:]))
+		body.SetAttributeRaw(attributeName, functionCallTokens("env", input[1:]))
 		return
 	}
 
 	body.SetAttributeValue(attributeName, cty.StringVal(input))
 }
 
-func envFunctionTokens(environmentVariableName string) hclwrite.Tokens {
+// WriteFileAttributeToHCL writes an attribute that reads its value from the file at path using the file function
+func WriteFileAttributeToHCL(attributeName, path string, body *hclwrite.Body) {
+	body.SetAttributeRaw(attributeName, functionCallTokens("file", path))
+}
+
+func functionCallTokens(functionName, argument string) hclwrite.Tokens {
 	return hclwrite.Tokens{
 		&hclwrite.Token{
 			Type:  hclsyntax.TokenIdent,
-			Bytes: []byte("env"),
+			Bytes: []byte(functionName),
 		},
 		&hclwrite.Token{
 			Type:  hclsyntax.TokenOParen,
@@ -29,7 +35,7 @@ func envFunctionTokens(environmentVariableName string) hclwrite.Tokens {
 		},
 		&hclwrite.Token{
 			Type:  hclsyntax.TokenQuotedLit,
-			Bytes: []byte("\"" + environmentVariableName + "\""),
+			Bytes: []byte(strconv.Quote(argument)),
 		},
 		&hclwrite.Token{
 			Type:  hclsyntax.TokenCParen,
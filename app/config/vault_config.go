@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/opencredo/venafi-vault-wizard/app/config/errors"
@@ -13,6 +14,24 @@ type VaultConfig struct {
 	VaultAddress string `hcl:"api_address"`
 	VaultToken   string `hcl:"token"`
 	SSHConfig    []SSH  `hcl:"ssh,block"`
+
+	// PluginDirectory is an optional override for the plugin_directory of the Vault server, used if it can't be read
+	// from sys/config/state/sanitized, for example if the token isn't privileged enough or on HCP Vault
+	PluginDirectory string `hcl:"plugin_directory,optional"`
+	// DisableMlock is an optional override for the disable_mlock option of the Vault server, used in the same way as
+	// PluginDirectory. It is a pointer so that an explicit false can be distinguished from it not being set
+	DisableMlock *bool `hcl:"disable_mlock,optional"`
+	// VaultConfigPath is the location of the Vault server's config file, which is read over SSH to discover the values
+	// above if they can't be read from the API and haven't been overridden. If it isn't specified then it is found by
+	// looking at the running Vault server process.
+	VaultConfigPath string `hcl:"vault_config_path,optional"`
+	// AllowMissingSSH turns the error raised when a cluster member has no corresponding ssh block into a warning
+	AllowMissingSSH bool `hcl:"allow_missing_ssh,optional"`
+	// PluginReloadTimeout is how long to wait for every node in a cluster to reload a plugin, as a duration string
+	PluginReloadTimeout string `hcl:"plugin_reload_timeout,optional"`
+	// VersionedPluginCatalog opts in to registering plugins in the versioned plugin catalog of Vault 1.12+, once per
+	// plugin version, rather than once per mount
+	VersionedPluginCatalog bool `hcl:"versioned_plugin_catalog,optional"`
 }
 
 type SSH struct {
@@ -29,6 +48,12 @@ func (c *VaultConfig) Validate() error {
 	if c.VaultToken == "" {
 		return fmt.Errorf("error with Vault token: %w", errors.ErrBlankParam)
 	}
+	if c.PluginReloadTimeout != "" {
+		_, err := time.ParseDuration(c.PluginReloadTimeout)
+		if err != nil {
+			return fmt.Errorf("error with Vault plugin_reload_timeout: %w", err)
+		}
+	}
 	for _, ssh := range c.SSHConfig {
 		if ssh.Hostname == "" {
 			return fmt.Errorf("error with Vault SSH Hostname: %w", errors.ErrBlankParam)
@@ -46,6 +71,13 @@ func (c *VaultConfig) Validate() error {
 	return nil
 }
 
+// GetPluginReloadTimeout returns PluginReloadTimeout as a time.Duration, or 0 if it isn't set. It should already have
+// been validated by Validate.
+func (c *VaultConfig) GetPluginReloadTimeout() time.Duration {
+	timeout, _ := time.ParseDuration(c.PluginReloadTimeout)
+	return timeout
+}
+
 // WriteHCL uses the hclwrite package to encode itself into HCL. It supports $ENVVARS for the string values, in that
 // format. This allows users in a wizard to specify the string params in a shell-like syntax, which will then be
 // serialised into the HCL syntax of env("ENVVARS")
@@ -56,6 +88,25 @@ func (c *VaultConfig) WriteHCL(hclBody *hclwrite.Body) {
 	generate.WriteStringAttributeToHCL("api_address", c.VaultAddress, vaultConfigBody)
 	generate.WriteStringAttributeToHCL("token", c.VaultToken, vaultConfigBody)
 
+	if c.PluginDirectory != "" {
+		generate.WriteStringAttributeToHCL("plugin_directory", c.PluginDirectory, vaultConfigBody)
+	}
+	if c.DisableMlock != nil {
+		vaultConfigBody.SetAttributeValue("disable_mlock", cty.BoolVal(*c.DisableMlock))
+	}
+	if c.VaultConfigPath != "" {
+		generate.WriteStringAttributeToHCL("vault_config_path", c.VaultConfigPath, vaultConfigBody)
+	}
+	if c.PluginReloadTimeout != "" {
+		generate.WriteStringAttributeToHCL("plugin_reload_timeout", c.PluginReloadTimeout, vaultConfigBody)
+	}
+	if c.AllowMissingSSH {
+		vaultConfigBody.SetAttributeValue("allow_missing_ssh", cty.BoolVal(true))
+	}
+	if c.VersionedPluginCatalog {
+		vaultConfigBody.SetAttributeValue("versioned_plugin_catalog", cty.BoolVal(true))
+	}
+
 	for _, sshHost := range c.SSHConfig {
 		vaultConfigBody.AppendNewline()
 
@@ -1,19 +1,28 @@
 package downloader
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"strings"
 )
 
 type PluginDownloader interface {
-	// DownloadPluginAndUnzip downloads the ZIP archive from the given URL, unzips it, and returns the plugin and its SHA
+	// DownloadPluginAndUnzip downloads the plugin from the given URL, extracting it if it's an archive, and returns the
+	// plugin and its SHA
 	DownloadPluginAndUnzip(url string) ([]byte, string, error)
+	// DownloadPluginWithChecksums is the same as DownloadPluginAndUnzip, but also checks the SHA256 of the downloaded
+	// file against the checksums file at checksumsURL, if it isn't blank
+	DownloadPluginWithChecksums(url, checksumsURL string) ([]byte, string, error)
 }
 
 type downloader struct{}
@@ -23,75 +32,150 @@ func NewPluginDownloader() PluginDownloader {
 	return &downloader{}
 }
 
-func (_ *downloader) DownloadPluginAndUnzip(url string) ([]byte, string, error) {
-	pluginBytes, err := downloadZipFile(url)
+func (d *downloader) DownloadPluginAndUnzip(url string) ([]byte, string, error) {
+	return d.DownloadPluginWithChecksums(url, "")
+}
+
+func (_ *downloader) DownloadPluginWithChecksums(url, checksumsURL string) ([]byte, string, error) {
+	downloadedBytes, err := downloadFile(url)
 	if err != nil {
 		return nil, "", err
 	}
 
-	plugin, expectedSHA, err := extractPluginAndSHA(pluginBytes)
+	if checksumsURL != "" {
+		checksums, err := downloadFile(checksumsURL)
+		if err != nil {
+			return nil, "", fmt.Errorf("error downloading checksums from %s: %w", checksumsURL, err)
+		}
+
+		expectedSHA, err := findChecksum(checksums, path.Base(url))
+		if err != nil {
+			return nil, "", err
+		}
+
+		err = checkSHAsMatch(expectedSHA, getSHAString(downloadedBytes))
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	plugin, expectedSHA, err := extractPluginAndSHA(downloadedBytes)
 	if err != nil {
 		return nil, "", err
 	}
 
 	actualSHA := getSHAString(plugin)
 
-	err = checkSHAsMatch(expectedSHA, actualSHA)
-	if err != nil {
-		return nil, "", err
+	// Only archives with a SHA256SUM file alongside the plugin, like the Venafi plugins, include its SHA
+	if expectedSHA != "" {
+		err = checkSHAsMatch(expectedSHA, actualSHA)
+		if err != nil {
+			return nil, "", err
+		}
 	}
 
-	return plugin, expectedSHA, nil
+	return plugin, actualSHA, nil
 }
 
-func downloadZipFile(url string) ([]byte, error) {
+func downloadFile(url string) ([]byte, error) {
 	response, err := http.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer response.Body.Close()
 
-	pluginBytes, err := io.ReadAll(response.Body)
+	fileBytes, err := io.ReadAll(response.Body)
 	if err != nil {
 		return nil, err
 	}
 
 	if response.StatusCode != 200 {
-		return nil, fmt.Errorf("uh oh")
+		return nil, fmt.Errorf("unexpected status %s downloading %s", response.Status, url)
 	}
 
-	return pluginBytes, nil
+	return fileBytes, nil
 }
 
-func extractPluginAndSHA(zipFile []byte) ([]byte, string, error) {
-	zipReader, err := zip.NewReader(bytes.NewReader(zipFile), int64(len(zipFile)))
-	if err != nil {
-		return nil, "", err
+// findChecksum returns the SHA256 of filename from a checksums file, in the "<sha>  <filename>" format output by
+// sha256sum. A file with only a SHA in it is taken to be the checksum of filename.
+func findChecksum(checksums []byte, filename string) (string, error) {
+	if fields := strings.Fields(string(checksums)); len(fields) == 1 {
+		return strings.ToLower(fields[0]), nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marks files read in binary mode with a *
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == filename {
+			return strings.ToLower(fields[0]), nil
+		}
 	}
 
-	// Check we've got two files, one for the plugin binary, and one for the SHA
-	if len(zipReader.File) != 2 {
-		return nil, "", fmt.Errorf("expected 2 files in the plugin's zip file, got %d", len(zipReader.File))
+	return "", fmt.Errorf("no checksum found for %s", filename)
+}
+
+// extractPluginAndSHA returns the plugin binary from downloaded, which can be a zip or tar.gz archive containing only
+// the plugin and optionally a SHA256SUM file, or the plugin binary itself. The SHA is blank if there's no SHA256SUM file.
+func extractPluginAndSHA(downloaded []byte) ([]byte, string, error) {
+	var files map[string][]byte
+	var err error
+	switch {
+	case bytes.HasPrefix(downloaded, []byte("PK\x03\x04")):
+		files, err = readZipFiles(downloaded)
+	case bytes.HasPrefix(downloaded, []byte("\x1f\x8b")):
+		files, err = readTarGzFiles(downloaded)
+	default:
+		return downloaded, "", nil
+	}
+	if err != nil {
+		return nil, "", err
 	}
 
 	var plugin []byte
 	var expectedSHA string
+	var pluginFiles []string
+	for name, fileBytes := range files {
+		if strings.Contains(path.Base(name), "SHA256SUM") {
+			expectedSHA = strings.ToLower(strings.Fields(string(fileBytes) + " ")[0])
+			continue
+		}
 
-	// Read SHA file to string and get plugin bytes
+		plugin = fileBytes
+		pluginFiles = append(pluginFiles, name)
+	}
+
+	if len(pluginFiles) != 1 {
+		return nil, "", fmt.Errorf(
+			"expected the plugin's archive to contain one plugin binary and optionally a SHA256SUM file, got %d other files",
+			len(pluginFiles),
+		)
+	}
+
+	return plugin, expectedSHA, nil
+}
+
+// readZipFiles returns the contents of each file in a zip archive, keyed by name
+func readZipFiles(zipFile []byte) (map[string][]byte, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(zipFile), int64(len(zipFile)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte, len(zipReader.File))
 	for _, file := range zipReader.File {
-		unzippedBytes, err := readZippedFile(file)
-		if err != nil {
-			return nil, "", err
+		if file.FileInfo().IsDir() {
+			continue
 		}
 
-		if strings.Contains(file.Name, "SHA256SUM") {
-			expectedSHA = strings.TrimSpace(string(unzippedBytes))
-		} else {
-			plugin = unzippedBytes
+		unzippedBytes, err := readZippedFile(file)
+		if err != nil {
+			return nil, err
 		}
+		files[file.Name] = unzippedBytes
 	}
 
-	return plugin, expectedSHA, nil
+	return files, nil
 }
 
 func readZippedFile(file *zip.File) ([]byte, error) {
@@ -109,6 +193,38 @@ func readZippedFile(file *zip.File) ([]byte, error) {
 	return unzipped, nil
 }
 
+// readTarGzFiles returns the contents of each regular file in a gzipped tar archive, keyed by name
+func readTarGzFiles(tarGzFile []byte) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(tarGzFile))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	files := make(map[string][]byte)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		fileBytes, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = fileBytes
+	}
+
+	return files, nil
+}
+
 func getSHAString(file []byte) string {
 	rawHash := sha256.Sum256(file)
 	return hex.EncodeToString(rawHash[:])
@@ -116,7 +232,7 @@ func getSHAString(file []byte) string {
 
 func checkSHAsMatch(expected, actual string) error {
 	if strings.Compare(expected, actual) != 0 {
-		return fmt.Errorf("expected SHA checksums to match")
+		return fmt.Errorf("expected SHA checksums to match, expected %s got %s", expected, actual)
 	}
 
 	return nil
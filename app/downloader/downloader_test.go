@@ -1,6 +1,13 @@
 package downloader
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -17,3 +24,140 @@ func TestDownloadPluginAndUnzip(t *testing.T) {
 
 	require.Equal(t, expectedSHA, actualSHA, "SHAs did not match, expected (%s), got (%s)", expectedSHA, actualSHA)
 }
+
+func TestExtractPluginAndSHA(t *testing.T) {
+	plugin := []byte("plugin binary")
+	pluginSHA := getSHAString(plugin)
+
+	tests := map[string]struct {
+		downloaded  []byte
+		wantSHA     string
+		expectError bool
+	}{
+		"raw binary": {
+			downloaded: plugin,
+		},
+		"zip with only the plugin": {
+			downloaded: newTestZip(t, map[string][]byte{"vault-plugin": plugin}),
+		},
+		"zip with the plugin and its SHA": {
+			downloaded: newTestZip(t, map[string][]byte{
+				"vault-plugin":           plugin,
+				"vault-plugin.SHA256SUM": []byte(pluginSHA + "\n"),
+			}),
+			wantSHA: pluginSHA,
+		},
+		"tar.gz with only the plugin": {
+			downloaded: newTestTarGz(t, map[string][]byte{"vault-plugin": plugin}),
+		},
+		"zip with several binaries": {
+			downloaded: newTestZip(t, map[string][]byte{
+				"vault-plugin": plugin,
+				"other-plugin": plugin,
+				"README.md":    []byte("readme"),
+			}),
+			expectError: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			extracted, sha, err := extractPluginAndSHA(tt.downloaded)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, plugin, extracted)
+			require.Equal(t, tt.wantSHA, sha)
+		})
+	}
+}
+
+func TestDownloadPluginWithChecksums(t *testing.T) {
+	plugin := []byte("plugin binary")
+	asset := newTestTarGz(t, map[string][]byte{"vault-plugin": plugin})
+
+	tests := map[string]struct {
+		checksums   string
+		expectError bool
+	}{
+		"no checksums": {},
+		"matching checksum": {
+			checksums: fmt.Sprintf("%s  other_linux_amd64.tar.gz\n%s  plugin_linux_amd64.tar.gz\n", getSHAString(nil), getSHAString(asset)),
+		},
+		"checksum only": {
+			checksums: getSHAString(asset) + "\n",
+		},
+		"wrong checksum": {
+			checksums:   fmt.Sprintf("%s  plugin_linux_amd64.tar.gz\n", getSHAString(plugin)),
+			expectError: true,
+		},
+		"asset missing from checksums": {
+			checksums:   fmt.Sprintf("%s  other_linux_amd64.tar.gz\n", getSHAString(asset)),
+			expectError: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/plugin_linux_amd64.tar.gz":
+					_, _ = w.Write(asset)
+				case "/checksums.txt":
+					_, _ = w.Write([]byte(tt.checksums))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			checksumsURL := ""
+			if tt.checksums != "" {
+				checksumsURL = server.URL + "/checksums.txt"
+			}
+
+			downloaded, sha, err := NewPluginDownloader().DownloadPluginWithChecksums(
+				server.URL+"/plugin_linux_amd64.tar.gz", checksumsURL,
+			)
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, plugin, downloaded)
+			require.Equal(t, getSHAString(plugin), sha)
+		})
+	}
+}
+
+func newTestZip(t *testing.T, files map[string][]byte) []byte {
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	for name, contents := range files {
+		writer, err := zipWriter.Create(name)
+		require.NoError(t, err)
+		_, err = writer.Write(contents)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+
+	return buffer.Bytes()
+}
+
+func newTestTarGz(t *testing.T, files map[string][]byte) []byte {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, contents := range files {
+		err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(contents)), Typeflag: tar.TypeReg})
+		require.NoError(t, err)
+		_, err = tarWriter.Write(contents)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,9 @@
+package plugins
+
+import "errors"
+
+var (
+	// ErrPendingApproval is returned by Plugin.Configure when it can't finish until something is approved outside of
+	// vvw, such as a certificate request in Venafi. Configuration should be resumed by running apply again later.
+	ErrPendingApproval = errors.New("configuring the plugin is pending approval")
+)
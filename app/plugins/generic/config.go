@@ -0,0 +1,156 @@
+package generic
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/opencredo/venafi-vault-wizard/app/config/errors"
+	"github.com/opencredo/venafi-vault-wizard/app/config/generate"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/questions"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// GenericPluginConfig allows any Vault plugin released on GitHub to be installed, by specifying where to download it
+// from and the config to write to it after it is mounted
+type GenericPluginConfig struct {
+	// MountPath is not decoded directly by using the struct tags, and is instead populated by
+	// ParseConfig when it is initialised
+	MountPath string
+	// Version is not decoded directly by using the struct tags, and is instead populated by ParseConfig
+	// when it is initialised
+	Version string
+	// BuildArch allows defining the build architecture
+	BuildArch string `hcl:"build_arch,optional"`
+
+	// Repository is the GitHub repository the plugin is released from, in the form owner/name
+	Repository string `hcl:"repository"`
+	// Asset is a template for the name of the release asset to download, which can include {{.Version}},
+	// {{.VersionNumber}}, {{.OS}} and {{.Arch}}. The first asset whose name contains it is used.
+	Asset string `hcl:"asset"`
+	// ChecksumsAsset is an optional template, the same as Asset, for the name of a release asset listing the SHA256 of
+	// the asset downloaded, in the format output by sha256sum
+	ChecksumsAsset string `hcl:"checksums_asset,optional"`
+	// Name is the name of the plugin used for its filename and catalog entry, defaulting to the repository name
+	Name string `hcl:"name,optional"`
+	// Kind is the type of Vault plugin, which is one of secret, auth or database, defaulting to secret
+	Kind string `hcl:"kind,optional"`
+
+	ConfigPaths []ConfigPath `hcl:"config,block"`
+}
+
+// ConfigPath is a path relative to the plugin's mount and the fields to write to it after the plugin is mounted
+type ConfigPath struct {
+	Path string   `hcl:"path,label"`
+	Body hcl.Body `hcl:",remain"`
+
+	// Fields is not decoded directly by using the struct tags, and is instead populated by ParseConfig from Body, as
+	// the attributes can be anything the plugin accepts
+	Fields map[string]interface{}
+}
+
+func (c *GenericPluginConfig) ValidateConfig() error {
+	err := plugins.ValidateBuildArch(c.BuildArch)
+	if err != nil {
+		return err
+	}
+	if c.Repository == "" {
+		return fmt.Errorf("error repository must be provided: %w", errors.ErrBlankParam)
+	}
+	if len(strings.Split(c.Repository, "/")) != 2 {
+		return fmt.Errorf("error repository %s must be in the form owner/name", c.Repository)
+	}
+	if c.Asset == "" {
+		return fmt.Errorf("error asset must be provided: %w", errors.ErrBlankParam)
+	}
+	_, err = c.getAssetSubstring()
+	if err != nil {
+		return err
+	}
+	if c.ChecksumsAsset != "" {
+		_, err = c.executeAssetTemplate(c.ChecksumsAsset)
+		if err != nil {
+			return err
+		}
+	}
+	if c.Kind != "" {
+		_, err = api.ParsePluginKind(c.Kind)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetName returns the Name of the plugin, defaulting to the name of its repository
+func (c *GenericPluginConfig) GetName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+
+	return path.Base(c.Repository)
+}
+
+// GetKind returns the Kind of the plugin, defaulting to PluginKindSecret
+func (c *GenericPluginConfig) GetKind() api.PluginKind {
+	kind, err := api.ParsePluginKind(c.Kind)
+	if err != nil {
+		return api.PluginKindSecret
+	}
+
+	return kind
+}
+
+func (c *GenericPluginConfig) WriteHCL(hclBody *hclwrite.Body) {
+	generate.WriteStringAttributeToHCL("repository", c.Repository, hclBody)
+	generate.WriteStringAttributeToHCL("asset", c.Asset, hclBody)
+	if c.ChecksumsAsset != "" {
+		generate.WriteStringAttributeToHCL("checksums_asset", c.ChecksumsAsset, hclBody)
+	}
+	if c.Name != "" {
+		generate.WriteStringAttributeToHCL("name", c.Name, hclBody)
+	}
+	if c.Kind != "" {
+		generate.WriteStringAttributeToHCL("kind", c.Kind, hclBody)
+	}
+}
+
+func (c *GenericPluginConfig) GenerateConfigAndWriteHCL(questioner questions.Questioner, hclBody *hclwrite.Body) error {
+	q := map[string]questions.Question{
+		"repository": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "Which GitHub repository is the plugin released from (owner/name)?",
+		}),
+		"asset": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "What is the name of the release asset to download ({{.Version}}, {{.OS}} and {{.Arch}} can be used)?",
+		}),
+		"kind": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "What kind of Vault plugin is it?",
+			Items:    []string{"Secrets engine", "Auth method", "Database plugin"},
+		}),
+	}
+	err := questions.AskQuestions([]questions.Question{
+		q["repository"],
+		q["asset"],
+		q["kind"],
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Repository = string(q["repository"].Answer())
+	c.Asset = string(q["asset"].Answer())
+	switch q["kind"].Answer() {
+	case "Auth method":
+		c.Kind = string(api.PluginKindAuth)
+	case "Database plugin":
+		c.Kind = string(api.PluginKindDatabase)
+	}
+
+	c.WriteHCL(hclBody)
+	return nil
+}
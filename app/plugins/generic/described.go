@@ -0,0 +1,171 @@
+package generic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/opencredo/venafi-vault-wizard/app/config/generate"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/questions"
+)
+
+// DescribedPluginConfig is a plugin whose type is described by a Descriptor, which provides everything that would be
+// given in the plugin block of the generic plugin type except its config blocks. These are validated against the
+// Descriptor's schema.
+type DescribedPluginConfig struct {
+	GenericPluginConfig
+
+	Descriptor *Descriptor
+}
+
+// describedPluginBody is the part of the plugin block decoded for described plugins
+type describedPluginBody struct {
+	BuildArch   string       `hcl:"build_arch,optional"`
+	ConfigPaths []ConfigPath `hcl:"config,block"`
+}
+
+// NewDescribedPlugin creates a DescribedPluginConfig for the plugin type described by descriptor
+func NewDescribedPlugin(descriptor *Descriptor) *DescribedPluginConfig {
+	return &DescribedPluginConfig{
+		GenericPluginConfig: descriptor.newPluginConfig(),
+		Descriptor:          descriptor,
+	}
+}
+
+func (c *DescribedPluginConfig) ParseConfig(config *plugins.PluginConfig, evalContext *hcl.EvalContext) error {
+	c.MountPath = config.MountPath
+	c.Version = config.Version
+	c.BuildArch = config.BuildArch
+
+	var body describedPluginBody
+	diagnostics := gohcl.DecodeBody(config.Config, evalContext, &body)
+	if diagnostics.HasErrors() {
+		return diagnostics
+	}
+	if body.BuildArch != "" {
+		c.BuildArch = body.BuildArch
+	}
+
+	c.ConfigPaths = body.ConfigPaths
+	for i := range c.ConfigPaths {
+		err := c.ConfigPaths[i].parseFields(evalContext)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *DescribedPluginConfig) ValidateConfig() error {
+	err := c.GenericPluginConfig.ValidateConfig()
+	if err != nil {
+		return err
+	}
+
+	return c.Descriptor.validateConfigPaths(c.ConfigPaths)
+}
+
+func (c *DescribedPluginConfig) GenerateConfigAndWriteHCL(questioner questions.Questioner, hclBody *hclwrite.Body) error {
+	for _, pathSchema := range c.Descriptor.ConfigPaths {
+		// There's no way of knowing which paths to ask about for wildcards, so they have to be added to the config later
+		if pathSchema.hasWildcard() {
+			continue
+		}
+
+		fieldTokens := make(map[string]hclwrite.Tokens)
+		var fieldNames []string
+		for _, fieldSchema := range pathSchema.Fields {
+			tokens, err := askForField(questioner, pathSchema.Path, fieldSchema)
+			if err != nil {
+				return err
+			}
+			if tokens == nil {
+				continue
+			}
+
+			fieldTokens[fieldSchema.Name] = tokens
+			fieldNames = append(fieldNames, fieldSchema.Name)
+		}
+
+		if len(fieldNames) == 0 && !pathSchema.Required {
+			continue
+		}
+
+		hclBody.AppendNewline()
+		configBody := hclBody.AppendNewBlock("config", []string{pathSchema.Path}).Body()
+		for _, name := range fieldNames {
+			configBody.SetAttributeRaw(name, fieldTokens[name])
+		}
+	}
+
+	return nil
+}
+
+// askForField asks for the value of a field, returning the tokens to write for it, or nil if it isn't required and
+// wasn't given. Fields of type map or any can't be asked for, so are left out.
+func askForField(questioner questions.Questioner, path string, fieldSchema FieldSchema) (hclwrite.Tokens, error) {
+	fieldType := fieldSchema.getType()
+	if fieldType == "map" || fieldType == "any" {
+		return nil, nil
+	}
+
+	question := fmt.Sprintf("What should %s be set to in %s?", fieldSchema.Name, path)
+	if fieldSchema.Description != "" {
+		question = fmt.Sprintf("%s (%s)", question, fieldSchema.Description)
+	}
+	if fieldType == "list" {
+		question += " Separate multiple values with commas"
+	}
+	if !fieldSchema.Required {
+		question += " Leave blank to skip"
+	}
+
+	fieldQuestion := questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+		Question: question,
+	})
+	err := fieldQuestion.Ask()
+	if err != nil {
+		return nil, err
+	}
+
+	answer := string(fieldQuestion.Answer())
+	if answer == "" {
+		if fieldSchema.Required {
+			return nil, fmt.Errorf("%s must be set in %s", fieldSchema.Name, path)
+		}
+		return nil, nil
+	}
+
+	switch fieldType {
+	case "number":
+		number, err := strconv.ParseFloat(answer, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number: %w", fieldSchema.Name, err)
+		}
+		return hclwrite.TokensForValue(cty.NumberFloatVal(number)), nil
+	case "bool":
+		boolean, err := strconv.ParseBool(answer)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false: %w", fieldSchema.Name, err)
+		}
+		return hclwrite.TokensForValue(cty.BoolVal(boolean)), nil
+	case "list":
+		var values []cty.Value
+		for _, value := range strings.Split(answer, ",") {
+			values = append(values, cty.StringVal(strings.TrimSpace(value)))
+		}
+		return hclwrite.TokensForValue(cty.ListVal(values)), nil
+	default:
+		// Write to a throwaway body so that environment variables can be referred to in the same way as elsewhere
+		body := hclwrite.NewEmptyFile().Body()
+		generate.WriteStringAttributeToHCL(fieldSchema.Name, answer, body)
+		return body.GetAttribute(fieldSchema.Name).Expr().BuildTokens(nil), nil
+	}
+}
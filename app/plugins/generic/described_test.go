@@ -0,0 +1,128 @@
+package generic
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/questions"
+	mockQuestions "github.com/opencredo/venafi-vault-wizard/mocks/app/questions"
+)
+
+var testDescriptor = &Descriptor{
+	Type:       "custom-secrets",
+	Repository: "example/vault-plugin-secrets-custom",
+	Asset:      "custom_{{.Version}}.zip",
+	ConfigPaths: []ConfigPathSchema{
+		{
+			Path:     "config",
+			Required: true,
+			Fields: []FieldSchema{
+				{Name: "url", Required: true, Description: "The URL of the backend"},
+				{Name: "retries", Type: "number"},
+				{Name: "verbose", Type: "bool"},
+			},
+		},
+		{
+			Path: "roles/*",
+			Fields: []FieldSchema{
+				{Name: "allowed_domains", Type: "list"},
+			},
+		},
+		{
+			Path: "rotate-root",
+		},
+	},
+}
+
+func TestDescribedPluginConfig_ValidateConfig(t *testing.T) {
+	tests := map[string]struct {
+		configPaths []ConfigPath
+		wantErr     bool
+	}{
+		"valid": {
+			configPaths: []ConfigPath{
+				{Path: "config", Fields: map[string]interface{}{"url": "https://example.com", "retries": float64(3)}},
+				{Path: "roles/web", Fields: map[string]interface{}{"allowed_domains": []interface{}{"example.com"}}},
+				{Path: "rotate-root", Fields: map[string]interface{}{"anything": "goes"}},
+			},
+		},
+		"missing required path": {
+			configPaths: []ConfigPath{
+				{Path: "roles/web"},
+			},
+			wantErr: true,
+		},
+		"missing required field": {
+			configPaths: []ConfigPath{
+				{Path: "config", Fields: map[string]interface{}{"retries": float64(3)}},
+			},
+			wantErr: true,
+		},
+		"unsupported field": {
+			configPaths: []ConfigPath{
+				{Path: "config", Fields: map[string]interface{}{"url": "https://example.com", "unknown": "field"}},
+			},
+			wantErr: true,
+		},
+		"wrong field type": {
+			configPaths: []ConfigPath{
+				{Path: "config", Fields: map[string]interface{}{"url": "https://example.com", "retries": "three"}},
+			},
+			wantErr: true,
+		},
+		"unsupported path": {
+			configPaths: []ConfigPath{
+				{Path: "config", Fields: map[string]interface{}{"url": "https://example.com"}},
+				{Path: "roles/web/extra"},
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := NewDescribedPlugin(testDescriptor)
+			config.ConfigPaths = tt.configPaths
+
+			err := config.ValidateConfig()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDescribedPluginConfig_GenerateConfigAndWriteHCL(t *testing.T) {
+	questioner := new(mockQuestions.Questioner)
+	defer questioner.AssertExpectations(t)
+
+	expectFieldQuestion(questioner, "What should url be set to in config? (The URL of the backend)", "$BACKEND_URL")
+	expectFieldQuestion(questioner, "What should retries be set to in config? Leave blank to skip", "3")
+	expectFieldQuestion(questioner, "What should verbose be set to in config? Leave blank to skip", "")
+
+	config := NewDescribedPlugin(testDescriptor)
+	file := hclwrite.NewEmptyFile()
+	err := config.GenerateConfigAndWriteHCL(questioner, file.Body())
+	require.NoError(t, err)
+
+	require.Equal(t, `
+config "config" {
+  url     = env("BACKEND_URL")
+  retries = 3
+}
+`, string(file.Bytes()))
+}
+
+func expectFieldQuestion(questioner *mockQuestions.Questioner, question, answer string) {
+	mockQuestion := new(mockQuestions.Question)
+	mockQuestion.On("Ask").Return(nil)
+	mockQuestion.On("Answer").Return(questions.Answer(answer))
+	questioner.On(
+		"NewOpenEndedQuestion",
+		mock.MatchedBy(func(q *questions.OpenEndedQuestion) bool { return q.Question == question }),
+	).Once().Return(mockQuestion)
+}
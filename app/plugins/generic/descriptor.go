@@ -0,0 +1,234 @@
+package generic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opencredo/venafi-vault-wizard/app/config/errors"
+)
+
+var fieldTypes = []string{"string", "number", "bool", "list", "map", "any"}
+
+// Descriptor declaratively describes a plugin type, so that it can be installed by referring to it by name in a plugin
+// block without having to specify where to download it from, and so that its config can be validated
+type Descriptor struct {
+	// Type is the name of the plugin type, used as the first label of plugin blocks
+	Type string `hcl:"type,label"`
+	// Repository is the GitHub repository the plugin is released from, in the form owner/name
+	Repository string `hcl:"repository"`
+	// Asset is a template for the name of the release asset to download, the same as GenericPluginConfig.Asset
+	Asset string `hcl:"asset"`
+	// ChecksumsAsset is an optional template for the name of a release asset listing checksums, the same as
+	// GenericPluginConfig.ChecksumsAsset
+	ChecksumsAsset string `hcl:"checksums_asset,optional"`
+	// CatalogName is the name of the plugin used for its filename and catalog entry, defaulting to Type
+	CatalogName string `hcl:"catalog_name,optional"`
+	// Kind is the type of Vault plugin, which is one of secret, auth or database, defaulting to secret
+	Kind string `hcl:"kind,optional"`
+
+	ConfigPaths []ConfigPathSchema `hcl:"config_path,block"`
+}
+
+// ConfigPathSchema describes a path, relative to the plugin's mount, that can be given in a config block. Segments of
+// the path can be * to allow any value, such as a role name.
+type ConfigPathSchema struct {
+	Path string `hcl:"path,label"`
+	// Required is whether a config block must be given for the path, which can't be set if the path has wildcards
+	Required bool `hcl:"required,optional"`
+
+	// Fields are the fields that can be written to the path. If none are given then any fields are allowed.
+	Fields []FieldSchema `hcl:"field,block"`
+}
+
+// FieldSchema describes a field of a config path
+type FieldSchema struct {
+	Name string `hcl:"name,label"`
+	// Type is one of string, number, bool, list, map or any, defaulting to string
+	Type        string `hcl:"type,optional"`
+	Required    bool   `hcl:"required,optional"`
+	Description string `hcl:"description,optional"`
+}
+
+func (d *Descriptor) Validate() error {
+	if d.Type == "" {
+		return fmt.Errorf("error plugin type name must be provided: %w", errors.ErrBlankParam)
+	}
+
+	pluginConfig := d.newPluginConfig()
+	err := pluginConfig.ValidateConfig()
+	if err != nil {
+		return fmt.Errorf("error with plugin type %s: %w", d.Type, err)
+	}
+
+	for _, pathSchema := range d.ConfigPaths {
+		if pathSchema.Required && pathSchema.hasWildcard() {
+			return fmt.Errorf(
+				"error with plugin type %s, config path %s can't be required as it has wildcards", d.Type, pathSchema.Path,
+			)
+		}
+
+		for _, fieldSchema := range pathSchema.Fields {
+			if !isValidFieldType(fieldSchema.Type) {
+				return fmt.Errorf(
+					"error with plugin type %s, field %s of config path %s has type %s which must be one of %v",
+					d.Type, fieldSchema.Name, pathSchema.Path, fieldSchema.Type, fieldTypes,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetCatalogName returns CatalogName, defaulting to Type
+func (d *Descriptor) GetCatalogName() string {
+	if d.CatalogName != "" {
+		return d.CatalogName
+	}
+
+	return d.Type
+}
+
+// newPluginConfig creates a GenericPluginConfig for downloading and configuring the plugin described
+func (d *Descriptor) newPluginConfig() GenericPluginConfig {
+	return GenericPluginConfig{
+		Repository:     d.Repository,
+		Asset:          d.Asset,
+		ChecksumsAsset: d.ChecksumsAsset,
+		Name:           d.GetCatalogName(),
+		Kind:           d.Kind,
+	}
+}
+
+// validateConfigPaths checks the config blocks given for the plugin against the schema of its config paths
+func (d *Descriptor) validateConfigPaths(configPaths []ConfigPath) error {
+	given := make(map[string]bool, len(configPaths))
+	for _, configPath := range configPaths {
+		pathSchema := d.findConfigPath(configPath.Path)
+		if pathSchema == nil {
+			return fmt.Errorf("error config path %s isn't supported by plugin type %s", configPath.Path, d.Type)
+		}
+		given[pathSchema.Path] = true
+
+		err := pathSchema.validateFields(configPath.Fields)
+		if err != nil {
+			return fmt.Errorf("error with config path %s: %w", configPath.Path, err)
+		}
+	}
+
+	for _, pathSchema := range d.ConfigPaths {
+		if pathSchema.Required && !given[pathSchema.Path] {
+			return fmt.Errorf("error config path %s must be provided: %w", pathSchema.Path, errors.ErrBlankParam)
+		}
+	}
+
+	return nil
+}
+
+func (d *Descriptor) findConfigPath(path string) *ConfigPathSchema {
+	for i, pathSchema := range d.ConfigPaths {
+		if pathSchema.matches(path) {
+			return &d.ConfigPaths[i]
+		}
+	}
+
+	return nil
+}
+
+func (s *ConfigPathSchema) hasWildcard() bool {
+	for _, segment := range strings.Split(s.Path, "/") {
+		if segment == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches returns whether path is the schema's path, with any wildcard segments matching any single segment
+func (s *ConfigPathSchema) matches(path string) bool {
+	schemaSegments := strings.Split(strings.Trim(s.Path, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(schemaSegments) != len(segments) {
+		return false
+	}
+
+	for i, segment := range segments {
+		if schemaSegments[i] != "*" && schemaSegments[i] != segment {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *ConfigPathSchema) validateFields(fields map[string]interface{}) error {
+	if len(s.Fields) == 0 {
+		return nil
+	}
+
+	fieldSchemas := make(map[string]FieldSchema, len(s.Fields))
+	for _, fieldSchema := range s.Fields {
+		fieldSchemas[fieldSchema.Name] = fieldSchema
+
+		if _, ok := fields[fieldSchema.Name]; fieldSchema.Required && !ok {
+			return fmt.Errorf("error field %s must be provided: %w", fieldSchema.Name, errors.ErrBlankParam)
+		}
+	}
+
+	for name, value := range fields {
+		fieldSchema, ok := fieldSchemas[name]
+		if !ok {
+			return fmt.Errorf("error field %s isn't supported", name)
+		}
+		if !fieldSchema.matchesType(value) {
+			return fmt.Errorf("error field %s must be a %s", name, fieldSchema.getType())
+		}
+	}
+
+	return nil
+}
+
+func (f *FieldSchema) getType() string {
+	if f.Type == "" {
+		return "string"
+	}
+
+	return f.Type
+}
+
+// matchesType checks the type of a field's value, as converted by ConfigPath.parseFields
+func (f *FieldSchema) matchesType(value interface{}) bool {
+	switch f.getType() {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	case "list":
+		_, ok := value.([]interface{})
+		return ok
+	case "map":
+		_, ok := value.(map[string]interface{})
+		return ok
+	default:
+		return true
+	}
+}
+
+func isValidFieldType(fieldType string) bool {
+	if fieldType == "" {
+		return true
+	}
+	for _, t := range fieldTypes {
+		if fieldType == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+package generic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/opencredo/venafi-vault-wizard/app/github"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// buildPlatforms maps each build_arch option to the OS and architecture names used by Go, which is how most plugin
+// release assets are named
+var buildPlatforms = map[string]struct{ OS, Arch string }{
+	"":          {"linux", "amd64"},
+	"linux":     {"linux", "amd64"},
+	"linux86":   {"linux", "386"},
+	"windows":   {"windows", "amd64"},
+	"windows86": {"windows", "386"},
+	"darwin":    {"darwin", "amd64"},
+}
+
+// assetTemplateData is what is available to the asset template
+type assetTemplateData struct {
+	Version       string
+	VersionNumber string
+	BuildArch     string
+	OS            string
+	Arch          string
+}
+
+func (c *GenericPluginConfig) GetDownloadURL() (string, error) {
+	assetSubstring, err := c.getAssetSubstring()
+	if err != nil {
+		return "", err
+	}
+
+	return github.GetRelease(c.Repository, c.Version, assetSubstring)
+}
+
+// GetChecksumsURL returns the URL of the ChecksumsAsset, or blank if it isn't given
+func (c *GenericPluginConfig) GetChecksumsURL() (string, error) {
+	if c.ChecksumsAsset == "" {
+		return "", nil
+	}
+
+	checksumsSubstring, err := c.executeAssetTemplate(c.ChecksumsAsset)
+	if err != nil {
+		return "", err
+	}
+
+	return github.GetRelease(c.Repository, c.Version, checksumsSubstring)
+}
+
+// getAssetSubstring executes the Asset template for the plugin's version and build architecture
+func (c *GenericPluginConfig) getAssetSubstring() (string, error) {
+	return c.executeAssetTemplate(c.Asset)
+}
+
+// executeAssetTemplate executes an asset name template for the plugin's version and build architecture
+func (c *GenericPluginConfig) executeAssetTemplate(assetName string) (string, error) {
+	assetTemplate, err := template.New("asset").Option("missingkey=error").Parse(assetName)
+	if err != nil {
+		return "", fmt.Errorf("error parsing asset template %s: %w", assetName, err)
+	}
+
+	platform := buildPlatforms[c.BuildArch]
+	var asset bytes.Buffer
+	err = assetTemplate.Execute(&asset, &assetTemplateData{
+		Version:       c.Version,
+		VersionNumber: strings.TrimPrefix(c.Version, "v"),
+		BuildArch:     c.BuildArch,
+		OS:            platform.OS,
+		Arch:          platform.Arch,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error executing asset template %s: %w", assetName, err)
+	}
+
+	return asset.String(), nil
+}
+
+// getConfigPath returns the Vault API path of a config path relative to the plugin's mount
+func (c *GenericPluginConfig) getConfigPath(configPath string) string {
+	mountPath := strings.Trim(c.MountPath, "/")
+	if c.GetKind() == api.PluginKindAuth {
+		mountPath = "auth/" + mountPath
+	}
+
+	return fmt.Sprintf("%s/%s", mountPath, strings.Trim(configPath, "/"))
+}
+
+func (c *GenericPluginConfig) Configure(report reporter.Report, vaultClient api.VaultAPIClient) error {
+	if len(c.ConfigPaths) == 0 {
+		return nil
+	}
+
+	configurePluginSection := report.AddSection(fmt.Sprintf("Setting up %s", c.GetName()))
+	for _, configPath := range c.ConfigPaths {
+		path := c.getConfigPath(configPath.Path)
+		check := configurePluginSection.AddCheck(fmt.Sprintf("Writing config to %s...", path))
+
+		_, err := vaultClient.WriteValue(path, configPath.Fields)
+		if err != nil {
+			check.Errorf("Error writing config to %s: %s", path, err)
+			return err
+		}
+
+		check.Success("Config written to " + path)
+	}
+
+	return nil
+}
+
+func (c *GenericPluginConfig) Check(report reporter.Report, vaultClient api.VaultAPIClient) error {
+	if len(c.ConfigPaths) == 0 {
+		return nil
+	}
+
+	checkConfigSection := report.AddSection(fmt.Sprintf("Checking config of %s", c.GetName()))
+	for _, configPath := range c.ConfigPaths {
+		path := c.getConfigPath(configPath.Path)
+		check := checkConfigSection.AddCheck(fmt.Sprintf("Checking config at %s...", path))
+
+		_, err := vaultClient.ReadValue(path)
+		if err != nil {
+			// Plenty of plugins have config paths that can only be written to, so don't fail if it can't be read back
+			if errors.Is(err, vault.ErrNotFound) {
+				check.Warning("Config at " + path + " can't be read back to check it")
+				continue
+			}
+
+			check.Errorf("Error reading config at %s: %s", path, err)
+			return err
+		}
+
+		check.Success("Config found at " + path)
+	}
+
+	return nil
+}
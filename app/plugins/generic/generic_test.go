@@ -0,0 +1,132 @@
+package generic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestGetAssetSubstring(t *testing.T) {
+	tests := map[string]struct {
+		asset     string
+		buildArch string
+		want      string
+		wantErr   bool
+	}{
+		"default build arch": {
+			asset: "plugin_{{.VersionNumber}}_{{.OS}}_{{.Arch}}.zip",
+			want:  "plugin_1.2.0_linux_amd64.zip",
+		},
+		"32bit windows": {
+			asset:     "plugin_{{.Version}}_{{.OS}}_{{.Arch}}.zip",
+			buildArch: "windows86",
+			want:      "plugin_v1.2.0_windows_386.zip",
+		},
+		"build arch as in other plugins": {
+			asset:     "{{.BuildArch}}.zip",
+			buildArch: "darwin",
+			want:      "darwin.zip",
+		},
+		"no templating": {
+			asset: "linux.zip",
+			want:  "linux.zip",
+		},
+		"unknown field": {
+			asset:   "{{.Unknown}}.zip",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := &GenericPluginConfig{
+				Version:   "v1.2.0",
+				BuildArch: tt.buildArch,
+				Asset:     tt.asset,
+			}
+
+			got, err := config.getAssetSubstring()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigureGenericPlugin(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	config := &GenericPluginConfig{
+		MountPath:  "custom",
+		Repository: "example/vault-plugin-auth-custom",
+		Kind:       string(api.PluginKindAuth),
+		ConfigPaths: []ConfigPath{
+			{Path: "config", Fields: map[string]interface{}{"url": "https://example.com"}},
+			{Path: "role/test", Fields: map[string]interface{}{"ttl": "1h"}},
+		},
+	}
+
+	// Auth methods are mounted under auth/
+	vaultAPIClient.On("WriteValue", "auth/custom/config", map[string]interface{}{"url": "https://example.com"}).
+		Return(nil, nil)
+	vaultAPIClient.On("WriteValue", "auth/custom/role/test", map[string]interface{}{"ttl": "1h"}).
+		Return(nil, nil)
+
+	err := config.Configure(report, vaultAPIClient)
+	require.NoError(t, err)
+}
+
+func TestCheckGenericPlugin(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+	check.On("Warning", mock.AnythingOfType("string"))
+
+	config := &GenericPluginConfig{
+		MountPath:  "custom",
+		Repository: "example/vault-plugin-secrets-custom",
+		ConfigPaths: []ConfigPath{
+			{Path: "config"},
+			{Path: "rotate-root"},
+		},
+	}
+
+	vaultAPIClient.On("ReadValue", "custom/config").Return(map[string]interface{}{}, nil)
+	// Write only paths should only warn
+	vaultAPIClient.On("ReadValue", "custom/rotate-root").Return(nil, vault.ErrNotFound)
+
+	err := config.Check(report, vaultAPIClient)
+	require.NoError(t, err)
+}
+
+func reportExpectations(report *mockReport.Report, section *mockReport.Section, check *mockReport.Check) {
+	report.On("AddSection", mock.AnythingOfType("string")).Return(section).Maybe()
+	section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+	section.On("Info", mock.AnythingOfType("string")).Maybe()
+	check.On("UpdateStatus", mock.AnythingOfType("string")).Maybe()
+	check.On("Success", mock.AnythingOfType("string"))
+}
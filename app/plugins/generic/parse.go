@@ -0,0 +1,63 @@
+package generic
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+)
+
+func (c *GenericPluginConfig) ParseConfig(config *plugins.PluginConfig, evalContext *hcl.EvalContext) error {
+	c.MountPath = config.MountPath
+	c.Version = config.Version
+	c.BuildArch = config.BuildArch
+
+	diagnostics := gohcl.DecodeBody(config.Config, evalContext, c)
+	if diagnostics.HasErrors() {
+		return diagnostics
+	}
+
+	for i := range c.ConfigPaths {
+		err := c.ConfigPaths[i].parseFields(evalContext)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseFields evaluates the attributes of the config block into Fields, converting them to the same types they would
+// have if decoded from JSON so that they can be written to Vault as they are
+func (p *ConfigPath) parseFields(evalContext *hcl.EvalContext) error {
+	attributes, diagnostics := p.Body.JustAttributes()
+	if diagnostics.HasErrors() {
+		return diagnostics
+	}
+
+	p.Fields = make(map[string]interface{}, len(attributes))
+	for name, attribute := range attributes {
+		value, diagnostics := attribute.Expr.Value(evalContext)
+		if diagnostics.HasErrors() {
+			return diagnostics
+		}
+
+		valueJSON, err := ctyjson.Marshal(value, value.Type())
+		if err != nil {
+			return fmt.Errorf("error converting field %s of config %s: %w", name, p.Path, err)
+		}
+
+		var field interface{}
+		err = json.Unmarshal(valueJSON, &field)
+		if err != nil {
+			return fmt.Errorf("error converting field %s of config %s: %w", name, p.Path, err)
+		}
+		p.Fields[name] = field
+	}
+
+	return nil
+}
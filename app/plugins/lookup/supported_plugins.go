@@ -2,8 +2,15 @@ package lookup
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/generic"
 	pki_backend "github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/pki-backend"
 	pki_monitor "github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/pki-monitor"
 )
@@ -21,6 +28,9 @@ var supportedPlugins = map[string]PluginImplConstructor{
 	"venafi-pki-monitor": func() plugins.Plugin {
 		return &pki_monitor.VenafiPKIMonitorConfig{}
 	},
+	"generic": func() plugins.Plugin {
+		return &generic.GenericPluginConfig{}
+	},
 }
 
 // GetPlugin goes from a generic config.Plugin and looks up its specific PluginImpl based on the Type field.
@@ -33,10 +43,68 @@ func GetPlugin(pluginType string) (plugins.Plugin, error) {
 	return constructor(), nil
 }
 
-// SupportedPluginNames returns a list of the names of all of the supported plugins
+// SupportedPluginNames returns a list of the names of all of the supported plugins, including any loaded from a plugin
+// registry, sorted so that they are always listed in the same order
 func SupportedPluginNames() (names []string) {
 	for name := range supportedPlugins {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return
 }
+
+// pluginRegistryFile is the schema of the descriptor files in a plugin registry, each of which can describe any number
+// of plugin types
+type pluginRegistryFile struct {
+	PluginTypes []generic.Descriptor `hcl:"plugin_type,block"`
+}
+
+// LoadPluginRegistry loads the plugin types described by the HCL or JSON descriptor files in registryDir, so that they
+// are supported alongside the built-in plugin types. Returns an error if any plugin types are already supported.
+func LoadPluginRegistry(registryDir string) error {
+	entries, err := os.ReadDir(registryDir)
+	if err != nil {
+		return fmt.Errorf("error reading plugin registry %s: %w", registryDir, err)
+	}
+
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if entry.IsDir() || (extension != ".hcl" && extension != ".json") {
+			continue
+		}
+
+		err = loadPluginRegistryFile(filepath.Join(registryDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func loadPluginRegistryFile(filename string) error {
+	var registryFile pluginRegistryFile
+	err := hclsimple.DecodeFile(filename, nil, &registryFile)
+	if err != nil {
+		return fmt.Errorf("error reading plugin descriptor file %s: %w", filename, err)
+	}
+
+	for i := range registryFile.PluginTypes {
+		descriptor := &registryFile.PluginTypes[i]
+		err = descriptor.Validate()
+		if err != nil {
+			return fmt.Errorf("error in plugin descriptor file %s: %w", filename, err)
+		}
+		if _, ok := supportedPlugins[descriptor.Type]; ok {
+			return fmt.Errorf(
+				"error in plugin descriptor file %s, plugin type %s is already supported", filename, descriptor.Type,
+			)
+		}
+
+		supportedPlugins[descriptor.Type] = func() plugins.Plugin {
+			return generic.NewDescribedPlugin(descriptor)
+		}
+	}
+
+	return nil
+}
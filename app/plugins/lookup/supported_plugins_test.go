@@ -0,0 +1,105 @@
+package lookup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/generic"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+const customSecretsDescriptor = `
+plugin_type "custom-secrets" {
+  repository = "example/vault-plugin-secrets-custom"
+  asset = "custom_{{.VersionNumber}}_{{.OS}}_{{.Arch}}.zip"
+
+  config_path "config" {
+    required = true
+
+    field "url" {
+      required = true
+    }
+  }
+}
+`
+
+const customAuthDescriptor = `{
+  "plugin_type": {
+    "custom-auth": {
+      "repository": "example/vault-plugin-auth-custom",
+      "asset": "custom_{{.Version}}.zip",
+      "catalog_name": "vault-plugin-auth-custom",
+      "kind": "auth"
+    }
+  }
+}`
+
+func TestLoadPluginRegistry(t *testing.T) {
+	registryDir := t.TempDir()
+	writeDescriptor(t, registryDir, "secrets.hcl", customSecretsDescriptor)
+	writeDescriptor(t, registryDir, "auth.json", customAuthDescriptor)
+	writeDescriptor(t, registryDir, "README.md", "Not a descriptor")
+	t.Cleanup(func() {
+		delete(supportedPlugins, "custom-secrets")
+		delete(supportedPlugins, "custom-auth")
+	})
+
+	err := LoadPluginRegistry(registryDir)
+	require.NoError(t, err)
+
+	require.Equal(
+		t,
+		[]string{"custom-auth", "custom-secrets", "generic", "venafi-pki-backend", "venafi-pki-monitor"},
+		SupportedPluginNames(),
+	)
+
+	plugin, err := GetPlugin("custom-auth")
+	require.NoError(t, err)
+	require.IsType(t, &generic.DescribedPluginConfig{}, plugin)
+	require.Equal(t, api.PluginKindAuth, plugin.GetKind())
+	require.Equal(t, "vault-plugin-auth-custom", plugin.(*generic.DescribedPluginConfig).GetName())
+
+	// Each plugin block should get its own instance
+	otherPlugin, err := GetPlugin("custom-auth")
+	require.NoError(t, err)
+	require.NotSame(t, plugin, otherPlugin)
+}
+
+func TestLoadPluginRegistry_already_supported(t *testing.T) {
+	registryDir := t.TempDir()
+	writeDescriptor(t, registryDir, "pki.hcl", `
+plugin_type "venafi-pki-backend" {
+  repository = "example/vault-pki-backend"
+  asset = "linux.zip"
+}
+`)
+
+	err := LoadPluginRegistry(registryDir)
+	require.Error(t, err)
+}
+
+func TestLoadPluginRegistry_invalid_descriptor(t *testing.T) {
+	registryDir := t.TempDir()
+	writeDescriptor(t, registryDir, "invalid.hcl", `
+plugin_type "invalid" {
+  repository = "example/vault-plugin-invalid"
+  asset = "linux.zip"
+
+  config_path "roles/*" {
+    required = true
+  }
+}
+`)
+
+	err := LoadPluginRegistry(registryDir)
+	require.Error(t, err)
+	require.NotContains(t, SupportedPluginNames(), "invalid")
+}
+
+func writeDescriptor(t *testing.T, registryDir, filename, contents string) {
+	err := os.WriteFile(filepath.Join(registryDir, filename), []byte(contents), 0644)
+	require.NoError(t, err)
+}
@@ -2,7 +2,9 @@ package plugins
 
 import (
 	"fmt"
+	"sort"
 
+	goversion "github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/opencredo/venafi-vault-wizard/app/questions"
@@ -12,6 +14,9 @@ import (
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
+// DatabaseSecretsEngine is the type of the built-in secrets engine that database plugins are used through
+const DatabaseSecretsEngine = "database"
+
 var archTypes = []string{"", "linux", "linux86", "windows", "windows86", "darwin"}
 
 // PluginConfig is a generic wrapper around a specific plugin implementation representing concerns common to all Vault plugins
@@ -28,6 +33,32 @@ type PluginConfig struct {
 	Config hcl.Body `hcl:",remain"`
 	// BuildArch allows defining the build architecture
 	BuildArch string `hcl:"build_arch,optional"`
+	// Args is an optional list of arguments that Vault should pass to the plugin process
+	Args []string `hcl:"args,optional"`
+	// Env is an optional map of environment variables that Vault should set for the plugin process, such as
+	// VAULT_API_ADDR or proxy settings
+	Env map[string]string `hcl:"env,optional"`
+
+	// VersionedCatalog is whether the plugin should be registered in the versioned plugin catalog of Vault 1.12+, in
+	// which case it is registered once per version rather than once per mount. It isn't decoded from the plugin block,
+	// and is instead populated by NewConfig from the vault block's versioned_plugin_catalog attribute.
+	VersionedCatalog bool
+	// LegacyCatalogName is set when using the versioned catalog if the plugin is already mounted using the per-mount
+	// catalog name from before, as Vault doesn't allow a mount's plugin to be changed. The versioned catalog entries
+	// are then registered under the per-mount name instead.
+	LegacyCatalogName bool
+	// VaultAddress and ConfigDir are the vault block's api_address and the directory of the config file. Like
+	// VersionedCatalog, they aren't decoded from the plugin block, and are instead populated by NewConfig before calling
+	// ParseConfig, for plugins that save state between runs.
+	VaultAddress string
+	ConfigDir    string
+
+	// Kind is the type of plugin, i.e. secrets engine, auth method or database plugin. Like Impl, it isn't decoded from
+	// the plugin block, and is instead populated by NewConfig from Impl.GetKind after the plugin's config is parsed.
+	Kind api.PluginKind
+	// Name is the name of the plugin used for its filename and catalog entry, when this isn't the same as Type. Like
+	// Kind, it isn't decoded from the plugin block, and is instead populated by NewConfig if Impl is a NamedPlugin.
+	Name string
 
 	// Impl is an implementation of the Plugin interface, defining both Configure and Check methods to perform the
 	// relevant Vault configuration tasks for the specific plugin. It is not populated by the initial HCL decoding, as
@@ -46,23 +77,127 @@ type Plugin interface {
 	ParseConfig(config *PluginConfig, evalContext *hcl.EvalContext) error
 	// GetDownloadURL returns a URL to download the required version of the plugin
 	GetDownloadURL() (string, error)
-	// Configure makes the necessary changes to Vault to configure the plugin
+	// Configure makes the necessary changes to Vault to configure the plugin, returning ErrPendingApproval if it can't
+	// finish yet
 	Configure(report reporter.Report, vaultClient api.VaultAPIClient) error
 	// Check is similar to Configure, except it shouldn't make any changes, only validate what is already there
 	Check(report reporter.Report, vaultClient api.VaultAPIClient) error
 	// ValidateConfig performs validation of the supplied configuration data, specific to the plugin
 	ValidateConfig() error
+	// GetKind returns the type of plugin, which determines how it is registered in the plugin catalog and mounted
+	GetKind() api.PluginKind
 	// GenerateConfigAndWriteHCL asks questions of the user to work out what the config should be and then writes it
 	// using the hclwrite package
 	GenerateConfigAndWriteHCL(questioner questions.Questioner, hclBody *hclwrite.Body) error
 }
 
+// NamedPlugin can be implemented by plugins whose name isn't determined by their type, such as the generic plugin type
+// which can install any plugin, so that the filename and catalog entries of different plugins don't clash
+type NamedPlugin interface {
+	// GetName returns the name of the plugin, used in place of its type in its filename and catalog entry
+	GetName() string
+}
+
+// PreflightPlugin can be implemented by plugins that depend on external services, such as Venafi, so that their
+// connection details can be checked before anything is installed into Vault
+type PreflightPlugin interface {
+	// Preflight checks the plugin's external services can be reached with the config given, without changing anything
+	Preflight(report reporter.Report) error
+}
+
+// ChecksummedPlugin can be implemented by plugins whose releases publish a separate file of checksums, so that the
+// downloaded plugin can be checked against it
+type ChecksummedPlugin interface {
+	// GetChecksumsURL returns a URL to download the checksums of the plugin's release from, or blank if there isn't one
+	GetChecksumsURL() (string, error)
+}
+
+// SecretRotatingPlugin can be implemented by plugins that store credentials for external services in Vault, so that
+// they can be rotated without reconfiguring the rest of the mount
+type SecretRotatingPlugin interface {
+	// RotateSecrets rewrites the secret called secretName, or every secret if secretName is blank, with fresh credentials
+	// and then checks certificates can still be issued. It returns whether any secret was rotated.
+	RotateSecrets(report reporter.Report, vaultClient api.VaultAPIClient, secretName string) (bool, error)
+}
+
+// GetName returns the Name of the plugin, defaulting to its Type if not set
+func (p *PluginConfig) GetName() string {
+	if p.Name == "" {
+		return p.Type
+	}
+
+	return p.Name
+}
+
 // GetCatalogName returns the name of the plugin as it appears in the plugin catalog. This does not include the plugin
 // version, to allow the plugin to be updated without needed to remount the associated instances. However it does
 // include the mount path to allow the version of the plugin to vary independently between different mounted instances
-// of it.
+// of it. When using the versioned catalog this isn't necessary, as each mount specifies its own plugin version, so
+// only the plugin type is used, unless the mount was already using the per-mount name.
 func (p *PluginConfig) GetCatalogName() string {
-	return fmt.Sprintf("%s-%s", p.Type, p.MountPath)
+	if p.VersionedCatalog && !p.LegacyCatalogName {
+		return p.GetName()
+	}
+
+	return p.GetPerMountCatalogName()
+}
+
+// GetKind returns the Kind of the plugin, defaulting to a secrets engine if not set
+func (p *PluginConfig) GetKind() api.PluginKind {
+	if p.Kind == "" {
+		return api.PluginKindSecret
+	}
+
+	return p.Kind
+}
+
+// GetMountKind returns the kind of mount the plugin needs. Database plugins are used by mounting the built-in database
+// secrets engine, so need a secrets engine mount.
+func (p *PluginConfig) GetMountKind() api.PluginKind {
+	if p.GetKind() == api.PluginKindDatabase {
+		return api.PluginKindSecret
+	}
+
+	return p.GetKind()
+}
+
+// GetMountType returns the type of backend that should be mounted at MountPath, which is the plugin itself unless it
+// is a database plugin, in which case it's the built-in database secrets engine
+func (p *PluginConfig) GetMountType() string {
+	if p.GetKind() == api.PluginKindDatabase {
+		return DatabaseSecretsEngine
+	}
+
+	return p.GetCatalogName()
+}
+
+// GetPerMountCatalogName returns the catalog name used when not using the versioned catalog, made up of the plugin
+// type and mount path
+func (p *PluginConfig) GetPerMountCatalogName() string {
+	return fmt.Sprintf("%s-%s", p.GetName(), p.MountPath)
+}
+
+// GetCatalogVersion returns the version of the plugin as it is registered in the plugin catalog, which is blank unless
+// using the versioned catalog. Database plugins always use the unversioned catalog, as their version is pinned in each
+// database connection's config rather than by the mount.
+func (p *PluginConfig) GetCatalogVersion() string {
+	if !p.VersionedCatalog || p.GetKind() == api.PluginKindDatabase {
+		return ""
+	}
+
+	version, _ := p.GetSemanticVersion()
+	return version
+}
+
+// GetSemanticVersion returns the plugin version in the form Vault requires for the versioned plugin catalog, i.e. a
+// semantic version with a leading v. Returns an error if Version isn't a semantic version.
+func (p *PluginConfig) GetSemanticVersion() (string, error) {
+	version, err := goversion.NewSemver(p.Version)
+	if err != nil {
+		return "", fmt.Errorf("error plugin version %s is not a semantic version: %w", p.Version, err)
+	}
+
+	return "v" + version.String(), nil
 }
 
 // GetFileName returns the filename of the plugin as it will be found in the plugin directory. This includes the plugin
@@ -74,7 +209,23 @@ func (p *PluginConfig) GetFileName() string {
 		return p.Filename
 	}
 
-	return fmt.Sprintf("%s_%s", p.Type, p.Version)
+	return fmt.Sprintf("%s_%s", p.GetName(), p.Version)
+}
+
+// GetEnv returns the Env map in the KEY=VALUE form used by the Vault plugin catalog, sorted by key so that it can be
+// compared with what is already registered
+func (p *PluginConfig) GetEnv() []string {
+	if len(p.Env) == 0 {
+		return nil
+	}
+
+	env := make([]string, 0, len(p.Env))
+	for key, value := range p.Env {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(env)
+
+	return env
 }
 
 // WriteHCL uses the hclwrite package to encode itself into HCL
@@ -84,6 +235,21 @@ func (p *PluginConfig) WriteHCL(hclBody *hclwrite.Body) {
 	pluginConfigBody := pluginConfigBlock.Body()
 
 	pluginConfigBody.SetAttributeValue("version", cty.StringVal(p.Version))
+
+	if len(p.Args) > 0 {
+		args := make([]cty.Value, 0, len(p.Args))
+		for _, arg := range p.Args {
+			args = append(args, cty.StringVal(arg))
+		}
+		pluginConfigBody.SetAttributeValue("args", cty.ListVal(args))
+	}
+	if len(p.Env) > 0 {
+		env := make(map[string]cty.Value, len(p.Env))
+		for key, value := range p.Env {
+			env[key] = cty.StringVal(value)
+		}
+		pluginConfigBody.SetAttributeValue("env", cty.MapVal(env))
+	}
 }
 
 func ValidateBuildArch(buildArch string) error {
@@ -1,13 +1,31 @@
 package venafi
 
 import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/opencredo/venafi-vault-wizard/app/config/errors"
 	"github.com/opencredo/venafi-vault-wizard/app/questions"
 	"github.com/zclconf/go-cty/cty"
 )
 
+const (
+	KeyTypeRSA = "rsa"
+	KeyTypeEC  = "ec"
+)
+
 type CertificateRequest struct {
 	CommonName   string `hcl:"common_name"`
 	OU           string `hcl:"ou"`
@@ -16,10 +34,26 @@ type CertificateRequest struct {
 	Province     string `hcl:"province"`
 	Country      string `hcl:"country"`
 	TTL          string `hcl:"ttl"`
+
+	AltNames  []string `hcl:"alt_names,optional"`
+	IPSANs    []string `hcl:"ip_sans,optional"`
+	URISANs   []string `hcl:"uri_sans,optional"`
+	EmailSANs []string `hcl:"email_sans,optional"`
+	// KeyType and KeyBits are passed on when generating a CA, and otherwise are checked against the key of the CSR and
+	// the issued certificate, as the key of an issued certificate is decided by the role
+	KeyType string `hcl:"key_type,optional"`
+	KeyBits int    `hcl:"key_bits,optional"`
+	// CSRFile is the path of a PEM encoded CSR, which is signed by the role's sign endpoint rather than Vault generating
+	// the private key through its issue endpoint
+	CSRFile string `hcl:"csr_file,optional"`
+
+	// Cleanup is what to do with the certificate once it's issued and verified, either none or revoke, defaulting to the
+	// plugin's test_certificate_cleanup
+	Cleanup string `hcl:"cleanup,optional"`
 }
 
 func (c *CertificateRequest) ToMap() map[string]interface{} {
-	return map[string]interface{}{
+	parameters := map[string]interface{}{
 		"common_name":  c.CommonName,
 		"ou":           c.OU,
 		"organization": c.Organisation,
@@ -28,6 +62,116 @@ func (c *CertificateRequest) ToMap() map[string]interface{} {
 		"country":      c.Country,
 		"ttl":          c.TTL,
 	}
+
+	// Vault takes email addresses as alt_names along with DNS names
+	altNames := append(append([]string{}, c.AltNames...), c.EmailSANs...)
+	if len(altNames) != 0 {
+		parameters["alt_names"] = strings.Join(altNames, ",")
+	}
+	if len(c.IPSANs) != 0 {
+		parameters["ip_sans"] = strings.Join(c.IPSANs, ",")
+	}
+	if len(c.URISANs) != 0 {
+		parameters["uri_sans"] = strings.Join(c.URISANs, ",")
+	}
+	if c.KeyType != "" {
+		parameters["key_type"] = c.KeyType
+	}
+	if c.KeyBits != 0 {
+		parameters["key_bits"] = c.KeyBits
+	}
+
+	return parameters
+}
+
+// GetIssueRequest returns the path of the role roleName in the plugin mounted at mountPath to request the certificate
+// from, and the parameters to write to it. This is the role's sign endpoint along with the CSR if csr_file is given,
+// and otherwise its issue endpoint.
+func (c *CertificateRequest) GetIssueRequest(mountPath, roleName string) (string, map[string]interface{}, error) {
+	// The key is decided by the role, or is the CSR's, so its type and size are only checked
+	parameters := c.ToMap()
+	delete(parameters, "key_type")
+	delete(parameters, "key_bits")
+	if c.CSRFile == "" {
+		return fmt.Sprintf("%s/issue/%s", mountPath, roleName), parameters, nil
+	}
+
+	csrPEM, _, err := c.readCSR()
+	if err != nil {
+		return "", nil, err
+	}
+	parameters["csr"] = csrPEM
+
+	return fmt.Sprintf("%s/sign/%s", mountPath, roleName), parameters, nil
+}
+
+// Validate checks the SANs are well formed, the key type and size are supported, and the cleanup is valid. Fields
+// that are only used by test certificates, csr_file and cleanup, are checked by the CA blocks themselves.
+func (c *CertificateRequest) Validate() error {
+	for _, ip := range c.IPSANs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("error, ip_sans %q is not an IP address", ip)
+		}
+	}
+	for _, uri := range c.URISANs {
+		parsed, err := url.Parse(uri)
+		if err != nil || parsed.Scheme == "" {
+			return fmt.Errorf("error, uri_sans %q is not a URI with a scheme", uri)
+		}
+	}
+	for _, email := range c.EmailSANs {
+		_, err := mail.ParseAddress(email)
+		if err != nil {
+			return fmt.Errorf("error, email_sans %q is not an email address: %s", email, err)
+		}
+	}
+
+	err := validateKey(c.KeyType, c.KeyBits)
+	if err != nil {
+		return err
+	}
+
+	return ValidateCleanup("cleanup", c.Cleanup)
+}
+
+// validateKey checks keyType is rsa or ec, and if given, keyBits is a size supported by both Vault and Venafi
+func validateKey(keyType string, keyBits int) error {
+	switch keyType {
+	case "":
+		if keyBits != 0 {
+			return fmt.Errorf("error, key_bits can only be given along with key_type: %w", errors.ErrBlankParam)
+		}
+		return nil
+	case KeyTypeRSA, KeyTypeEC:
+	default:
+		return fmt.Errorf("error, key_type must be either %q or %q, not %q", KeyTypeRSA, KeyTypeEC, keyType)
+	}
+
+	err := ValidateKeyBits(keyType, keyBits)
+	if err != nil {
+		return fmt.Errorf("error, %w", err)
+	}
+
+	return nil
+}
+
+// readCSR reads the PEM encoded CSR from CSRFile, returning it along with the parsed CSR
+func (c *CertificateRequest) readCSR() (string, *x509.CertificateRequest, error) {
+	csrPEM, err := os.ReadFile(c.CSRFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading csr_file: %w", err)
+	}
+
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return "", nil, fmt.Errorf("error, csr_file %s doesn't contain a PEM encoded CERTIFICATE REQUEST", c.CSRFile)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing csr_file %s: %w", c.CSRFile, err)
+	}
+
+	return string(csrPEM), csr, nil
 }
 
 func GenerateCertRequestConfig(questioner questions.Questioner) (*CertificateRequest, error) {
@@ -86,6 +230,31 @@ func (c *CertificateRequest) WriteHCL(hclBody *hclwrite.Body) {
 	hclBody.SetAttributeValue("province", cty.StringVal(c.Province))
 	hclBody.SetAttributeValue("country", cty.StringVal(c.Country))
 	hclBody.SetAttributeValue("ttl", cty.StringVal(c.TTL))
+	for _, list := range []struct {
+		name   string
+		values []string
+	}{
+		{"alt_names", c.AltNames},
+		{"ip_sans", c.IPSANs},
+		{"uri_sans", c.URISANs},
+		{"email_sans", c.EmailSANs},
+	} {
+		if len(list.values) != 0 {
+			hclBody.SetAttributeValue(list.name, StringListVal(list.values))
+		}
+	}
+	if c.KeyType != "" {
+		hclBody.SetAttributeValue("key_type", cty.StringVal(c.KeyType))
+	}
+	if c.KeyBits != 0 {
+		hclBody.SetAttributeValue("key_bits", cty.NumberIntVal(int64(c.KeyBits)))
+	}
+	if c.CSRFile != "" {
+		hclBody.SetAttributeValue("csr_file", cty.StringVal(c.CSRFile))
+	}
+	if c.Cleanup != "" {
+		hclBody.SetAttributeValue("cleanup", cty.StringVal(c.Cleanup))
+	}
 }
 
 func AskForTestCertificates(questioner questions.Questioner) ([]CertificateRequest, error) {
@@ -127,3 +296,161 @@ func AskForTestCertificates(questioner questions.Questioner) ([]CertificateReque
 
 	return csrs, nil
 }
+
+// ValidateAgainstZone checks the subject of the certificate request matches the patterns of zoneConfig, and its TTL is
+// no longer than maxTTL if given, returning an error naming the field and the rule it breaks. Zones without patterns
+// for a field allow any value.
+func (c *CertificateRequest) ValidateAgainstZone(zoneConfig *endpoint.ZoneConfiguration, maxTTL string) error {
+	for _, field := range []struct {
+		name    string
+		value   string
+		regexes []string
+	}{
+		{"common_name", c.CommonName, zoneConfig.SubjectCNRegexes},
+		{"organisation", c.Organisation, zoneConfig.SubjectORegexes},
+		{"ou", c.OU, zoneConfig.SubjectOURegexes},
+		{"locality", c.Locality, zoneConfig.SubjectLRegexes},
+		{"province", c.Province, zoneConfig.SubjectSTRegexes},
+		{"country", c.Country, zoneConfig.SubjectCRegexes},
+	} {
+		if len(field.regexes) != 0 && !matchesAny(field.value, field.regexes) {
+			return fmt.Errorf("%s %q doesn't match any of the zone's patterns: %s", field.name, field.value, strings.Join(field.regexes, ", "))
+		}
+	}
+
+	for _, field := range []struct {
+		name    string
+		values  []string
+		regexes []string
+	}{
+		{"alt_names", c.AltNames, zoneConfig.DnsSanRegExs},
+		{"ip_sans", c.IPSANs, zoneConfig.IpSanRegExs},
+		{"uri_sans", c.URISANs, zoneConfig.UriSanRegExs},
+		{"email_sans", c.EmailSANs, zoneConfig.EmailSanRegExs},
+	} {
+		for _, value := range field.values {
+			if len(field.regexes) != 0 && !matchesAny(value, field.regexes) {
+				return fmt.Errorf("%s %q doesn't match any of the zone's patterns: %s", field.name, value, strings.Join(field.regexes, ", "))
+			}
+		}
+	}
+
+	if c.KeyType != "" && len(zoneConfig.AllowedKeyConfigurations) != 0 && !c.keyAllowed(zoneConfig.AllowedKeyConfigurations) {
+		return fmt.Errorf("key_type %s with key_bits %d isn't allowed by the zone, which allows keys %s", c.KeyType, c.KeyBits, describeAllowedKeys(zoneConfig))
+	}
+
+	ttl, err := time.ParseDuration(c.TTL)
+	if err != nil {
+		return fmt.Errorf("ttl %q isn't a duration: %s", c.TTL, err)
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("ttl %q must be positive", c.TTL)
+	}
+	if maxTTL != "" {
+		// Already validated along with the rest of the role's config
+		maxDuration, _ := time.ParseDuration(maxTTL)
+		if ttl > maxDuration {
+			return fmt.Errorf("ttl %s is longer than the role's max_ttl of %s", c.TTL, maxTTL)
+		}
+	}
+
+	return nil
+}
+
+// Subject returns the subject of the certificate requested
+func (c *CertificateRequest) Subject() pkix.Name {
+	return pkix.Name{
+		CommonName:         c.CommonName,
+		Organization:       []string{c.Organisation},
+		OrganizationalUnit: []string{c.OU},
+		Locality:           []string{c.Locality},
+		Province:           []string{c.Province},
+		Country:            []string{c.Country},
+	}
+}
+
+// ToVCertRequest returns the certificate request in the form vcert generates requests from, with its CSR generated as
+// csrOrigin says
+func (c *CertificateRequest) ToVCertRequest(csrOrigin certificate.CSrOriginOption) *certificate.Request {
+	request := &certificate.Request{
+		Subject:        c.Subject(),
+		DNSNames:       append([]string{c.CommonName}, c.AltNames...),
+		EmailAddresses: c.EmailSANs,
+		CsrOrigin:      csrOrigin,
+	}
+
+	// Already validated by Validate
+	for _, ip := range c.IPSANs {
+		request.IPAddresses = append(request.IPAddresses, net.ParseIP(ip))
+	}
+	for _, uri := range c.URISANs {
+		parsed, _ := url.Parse(uri)
+		request.URIs = append(request.URIs, parsed)
+	}
+
+	switch c.KeyType {
+	case KeyTypeRSA:
+		request.KeyType = certificate.KeyTypeRSA
+		request.KeyLength = c.KeyBits
+	case KeyTypeEC:
+		request.KeyType = certificate.KeyTypeECDSA
+		request.KeyCurve = ellipticCurve(c.KeyBits)
+	}
+
+	return request
+}
+
+// keyAllowed returns whether the key type and size of the request are in allowedKeys. A key_type without key_bits
+// allows any size.
+func (c *CertificateRequest) keyAllowed(allowedKeys []endpoint.AllowedKeyConfiguration) bool {
+	for _, allowedKey := range allowedKeys {
+		switch {
+		case c.KeyType == KeyTypeRSA && allowedKey.KeyType == certificate.KeyTypeRSA:
+			if c.KeyBits == 0 || len(allowedKey.KeySizes) == 0 {
+				return true
+			}
+			for _, size := range allowedKey.KeySizes {
+				if size == c.KeyBits {
+					return true
+				}
+			}
+		case c.KeyType == KeyTypeEC && allowedKey.KeyType == certificate.KeyTypeECDSA:
+			if c.KeyBits == 0 || len(allowedKey.KeyCurves) == 0 {
+				return true
+			}
+			for _, curve := range allowedKey.KeyCurves {
+				if curve == ellipticCurve(c.KeyBits) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// ellipticCurve returns the vcert curve of EC keys of keyBits, which is not set if keyBits isn't given
+func ellipticCurve(keyBits int) certificate.EllipticCurve {
+	switch keyBits {
+	case 256:
+		return certificate.EllipticCurveP256
+	case 384:
+		return certificate.EllipticCurveP384
+	case 521:
+		return certificate.EllipticCurveP521
+	default:
+		return certificate.EllipticCurveNotSet
+	}
+}
+
+// matchesAny returns whether value matches any of regexes, ignoring any that aren't valid in the same way as vcert
+func matchesAny(value string, regexes []string) bool {
+	for _, r := range regexes {
+		matched, err := regexp.MatchString(r, value)
+		if err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,198 @@
+package venafi
+
+import (
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/testcerts"
+)
+
+func TestCertificateRequest_ValidateAgainstZone(t *testing.T) {
+	zoneConfig := &endpoint.ZoneConfiguration{
+		Policy: endpoint.Policy{
+			SubjectCNRegexes: []string{`^.*\.example\.com$`},
+			SubjectORegexes:  []string{"^OpenCredo$"},
+			SubjectOURegexes: []string{".*"},
+			SubjectCRegexes:  []string{"^GB$", "^US$"},
+			DnsSanRegExs:     []string{`^.*\.example\.com$`},
+			IpSanRegExs:      []string{`^10\.`},
+			AllowedKeyConfigurations: []endpoint.AllowedKeyConfiguration{
+				{KeyType: certificate.KeyTypeRSA, KeySizes: []int{2048, 4096}},
+				{KeyType: certificate.KeyTypeECDSA, KeyCurves: []certificate.EllipticCurve{certificate.EllipticCurveP256}},
+			},
+		},
+	}
+	validRequest := CertificateRequest{
+		CommonName:   "test.example.com",
+		OU:           "VVW",
+		Organisation: "OpenCredo",
+		Locality:     "London",
+		Province:     "London",
+		Country:      "GB",
+		TTL:          "1h",
+	}
+
+	tests := map[string]struct {
+		modify  func(c *CertificateRequest)
+		maxTTL  string
+		wantErr string
+	}{
+		"valid": {
+			modify: func(c *CertificateRequest) {},
+			maxTTL: "24h",
+		},
+		"common name not allowed": {
+			modify:  func(c *CertificateRequest) { c.CommonName = "test.example.org" },
+			wantErr: `common_name "test.example.org" doesn't match any of the zone's patterns: ^.*\.example\.com$`,
+		},
+		"organisation not allowed": {
+			modify:  func(c *CertificateRequest) { c.Organisation = "Venafi" },
+			wantErr: `organisation "Venafi" doesn't match any of the zone's patterns: ^OpenCredo$`,
+		},
+		"country not allowed": {
+			modify:  func(c *CertificateRequest) { c.Country = "FR" },
+			wantErr: `country "FR" doesn't match any of the zone's patterns: ^GB$, ^US$`,
+		},
+		"alt name not allowed": {
+			modify:  func(c *CertificateRequest) { c.AltNames = []string{"www.example.com", "www.example.org"} },
+			wantErr: `alt_names "www.example.org" doesn't match any of the zone's patterns`,
+		},
+		"ip san not allowed": {
+			modify:  func(c *CertificateRequest) { c.IPSANs = []string{"192.168.0.1"} },
+			wantErr: `ip_sans "192.168.0.1" doesn't match any of the zone's patterns: ^10\.`,
+		},
+		"allowed key": {
+			modify: func(c *CertificateRequest) { c.KeyType, c.KeyBits = KeyTypeRSA, 4096 },
+		},
+		"key size not allowed": {
+			modify:  func(c *CertificateRequest) { c.KeyType, c.KeyBits = KeyTypeRSA, 3072 },
+			wantErr: "key_type rsa with key_bits 3072 isn't allowed by the zone",
+		},
+		"key curve not allowed": {
+			modify:  func(c *CertificateRequest) { c.KeyType, c.KeyBits = KeyTypeEC, 384 },
+			wantErr: "key_type ec with key_bits 384 isn't allowed by the zone",
+		},
+		"ttl not a duration": {
+			modify:  func(c *CertificateRequest) { c.TTL = "an hour" },
+			wantErr: `ttl "an hour" isn't a duration`,
+		},
+		"ttl longer than max_ttl": {
+			modify:  func(c *CertificateRequest) { c.TTL = "48h" },
+			maxTTL:  "24h",
+			wantErr: "ttl 48h is longer than the role's max_ttl of 24h",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			request := validRequest
+			tt.modify(&request)
+
+			err := request.ValidateAgainstZone(zoneConfig, tt.maxTTL)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestCertificateRequest_Validate(t *testing.T) {
+	tests := map[string]struct {
+		request CertificateRequest
+		wantErr bool
+	}{
+		"no optional fields": {
+			request: CertificateRequest{CommonName: "test.example.com"},
+		},
+		"all optional fields": {
+			request: testIssuedRequest,
+		},
+		"invalid ip san": {
+			request: CertificateRequest{IPSANs: []string{"10.0.0"}},
+			wantErr: true,
+		},
+		"uri san without scheme": {
+			request: CertificateRequest{URISANs: []string{"example.com/test"}},
+			wantErr: true,
+		},
+		"invalid email san": {
+			request: CertificateRequest{EmailSANs: []string{"test.example.com"}},
+			wantErr: true,
+		},
+		"unsupported key type": {
+			request: CertificateRequest{KeyType: "ed25519"},
+			wantErr: true,
+		},
+		"unsupported rsa key size": {
+			request: CertificateRequest{KeyType: KeyTypeRSA, KeyBits: 1024},
+			wantErr: true,
+		},
+		"rsa key size for ec key": {
+			request: CertificateRequest{KeyType: KeyTypeEC, KeyBits: 2048},
+			wantErr: true,
+		},
+		"key_bits without key_type": {
+			request: CertificateRequest{KeyBits: 2048},
+			wantErr: true,
+		},
+		"unknown cleanup": {
+			request: CertificateRequest{Cleanup: "delete"},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCertificateRequest_GetIssueRequest(t *testing.T) {
+	request := testIssuedRequest
+
+	path, parameters, err := request.GetIssueRequest("pki", "web")
+	require.NoError(t, err)
+	require.Equal(t, "pki/issue/web", path)
+	require.Equal(t, map[string]interface{}{
+		"common_name":  "test.example.com",
+		"ou":           "VVW",
+		"organization": "OpenCredo",
+		"locality":     "London",
+		"province":     "London",
+		"country":      "GB",
+		"ttl":          "1h",
+		"alt_names":    "www.example.com,test@example.com",
+		"ip_sans":      "10.0.0.1",
+		"uri_sans":     "spiffe://example.com/test",
+	}, parameters)
+
+	request.CSRFile = writeTestCSR(t, testcerts.NewKey(t))
+
+	path, parameters, err = request.GetIssueRequest("pki", "web")
+	require.NoError(t, err)
+	require.Equal(t, "pki/sign/web", path)
+	require.Contains(t, parameters["csr"], "-----BEGIN CERTIFICATE REQUEST-----")
+
+	request.CSRFile = "does-not-exist.csr"
+	_, _, err = request.GetIssueRequest("pki", "web")
+	require.Error(t, err)
+}
+
+func TestCertificateRequest_ToMap_CA(t *testing.T) {
+	request := CertificateRequest{CommonName: "Test CA", TTL: "8760h", KeyType: KeyTypeRSA, KeyBits: 4096}
+
+	parameters := request.ToMap()
+	require.Equal(t, KeyTypeRSA, parameters["key_type"])
+	require.Equal(t, 4096, parameters["key_bits"])
+	require.NotContains(t, parameters, "alt_names")
+}
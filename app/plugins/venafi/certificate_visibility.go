@@ -0,0 +1,67 @@
+package venafi
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+)
+
+// DefaultVisibilityTimeout is how long to wait for an issued certificate to appear in Venafi if no timeout is given
+const DefaultVisibilityTimeout = 5 * time.Minute
+
+// visibilityPollInterval is how often to search a zone while waiting for a certificate to appear in it
+var visibilityPollInterval = 10 * time.Second
+
+// WaitForCertificateInZone polls zone in Venafi until the certificate in the data returned by Vault appears in it,
+// searched for by its thumbprint, or fails once timeout has passed
+func WaitForCertificateInZone(
+	reportSection reporter.Section,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	zone string,
+	data map[string]interface{},
+	timeout time.Duration,
+) error {
+	certificatePEM, _ := data["certificate"].(string)
+	issued, err := parseCertificate(certificatePEM)
+	if err != nil {
+		check := reportSection.AddCheck(fmt.Sprintf("Checking test certificate appears in zone %s...", zone))
+		check.Errorf("Error parsing test certificate to look for in zone %s: %s", zone, err)
+		return err
+	}
+
+	check := reportSection.AddCheck(
+		fmt.Sprintf("Checking test certificate with CN:%s appears in zone %s...", issued.Subject.CommonName, zone),
+	)
+	thumbprint := certificateThumbprint(issued)
+
+	if timeout == 0 {
+		timeout = DefaultVisibilityTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		found, err := venafiClient.FindCertificateInZone(zone, thumbprint)
+		if err != nil {
+			check.Errorf("Error searching for the test certificate in zone %s: %s", zone, err)
+			return err
+		}
+		if found {
+			check.Successf("Test certificate with CN:%s was imported into zone %s", issued.Subject.CommonName, zone)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			check.Errorf(
+				"Test certificate with CN:%s didn't appear in zone %s within %s, check the import policy is working",
+				issued.Subject.CommonName, zone, timeout,
+			)
+			return fmt.Errorf("certificate with thumbprint %s wasn't imported into zone %s within %s", thumbprint, zone, timeout)
+		}
+
+		check.UpdateStatus(fmt.Sprintf(
+			"Waiting for test certificate with CN:%s to appear in zone %s...", issued.Subject.CommonName, zone,
+		))
+		time.Sleep(visibilityPollInterval)
+	}
+}
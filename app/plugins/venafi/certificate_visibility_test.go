@@ -0,0 +1,74 @@
+package venafi
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockVenafi "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+)
+
+func TestWaitForCertificateInZone(t *testing.T) {
+	visibilityPollInterval = time.Millisecond
+
+	data := newTestIssuedData(t, testIssuedRequest, time.Hour)
+	issued, err := parseCertificate(data["certificate"].(string))
+	require.NoError(t, err)
+	thumbprint := certificateThumbprint(issued)
+
+	tests := map[string]struct {
+		searchResults []bool
+		searchErr     error
+		wantErr       bool
+	}{
+		"found": {
+			searchResults: []bool{true},
+		},
+		"found once imported": {
+			searchResults: []bool{false, true},
+		},
+		"never imported": {
+			searchResults: []bool{false},
+			wantErr:       true,
+		},
+		"error searching for certificate": {
+			searchErr: fmt.Errorf("zone not found"),
+			wantErr:   true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			venafiClient := new(mockVenafi.VenafiWrapper)
+
+			section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+			check.On("UpdateStatus", mock.AnythingOfType("string")).Maybe()
+			check.On("Successf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+			check.On("Errorf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+
+			if tt.searchErr != nil {
+				venafiClient.On("FindCertificateInZone", "zone", thumbprint).Return(false, tt.searchErr)
+			}
+			for i, found := range tt.searchResults {
+				call := venafiClient.On("FindCertificateInZone", "zone", thumbprint).Return(found, nil)
+				if i < len(tt.searchResults)-1 {
+					call.Once()
+				}
+			}
+
+			err := WaitForCertificateInZone(section, venafiClient, "zone", data, 20*time.Millisecond)
+			if tt.wantErr {
+				require.Error(t, err)
+				check.AssertCalled(t, "Errorf", mock.AnythingOfType("string"), mock.Anything)
+			} else {
+				require.NoError(t, err)
+				check.AssertCalled(t, "Successf", mock.AnythingOfType("string"), mock.Anything)
+			}
+		})
+	}
+}
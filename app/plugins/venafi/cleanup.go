@@ -0,0 +1,146 @@
+package venafi
+
+import (
+	"fmt"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+const (
+	// CleanupNone leaves issued test certificates in place, which is the default
+	CleanupNone = "none"
+	// CleanupRevoke revokes each test certificate once it has been issued and verified
+	CleanupRevoke = "revoke"
+)
+
+// ValidateCleanup checks cleanup, given by attribute, is either blank or one of the supported cleanup actions
+func ValidateCleanup(attribute, cleanup string) error {
+	switch cleanup {
+	case "", CleanupNone, CleanupRevoke:
+		return nil
+	default:
+		return fmt.Errorf("error, %s must be either %q or %q, not %q", attribute, CleanupNone, CleanupRevoke, cleanup)
+	}
+}
+
+// GetCleanup returns what to do with the certificate once it's issued, which is defaultCleanup unless the certificate
+// gives its own cleanup
+func (c *CertificateRequest) GetCleanup(defaultCleanup string) string {
+	if c.Cleanup != "" {
+		return c.Cleanup
+	}
+	if defaultCleanup != "" {
+		return defaultCleanup
+	}
+
+	return CleanupNone
+}
+
+// RevokeVenafiCertificate revokes the certificate in the data returned by Vault directly in Venafi, identifying it by
+// its thumbprint. Venafi as a Service doesn't support revocation through its API, so vaas is used to report that the
+// certificate was left in place instead.
+func RevokeVenafiCertificate(
+	reportSection reporter.Section,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	vaas bool,
+	data map[string]interface{},
+) error {
+	certificatePEM, _ := data["certificate"].(string)
+	issued, err := parseCertificate(certificatePEM)
+	if err != nil {
+		check := reportSection.AddCheck("Revoking test certificate in Venafi...")
+		check.Errorf("Error parsing test certificate to revoke: %s", err)
+		return err
+	}
+
+	check := reportSection.AddCheck(fmt.Sprintf("Revoking test certificate with CN:%s in Venafi...", issued.Subject.CommonName))
+	if vaas {
+		check.Warningf(
+			"Test certificate with CN:%s was left in place, as Venafi as a Service doesn't support revoking certificates",
+			issued.Subject.CommonName,
+		)
+		return nil
+	}
+
+	thumbprint := certificateThumbprint(issued)
+	err = venafiClient.RevokeCertificate(&certificate.RevocationRequest{
+		Thumbprint: thumbprint,
+		Reason:     "cessation-of-operation",
+		Comments:   "Test certificate requested by the Venafi Vault Wizard",
+	})
+	if err != nil {
+		check.Errorf("Error revoking test certificate with CN:%s in Venafi: %s", issued.Subject.CommonName, err)
+		return err
+	}
+
+	check.Successf("Revoked test certificate with CN:%s, thumbprint %s, in Venafi", issued.Subject.CommonName, thumbprint)
+	return nil
+}
+
+// RevokePluginCertificate revokes the certificate in the data returned by Vault through the Venafi PKI backend's
+// revoke/<role> endpoint at revokePath. The plugin finds the certificate by the ID it was stored under, so storeBy must
+// be the role's store_by, which defaults to the serial number.
+func RevokePluginCertificate(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	revokePath string,
+	storeBy string,
+	data map[string]interface{},
+) error {
+	certificatePEM, _ := data["certificate"].(string)
+	issued, err := parseCertificate(certificatePEM)
+	if err != nil {
+		check := reportSection.AddCheck(fmt.Sprintf("Revoking test certificate through %s...", revokePath))
+		check.Errorf("Error parsing test certificate to revoke: %s", err)
+		return err
+	}
+
+	var certificateUID string
+	switch storeBy {
+	case "cn":
+		certificateUID = issued.Subject.CommonName
+	case "hash":
+		certificateUID = certificateThumbprint(issued)
+	default:
+		certificateUID, _ = data["serial_number"].(string)
+	}
+
+	check := reportSection.AddCheck(fmt.Sprintf("Revoking test certificate with CN:%s through %s...", issued.Subject.CommonName, revokePath))
+	_, err = vaultClient.WriteValue(revokePath, map[string]interface{}{
+		"certificate_uid": certificateUID,
+	})
+	if err != nil {
+		check.Errorf("Error revoking test certificate with CN:%s: %s", issued.Subject.CommonName, err)
+		return err
+	}
+
+	check.Successf("Revoked test certificate with CN:%s, stored as %s", issued.Subject.CommonName, certificateUID)
+	return nil
+}
+
+// RevokeVaultCertificate revokes the certificate in the data returned by Vault through the revoke endpoint at
+// revokePath, identifying it by its serial number
+func RevokeVaultCertificate(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	revokePath string,
+	data map[string]interface{},
+) error {
+	serialNumber, _ := data["serial_number"].(string)
+	check := reportSection.AddCheck(fmt.Sprintf("Revoking test certificate with serial number %s through %s...", serialNumber, revokePath))
+
+	_, err := vaultClient.WriteValue(revokePath, map[string]interface{}{
+		"serial_number": serialNumber,
+	})
+	if err != nil {
+		check.Errorf("Error revoking test certificate with serial number %s: %s", serialNumber, err)
+		return err
+	}
+
+	check.Successf("Revoked test certificate with serial number %s", serialNumber)
+	return nil
+}
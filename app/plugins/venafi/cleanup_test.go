@@ -0,0 +1,158 @@
+package venafi
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockVenafi "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestValidateCleanup(t *testing.T) {
+	for _, cleanup := range []string{"", CleanupNone, CleanupRevoke} {
+		require.NoError(t, ValidateCleanup("cleanup", cleanup))
+	}
+	require.Error(t, ValidateCleanup("cleanup", "delete"))
+}
+
+func TestCertificateRequest_GetCleanup(t *testing.T) {
+	require.Equal(t, CleanupNone, (&CertificateRequest{}).GetCleanup(""))
+	require.Equal(t, CleanupRevoke, (&CertificateRequest{}).GetCleanup(CleanupRevoke))
+	require.Equal(t, CleanupNone, (&CertificateRequest{Cleanup: CleanupNone}).GetCleanup(CleanupRevoke))
+	require.Equal(t, CleanupRevoke, (&CertificateRequest{Cleanup: CleanupRevoke}).GetCleanup(""))
+}
+
+func TestRevokeVenafiCertificate(t *testing.T) {
+	data := newTestIssuedData(t, testIssuedRequest, time.Hour)
+	issued, err := parseCertificate(data["certificate"].(string))
+	require.NoError(t, err)
+	revocationRequest := &certificate.RevocationRequest{
+		Thumbprint: fmt.Sprintf("%X", sha1.Sum(issued.Raw)),
+		Reason:     "cessation-of-operation",
+		Comments:   "Test certificate requested by the Venafi Vault Wizard",
+	}
+
+	t.Run("revoked in tpp", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		venafiClient := new(mockVenafi.VenafiWrapper)
+		defer venafiClient.AssertExpectations(t)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+		venafiClient.On("RevokeCertificate", revocationRequest).Return(nil)
+
+		err := RevokeVenafiCertificate(section, venafiClient, false, data)
+		require.NoError(t, err)
+	})
+
+	t.Run("revocation fails", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		venafiClient := new(mockVenafi.VenafiWrapper)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Errorf", mock.AnythingOfType("string"), mock.Anything)
+		venafiClient.On("RevokeCertificate", revocationRequest).Return(fmt.Errorf("certificate not found"))
+
+		err := RevokeVenafiCertificate(section, venafiClient, false, data)
+		require.Error(t, err)
+	})
+
+	t.Run("left in place in vaas", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Warningf", mock.AnythingOfType("string"), mock.Anything)
+
+		err := RevokeVenafiCertificate(section, nil, true, data)
+		require.NoError(t, err)
+	})
+}
+
+func TestRevokePluginCertificate(t *testing.T) {
+	data := newTestIssuedData(t, testIssuedRequest, time.Hour)
+	issued, err := parseCertificate(data["certificate"].(string))
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		storeBy        string
+		certificateUID string
+	}{
+		"stored by serial by default": {
+			certificateUID: data["serial_number"].(string),
+		},
+		"stored by serial": {
+			storeBy:        "serial",
+			certificateUID: data["serial_number"].(string),
+		},
+		"stored by cn": {
+			storeBy:        "cn",
+			certificateUID: testIssuedRequest.CommonName,
+		},
+		"stored by hash": {
+			storeBy:        "hash",
+			certificateUID: fmt.Sprintf("%X", sha1.Sum(issued.Raw)),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			defer vaultAPIClient.AssertExpectations(t)
+			defer check.AssertExpectations(t)
+
+			section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+			check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+			vaultAPIClient.On("WriteValue", "venafi-pki/revoke/role", map[string]interface{}{
+				"certificate_uid": tc.certificateUID,
+			}).Return(nil, nil)
+
+			err := RevokePluginCertificate(section, vaultAPIClient, "venafi-pki/revoke/role", tc.storeBy, data)
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("revocation fails", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		vaultAPIClient := new(mockAPI.VaultAPIClient)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Errorf", mock.AnythingOfType("string"), mock.Anything)
+		vaultAPIClient.On("WriteValue", "venafi-pki/revoke/role", mock.Anything).
+			Return(nil, fmt.Errorf("certificate not found"))
+
+		err := RevokePluginCertificate(section, vaultAPIClient, "venafi-pki/revoke/role", "", data)
+		require.Error(t, err)
+	})
+}
+
+func TestRevokeVaultCertificate(t *testing.T) {
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+	check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+	vaultAPIClient.On("WriteValue", "pki/revoke", map[string]interface{}{"serial_number": "1a:2b"}).Return(nil, nil)
+
+	err := RevokeVaultCertificate(section, vaultAPIClient, "pki/revoke", map[string]interface{}{"serial_number": "1a:2b"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,358 @@
+package venafi
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+)
+
+// ttlTolerance allows for clock skew between vvw and the issuer when checking a certificate's TTL was honoured
+const ttlTolerance = 5 * time.Minute
+
+// VerifyIssuedCertificate checks the data returned by Vault when issuing the certificate requested by certRequest. The
+// certificate must have the requested subject, other than blank fields and those set by zoneConfig if it's given, SANs and key type, expire no later than its TTL, chain to one of roots
+// (or the system's roots if nil) through issuing_ca and ca_chain, and match private_key, or the key of the CSR if one
+// was signed, and serial_number must be its serial number.
+func VerifyIssuedCertificate(
+	data map[string]interface{},
+	certRequest CertificateRequest,
+	roots *x509.CertPool,
+	zoneConfig *endpoint.ZoneConfiguration,
+) error {
+	certificatePEM, ok := data["certificate"].(string)
+	if !ok {
+		return fmt.Errorf("no certificate was returned")
+	}
+	certificate, err := parseCertificate(certificatePEM)
+	if err != nil {
+		return fmt.Errorf("error parsing returned certificate: %w", err)
+	}
+
+	err = verifySubject(certificate, certRequest, zoneConfig)
+	if err != nil {
+		return err
+	}
+
+	err = verifyTTL(certificate, certRequest.TTL)
+	if err != nil {
+		return err
+	}
+
+	err = verifySANs(certificate, certRequest)
+	if err != nil {
+		return err
+	}
+
+	err = verifyKeyType(certificate, certRequest)
+	if err != nil {
+		return err
+	}
+
+	err = verifyChain(certificate, data, roots)
+	if err != nil {
+		return err
+	}
+
+	// Vault only returns the private key when it generates it
+	if certRequest.CSRFile != "" {
+		err = verifyCSRPublicKey(certificate, certRequest)
+	} else {
+		err = verifyPrivateKey(certificate, data)
+	}
+	if err != nil {
+		return err
+	}
+
+	return verifySerialNumber(certificate, data)
+}
+
+// verifySubject checks the certificate has the subject requested by certRequest. Blank fields may be filled in with the
+// zone's defaults, and the zone may override the values requested, so those fields, or any zoneConfig sets, are skipped.
+func verifySubject(
+	certificate *x509.Certificate,
+	certRequest CertificateRequest,
+	zoneConfig *endpoint.ZoneConfiguration,
+) error {
+	if certificate.Subject.CommonName != certRequest.CommonName {
+		return fmt.Errorf("certificate's common name was not as expected: expected %s got %s", certRequest.CommonName, certificate.Subject.CommonName)
+	}
+	if zoneConfig == nil {
+		zoneConfig = &endpoint.ZoneConfiguration{}
+	}
+
+	for _, field := range []struct {
+		name      string
+		expected  string
+		actual    []string
+		zoneValue string
+	}{
+		{"organisation", certRequest.Organisation, certificate.Subject.Organization, zoneConfig.Organization},
+		{"ou", certRequest.OU, certificate.Subject.OrganizationalUnit, strings.Join(zoneConfig.OrganizationalUnit, ", ")},
+		{"locality", certRequest.Locality, certificate.Subject.Locality, zoneConfig.Locality},
+		{"province", certRequest.Province, certificate.Subject.Province, zoneConfig.Province},
+		{"country", certRequest.Country, certificate.Subject.Country, zoneConfig.Country},
+	} {
+		if field.expected == "" || field.zoneValue != "" {
+			continue
+		}
+
+		actual := strings.Join(field.actual, ", ")
+		if actual != field.expected {
+			return fmt.Errorf("certificate's %s was not as expected: expected %s got %s", field.name, field.expected, actual)
+		}
+	}
+
+	return nil
+}
+
+func verifySANs(certificate *x509.Certificate, certRequest CertificateRequest) error {
+	var uris []string
+	for _, uri := range certificate.URIs {
+		uris = append(uris, uri.String())
+	}
+	var ips []string
+	for _, ip := range certificate.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	for _, field := range []struct {
+		name      string
+		requested []string
+		actual    []string
+	}{
+		{"alt_names", certRequest.AltNames, certificate.DNSNames},
+		{"ip_sans", certRequest.IPSANs, ips},
+		{"uri_sans", certRequest.URISANs, uris},
+		{"email_sans", certRequest.EmailSANs, certificate.EmailAddresses},
+	} {
+		for _, requested := range field.requested {
+			if field.name == "ip_sans" {
+				// Normalise the IP address, as it is formatted differently once parsed
+				requested = net.ParseIP(requested).String()
+			}
+			if !ContainsString(field.actual, requested) {
+				return fmt.Errorf("certificate's SANs don't include %s %s, it has %s", field.name, requested, strings.Join(field.actual, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+func verifyKeyType(certificate *x509.Certificate, certRequest CertificateRequest) error {
+	var keyType string
+	var keyBits int
+	switch publicKey := certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		keyType, keyBits = KeyTypeRSA, publicKey.N.BitLen()
+	case *ecdsa.PublicKey:
+		keyType, keyBits = KeyTypeEC, publicKey.Curve.Params().BitSize
+	default:
+		keyType = fmt.Sprintf("%T", publicKey)
+	}
+
+	if certRequest.KeyType != "" && keyType != certRequest.KeyType {
+		return fmt.Errorf("certificate's key type was not as expected: expected %s got %s", certRequest.KeyType, keyType)
+	}
+	if certRequest.KeyBits != 0 && keyBits != certRequest.KeyBits {
+		return fmt.Errorf("certificate's key size was not as expected: expected %d got %d", certRequest.KeyBits, keyBits)
+	}
+
+	return nil
+}
+
+func verifyTTL(certificate *x509.Certificate, ttl string) error {
+	requestedTTL, err := time.ParseDuration(ttl)
+	if err != nil {
+		return fmt.Errorf("error parsing requested ttl %s: %w", ttl, err)
+	}
+
+	latestExpiry := time.Now().Add(requestedTTL + ttlTolerance)
+	if certificate.NotAfter.After(latestExpiry) {
+		return fmt.Errorf("certificate expires at %s, later than its requested ttl of %s", certificate.NotAfter.Format(time.RFC3339), ttl)
+	}
+	if certificate.NotAfter.Before(time.Now()) {
+		return fmt.Errorf("certificate has already expired at %s", certificate.NotAfter.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// verifyChain checks the certificate chains to one of roots, or the system's roots if nil, through the certificates of
+// issuing_ca and ca_chain. The returned chain isn't trusted itself, as it could end in any self-signed certificate.
+func verifyChain(certificate *x509.Certificate, data map[string]interface{}, roots *x509.CertPool) error {
+	var chainPEMs []string
+	issuingCA, _ := data["issuing_ca"].(string)
+	if issuingCA != "" {
+		chainPEMs = append(chainPEMs, issuingCA)
+	}
+	switch caChain := data["ca_chain"].(type) {
+	case []interface{}:
+		for _, caPEM := range caChain {
+			chainPEMs = append(chainPEMs, fmt.Sprint(caPEM))
+		}
+	case []string:
+		chainPEMs = append(chainPEMs, caChain...)
+	case string:
+		chainPEMs = append(chainPEMs, caChain)
+	}
+	if len(chainPEMs) == 0 {
+		return fmt.Errorf("neither issuing_ca nor ca_chain were returned")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, chainPEM := range chainPEMs {
+		certificates, err := parseCertificates(chainPEM)
+		if err != nil {
+			return fmt.Errorf("error parsing returned CA chain: %w", err)
+		}
+		for _, ca := range certificates {
+			intermediates.AddCert(ca)
+		}
+	}
+
+	_, err := certificate.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("certificate doesn't chain to a trusted CA: %w", err)
+	}
+
+	return nil
+}
+
+// NewCertPool returns a pool of the certificates in certificatesPEM, of which there must be at least one
+func NewCertPool(certificatesPEM string) (*x509.CertPool, error) {
+	certificates, err := parseCertificates(certificatesPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	for _, certificate := range certificates {
+		pool.AddCert(certificate)
+	}
+
+	return pool, nil
+}
+
+func verifyPrivateKey(certificate *x509.Certificate, data map[string]interface{}) error {
+	privateKeyPEM, _ := data["private_key"].(string)
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return fmt.Errorf("no PEM encoded private key was returned")
+	}
+
+	var privateKey interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing returned private key: %w", err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("returned private key of type %T is not supported", privateKey)
+	}
+	publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(certificate.PublicKey) {
+		return fmt.Errorf("returned private key doesn't match the certificate")
+	}
+
+	return nil
+}
+
+func verifyCSRPublicKey(certificate *x509.Certificate, certRequest CertificateRequest) error {
+	_, csr, err := certRequest.readCSR()
+	if err != nil {
+		return err
+	}
+
+	publicKey, ok := csr.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(certificate.PublicKey) {
+		return fmt.Errorf("certificate's public key doesn't match the key of csr_file %s", certRequest.CSRFile)
+	}
+
+	return nil
+}
+
+func verifySerialNumber(certificate *x509.Certificate, data map[string]interface{}) error {
+	serialNumber, _ := data["serial_number"].(string)
+	if serialNumber == "" {
+		return fmt.Errorf("no serial_number was returned")
+	}
+
+	expected := fmt.Sprintf("%x", certificate.SerialNumber)
+	if normaliseSerialNumber(serialNumber) != normaliseSerialNumber(expected) {
+		return fmt.Errorf("serial_number %s doesn't match the certificate's serial number %s", serialNumber, expected)
+	}
+
+	return nil
+}
+
+// normaliseSerialNumber returns serialNumber as lower case hex without separators or leading zeros, so that Vault's
+// colon separated format can be compared with others
+func normaliseSerialNumber(serialNumber string) string {
+	return strings.TrimLeft(strings.ToLower(strings.ReplaceAll(serialNumber, ":", "")), "0")
+}
+
+// certificateThumbprint returns the SHA-1 thumbprint of certificate as upper case hex, which is how Venafi identifies it
+func certificateThumbprint(certificate *x509.Certificate) string {
+	return fmt.Sprintf("%X", sha1.Sum(certificate.Raw))
+}
+
+// parseCertificate returns the first certificate in certificatePEM
+func parseCertificate(certificatePEM string) (*x509.Certificate, error) {
+	certificates, err := parseCertificates(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return certificates[0], nil
+}
+
+// parseCertificates returns all of the certificates in certificatesPEM, of which there must be at least one
+func parseCertificates(certificatesPEM string) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	rest := []byte(certificatesPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	return certificates, nil
+}
@@ -0,0 +1,232 @@
+package venafi
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/testcerts"
+)
+
+var testIssuedRequest = CertificateRequest{
+	CommonName:   "test.example.com",
+	OU:           "VVW",
+	Organisation: "OpenCredo",
+	Locality:     "London",
+	Province:     "London",
+	Country:      "GB",
+	TTL:          "1h",
+	AltNames:     []string{"www.example.com"},
+	IPSANs:       []string{"10.0.0.1"},
+	URISANs:      []string{"spiffe://example.com/test"},
+	EmailSANs:    []string{"test@example.com"},
+	KeyType:      KeyTypeEC,
+	KeyBits:      256,
+}
+
+func TestVerifyIssuedCertificate(t *testing.T) {
+	tests := map[string]struct {
+		modify     func(data map[string]interface{})
+		request    func(request *CertificateRequest)
+		otherRoot  bool
+		zoneConfig *endpoint.ZoneConfiguration
+		wantErr    string
+	}{
+		"valid": {},
+		"no certificate": {
+			modify:  func(data map[string]interface{}) { delete(data, "certificate") },
+			wantErr: "no certificate was returned",
+		},
+		"certificate not PEM": {
+			modify:  func(data map[string]interface{}) { data["certificate"] = "not a certificate" },
+			wantErr: "error parsing returned certificate",
+		},
+		"wrong organisation": {
+			request: func(request *CertificateRequest) { request.Organisation = "Venafi" },
+			wantErr: "certificate's organisation was not as expected: expected Venafi got OpenCredo",
+		},
+		"blank organisation filled in by the zone": {
+			request: func(request *CertificateRequest) { request.Organisation = "" },
+		},
+		"organisation overridden by the zone": {
+			request:    func(request *CertificateRequest) { request.Organisation = "Venafi" },
+			zoneConfig: &endpoint.ZoneConfiguration{Organization: "OpenCredo"},
+		},
+		"wrong locality with the zone setting organisation": {
+			request:    func(request *CertificateRequest) { request.Locality = "Manchester" },
+			zoneConfig: &endpoint.ZoneConfiguration{Organization: "OpenCredo"},
+			wantErr:    "certificate's locality was not as expected: expected Manchester got London",
+		},
+		"ttl not honoured": {
+			request: func(request *CertificateRequest) { request.TTL = "10m" },
+			wantErr: "later than its requested ttl of 10m",
+		},
+		"no chain": {
+			modify: func(data map[string]interface{}) {
+				delete(data, "issuing_ca")
+				delete(data, "ca_chain")
+			},
+			wantErr: "neither issuing_ca nor ca_chain were returned",
+		},
+		"chain to an untrusted root": {
+			// The returned chain is valid, but ends in a root other than the one trusted
+			otherRoot: true,
+			wantErr:   "certificate doesn't chain to a trusted CA",
+		},
+		"chain ending in another self-signed certificate": {
+			modify: func(data map[string]interface{}) {
+				otherRoot := testcerts.NewCA(t, pkix.Name{CommonName: "Other Root"}, 24*time.Hour, nil)
+				data["issuing_ca"] = otherRoot.PEM
+				data["ca_chain"] = []interface{}{otherRoot.PEM}
+			},
+			wantErr: "certificate doesn't chain to a trusted CA",
+		},
+		"issuing_ca only": {
+			modify: func(data map[string]interface{}) { delete(data, "ca_chain") },
+		},
+		"private key doesn't match": {
+			modify: func(data map[string]interface{}) {
+				data["private_key"] = testcerts.EncodeKey(t, testcerts.NewKey(t))
+			},
+			wantErr: "returned private key doesn't match the certificate",
+		},
+		"no private key": {
+			modify:  func(data map[string]interface{}) { delete(data, "private_key") },
+			wantErr: "no PEM encoded private key was returned",
+		},
+		"missing alt name": {
+			request: func(request *CertificateRequest) { request.AltNames = []string{"api.example.com"} },
+			wantErr: "certificate's SANs don't include alt_names api.example.com",
+		},
+		"missing ip san": {
+			request: func(request *CertificateRequest) { request.IPSANs = []string{"10.0.0.2"} },
+			wantErr: "certificate's SANs don't include ip_sans 10.0.0.2",
+		},
+		"missing uri san": {
+			request: func(request *CertificateRequest) { request.URISANs = []string{"spiffe://example.com/other"} },
+			wantErr: "certificate's SANs don't include uri_sans spiffe://example.com/other",
+		},
+		"missing email san": {
+			request: func(request *CertificateRequest) { request.EmailSANs = []string{"other@example.com"} },
+			wantErr: "certificate's SANs don't include email_sans other@example.com",
+		},
+		"wrong key type": {
+			request: func(request *CertificateRequest) { request.KeyType, request.KeyBits = KeyTypeRSA, 2048 },
+			wantErr: "certificate's key type was not as expected: expected rsa got ec",
+		},
+		"wrong key size": {
+			request: func(request *CertificateRequest) { request.KeyBits = 384 },
+			wantErr: "certificate's key size was not as expected: expected 384 got 256",
+		},
+		"no serial number": {
+			modify:  func(data map[string]interface{}) { delete(data, "serial_number") },
+			wantErr: "no serial_number was returned",
+		},
+		"wrong serial number": {
+			modify:  func(data map[string]interface{}) { data["serial_number"] = "01:02" },
+			wantErr: "serial_number 01:02 doesn't match",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, root := newTestIssuedDataForKey(t, testIssuedRequest, time.Hour, testcerts.NewKey(t))
+			if tt.otherRoot {
+				root = testcerts.NewCA(t, pkix.Name{CommonName: "Test Root"}, 24*time.Hour, nil)
+			}
+			roots, err := NewCertPool(root.PEM)
+			require.NoError(t, err)
+			if tt.modify != nil {
+				tt.modify(data)
+			}
+			request := testIssuedRequest
+			if tt.request != nil {
+				tt.request(&request)
+			}
+
+			err = VerifyIssuedCertificate(data, request, roots, tt.zoneConfig)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+// newTestIssuedData returns the data Vault would return when issuing request, with a chain of a root and
+// intermediate CA
+func newTestIssuedData(t *testing.T, request CertificateRequest, validity time.Duration) map[string]interface{} {
+	data, _ := newTestIssuedDataForKey(t, request, validity, testcerts.NewKey(t))
+	return data
+}
+
+// newTestIssuedDataForKey is like newTestIssuedData, but issues the certificate for key, and also returns the root CA
+func newTestIssuedDataForKey(
+	t *testing.T,
+	request CertificateRequest,
+	validity time.Duration,
+	key *ecdsa.PrivateKey,
+) (map[string]interface{}, *testcerts.CA) {
+	root := testcerts.NewCA(t, pkix.Name{CommonName: "Test Root"}, 24*time.Hour, nil)
+	intermediate := testcerts.NewCA(t, pkix.Name{CommonName: "Test Intermediate"}, 24*time.Hour, root)
+
+	template := testcerts.NewTemplate(t, request.Subject(), validity)
+	template.DNSNames = append([]string{request.CommonName}, request.AltNames...)
+	template.EmailAddresses = request.EmailSANs
+	for _, ip := range request.IPSANs {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+	}
+	for _, uri := range request.URISANs {
+		parsed, err := url.Parse(uri)
+		require.NoError(t, err)
+		template.URIs = append(template.URIs, parsed)
+	}
+
+	return testcerts.IssuedData(t, template, key, intermediate, root), root
+}
+
+func TestVerifyIssuedCertificate_CSR(t *testing.T) {
+	key := testcerts.NewKey(t)
+	request := testIssuedRequest
+	request.CSRFile = writeTestCSR(t, key)
+
+	data, root := newTestIssuedDataForKey(t, request, time.Hour, key)
+	// Vault doesn't return a private key when signing a CSR
+	delete(data, "private_key")
+	roots, err := NewCertPool(root.PEM)
+	require.NoError(t, err)
+
+	err = VerifyIssuedCertificate(data, request, roots, nil)
+	require.NoError(t, err)
+
+	request.CSRFile = writeTestCSR(t, testcerts.NewKey(t))
+	err = VerifyIssuedCertificate(data, request, roots, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "certificate's public key doesn't match the key of csr_file")
+}
+
+// writeTestCSR writes a PEM encoded CSR for key to a temporary file, returning its path
+func writeTestCSR(t *testing.T, key *ecdsa.PrivateKey) string {
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: testIssuedRequest.CommonName},
+	}, key)
+	require.NoError(t, err)
+
+	csrFile := filepath.Join(t.TempDir(), "test.csr")
+	err = os.WriteFile(csrFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	return csrFile
+}
@@ -0,0 +1,49 @@
+package venafi
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// RSAKeyBits are the sizes of RSA keys supported by both Vault and Venafi
+	RSAKeyBits = []int{2048, 3072, 4096, 8192}
+	// ECKeyBits are the sizes of EC keys supported by both Vault and Venafi, which are those of the P256, P384 and
+	// P521 curves. Vault also supports P224, but Venafi doesn't.
+	ECKeyBits = []int{256, 384, 521}
+	// ECKeyCurves are the names the venafi-pki-backend plugin's key_curve gives the curves of ECKeyBits
+	ECKeyCurves = []string{"P256", "P384", "P521"}
+)
+
+// ValidateKeyBits checks keyBits, if given, is a size of keyType keys supported by both Vault and Venafi. Key types
+// other than rsa and ec, such as ed25519, don't have a size.
+func ValidateKeyBits(keyType string, keyBits int) error {
+	if keyBits == 0 {
+		return nil
+	}
+
+	var supportedBits []int
+	switch keyType {
+	case KeyTypeRSA:
+		supportedBits = RSAKeyBits
+	case KeyTypeEC:
+		supportedBits = ECKeyBits
+	default:
+		return fmt.Errorf("key_bits cannot be given for %s keys", keyType)
+	}
+
+	if !ContainsInt(supportedBits, keyBits) {
+		return fmt.Errorf("key_bits must be one of %s for %s keys, got %d", JoinInts(supportedBits), keyType, keyBits)
+	}
+
+	return nil
+}
+
+// ValidateKeyCurve checks keyCurve, if given, is one of ECKeyCurves
+func ValidateKeyCurve(keyCurve string) error {
+	if keyCurve != "" && !ContainsString(ECKeyCurves, keyCurve) {
+		return fmt.Errorf("key_curve must be one of %s, got %s", strings.Join(ECKeyCurves, ", "), keyCurve)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package venafi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKeyBits(t *testing.T) {
+	tests := map[string]struct {
+		keyType string
+		keyBits int
+		wantErr bool
+	}{
+		"no key bits":          {keyType: "ed25519"},
+		"rsa":                  {keyType: KeyTypeRSA, keyBits: 4096},
+		"ec":                   {keyType: KeyTypeEC, keyBits: 384},
+		"unsupported rsa size": {keyType: KeyTypeRSA, keyBits: 1024, wantErr: true},
+		"P224 ec key":          {keyType: KeyTypeEC, keyBits: 224, wantErr: true},
+		"rsa size for ec key":  {keyType: KeyTypeEC, keyBits: 2048, wantErr: true},
+		"key bits for ed25519": {keyType: "ed25519", keyBits: 256, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateKeyBits(tt.keyType, tt.keyBits)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateKeyCurve(t *testing.T) {
+	for _, curve := range append([]string{""}, ECKeyCurves...) {
+		require.NoError(t, ValidateKeyCurve(curve))
+	}
+	require.Error(t, ValidateKeyCurve("P224"))
+	require.Error(t, ValidateKeyCurve("p256"))
+}
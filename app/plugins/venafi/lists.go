@@ -0,0 +1,50 @@
+package venafi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ContainsString returns whether values contains value
+func ContainsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsInt returns whether values contains value
+func ContainsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JoinInts returns values separated by commas, for error messages listing valid values
+func JoinInts(values []int) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = fmt.Sprint(v)
+	}
+
+	return strings.Join(strs, ", ")
+}
+
+// StringListVal returns values as a cty list, for writing list attributes to HCL
+func StringListVal(values []string) cty.Value {
+	ctyValues := make([]cty.Value, len(values))
+	for i, value := range values {
+		ctyValues[i] = cty.StringVal(value)
+	}
+
+	return cty.ListVal(ctyValues)
+}
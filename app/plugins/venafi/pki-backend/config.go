@@ -21,6 +21,12 @@ type VenafiPKIBackendConfig struct {
 	Version string
 	// BuildArch allows defining the build architecture
 	BuildArch string `hcl:"build_arch,optional"`
+	// TestCertificateCleanup is what to do with test certificates once they're issued and verified, either none or
+	// revoke, unless a test_certificate gives its own cleanup
+	TestCertificateCleanup string `hcl:"test_certificate_cleanup,optional"`
+	// TestCertificateCA is the path of a PEM file of the CA certificates that test certificates must chain to, such as
+	// the root of the zones' certificate authority. The system's roots are trusted if it isn't given.
+	TestCertificateCA string `hcl:"test_certificate_ca,optional"`
 
 	Roles []Role `hcl:"role,block"`
 }
@@ -30,7 +36,7 @@ type Role struct {
 	Secret    ZonedSecret                 `hcl:"secret,block"`
 	TestCerts []venafi.CertificateRequest `hcl:"test_certificate,block"`
 
-	OptionalConfig *venafi.OptionalConfig `hcl:"optional_config,block"`
+	OptionalConfig *OptionalConfig `hcl:"optional_config,block"`
 }
 
 // ZonedSecret Used to overly the zone requirement on VenafiSecrets with PKI Backend plugin
@@ -45,6 +51,10 @@ func (c *VenafiPKIBackendConfig) ValidateConfig() error {
 	if err != nil {
 		return err
 	}
+	err = venafi.ValidateCleanup("test_certificate_cleanup", c.TestCertificateCleanup)
+	if err != nil {
+		return err
+	}
 	if len(c.Roles) == 0 {
 		return fmt.Errorf("error at least one role must be provided: %w", errors.ErrBlankParam)
 	}
@@ -70,6 +80,13 @@ func (r *Role) Validate() error {
 		}
 	}
 
+	for _, testCert := range r.TestCerts {
+		err = testCert.Validate()
+		if err != nil {
+			return fmt.Errorf("error with test_certificate %s of role %s: %w", testCert.CommonName, r.Name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -210,7 +227,12 @@ func askForRole(questioner questions.Questioner) (*Role, error) {
 	if err != nil {
 		return nil, err
 	}
-	role.OptionalConfig = optionalConfig
+	if optionalConfig != nil {
+		role.OptionalConfig, err = askForOptionalConfig(questioner, optionalConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	testCertificates, err := venafi.AskForTestCertificates(questioner)
 	if err != nil {
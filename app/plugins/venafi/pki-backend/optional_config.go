@@ -0,0 +1,443 @@
+package pki_backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/questions"
+)
+
+var (
+	validStoreBy     = []string{"cn", "serial", "hash"}
+	validChainOption = []string{"first", "last"}
+	validKeyType     = []string{venafi.KeyTypeRSA, venafi.KeyTypeEC}
+	validIssuerHint  = []string{"microsoft", "digicert", "entrust"}
+)
+
+// OptionalConfig is the optional_config block of a venafi-pki-backend role. As well as the parameters shared with the
+// venafi-pki-monitor plugin, it has those specific to the backend's roles.
+type OptionalConfig struct {
+	StoreBy              string   `hcl:"store_by,optional"`
+	NoStore              bool     `hcl:"no_store,optional"`
+	ServiceGeneratedCert bool     `hcl:"service_generated_cert,optional"`
+	StorePrivateKey      bool     `hcl:"store_pkey,optional"`
+	ChainOption          string   `hcl:"chain_option,optional"`
+	KeyType              string   `hcl:"key_type,optional"`
+	KeyBits              int      `hcl:"key_bits,optional"`
+	KeyCurve             string   `hcl:"key_curve,optional"`
+	AllowedDomains       []string `hcl:"allowed_domains,optional"`
+	AllowSubdomains      bool     `hcl:"allow_subdomains,optional"`
+	ServerTimeout        int      `hcl:"server_timeout,optional"`
+	CustomFields         []string `hcl:"custom_fields,optional"`
+	MinCertTimeLeft      string   `hcl:"min_cert_time_left,optional"`
+	IgnoreLocalStorage   bool     `hcl:"ignore_local_storage,optional"`
+	IssuerHint           string   `hcl:"issuer_hint,optional"`
+
+	venafi.OptionalConfig `hcl:",remain"` // gohcl currently ignores any field without hcl tags, even in an embedded struct with nested tagged fields
+}
+
+func (oc *OptionalConfig) Validate() error {
+	err := oc.OptionalConfig.Validate()
+	if err != nil {
+		return err
+	}
+
+	if oc.StoreBy != "" && !venafi.ContainsString(validStoreBy, oc.StoreBy) {
+		return fmt.Errorf("store_by must be one of %s, got %s", strings.Join(validStoreBy, ", "), oc.StoreBy)
+	}
+	if oc.NoStore && (oc.StoreBy != "" || oc.StorePrivateKey) {
+		return fmt.Errorf("store_by and store_pkey cannot be given along with no_store")
+	}
+	if oc.ChainOption != "" && !venafi.ContainsString(validChainOption, oc.ChainOption) {
+		return fmt.Errorf("chain_option must be one of %s, got %s", strings.Join(validChainOption, ", "), oc.ChainOption)
+	}
+
+	err = oc.validateKey()
+	if err != nil {
+		return err
+	}
+
+	for _, domain := range oc.AllowedDomains {
+		if domain == "" {
+			return fmt.Errorf("allowed_domains cannot contain an empty domain")
+		}
+	}
+	if oc.ServerTimeout < 0 {
+		return fmt.Errorf("server_timeout must be a number of seconds, got %d", oc.ServerTimeout)
+	}
+	for _, customField := range oc.CustomFields {
+		name, _, found := strings.Cut(customField, "=")
+		if !found || name == "" {
+			return fmt.Errorf("custom_fields must be given as name=value, got %s", customField)
+		}
+	}
+	if oc.MinCertTimeLeft != "" {
+		_, err = time.ParseDuration(oc.MinCertTimeLeft)
+		if err != nil {
+			return fmt.Errorf("cannot parse min_cert_time_left: %s", err)
+		}
+	}
+	if oc.IssuerHint != "" && !venafi.ContainsString(validIssuerHint, oc.IssuerHint) {
+		return fmt.Errorf("issuer_hint must be one of %s, got %s", strings.Join(validIssuerHint, ", "), oc.IssuerHint)
+	}
+
+	return nil
+}
+
+func (oc *OptionalConfig) validateKey() error {
+	if oc.KeyType != "" && !venafi.ContainsString(validKeyType, oc.KeyType) {
+		return fmt.Errorf("key_type must be one of %s, got %s", strings.Join(validKeyType, ", "), oc.KeyType)
+	}
+
+	// The plugin defaults to RSA keys
+	isEC := oc.KeyType == "ec"
+	if oc.KeyBits != 0 {
+		if isEC {
+			return fmt.Errorf("key_bits can only be given for rsa keys, use key_curve for ec keys")
+		}
+		err := venafi.ValidateKeyBits(venafi.KeyTypeRSA, oc.KeyBits)
+		if err != nil {
+			return err
+		}
+	}
+	if oc.KeyCurve != "" {
+		if !isEC {
+			return fmt.Errorf("key_curve can only be given for ec keys, use key_bits for rsa keys")
+		}
+		err := venafi.ValidateKeyCurve(oc.KeyCurve)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (oc *OptionalConfig) WriteHCL(hclBody *hclwrite.Body) {
+	oc.OptionalConfig.WriteHCL(hclBody)
+
+	if oc.StoreBy != "" {
+		hclBody.SetAttributeValue("store_by", cty.StringVal(oc.StoreBy))
+	}
+	if oc.NoStore {
+		hclBody.SetAttributeValue("no_store", cty.True)
+	}
+	if oc.ServiceGeneratedCert {
+		hclBody.SetAttributeValue("service_generated_cert", cty.True)
+	}
+	if oc.StorePrivateKey {
+		hclBody.SetAttributeValue("store_pkey", cty.True)
+	}
+	if oc.ChainOption != "" {
+		hclBody.SetAttributeValue("chain_option", cty.StringVal(oc.ChainOption))
+	}
+	if oc.KeyType != "" {
+		hclBody.SetAttributeValue("key_type", cty.StringVal(oc.KeyType))
+	}
+	if oc.KeyBits != 0 {
+		hclBody.SetAttributeValue("key_bits", cty.NumberIntVal(int64(oc.KeyBits)))
+	}
+	if oc.KeyCurve != "" {
+		hclBody.SetAttributeValue("key_curve", cty.StringVal(oc.KeyCurve))
+	}
+	if len(oc.AllowedDomains) != 0 {
+		hclBody.SetAttributeValue("allowed_domains", venafi.StringListVal(oc.AllowedDomains))
+	}
+	if oc.AllowSubdomains {
+		hclBody.SetAttributeValue("allow_subdomains", cty.True)
+	}
+	if oc.ServerTimeout != 0 {
+		hclBody.SetAttributeValue("server_timeout", cty.NumberIntVal(int64(oc.ServerTimeout)))
+	}
+	if len(oc.CustomFields) != 0 {
+		hclBody.SetAttributeValue("custom_fields", venafi.StringListVal(oc.CustomFields))
+	}
+	if oc.MinCertTimeLeft != "" {
+		hclBody.SetAttributeValue("min_cert_time_left", cty.StringVal(oc.MinCertTimeLeft))
+	}
+	if oc.IgnoreLocalStorage {
+		hclBody.SetAttributeValue("ignore_local_storage", cty.True)
+	}
+	if oc.IssuerHint != "" {
+		hclBody.SetAttributeValue("issuer_hint", cty.StringVal(oc.IssuerHint))
+	}
+}
+
+// GetAsMap returns the parameters to write to the role. Those specific to the backend are only included if they're
+// given, so that the plugin's defaults are used otherwise.
+func (oc *OptionalConfig) GetAsMap() map[string]interface{} {
+	if oc == nil {
+		return map[string]interface{}{}
+	}
+
+	parameters := oc.OptionalConfig.GetAsMap()
+	for name, value := range oc.getBackendParameters() {
+		parameters[name] = value
+	}
+
+	return parameters
+}
+
+func (oc *OptionalConfig) getBackendParameters() map[string]interface{} {
+	parameters := map[string]interface{}{}
+
+	if oc.StoreBy != "" {
+		parameters["store_by"] = oc.StoreBy
+	}
+	if oc.NoStore {
+		parameters["no_store"] = true
+	}
+	if oc.ServiceGeneratedCert {
+		parameters["service_generated_cert"] = true
+	}
+	if oc.StorePrivateKey {
+		parameters["store_pkey"] = true
+	}
+	if oc.ChainOption != "" {
+		parameters["chain_option"] = oc.ChainOption
+	}
+	if oc.KeyType != "" {
+		parameters["key_type"] = oc.KeyType
+	}
+	if oc.KeyBits != 0 {
+		parameters["key_bits"] = oc.KeyBits
+	}
+	if oc.KeyCurve != "" {
+		parameters["key_curve"] = oc.KeyCurve
+	}
+	if len(oc.AllowedDomains) != 0 {
+		parameters["allowed_domains"] = oc.AllowedDomains
+	}
+	if oc.AllowSubdomains {
+		parameters["allow_subdomains"] = true
+	}
+	if oc.ServerTimeout != 0 {
+		parameters["server_timeout"] = oc.ServerTimeout
+	}
+	if len(oc.CustomFields) != 0 {
+		parameters["custom_fields"] = oc.CustomFields
+	}
+	if oc.MinCertTimeLeft != "" {
+		parameters["min_cert_time_left"] = oc.MinCertTimeLeft
+	}
+	if oc.IgnoreLocalStorage {
+		parameters["ignore_local_storage"] = true
+	}
+	if oc.IssuerHint != "" {
+		parameters["issuer_hint"] = oc.IssuerHint
+	}
+
+	return parameters
+}
+
+// getMaxTTL returns the role's max_ttl, or an empty string if it isn't given
+func (oc *OptionalConfig) getMaxTTL() string {
+	if oc == nil {
+		return ""
+	}
+
+	return oc.MaxTTL
+}
+
+// getCSROrigin returns where the CSRs of the role's certificates are generated, which is by Venafi with
+// service_generated_cert, and otherwise by the plugin
+func (oc *OptionalConfig) getCSROrigin() certificate.CSrOriginOption {
+	if oc != nil && oc.ServiceGeneratedCert {
+		return certificate.ServiceGeneratedCSR
+	}
+
+	return certificate.LocalGeneratedCSR
+}
+
+// GetExpectedRoleData returns the parameters that reading the role back should return. The plugin returns durations
+// as a number of seconds, so they are converted to match.
+func (oc *OptionalConfig) GetExpectedRoleData() map[string]interface{} {
+	if oc == nil {
+		return map[string]interface{}{}
+	}
+
+	expected := oc.getBackendParameters()
+	if oc.GenerateLease {
+		expected["generate_lease"] = true
+	}
+	for name, duration := range map[string]string{
+		"ttl":                oc.TTL,
+		"max_ttl":            oc.MaxTTL,
+		"min_cert_time_left": oc.MinCertTimeLeft,
+	} {
+		if duration == "" {
+			continue
+		}
+		// Already validated by Validate
+		parsed, _ := time.ParseDuration(duration)
+		expected[name] = int64(parsed.Seconds())
+	}
+
+	return expected
+}
+
+// askForOptionalConfig asks for the backend specific role parameters, after the shared ones in optionalConfig have
+// been asked for
+func askForOptionalConfig(questioner questions.Questioner, optionalConfig *venafi.OptionalConfig) (*OptionalConfig, error) {
+	q := map[string]questions.Question{
+		"store": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "Should Vault store the certificates it issues?",
+			Items:    []string{"Yes", "No"},
+		}),
+		"store_by": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "What should certificates be stored by?",
+			Items:    []string{"Common name (cn)", "Serial number (serial)", "Hash of the request (hash)"},
+		}),
+		"store_pkey": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "Should the certificates' private keys also be stored?",
+			Items:    []string{"Yes", "No"},
+		}),
+		"ignore_local_storage": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "Should stored certificates be ignored, always requesting new ones from Venafi?",
+			Items:    []string{"Yes", "No"},
+		}),
+		"min_cert_time_left": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "How long must a stored certificate have left before a new one is requested instead? (blank to use plugin default)",
+			Validate: validateOptionalDuration,
+		}),
+		"service_generated_cert": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "Should Venafi generate the certificates' private keys rather than Vault?",
+			Items:    []string{"Yes", "No"},
+		}),
+		"key_type": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "What type of private keys should certificates have?",
+			Items:    []string{"RSA", "ECDSA"},
+		}),
+		"key_bits": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "How many bits should RSA keys have?",
+			Items:    []string{"2048", "3072", "4096", "8192"},
+		}),
+		"key_curve": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "Which curve should ECDSA keys use?",
+			Items:    venafi.ECKeyCurves,
+		}),
+		"chain_option": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "Where should the issuing CA certificate be in returned chains?",
+			Items:    []string{"Last", "First"},
+		}),
+		"allowed_domains": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "Which domains can certificates be requested for? (comma separated, blank for any)",
+		}),
+		"allow_subdomains": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "Can certificates also be requested for subdomains of those domains?",
+			Items:    []string{"Yes", "No"},
+		}),
+		"server_timeout": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "How many seconds should Vault wait for Venafi to issue a certificate? (blank to use plugin default)",
+			Validate: func(input string) error {
+				if input == "" {
+					return nil
+				}
+				_, err := strconv.Atoi(input)
+				return err
+			},
+		}),
+		"custom_fields": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "Which custom fields should be set on certificates in Venafi? (comma separated name=value pairs, blank for none)",
+		}),
+		"issuer_hint": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "Which CA issues certificates in the Venafi zone, for TPP to set their validity?",
+			Items:    []string{"Default", "Microsoft", "DigiCert", "Entrust"},
+		}),
+	}
+
+	err := questions.AskQuestions([]questions.Question{
+		&questions.QuestionBranch{
+			ConditionQuestion: q["store"],
+			ConditionAnswer:   "Yes",
+			BranchA: []questions.Question{
+				q["store_by"],
+				q["store_pkey"],
+				q["ignore_local_storage"],
+				q["min_cert_time_left"],
+			},
+		},
+		q["service_generated_cert"],
+		&questions.QuestionBranch{
+			ConditionQuestion: q["key_type"],
+			ConditionAnswer:   "RSA",
+			BranchA:           []questions.Question{q["key_bits"]},
+			BranchB:           []questions.Question{q["key_curve"]},
+		},
+		q["chain_option"],
+		q["allowed_domains"],
+		q["allow_subdomains"],
+		q["server_timeout"],
+		q["custom_fields"],
+		q["issuer_hint"],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	backendConfig := &OptionalConfig{
+		ServiceGeneratedCert: q["service_generated_cert"].Answer() == "Yes",
+		ChainOption:          strings.ToLower(string(q["chain_option"].Answer())),
+		AllowedDomains:       splitList(string(q["allowed_domains"].Answer())),
+		AllowSubdomains:      q["allow_subdomains"].Answer() == "Yes",
+		CustomFields:         splitList(string(q["custom_fields"].Answer())),
+		OptionalConfig:       *optionalConfig,
+	}
+
+	if q["store"].Answer() == "Yes" {
+		storeBy := string(q["store_by"].Answer())
+		backendConfig.StoreBy = storeBy[strings.Index(storeBy, "(")+1 : len(storeBy)-1]
+		backendConfig.StorePrivateKey = q["store_pkey"].Answer() == "Yes"
+		backendConfig.IgnoreLocalStorage = q["ignore_local_storage"].Answer() == "Yes"
+		backendConfig.MinCertTimeLeft = string(q["min_cert_time_left"].Answer())
+	} else {
+		backendConfig.NoStore = true
+	}
+
+	if q["key_type"].Answer() == "RSA" {
+		backendConfig.KeyType = "rsa"
+		backendConfig.KeyBits, _ = strconv.Atoi(string(q["key_bits"].Answer()))
+	} else {
+		backendConfig.KeyType = "ec"
+		backendConfig.KeyCurve = string(q["key_curve"].Answer())
+	}
+
+	if serverTimeout := string(q["server_timeout"].Answer()); serverTimeout != "" {
+		backendConfig.ServerTimeout, _ = strconv.Atoi(serverTimeout)
+	}
+
+	if issuerHint := q["issuer_hint"].Answer(); issuerHint != "Default" {
+		backendConfig.IssuerHint = strings.ToLower(string(issuerHint))
+	}
+
+	return backendConfig, nil
+}
+
+func validateOptionalDuration(input string) error {
+	if input == "" {
+		return nil
+	}
+
+	_, err := time.ParseDuration(input)
+	return err
+}
+
+// splitList splits a comma separated answer into its trimmed items, returning nil if it's blank
+func splitList(answer string) []string {
+	var items []string
+	for _, item := range strings.Split(answer, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
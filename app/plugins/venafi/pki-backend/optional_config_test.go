@@ -0,0 +1,184 @@
+package pki_backend
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestOptionalConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		config  OptionalConfig
+		wantErr bool
+	}{
+		"all parameters": {
+			config: OptionalConfig{
+				StoreBy:              "serial",
+				StorePrivateKey:      true,
+				ServiceGeneratedCert: true,
+				ChainOption:          "first",
+				KeyType:              "rsa",
+				KeyBits:              4096,
+				AllowedDomains:       []string{"example.com"},
+				AllowSubdomains:      true,
+				ServerTimeout:        60,
+				CustomFields:         []string{"team=platform"},
+				MinCertTimeLeft:      "720h",
+				IgnoreLocalStorage:   true,
+				IssuerHint:           "digicert",
+				OptionalConfig:       venafi.OptionalConfig{TTL: "1h", MaxTTL: "2h"},
+			},
+		},
+		"ec key": {
+			config: OptionalConfig{KeyType: "ec", KeyCurve: "P384"},
+		},
+		"invalid store_by": {
+			config:  OptionalConfig{StoreBy: "name"},
+			wantErr: true,
+		},
+		"store_by with no_store": {
+			config:  OptionalConfig{StoreBy: "cn", NoStore: true},
+			wantErr: true,
+		},
+		"invalid chain_option": {
+			config:  OptionalConfig{ChainOption: "middle"},
+			wantErr: true,
+		},
+		"invalid key_bits": {
+			config:  OptionalConfig{KeyBits: 1024},
+			wantErr: true,
+		},
+		"key_curve for rsa key": {
+			config:  OptionalConfig{KeyCurve: "P256"},
+			wantErr: true,
+		},
+		"key_bits for ec key": {
+			config:  OptionalConfig{KeyType: "ec", KeyBits: 2048},
+			wantErr: true,
+		},
+		"custom field without value": {
+			config:  OptionalConfig{CustomFields: []string{"team"}},
+			wantErr: true,
+		},
+		"invalid min_cert_time_left": {
+			config:  OptionalConfig{MinCertTimeLeft: "30 days"},
+			wantErr: true,
+		},
+		"invalid issuer_hint": {
+			config:  OptionalConfig{IssuerHint: "letsencrypt"},
+			wantErr: true,
+		},
+		"invalid ttl": {
+			config:  OptionalConfig{OptionalConfig: venafi.OptionalConfig{TTL: "2h", MaxTTL: "1h"}},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOptionalConfig_GetAsMap(t *testing.T) {
+	config := &OptionalConfig{
+		StoreBy:         "hash",
+		KeyType:         "ec",
+		KeyCurve:        "P256",
+		AllowedDomains:  []string{"example.com"},
+		MinCertTimeLeft: "24h",
+		OptionalConfig:  venafi.OptionalConfig{TTL: "1h"},
+	}
+
+	require.Equal(t, map[string]interface{}{
+		"ttl":                "1h",
+		"max_ttl":            "",
+		"allow_any_name":     false,
+		"generate_lease":     false,
+		"store_by":           "hash",
+		"key_type":           "ec",
+		"key_curve":          "P256",
+		"allowed_domains":    []string{"example.com"},
+		"min_cert_time_left": "24h",
+	}, config.GetAsMap())
+
+	require.Equal(t, map[string]interface{}{
+		"ttl":                int64(3600),
+		"store_by":           "hash",
+		"key_type":           "ec",
+		"key_curve":          "P256",
+		"allowed_domains":    []string{"example.com"},
+		"min_cert_time_left": int64(86400),
+	}, config.GetExpectedRoleData())
+}
+
+func TestVerifyVenafiRole(t *testing.T) {
+	const rolePath = "pki/roles/web"
+
+	expected := (&OptionalConfig{
+		KeyBits:        4096,
+		AllowedDomains: []string{"example.com"},
+		OptionalConfig: venafi.OptionalConfig{TTL: "1h"},
+	}).GetExpectedRoleData()
+
+	tests := map[string]struct {
+		data    map[string]interface{}
+		wantErr bool
+	}{
+		"matching role": {
+			data: map[string]interface{}{
+				"venafi_secret":   "vaas",
+				"key_bits":        json.Number("4096"),
+				"allowed_domains": []interface{}{"example.com"},
+				"ttl":             json.Number("3600"),
+			},
+		},
+		"parameter not returned by plugin": {
+			data: map[string]interface{}{
+				"venafi_secret": "vaas",
+				"key_bits":      json.Number("4096"),
+			},
+		},
+		"different secret": {
+			data:    map[string]interface{}{"venafi_secret": "tpp"},
+			wantErr: true,
+		},
+		"different parameter": {
+			data: map[string]interface{}{
+				"venafi_secret": "vaas",
+				"key_bits":      json.Number("2048"),
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+
+			reportExpectations(report, section, check)
+			check.On("Errorf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+			vaultAPIClient.On("ReadValue", rolePath).Return(tt.data, nil)
+
+			err := VerifyVenafiRole(section, vaultAPIClient, rolePath, "vaas", expected)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
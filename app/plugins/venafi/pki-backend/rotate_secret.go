@@ -0,0 +1,75 @@
+package pki_backend
+
+import (
+	"fmt"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// RotateSecrets rewrites the Venafi secret of each role using secretName, or of every role if secretName is blank,
+// with fresh TPP tokens or the API key given in the config. The rest of the mount is left alone, and the role's test
+// certificates are then requested to check the new secret works.
+func (c *VenafiPKIBackendConfig) RotateSecrets(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	secretName string,
+) (bool, error) {
+	venafiClients := make(map[string]venafi_wrapper.VenafiWrapper)
+	for _, role := range c.Roles {
+		if secretName != "" && role.Secret.Name != secretName {
+			continue
+		}
+		if _, ok := venafiClients[role.Secret.Name]; ok {
+			continue
+		}
+
+		venafiClient, err := connectToVenafi(
+			report.AddSection(fmt.Sprintf("Connecting to Venafi with secret %s of %s", role.Secret.Name, c.MountPath)),
+			role.Secret,
+		)
+		if err != nil {
+			return true, err
+		}
+		venafiClients[role.Secret.Name] = venafiClient
+	}
+	if len(venafiClients) == 0 {
+		return false, nil
+	}
+
+	return true, c.rotateSecrets(report, vaultClient, venafiClients, secretName)
+}
+
+func (c *VenafiPKIBackendConfig) rotateSecrets(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	venafiClients map[string]venafi_wrapper.VenafiWrapper,
+	secretName string,
+) error {
+	roles := make([]venafi.RotatingRole, len(c.Roles))
+	for i, role := range c.Roles {
+		roles[i] = venafi.RotatingRole{
+			Name:         role.Name,
+			SecretName:   role.Secret.Name,
+			Secret:       role.Secret,
+			Zone:         &c.Roles[i].Secret.Zone,
+			HasTestCerts: len(role.TestCerts) != 0,
+		}
+	}
+
+	return venafi.RotateVenafiSecrets(
+		report,
+		vaultClient,
+		venafiClients,
+		c.MountPath,
+		venafi.SecretsEngine,
+		roles,
+		secretName,
+		func(rotateSection reporter.Section, i int) error {
+			role := c.Roles[i]
+			return c.requestTestCertificates(rotateSection, vaultClient, role, venafiClients[role.Secret.Name])
+		},
+	)
+}
@@ -0,0 +1,169 @@
+package pki_backend
+
+import (
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/venafi/tpp"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	mockVenafiWrapper "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestVenafiPKIBackendConfig_rotateSecrets(t *testing.T) {
+	testCSR := venafi.CertificateRequest{
+		CommonName:   "test.venafidemo.com",
+		OU:           "VVW",
+		Organisation: "OpenCredo",
+		Locality:     "London",
+		Province:     "London",
+		Country:      "GB",
+		TTL:          "1h",
+	}
+	tppSecret := ZonedSecret{
+		Name: "tpp",
+		Zone: "zone",
+		VenafiSecret: venafi.VenafiSecret{
+			TPP: &venafi.VenafiTPPConnection{
+				URL:      "https://tpp.example.com",
+				Username: "username",
+				Password: "password",
+			},
+		},
+	}
+	tokenSecret := ZonedSecret{
+		Name: "tpp",
+		Zone: "zone",
+		VenafiSecret: venafi.VenafiSecret{
+			TPP: &venafi.VenafiTPPConnection{
+				URL:          "https://tpp.example.com",
+				AccessToken:  "access token",
+				RefreshToken: "refresh token",
+			},
+		},
+	}
+	tppSecretData := map[string]interface{}{
+		"access_token":  "new access token",
+		"refresh_token": "new refresh token",
+		"url":           "https://tpp.example.com",
+		"zone":          "zone",
+	}
+
+	tests := map[string]struct {
+		roles       []Role
+		wantSecret  map[string]interface{}
+		wantWarning bool
+	}{
+		"tpp secret with test certificate": {
+			roles: []Role{
+				{Name: "web", Secret: tppSecret, TestCerts: []venafi.CertificateRequest{testCSR}},
+			},
+			wantSecret: tppSecretData,
+		},
+		"tpp tokens refreshed": {
+			roles: []Role{
+				{Name: "web", Secret: tokenSecret, TestCerts: []venafi.CertificateRequest{testCSR}},
+			},
+			wantSecret: tppSecretData,
+		},
+		"secret shared by roles": {
+			roles: []Role{
+				{Name: "web", Secret: tokenSecret, TestCerts: []venafi.CertificateRequest{testCSR}},
+				{Name: "database", Secret: tokenSecret, TestCerts: []venafi.CertificateRequest{testCSR}},
+			},
+			wantSecret: tppSecretData,
+		},
+		"vaas secret without test certificates": {
+			roles: []Role{
+				{
+					Name: "web",
+					Secret: ZonedSecret{
+						Name: "vaas",
+						Zone: "zone",
+						VenafiSecret: venafi.VenafiSecret{
+							VaaS: &venafi.VenafiVaaSConnection{APIKey: "new API key"},
+						},
+					},
+				},
+			},
+			wantSecret: map[string]interface{}{
+				"apikey": "new API key",
+				"zone":   "zone",
+			},
+			wantWarning: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+			defer venafiClient.AssertExpectations(t)
+			defer check.AssertExpectations(t)
+
+			reportExpectations(report, section, check)
+			check.On("Successf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+			venafiClient.On("ReadZoneConfiguration", "zone").Return(&endpoint.ZoneConfiguration{}, nil).Maybe()
+
+			// Only the secret, once, and the test certificates should be written
+			secret := tt.roles[0].Secret
+			vaultAPIClient.On("WriteValue", "pki/venafi/"+secret.Name, tt.wantSecret).Return(nil, nil).Once()
+			if secret.TPP != nil && secret.TPP.RefreshToken != "" {
+				// The configured refresh token was spent by an earlier rotation, so the one stored in Vault is used
+				vaultAPIClient.On("ReadValue", "pki/venafi/"+secret.Name).Return(map[string]interface{}{
+					"access_token":  "stored access token",
+					"refresh_token": "stored refresh token",
+				}, nil).Once()
+				venafiClient.On("RefreshAccessToken", &endpoint.Authentication{
+					RefreshToken: "stored refresh token",
+					ClientId:     "hashicorp-vault-by-venafi",
+				}).Return(tpp.OauthRefreshAccessTokenResponse{
+					Access_token:  "new access token",
+					Refresh_token: "new refresh token",
+				}, nil).Once()
+			} else if secret.TPP != nil {
+				venafiClient.On("GetRefreshToken", mock.Anything).Return(tpp.OauthGetRefreshTokenResponse{
+					Access_token:  "new access token",
+					Refresh_token: "new refresh token",
+				}, nil).Once()
+			}
+			ca, caFile := newTestCA(t)
+			for _, role := range tt.roles {
+				for _, cert := range role.TestCerts {
+					vaultAPIClient.On("WriteValue", "pki/issue/"+role.Name, cert.ToMap()).Return(newTestIssuedData(t, ca, cert), nil)
+				}
+			}
+			if tt.wantWarning {
+				check.On("Warning", mock.AnythingOfType("string"))
+			}
+
+			config := &VenafiPKIBackendConfig{MountPath: "pki", TestCertificateCA: caFile, Roles: tt.roles}
+			venafiClients := map[string]venafi_wrapper.VenafiWrapper{secret.Name: venafiClient}
+			err := config.rotateSecrets(report, vaultAPIClient, venafiClients, "")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVenafiPKIBackendConfig_RotateSecrets_NoMatchingSecret(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+
+	config := &VenafiPKIBackendConfig{
+		MountPath: "pki",
+		Roles:     []Role{{Name: "web", Secret: ZonedSecret{Name: "tpp"}}},
+	}
+	rotated, err := config.RotateSecrets(report, vaultAPIClient, "vaas")
+	require.NoError(t, err)
+	require.False(t, rotated)
+}
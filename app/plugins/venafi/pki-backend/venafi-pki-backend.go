@@ -1,7 +1,9 @@
 package pki_backend
 
 import (
+	"crypto/x509"
 	"fmt"
+	"os"
 
 	"github.com/opencredo/venafi-vault-wizard/app/github"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
@@ -25,6 +27,11 @@ func (c *VenafiPKIBackendConfig) GetDownloadURL() (string, error) {
 	)
 }
 
+// GetKind returns PluginKindSecret, as the plugin is a secrets engine
+func (c *VenafiPKIBackendConfig) GetKind() api.PluginKind {
+	return api.PluginKindSecret
+}
+
 func (c *VenafiPKIBackendConfig) Configure(report reporter.Report, vaultClient api.VaultAPIClient) error {
 	configurePluginSection := report.AddSection("Setting up venafi-pki-backend")
 
@@ -42,23 +49,63 @@ func (c *VenafiPKIBackendConfig) Configure(report reporter.Report, vaultClient a
 	return nil
 }
 
+// Preflight authenticates to Venafi with each role's secret and reads its zone, before anything is installed
+func (c *VenafiPKIBackendConfig) Preflight(report reporter.Report) error {
+	preflightSection := report.AddSection("Checking Venafi for venafi-pki-backend")
+
+	for _, role := range c.Roles {
+		venafiClient, err := connectToVenafi(preflightSection, role.Secret)
+		if err != nil {
+			return err
+		}
+
+		err = role.Preflight(preflightSection, venafiClient)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// connectToVenafi authenticates to Venafi with secret, reporting whether it succeeded
+func connectToVenafi(reportSection reporter.Section, secret ZonedSecret) (venafi_wrapper.VenafiWrapper, error) {
+	check := reportSection.AddCheck(fmt.Sprintf("Connecting to Venafi with secret %s...", secret.Name))
+	venafiClient, err := vcert_wrapper.NewVenafiClient(secret.VenafiSecret)
+	if err != nil {
+		check.Errorf("Error connecting to Venafi with secret %s: %s", secret.Name, err)
+		return nil, err
+	}
+	check.Successf("Connected to Venafi with secret %s", secret.Name)
+
+	return venafiClient, nil
+}
+
+// Preflight reads the zone of the role's secret from Venafi, and checks the role's test certificates meet its policy
+func (r *Role) Preflight(preflightSection reporter.Section, venafiClient venafi_wrapper.VenafiWrapper) error {
+	zoneConfig, err := venafi.CheckVenafiZone(preflightSection, venafiClient, fmt.Sprintf("role %s", r.Name), r.Secret.Zone)
+	if err != nil {
+		return err
+	}
+
+	return venafi.CheckTestCertificates(
+		preflightSection,
+		venafiClient,
+		r.Secret.Zone,
+		zoneConfig,
+		r.TestCerts,
+		r.OptionalConfig.getMaxTTL(),
+		r.OptionalConfig.getCSROrigin(),
+	)
+}
+
 func (r *Role) Configure(
 	configurePluginSection reporter.Section,
 	mountPath string,
 	vaultClient api.VaultAPIClient,
 	venafiClient venafi_wrapper.VenafiWrapper,
 ) error {
-	var err error
-
-	err = venafi.ConfigureVenafiSecret(
-		configurePluginSection,
-		vaultClient,
-		venafiClient,
-		fmt.Sprintf("%s/venafi/%s", mountPath, r.Secret.Name),
-		r.Secret,
-		venafi.SecretsEngine,
-		&r.Secret.Zone,
-	)
+	err := r.configureSecret(configurePluginSection, mountPath, vaultClient, venafiClient)
 	if err != nil {
 		return err
 	}
@@ -77,26 +124,178 @@ func (r *Role) Configure(
 	return nil
 }
 
+// configureSecret writes the role's Venafi secret, along with the zone, requesting fresh TPP tokens if needed
+func (r *Role) configureSecret(
+	reportSection reporter.Section,
+	mountPath string,
+	vaultClient api.VaultAPIClient,
+	venafiClient venafi_wrapper.VenafiWrapper,
+) error {
+	return venafi.ConfigureVenafiSecret(
+		reportSection,
+		vaultClient,
+		venafiClient,
+		fmt.Sprintf("%s/venafi/%s", mountPath, r.Secret.Name),
+		r.Secret,
+		venafi.SecretsEngine,
+		&r.Secret.Zone,
+		false,
+	)
+}
+
 func (c *VenafiPKIBackendConfig) Check(report reporter.Report, vaultClient api.VaultAPIClient) error {
 	for _, role := range c.Roles {
-		roleIssuePath := fmt.Sprintf("%s/issue/%s", c.MountPath, role.Name)
-
-		fetchCertSection := report.AddSection(
-			fmt.Sprintf("Requesting test certificates from %s", roleIssuePath),
-		)
-		for _, cert := range role.TestCerts {
-			err := venafi.RequestVenafiCertificate(
-				fetchCertSection,
-				vaultClient,
-				roleIssuePath,
-				cert,
+		// Venafi is only needed to check the test certificates against the role's zone
+		var venafiClient venafi_wrapper.VenafiWrapper
+		if len(role.TestCerts) != 0 {
+			var err error
+			venafiClient, err = connectToVenafi(
+				report.AddSection(fmt.Sprintf("Connecting to Venafi for role %s", role.Name)),
+				role.Secret,
 			)
 			if err != nil {
 				return err
 			}
 		}
 
-		fetchCertSection.Info(fmt.Sprintf("Certificates can be requested using:\nvault write %s common_name=\"test.example.com\"", roleIssuePath))
+		err := c.checkRole(report, vaultClient, venafiClient, role)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRole checks the role is configured in Vault, and that its test certificates can be issued, using venafiClient
+// to read its zone
+func (c *VenafiPKIBackendConfig) checkRole(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	role Role,
+) error {
+	rolePath := fmt.Sprintf("%s/roles/%s", c.MountPath, role.Name)
+	err := VerifyVenafiRole(
+		report.AddSection(fmt.Sprintf("Checking role %s", rolePath)),
+		vaultClient,
+		rolePath,
+		role.Secret.Name,
+		role.OptionalConfig.GetExpectedRoleData(),
+	)
+	if err != nil {
+		return err
+	}
+
+	roleIssuePath := fmt.Sprintf("%s/issue/%s", c.MountPath, role.Name)
+
+	fetchCertSection := report.AddSection(
+		fmt.Sprintf("Requesting test certificates from %s", roleIssuePath),
+	)
+	err = c.requestTestCertificates(fetchCertSection, vaultClient, role, venafiClient)
+	if err != nil {
+		return err
+	}
+
+	fetchCertSection.Info(fmt.Sprintf("Certificates can be requested using:\nvault write %s common_name=\"test.example.com\"", roleIssuePath))
+	return nil
+}
+
+// requestTestCertificates requests each of the role's test certificates, checking they chain to test_certificate_ca
+// and have the subject requested, other than the fields set by the role's zone, and then cleans them up. venafiClient
+// is used to read the zone, and to revoke certificates the plugin doesn't store.
+func (c *VenafiPKIBackendConfig) requestTestCertificates(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	role Role,
+	venafiClient venafi_wrapper.VenafiWrapper,
+) error {
+	if len(role.TestCerts) == 0 {
+		return nil
+	}
+
+	roots, err := c.getTestCertificateRoots()
+	if err != nil {
+		check := reportSection.AddCheck("Reading the CA certificates to verify test certificates against...")
+		check.Errorf("Error reading test_certificate_ca: %s", err)
+		return err
+	}
+
+	zoneConfig, err := venafi.CheckVenafiZone(reportSection, venafiClient, fmt.Sprintf("role %s", role.Name), role.Secret.Zone)
+	if err != nil {
+		return err
+	}
+
+	for _, cert := range role.TestCerts {
+		data, err := venafi.RequestVenafiCertificate(
+			reportSection,
+			vaultClient,
+			c.MountPath,
+			role.Name,
+			cert,
+			roots,
+			zoneConfig,
+		)
+		if err != nil && data == nil {
+			return err
+		}
+
+		// A certificate failing verification was still issued, so is cleaned up before its error is returned
+		cleanupErr := c.cleanupTestCertificate(reportSection, vaultClient, venafiClient, role, cert, data)
+		if err != nil {
+			return err
+		}
+		if cleanupErr != nil {
+			return cleanupErr
+		}
 	}
+
 	return nil
 }
+
+// cleanupTestCertificate revokes the test certificate issued in data if cert's cleanup, or the plugin's
+// test_certificate_cleanup, is revoke
+func (c *VenafiPKIBackendConfig) cleanupTestCertificate(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	role Role,
+	cert venafi.CertificateRequest,
+	data map[string]interface{},
+) error {
+	if cert.GetCleanup(c.TestCertificateCleanup) != venafi.CleanupRevoke {
+		return nil
+	}
+
+	// Certificates stored by the plugin are revoked through its revoke endpoint, which looks them up by how they're
+	// stored. Without a stored copy, or for VaaS which can't revoke, they're handled in Venafi directly instead.
+	if role.Secret.VaaS == nil && (role.OptionalConfig == nil || !role.OptionalConfig.NoStore) {
+		var storeBy string
+		if role.OptionalConfig != nil {
+			storeBy = role.OptionalConfig.StoreBy
+		}
+		return venafi.RevokePluginCertificate(
+			reportSection,
+			vaultClient,
+			fmt.Sprintf("%s/revoke/%s", c.MountPath, role.Name),
+			storeBy,
+			data,
+		)
+	}
+
+	return venafi.RevokeVenafiCertificate(reportSection, venafiClient, role.Secret.VaaS != nil, data)
+}
+
+// getTestCertificateRoots returns a pool of the certificates in TestCertificateCA, or nil to trust the system's roots if
+// it isn't given
+func (c *VenafiPKIBackendConfig) getTestCertificateRoots() (*x509.CertPool, error) {
+	if c.TestCertificateCA == "" {
+		return nil, nil
+	}
+
+	caPEM, err := os.ReadFile(c.TestCertificateCA)
+	if err != nil {
+		return nil, err
+	}
+
+	return venafi.NewCertPool(string(caPEM))
+}
@@ -1,16 +1,22 @@
 package pki_backend
 
 import (
+	"crypto/x509/pkix"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Venafi/vcert/v4/pkg/venafi/tpp"
 	mockVenafiWrapper "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
 
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/testcerts"
 	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
 	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
 )
@@ -118,17 +124,20 @@ func TestConfigureVenafiPKIBackend(t *testing.T) {
 	}
 }
 
-func TestCheckVenafiPKIBackend(t *testing.T) {
+func TestVenafiPKIBackendConfig_checkRole(t *testing.T) {
 	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	venafiClient := new(mockVenafiWrapper.VenafiWrapper)
 	report := new(mockReport.Report)
 	section := new(mockReport.Section)
 	check := new(mockReport.Check)
 	defer vaultAPIClient.AssertExpectations(t)
+	defer venafiClient.AssertExpectations(t)
 	defer report.AssertExpectations(t)
 	defer section.AssertExpectations(t)
 	defer check.AssertExpectations(t)
 
 	reportExpectations(report, section, check)
+	check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
 
 	var pluginMountPath = "pki"
 	var roleName = "roleName"
@@ -143,27 +152,175 @@ func TestCheckVenafiPKIBackend(t *testing.T) {
 		TTL:          "1h",
 	}
 
+	// The zone overrides the organisation requested, which the certificate has instead
+	issued := testCSR
+	issued.Organisation = "Venafi"
+	venafiClient.On("ReadZoneConfiguration", "zone").Return(&endpoint.ZoneConfiguration{Organization: "Venafi"}, nil)
+
+	ca, caFile := newTestCA(t)
+	vaultAPIClient.On("ReadValue", fmt.Sprintf("%s/roles/%s", pluginMountPath, roleName)).
+		Return(map[string]interface{}{
+			"venafi_secret": "",
+			"key_type":      "rsa",
+		}, nil)
 	vaultAPIClient.On("WriteValue", roleIssuePath, testCSR.ToMap()).
 		Return(
-			map[string]interface{}{
-				"certificate": testCert,
-			}, nil,
+			newTestIssuedData(t, ca, issued), nil,
 		)
 
 	config := VenafiPKIBackendConfig{
-		MountPath: pluginMountPath,
+		MountPath:         pluginMountPath,
+		TestCertificateCA: caFile,
 		Roles: []Role{
 			{
 				Name:      roleName,
+				Secret:    ZonedSecret{Zone: "zone"},
 				TestCerts: []venafi.CertificateRequest{testCSR},
 			},
 		},
 	}
-	err := config.Check(report, vaultAPIClient)
+	err := config.checkRole(report, vaultAPIClient, venafiClient, config.Roles[0])
 	require.NoError(t, err)
 }
 
-const testCert = "-----BEGIN CERTIFICATE-----\nMIIFQDCCBCigAwIBAgITLwAAAExjVGItPJSAugAAAAAATDANBgkqhkiG9w0BAQsF\nADBNMRMwEQYKCZImiZPyLGQBGRYDY29tMRowGAYKCZImiZPyLGQBGRYKdmVuYWZp\nZGVtbzEaMBgGA1UEAxMRdmVuYWZpZGVtby1UUFAtQ0EwHhcNMjEwNDI5MTA1ODE5\nWhcNMjMwNDI5MTA1ODE5WjAeMRwwGgYDVQQDExN0ZXN0LnZlbmFmaWRlbW8uY29t\nMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAsK68Yp3BpDm/H7EY1uAn\nsu+OFuUBPNKa1XtMf3/Ajx3I8xFFbZOa89kD6i9eHoA+qdP9NeIoOf0UAIXuFnwN\nqfjF1TdbIk3QaoydW09PDv+xyBpLVTCMqSpDAK4ittxOIp3yY1WDAJbqVSCSm/hW\ncMjG6INFXtGcQhvBSL3n2Shm6TjVPmD2FORRFDwe4ax/cyMGy6rwOAEAyUK4n7SC\nLdRIFY9V5EpwjI4bQPGZc/Md2p0wRNQQF6jJt6VjGsWAzV5RsNumBbaMEsgmNOWs\nIWCqW4p7Zq81juVrGabWKeK1QLYOt/XqgYbXFKVkmmfzSUhPakdAdcdOdbpkCZrQ\n9wIDAQABo4ICRjCCAkIwHgYDVR0RBBcwFYITdGVzdC52ZW5hZmlkZW1vLmNvbTAd\nBgNVHQ4EFgQUtzq8zz3NqFExIj3Vgnh6ZcZ3j2wwHwYDVR0jBBgwFoAUg3V6VFgY\nuCIdKHe+7eUpP9ih9f4wgc4GA1UdHwSBxjCBwzCBwKCBvaCBuoaBt2xkYXA6Ly8v\nQ049dmVuYWZpZGVtby1UUFAtQ0EsQ049dHBwLENOPUNEUCxDTj1QdWJsaWMlMjBL\nZXklMjBTZXJ2aWNlcyxDTj1TZXJ2aWNlcyxDTj1Db25maWd1cmF0aW9uLERDPXZl\nbmFmaWRlbW8sREM9Y29tP2NlcnRpZmljYXRlUmV2b2NhdGlvbkxpc3Q/YmFzZT9v\nYmplY3RDbGFzcz1jUkxEaXN0cmlidXRpb25Qb2ludDCBxgYIKwYBBQUHAQEEgbkw\ngbYwgbMGCCsGAQUFBzAChoGmbGRhcDovLy9DTj12ZW5hZmlkZW1vLVRQUC1DQSxD\nTj1BSUEsQ049UHVibGljJTIwS2V5JTIwU2VydmljZXMsQ049U2VydmljZXMsQ049\nQ29uZmlndXJhdGlvbixEQz12ZW5hZmlkZW1vLERDPWNvbT9jQUNlcnRpZmljYXRl\nP2Jhc2U/b2JqZWN0Q2xhc3M9Y2VydGlmaWNhdGlvbkF1dGhvcml0eTAhBgkrBgEE\nAYI3FAIEFB4SAFcAZQBiAFMAZQByAHYAZQByMA4GA1UdDwEB/wQEAwIFoDATBgNV\nHSUEDDAKBggrBgEFBQcDATANBgkqhkiG9w0BAQsFAAOCAQEAA/nWT2AgWgDdnLrC\nTci7fAfo7yxW3QLfoULWUm7k5odQEM80I1aJo+bu+u/dW8ptWkwXUCaiHLgoVmh/\nzCto5GTmMKCNDFvjpgjYUPRItcAptPfstjPsV4jJ8N7oGJ1HYApwdZEy0cC1zKpi\n1i/iZ7iYVeVN+GPF5Sfa/eoCOpha/+8kL4b/hlY1Hpr29oKcurqPsrVLKGHCz55v\neRI58tWIWiG8nzqPK7pCFkw2Vb8DhpeZbjuU1BOcMN4itRereS5dhl/36JBtvdLq\nREed+xyGYi/tPhZ0XMjgL1zIBTAN4nPJKrN2zW/wU8Gh13MuD3HBh9/sE5zeW33D\nVCKviA==\n-----END CERTIFICATE-----"
+func TestVenafiPKIBackendConfig_requestTestCertificates(t *testing.T) {
+	var testCSR = venafi.CertificateRequest{
+		CommonName:   "test.venafidemo.com",
+		OU:           "VVW",
+		Organisation: "OpenCredo",
+		Locality:     "London",
+		Province:     "London",
+		Country:      "GB",
+		TTL:          "1h",
+		Cleanup:      venafi.CleanupRevoke,
+	}
+
+	t.Run("revoked through the plugin", func(t *testing.T) {
+		vaultAPIClient := new(mockAPI.VaultAPIClient)
+		venafiClient := newZoneVenafiClient()
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		defer vaultAPIClient.AssertExpectations(t)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		section.On("Info", mock.AnythingOfType("string")).Maybe()
+		check.On("UpdateStatus", mock.AnythingOfType("string")).Maybe()
+		check.On("Success", mock.AnythingOfType("string"))
+		check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+
+		ca, caFile := newTestCA(t)
+		vaultAPIClient.On("WriteValue", "pki/issue/roleName", testCSR.ToMap()).
+			Return(newTestIssuedData(t, ca, testCSR), nil)
+		vaultAPIClient.On("WriteValue", "pki/revoke/roleName", map[string]interface{}{
+			"certificate_uid": testCSR.CommonName,
+		}).Return(nil, nil)
+
+		config := VenafiPKIBackendConfig{
+			MountPath:         "pki",
+			TestCertificateCA: caFile,
+		}
+		role := Role{
+			Name:           "roleName",
+			TestCerts:      []venafi.CertificateRequest{testCSR},
+			OptionalConfig: &OptionalConfig{StoreBy: "cn"},
+		}
+		err := config.requestTestCertificates(section, vaultAPIClient, role, venafiClient)
+		require.NoError(t, err)
+	})
+
+	t.Run("revoked when failing verification", func(t *testing.T) {
+		vaultAPIClient := new(mockAPI.VaultAPIClient)
+		venafiClient := newZoneVenafiClient()
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		defer vaultAPIClient.AssertExpectations(t)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		section.On("Info", mock.AnythingOfType("string")).Maybe()
+		check.On("Errorf", mock.AnythingOfType("string"), mock.Anything)
+		check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+
+		// Issued by a CA other than test_certificate_ca, so isn't trusted
+		_, caFile := newTestCA(t)
+		otherCA, _ := newTestCA(t)
+		data := newTestIssuedData(t, otherCA, testCSR)
+		vaultAPIClient.On("WriteValue", "pki/issue/roleName", testCSR.ToMap()).Return(data, nil)
+		vaultAPIClient.On("WriteValue", "pki/revoke/roleName", map[string]interface{}{
+			"certificate_uid": data["serial_number"],
+		}).Return(nil, nil)
+
+		config := VenafiPKIBackendConfig{
+			MountPath:         "pki",
+			TestCertificateCA: caFile,
+		}
+		role := Role{
+			Name:      "roleName",
+			TestCerts: []venafi.CertificateRequest{testCSR},
+		}
+		err := config.requestTestCertificates(section, vaultAPIClient, role, venafiClient)
+		require.Error(t, err)
+	})
+
+	t.Run("revoked in venafi when not stored", func(t *testing.T) {
+		vaultAPIClient := new(mockAPI.VaultAPIClient)
+		venafiClient := newZoneVenafiClient()
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		defer vaultAPIClient.AssertExpectations(t)
+		defer venafiClient.AssertExpectations(t)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		section.On("Info", mock.AnythingOfType("string")).Maybe()
+		check.On("UpdateStatus", mock.AnythingOfType("string")).Maybe()
+		check.On("Success", mock.AnythingOfType("string"))
+		check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+
+		ca, caFile := newTestCA(t)
+		vaultAPIClient.On("WriteValue", "pki/issue/roleName", testCSR.ToMap()).
+			Return(newTestIssuedData(t, ca, testCSR), nil)
+		venafiClient.On("RevokeCertificate", mock.AnythingOfType("*certificate.RevocationRequest")).Return(nil)
+
+		config := VenafiPKIBackendConfig{
+			MountPath:         "pki",
+			TestCertificateCA: caFile,
+		}
+		role := Role{
+			Name:           "roleName",
+			TestCerts:      []venafi.CertificateRequest{testCSR},
+			OptionalConfig: &OptionalConfig{NoStore: true},
+		}
+		err := config.requestTestCertificates(section, vaultAPIClient, role, venafiClient)
+		require.NoError(t, err)
+	})
+}
+
+// newZoneVenafiClient returns a mock Venafi client whose zones set no subject fields
+func newZoneVenafiClient() *mockVenafiWrapper.VenafiWrapper {
+	venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+	venafiClient.On("ReadZoneConfiguration", mock.AnythingOfType("string")).Return(&endpoint.ZoneConfiguration{}, nil)
+
+	return venafiClient
+}
+
+// newTestCA returns a self-signed CA, along with the path of a file containing its certificate to use as the
+// test_certificate_ca
+func newTestCA(t *testing.T) (*testcerts.CA, string) {
+	ca := testcerts.NewCA(t, pkix.Name{CommonName: "Test CA"}, 24*time.Hour, nil)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte(ca.PEM), 0600))
+
+	return ca, caFile
+}
+
+// newTestIssuedData returns the data Vault would return when issuing request from a role whose CA is ca
+func newTestIssuedData(t *testing.T, ca *testcerts.CA, request venafi.CertificateRequest) map[string]interface{} {
+	template := testcerts.NewTemplate(t, request.Subject(), time.Hour)
+
+	return testcerts.IssuedData(t, template, testcerts.NewKey(t), ca)
+}
 
 func reportExpectations(report *mockReport.Report, section *mockReport.Section, check *mockReport.Check) {
 	report.On("AddSection", mock.AnythingOfType("string")).Return(section).Maybe()
@@ -27,10 +27,13 @@ func ConfigureVenafiRole(
 	return nil
 }
 
+// VerifyVenafiRole checks the role at rolePath uses the Venafi secret secretName, and has the parameters in expected.
+// Parameters that the plugin version doesn't return aren't checked.
 func VerifyVenafiRole(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
-	rolePath, secretName, zone string,
+	rolePath, secretName string,
+	expected map[string]interface{},
 ) error {
 	check := reportSection.AddCheck("Checking Venafi role...")
 
@@ -45,9 +48,17 @@ func VerifyVenafiRole(
 		return fmt.Errorf("venafi role incorrect")
 	}
 
-	if data["zone"] != zone {
-		check.Errorf("The Venafi role's zone field was not as expected: expected %s got %s", zone, data["zone"])
-		return fmt.Errorf("venafi role incorrect")
+	for name, expectedValue := range expected {
+		actualValue, ok := data[name]
+		if !ok {
+			continue
+		}
+
+		// Vault returns numbers as json.Number and lists as []interface{}, so compare how they're formatted instead
+		if fmt.Sprint(actualValue) != fmt.Sprint(expectedValue) {
+			check.Errorf("The Venafi role's %s field was not as expected: expected %v got %v", name, expectedValue, actualValue)
+			return fmt.Errorf("venafi role incorrect")
+		}
 	}
 
 	check.Success("Venafi role correctly configured at " + rolePath)
@@ -0,0 +1,349 @@
+package pki_monitor
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	configErrors "github.com/opencredo/venafi-vault-wizard/app/config/errors"
+	"github.com/opencredo/venafi-vault-wizard/app/config/generate"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// CA is the certificate authority the mount uses to issue certificates, which is shared by all of its roles. Exactly
+// one of its blocks must be given.
+type CA struct {
+	// Root is a self-signed root certificate generated by Vault
+	Root *venafi.CertificateRequest `hcl:"root,block"`
+	// VenafiIntermediate is an intermediate certificate generated by Vault and signed by Venafi
+	VenafiIntermediate *IntermediateCertRequest `hcl:"venafi_intermediate,block"`
+	// Import is an existing CA certificate and private key
+	Import *ImportedCA `hcl:"import,block"`
+
+	// RenewalWindow is how long before the CA expires that it is renewed, such as "720h", defaulting to
+	// DefaultRenewalWindow. With "0s" it is only renewed once it has expired.
+	RenewalWindow string `hcl:"renewal_window,optional"`
+}
+
+// DefaultRenewalWindow is how long before expiring that the CA is renewed, by both apply and renew-ca, when the ca
+// block's renewal_window isn't given
+const DefaultRenewalWindow = 30 * 24 * time.Hour
+
+type ImportedCA struct {
+	// Certificate is the PEM encoded CA certificate, which can be followed by the rest of its chain
+	Certificate string `hcl:"certificate"`
+	// PrivateKey is the PEM encoded private key of the CA certificate
+	PrivateKey string `hcl:"private_key"`
+
+	// CertificateFile and PrivateKeyFile are not decoded, and are instead the paths given when generating config, so that
+	// the certificate and private key are written using the file function rather than inline
+	CertificateFile string
+	PrivateKeyFile  string
+}
+
+func (c *CA) Validate() error {
+	blocksProvided := 0
+	for _, provided := range []bool{c.Root != nil, c.VenafiIntermediate != nil, c.Import != nil} {
+		if provided {
+			blocksProvided++
+		}
+	}
+	if blocksProvided != 1 {
+		return fmt.Errorf("error, ca must contain exactly one of either the root, venafi_intermediate or import blocks: %w", configErrors.ErrConflictingBlocks)
+	}
+
+	var caRequest *venafi.CertificateRequest
+	if c.Root != nil {
+		caRequest = c.Root
+	} else if c.VenafiIntermediate != nil {
+		caRequest = &c.VenafiIntermediate.CertificateRequest
+	}
+	if caRequest != nil {
+		if caRequest.Cleanup != "" || caRequest.CSRFile != "" {
+			return fmt.Errorf("error, cleanup and csr_file can only be given for a test_certificate, not the CA certificate")
+		}
+		err := caRequest.Validate()
+		if err != nil {
+			return fmt.Errorf("error with ca certificate: %w", err)
+		}
+	}
+
+	if c.VenafiIntermediate != nil {
+		err := c.VenafiIntermediate.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.RenewalWindow != "" {
+		_, err := time.ParseDuration(c.RenewalWindow)
+		if err != nil {
+			return fmt.Errorf("error, ca renewal_window must be a duration such as 720h: %w", err)
+		}
+	}
+
+	if c.Import != nil {
+		return c.Import.Validate()
+	}
+
+	return nil
+}
+
+// getRenewalWindow returns RenewalWindow as a duration, or DefaultRenewalWindow if not given
+func (c *CA) getRenewalWindow() time.Duration {
+	if c.RenewalWindow == "" {
+		return DefaultRenewalWindow
+	}
+
+	// Already validated by Validate
+	renewalWindow, _ := time.ParseDuration(c.RenewalWindow)
+	return renewalWindow
+}
+
+// needsRenewal returns whether currentCA has expired or will expire within the renewal window
+func (c *CA) needsRenewal(currentCA *x509.Certificate) bool {
+	return time.Until(currentCA.NotAfter) <= c.getRenewalWindow()
+}
+
+func (i *ImportedCA) Validate() error {
+	if i.Certificate == "" {
+		return fmt.Errorf("error, import certificate cannot be an empty string: %w", configErrors.ErrBlankParam)
+	}
+	if i.PrivateKey == "" {
+		return fmt.Errorf("error, import private_key cannot be an empty string: %w", configErrors.ErrBlankParam)
+	}
+
+	_, err := parseCertificatePEM(i.Certificate)
+	if err != nil {
+		return fmt.Errorf("error with import certificate: %w", err)
+	}
+
+	return nil
+}
+
+func (c *CA) WriteHCL(hclBody *hclwrite.Body) {
+	caBody := hclBody.AppendNewBlock("ca", nil).Body()
+	if c.RenewalWindow != "" {
+		caBody.SetAttributeValue("renewal_window", cty.StringVal(c.RenewalWindow))
+	}
+
+	if c.Root != nil {
+		c.Root.WriteHCL(caBody.AppendNewBlock("root", nil).Body())
+	}
+
+	if c.VenafiIntermediate != nil {
+		intermediateBody := caBody.AppendNewBlock("venafi_intermediate", nil).Body()
+		c.VenafiIntermediate.WriteHCL(intermediateBody)
+	}
+
+	if c.Import != nil {
+		c.Import.WriteHCL(caBody.AppendNewBlock("import", nil).Body())
+	}
+}
+
+func (i *ImportedCA) WriteHCL(hclBody *hclwrite.Body) {
+	if i.CertificateFile != "" {
+		generate.WriteFileAttributeToHCL("certificate", i.CertificateFile, hclBody)
+	} else {
+		hclBody.SetAttributeValue("certificate", cty.StringVal(i.Certificate))
+	}
+
+	if i.PrivateKeyFile != "" {
+		generate.WriteFileAttributeToHCL("private_key", i.PrivateKeyFile, hclBody)
+	} else {
+		hclBody.SetAttributeValue("private_key", cty.StringVal(i.PrivateKey))
+	}
+}
+
+// Configure sets up the CA in the plugin mounted at mountPath, unless the CA it already has matches the config and
+// isn't due to be renewed
+func (c *CA) Configure(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	mountPath string,
+	venafiClient venafi_wrapper.VenafiWrapper,
+) error {
+	check := reportSection.AddCheck("Checking the CA certificate of the mount...")
+
+	currentCA, err := GetCurrentCA(vaultClient, mountPath)
+	if err != nil {
+		check.Errorf("Error reading the current CA certificate: %s", err)
+		return err
+	}
+
+	pending := false
+	if c.VenafiIntermediate != nil && c.VenafiIntermediate.PendingApproval {
+		pickupID, err := c.VenafiIntermediate.getPendingRequests().Get(mountPath)
+		if err != nil {
+			check.Errorf("Error reading pending intermediate certificate requests: %s", err)
+			return err
+		}
+		pending = pickupID != ""
+	}
+
+	switch {
+	case pending:
+		// Whatever the current CA is, the earlier request still needs to be finished off
+		check.Success("Intermediate certificate request from an earlier run is pending approval")
+	case currentCA == nil:
+		check.Success("No CA certificate configured yet")
+	default:
+		matches, err := c.matches(currentCA, venafiClient)
+		if err != nil {
+			check.Errorf("Error comparing the current CA certificate with the config: %s", err)
+			return err
+		}
+
+		switch {
+		case !matches:
+			check.Warningf(
+				"CA certificate %s doesn't match the config, so will be replaced", currentCA.Subject.CommonName,
+			)
+		case !c.needsRenewal(currentCA):
+			check.Successf(
+				"CA certificate %s already configured, valid until %s",
+				currentCA.Subject.CommonName, currentCA.NotAfter.Format(time.RFC3339),
+			)
+			return nil
+		case c.Import != nil:
+			// There's no way of renewing an imported certificate, so all that can be done is to point it out
+			check.Warningf(
+				"Imported CA certificate %s expires at %s, so should be replaced with a new one",
+				currentCA.Subject.CommonName, currentCA.NotAfter.Format(time.RFC3339),
+			)
+			return nil
+		default:
+			check.Warningf(
+				"CA certificate %s expires at %s, within the renewal window, so will be renewed",
+				currentCA.Subject.CommonName, currentCA.NotAfter.Format(time.RFC3339),
+			)
+		}
+	}
+
+	if c.VenafiIntermediate != nil {
+		return ConfigureIntermediateCertificate(
+			reportSection,
+			vaultClient,
+			mountPath,
+			&c.VenafiIntermediate.CertificateRequest,
+			venafiClient,
+			c.VenafiIntermediate,
+		)
+	}
+
+	if c.Import != nil {
+		return ConfigureImportedCertificate(reportSection, vaultClient, mountPath, c.Import)
+	}
+
+	return ConfigureSelfsignedCertificate(reportSection, vaultClient, mountPath, c.Root)
+}
+
+// matches returns whether currentCA is the CA described by the config. Generated CAs match if they have the subject
+// that would be requested, and imported ones if they are the same certificate. As Venafi zones can override subject
+// fields of the certificates they issue, the fields a venafi_intermediate's zone sets aren't compared.
+func (c *CA) matches(currentCA *x509.Certificate, venafiClient venafi_wrapper.VenafiWrapper) (bool, error) {
+	if c.Import != nil {
+		importedCA, err := parseCertificatePEM(c.Import.Certificate)
+		if err != nil {
+			return false, err
+		}
+
+		return currentCA.Equal(importedCA), nil
+	}
+
+	if c.VenafiIntermediate != nil {
+		zoneConfig, err := venafiClient.ReadZoneConfiguration(c.VenafiIntermediate.Zone)
+		if err != nil {
+			return false, fmt.Errorf("error reading zone %s from Venafi: %w", c.VenafiIntermediate.Zone, err)
+		}
+
+		return subjectMatches(currentCA, &c.VenafiIntermediate.CertificateRequest, zoneConfig), nil
+	}
+
+	return subjectMatches(currentCA, c.Root, nil), nil
+}
+
+// GetCurrentCA returns the CA certificate of the plugin mounted at mountPath, or nil if it doesn't have one yet
+func GetCurrentCA(vaultClient api.VaultAPIClient, mountPath string) (*x509.Certificate, error) {
+	data, err := vaultClient.ReadValue(mountPath + "/cert/ca")
+	if errors.Is(err, vault.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	certificatePEM, _ := data["certificate"].(string)
+	if certificatePEM == "" {
+		return nil, nil
+	}
+
+	return parseCertificatePEM(certificatePEM)
+}
+
+// parseCertificatePEM parses the first certificate in a PEM encoded bundle
+func parseCertificatePEM(certificatePEM string) (*x509.Certificate, error) {
+	rest := []byte(certificatePEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM encoded certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// subjectMatches returns whether certificate has the subject that would be requested by request. If zoneConfig is
+// given, the fields it sets are skipped, as the zone may have overridden the values requested.
+func subjectMatches(
+	certificate *x509.Certificate,
+	request *venafi.CertificateRequest,
+	zoneConfig *endpoint.ZoneConfiguration,
+) bool {
+	if certificate.Subject.CommonName != request.CommonName {
+		return false
+	}
+	if zoneConfig == nil {
+		zoneConfig = &endpoint.ZoneConfiguration{}
+	}
+
+	for _, field := range []struct {
+		names     []string
+		want      string
+		zoneValue string
+	}{
+		{certificate.Subject.OrganizationalUnit, request.OU, strings.Join(zoneConfig.OrganizationalUnit, ", ")},
+		{certificate.Subject.Organization, request.Organisation, zoneConfig.Organization},
+		{certificate.Subject.Locality, request.Locality, zoneConfig.Locality},
+		{certificate.Subject.Province, request.Province, zoneConfig.Province},
+		{certificate.Subject.Country, request.Country, zoneConfig.Country},
+	} {
+		if field.zoneValue == "" && !nameMatches(field.names, field.want) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func nameMatches(names []string, want string) bool {
+	if want == "" {
+		return len(names) == 0
+	}
+
+	return len(names) == 1 && names[0] == want
+}
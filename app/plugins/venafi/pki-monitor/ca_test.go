@@ -0,0 +1,251 @@
+package pki_monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/testcerts"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	mockVenafiWrapper "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+var testCARequest = venafi.CertificateRequest{
+	CommonName:   "Vault CA",
+	OU:           "VVW",
+	Organisation: "OpenCredo",
+	Locality:     "London",
+	Province:     "London",
+	Country:      "GB",
+	TTL:          "1h",
+}
+
+func TestCA_matches(t *testing.T) {
+	currentCA := generateTestCA(t, testCARequest, 24*time.Hour)
+	otherCA := generateTestCA(t, testCARequest, 24*time.Hour)
+
+	differentRequest := testCARequest
+	differentRequest.CommonName = "Another CA"
+
+	// The zone locks the organisation and country, so the CA can have different ones to those requested
+	overriddenRequest := testCARequest
+	overriddenRequest.Organisation = "Venafi"
+	overriddenRequest.Country = "US"
+	zoneConfig := &endpoint.ZoneConfiguration{Organization: "OpenCredo", Country: "GB"}
+
+	tests := map[string]struct {
+		ca         CA
+		zoneConfig *endpoint.ZoneConfiguration
+		want       bool
+	}{
+		"root with same subject": {
+			ca:   CA{Root: &testCARequest},
+			want: true,
+		},
+		"root with different subject": {
+			ca:   CA{Root: &differentRequest},
+			want: false,
+		},
+		"venafi intermediate with same subject": {
+			ca: CA{VenafiIntermediate: &IntermediateCertRequest{
+				Zone:               "zone",
+				CertificateRequest: testCARequest,
+			}},
+			zoneConfig: &endpoint.ZoneConfiguration{},
+			want:       true,
+		},
+		"venafi intermediate with subject overridden by zone": {
+			ca: CA{VenafiIntermediate: &IntermediateCertRequest{
+				Zone:               "zone",
+				CertificateRequest: overriddenRequest,
+			}},
+			zoneConfig: zoneConfig,
+			want:       true,
+		},
+		"venafi intermediate with different subject not overridden by zone": {
+			ca: CA{VenafiIntermediate: &IntermediateCertRequest{
+				Zone:               "zone",
+				CertificateRequest: overriddenRequest,
+			}},
+			zoneConfig: &endpoint.ZoneConfiguration{Organization: "OpenCredo"},
+			want:       false,
+		},
+		"same imported certificate": {
+			ca:   CA{Import: &ImportedCA{Certificate: currentCA, PrivateKey: "key"}},
+			want: true,
+		},
+		"different imported certificate with same subject": {
+			ca:   CA{Import: &ImportedCA{Certificate: otherCA, PrivateKey: "key"}},
+			want: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			certificate, err := parseCertificatePEM(currentCA)
+			require.NoError(t, err)
+
+			venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+			defer venafiClient.AssertExpectations(t)
+			if tt.zoneConfig != nil {
+				venafiClient.On("ReadZoneConfiguration", "zone").Return(tt.zoneConfig, nil)
+			}
+
+			got, err := tt.ca.matches(certificate, venafiClient)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCA_Configure(t *testing.T) {
+	const mountPath = "pki"
+	const caPath = mountPath + "/cert/ca"
+	const rootPath = mountPath + "/root/generate/internal"
+
+	differentRequest := testCARequest
+	differentRequest.CommonName = "Another CA"
+
+	expiredCA := generateTestCA(t, testCARequest, -time.Minute)
+
+	tests := map[string]struct {
+		ca           CA
+		currentCA    string
+		expectWrites func(vaultAPIClient *mockAPI.VaultAPIClient)
+	}{
+		"no current CA": {
+			ca: CA{Root: &testCARequest},
+			expectWrites: func(vaultAPIClient *mockAPI.VaultAPIClient) {
+				vaultAPIClient.On("WriteValue", rootPath, testCARequest.ToMap()).Return(nil, nil)
+			},
+		},
+		"matching current CA": {
+			ca:        CA{Root: &testCARequest},
+			currentCA: generateTestCA(t, testCARequest, 60*24*time.Hour),
+		},
+		"different current CA": {
+			ca:        CA{Root: &differentRequest},
+			currentCA: generateTestCA(t, testCARequest, 24*time.Hour),
+			expectWrites: func(vaultAPIClient *mockAPI.VaultAPIClient) {
+				vaultAPIClient.On("WriteValue", rootPath, differentRequest.ToMap()).Return(nil, nil)
+			},
+		},
+		"current CA within renewal window": {
+			ca:        CA{Root: &testCARequest, RenewalWindow: "48h"},
+			currentCA: generateTestCA(t, testCARequest, 24*time.Hour),
+			expectWrites: func(vaultAPIClient *mockAPI.VaultAPIClient) {
+				vaultAPIClient.On("WriteValue", rootPath, testCARequest.ToMap()).Return(nil, nil)
+			},
+		},
+		"current CA within default renewal window": {
+			ca:        CA{Root: &testCARequest},
+			currentCA: generateTestCA(t, testCARequest, 24*time.Hour),
+			expectWrites: func(vaultAPIClient *mockAPI.VaultAPIClient) {
+				vaultAPIClient.On("WriteValue", rootPath, testCARequest.ToMap()).Return(nil, nil)
+			},
+		},
+		"expired current CA": {
+			ca:        CA{Root: &testCARequest},
+			currentCA: expiredCA,
+			expectWrites: func(vaultAPIClient *mockAPI.VaultAPIClient) {
+				vaultAPIClient.On("WriteValue", rootPath, testCARequest.ToMap()).Return(nil, nil)
+			},
+		},
+		"current CA outside renewal window": {
+			ca:        CA{Root: &testCARequest, RenewalWindow: "12h"},
+			currentCA: generateTestCA(t, testCARequest, 24*time.Hour),
+		},
+		"expired imported CA": {
+			// Can't be renewed, so should only warn
+			ca:        CA{Import: &ImportedCA{Certificate: expiredCA, PrivateKey: "key"}},
+			currentCA: expiredCA,
+		},
+		"imported CA": {
+			ca: CA{Import: &ImportedCA{Certificate: "cert", PrivateKey: "key"}},
+			expectWrites: func(vaultAPIClient *mockAPI.VaultAPIClient) {
+				vaultAPIClient.On("WriteValue", mountPath+"/config/ca", map[string]interface{}{
+					"pem_bundle": "cert\nkey",
+				}).Return(nil, nil)
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+			defer section.AssertExpectations(t)
+
+			reportExpectations(report, section, check)
+			check.On("Successf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+			check.On("Warningf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+
+			if tt.currentCA == "" {
+				vaultAPIClient.On("ReadValue", caPath).Return(nil, vault.ErrNotFound)
+			} else {
+				vaultAPIClient.On("ReadValue", caPath).Return(map[string]interface{}{"certificate": tt.currentCA}, nil)
+			}
+			if tt.expectWrites != nil {
+				tt.expectWrites(vaultAPIClient)
+			}
+
+			err := tt.ca.Configure(section, vaultAPIClient, mountPath, venafiClient)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCA_Configure_PendingRequest(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer venafiClient.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	pendingRequests := &PendingRequests{Filename: filepath.Join(t.TempDir(), "pending.json")}
+	require.NoError(t, pendingRequests.Save("pki", "requestID"))
+
+	ca := CA{VenafiIntermediate: &IntermediateCertRequest{
+		Zone:                "zone",
+		PendingApproval:     true,
+		PendingRequestsFile: pendingRequests.Filename,
+		CertificateRequest:  testCARequest,
+	}}
+
+	// Even though the current CA matches, the pending request should be picked up rather than a new one submitted
+	vaultAPIClient.On("ReadValue", "pki/cert/ca").
+		Return(map[string]interface{}{"certificate": generateTestCA(t, testCARequest, 24*time.Hour)}, nil)
+	venafiClient.On("RetrieveCertificate", mock.MatchedBy(func(request *certificate.Request) bool {
+		return request.PickupID == "requestID"
+	}), "zone").Return(&certificate.PEMCollection{Certificate: testCert}, nil)
+	vaultAPIClient.On("WriteValue", "pki/intermediate/set-signed", map[string]interface{}{
+		"certificate": testCert,
+	}).Return(nil, nil)
+
+	err := ca.Configure(section, vaultAPIClient, "pki", venafiClient)
+	require.NoError(t, err)
+
+	pickupID, err := pendingRequests.Get("pki")
+	require.NoError(t, err)
+	require.Empty(t, pickupID)
+}
+
+// generateTestCA returns a PEM encoded self-signed certificate with the subject that request would give, which is valid
+// for validity from now
+func generateTestCA(t *testing.T, request venafi.CertificateRequest, validity time.Duration) string {
+	return testcerts.NewCA(t, request.Subject(), validity, nil).PEM
+}
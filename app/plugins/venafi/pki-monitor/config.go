@@ -2,6 +2,7 @@ package pki_monitor
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins"
 
@@ -9,7 +10,6 @@ import (
 	"github.com/opencredo/venafi-vault-wizard/app/config/errors"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
 	"github.com/opencredo/venafi-vault-wizard/app/questions"
-	"github.com/zclconf/go-cty/cty"
 )
 
 type VenafiPKIMonitorConfig struct {
@@ -21,8 +21,21 @@ type VenafiPKIMonitorConfig struct {
 	Version string
 	// BuildArch allows defining the build architecture
 	BuildArch string
+	// TestCertificateCleanup is what to do with test certificates once they're issued and verified, either none or
+	// revoke, unless a test_certificate gives its own cleanup
+	TestCertificateCleanup string `hcl:"test_certificate_cleanup,optional"`
+	// TestCertificateImportTimeout is how long to wait for test certificates to appear in the zones of the import
+	// policies of their role, defaulting to venafi.DefaultVisibilityTimeout
+	TestCertificateImportTimeout string `hcl:"test_certificate_import_timeout,optional"`
 
-	Role Role `hcl:"role,block"`
+	// CA is the certificate authority for the mount. Older configs give an intermediate_certificate or root_certificate
+	// block in the role instead, as only one role was supported, in which case ParseConfig moves it here.
+	CA *CA `hcl:"ca,block"`
+
+	Roles []Role `hcl:"role,block"`
+
+	// Policies are Venafi policies shared by several roles, on top of each role's enforcement_policy and import_policy
+	Policies []MountPolicy `hcl:"policy,block"`
 }
 
 type Role struct {
@@ -38,16 +51,51 @@ type Role struct {
 
 	TestCerts []venafi.CertificateRequest `hcl:"test_certificate,block"`
 
-	OptionalConfig *venafi.OptionalConfig `hcl:"optional_config,block"`
+	OptionalConfig *OptionalConfig `hcl:"optional_config,block"`
 }
 
 type IntermediateCertRequest struct {
-	Zone                      string          `hcl:"zone"`
+	Zone string `hcl:"zone"`
+	// VenafiSecret is the name of the secret of one of the roles to request the certificate with, defaulting to the
+	// first role's
+	VenafiSecret string `hcl:"venafi_secret,optional"`
+	// RetrieveTimeout is how long to wait for Venafi to issue the certificate, defaulting to 180s
+	RetrieveTimeout string `hcl:"retrieve_timeout,optional"`
+	// PendingApproval allows the certificate to still be pending approval once RetrieveTimeout is reached, in which case
+	// the pickup ID is saved to PendingRequestsFile and picked up by a later run, rather than failing
+	PendingApproval     bool   `hcl:"pending_approval,optional"`
+	PendingRequestsFile string `hcl:"pending_requests_file,optional"`
+
+	// VaultAddress and ConfigDir aren't decoded, and are instead populated by ParseConfig, so that pending requests are
+	// saved next to the config file and kept apart from those of other Vault clusters with the same mount path
+	VaultAddress string
+	ConfigDir    string
+
 	venafi.CertificateRequest `hcl:",remain"` // gohcl currently ignores any field without hcl tags, even in an embedded struct with nested tagged fields
 }
 
+// Policy is a Venafi policy in the plugin, which takes the certificate policy from a zone in Venafi and enforces it on,
+// or imports certificates issued by, roles
 type Policy struct {
-	Zone string `hcl:"zone"`
+	// Name is the name of the policy in the plugin. For the policies of a role it defaults to default or visibility,
+	// with the role name appended for roles other than the first. Policies of the mount are named by their label.
+	Name                string `hcl:"name,optional"`
+	Zone                string `hcl:"zone"`
+	AutoRefreshInterval int    `hcl:"auto_refresh_interval,optional"`
+	ImportTimeout       int    `hcl:"import_timeout,optional"`
+	ImportWorkers       int    `hcl:"import_workers,optional"`
+	CreateRole          bool   `hcl:"create_role,optional"`
+}
+
+// MountPolicy is a named Venafi policy given for the whole mount, which can apply to any of its roles
+type MountPolicy struct {
+	Name             string   `hcl:"name,label"`
+	VenafiSecret     string   `hcl:"venafi_secret"`
+	EnforcementRoles []string `hcl:"enforcement_roles,optional"`
+	DefaultsRoles    []string `hcl:"defaults_roles,optional"`
+	ImportRoles      []string `hcl:"import_roles,optional"`
+
+	Policy `hcl:",remain"` // gohcl currently ignores any field without hcl tags, even in an embedded struct with nested tagged fields
 }
 
 // UnZonedSecret Used to add the label, and to maintain consistent structure with other uses of VenafiSecret.
@@ -61,10 +109,99 @@ func (c *VenafiPKIMonitorConfig) ValidateConfig() error {
 	if err != nil {
 		return err
 	}
-	err = c.Role.Validate()
+	err = venafi.ValidateCleanup("test_certificate_cleanup", c.TestCertificateCleanup)
 	if err != nil {
 		return err
 	}
+	if c.TestCertificateImportTimeout != "" {
+		_, err = time.ParseDuration(c.TestCertificateImportTimeout)
+		if err != nil {
+			return fmt.Errorf("error, test_certificate_import_timeout must be a duration such as 10m: %w", err)
+		}
+	}
+	if len(c.Roles) == 0 {
+		return fmt.Errorf("error at least one role must be provided: %w", errors.ErrBlankParam)
+	}
+
+	roleNames := make(map[string]bool, len(c.Roles))
+	for _, role := range c.Roles {
+		if roleNames[role.Name] {
+			return fmt.Errorf("error role %s is defined more than once", role.Name)
+		}
+		roleNames[role.Name] = true
+
+		err = role.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.validatePolicies(roleNames)
+	if err != nil {
+		return err
+	}
+
+	if c.CA == nil {
+		return fmt.Errorf("error a ca block must be provided: %w", errors.ErrBlankParam)
+	}
+
+	err = c.CA.Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.CA.VenafiIntermediate != nil {
+		return c.CA.VenafiIntermediate.validateSecret(c.getSecretNames())
+	}
+
+	return nil
+}
+
+// getSecretNames returns the names of the roles' secrets, which the mount's policies and intermediate CA refer to
+func (c *VenafiPKIMonitorConfig) getSecretNames() map[string]bool {
+	secretNames := make(map[string]bool, len(c.Roles))
+	for _, role := range c.Roles {
+		secretNames[role.Secret.Name] = true
+	}
+
+	return secretNames
+}
+
+// getIntermediateSecret returns the secret the intermediate CA certificate is requested with, which is the one named by
+// the venafi_intermediate block's venafi_secret, or otherwise the first role's
+func (c *VenafiPKIMonitorConfig) getIntermediateSecret() UnZonedSecret {
+	for _, role := range c.Roles {
+		if role.Secret.Name == c.CA.VenafiIntermediate.VenafiSecret {
+			return role.Secret
+		}
+	}
+
+	return c.Roles[0].Secret
+}
+
+// moveRoleCAToMount moves an intermediate_certificate or root_certificate block given in a role to the mount's ca
+// block. Only one role can give one, and only if there isn't a ca block.
+func (c *VenafiPKIMonitorConfig) moveRoleCAToMount() error {
+	for i := range c.Roles {
+		role := &c.Roles[i]
+		if role.IntermediateCert == nil && role.RootCert == nil {
+			continue
+		}
+
+		if c.CA != nil {
+			return fmt.Errorf(
+				"error, the intermediate_certificate or root_certificate block of role %s conflicts with another, there can only be one CA per mount: %w",
+				role.Name, errors.ErrConflictingBlocks,
+			)
+		}
+
+		c.CA = &CA{
+			Root:               role.RootCert,
+			VenafiIntermediate: role.IntermediateCert,
+		}
+		role.IntermediateCert, role.RootCert = nil, nil
+	}
+
 	return nil
 }
 
@@ -81,27 +218,29 @@ func (r *Role) Validate() error {
 		}
 	}
 
-	intermediateCertProvided := r.IntermediateCert != nil
-	rootCertProvided := r.RootCert != nil
-
-	if (intermediateCertProvided && rootCertProvided) || (!intermediateCertProvided && !rootCertProvided) {
-		return fmt.Errorf("error, must provide exactly one of either the intermediate_certificate or root_certificate blocks: %w", errors.ErrConflictingBlocks)
+	for _, testCert := range r.TestCerts {
+		err = testCert.Validate()
+		if err != nil {
+			return fmt.Errorf("error with test_certificate %s of role %s: %w", testCert.CommonName, r.Name, err)
+		}
 	}
 
 	if r.EnforcementPolicy == nil && r.ImportPolicy == nil {
 		return fmt.Errorf("error, at least one of either enforcement_policy or import_policy must be provided: %w", errors.ErrBlankParam)
 	}
 
-	if intermediateCertProvided && r.IntermediateCert.Zone == "" {
-		return fmt.Errorf("error, intermediate_certificate zone cannot be an empty string: %w", errors.ErrBlankParam)
-	}
-
-	if r.EnforcementPolicy != nil && r.EnforcementPolicy.Zone == "" {
-		return fmt.Errorf("error, enforcement_policy zone cannot be an empty string: %w", errors.ErrBlankParam)
+	if r.EnforcementPolicy != nil {
+		err = r.EnforcementPolicy.Validate("enforcement_policy")
+		if err != nil {
+			return err
+		}
 	}
 
-	if r.ImportPolicy != nil && r.ImportPolicy.Zone == "" {
-		return fmt.Errorf("error, import_policy zone cannot be an empty string: %w", errors.ErrBlankParam)
+	if r.ImportPolicy != nil {
+		err = r.ImportPolicy.Validate("import_policy")
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -115,27 +254,13 @@ func (r *Role) WriteHCL(hclBody *hclwrite.Body) {
 	if r.EnforcementPolicy != nil {
 		roleBody.AppendNewline()
 		policyBlock := roleBody.AppendNewBlock("enforcement_policy", nil)
-		policyBlock.Body().SetAttributeValue("zone", cty.StringVal(r.EnforcementPolicy.Zone))
+		r.EnforcementPolicy.WriteHCL(policyBlock.Body())
 	}
 
 	if r.ImportPolicy != nil {
 		roleBody.AppendNewline()
 		policyBlock := roleBody.AppendNewBlock("import_policy", nil)
-		policyBlock.Body().SetAttributeValue("zone", cty.StringVal(r.ImportPolicy.Zone))
-	}
-
-	if r.IntermediateCert != nil {
-		roleBody.AppendNewline()
-		certBlock := roleBody.AppendNewBlock("intermediate_certificate", nil)
-		certBody := certBlock.Body()
-		certBody.SetAttributeValue("zone", cty.StringVal(r.IntermediateCert.Zone))
-		r.IntermediateCert.WriteHCL(certBody)
-	}
-
-	if r.RootCert != nil {
-		roleBody.AppendNewline()
-		certBlock := roleBody.AppendNewBlock("root_certificate", nil)
-		r.RootCert.WriteHCL(certBlock.Body())
+		r.ImportPolicy.WriteHCL(policyBlock.Body())
 	}
 
 	if r.OptionalConfig != nil {
@@ -158,13 +283,109 @@ func (s *UnZonedSecret) WriteHCL(hclBody *hclwrite.Body) {
 }
 
 func (c *VenafiPKIMonitorConfig) GenerateConfigAndWriteHCL(questioner questions.Questioner, hclBody *hclwrite.Body) error {
-	role, err := askForRole(questioner)
+	err := c.askForCA(questioner)
 	if err != nil {
 		return err
 	}
 
 	hclBody.AppendNewline()
-	role.WriteHCL(hclBody)
+	c.CA.WriteHCL(hclBody)
+
+	for i := 1; true; i++ {
+		role, err := askForRole(questioner)
+		if err != nil {
+			return err
+		}
+
+		hclBody.AppendNewline()
+		role.WriteHCL(hclBody)
+
+		moreRolesQuestion := questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: fmt.Sprintf("You have configured %d roles, are there more", i),
+			Items:    []string{"Yes", "No that's it"},
+		})
+		err = moreRolesQuestion.Ask()
+		if err != nil {
+			return err
+		}
+		if moreRolesQuestion.Answer() != "Yes" {
+			break
+		}
+	}
+
+	return nil
+}
+
+// askForCA asks for the certificate the mount should use to issue certificates, which is shared by all of its roles
+func (c *VenafiPKIMonitorConfig) askForCA(questioner questions.Questioner) error {
+	q := map[string]questions.Question{
+		"issuing_cert_type": questioner.NewClosedQuestion(&questions.ClosedQuestion{
+			Question: "What type of certificate should Vault use to issue certificates?",
+			Items: []string{
+				"Self-signed root certificate",
+				"Intermediate certificate issued by Venafi",
+				"Existing CA certificate to import",
+			},
+		}),
+		"subca_policy": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "Which policy should be used to issue the subordinate CA certificate?",
+		}),
+		"import_certificate": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "What is the path of the PEM encoded CA certificate to import?",
+		}),
+		"import_private_key": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
+			Question: "What is the path of the PEM encoded private key of the CA certificate?",
+		}),
+	}
+
+	err := q["issuing_cert_type"].Ask()
+	if err != nil {
+		return err
+	}
+
+	switch q["issuing_cert_type"].Answer() {
+	case "Existing CA certificate to import":
+		err = questions.AskQuestions([]questions.Question{
+			q["import_certificate"],
+			q["import_private_key"],
+		})
+		if err != nil {
+			return err
+		}
+
+		c.CA = &CA{
+			Import: &ImportedCA{
+				CertificateFile: string(q["import_certificate"].Answer()),
+				PrivateKeyFile:  string(q["import_private_key"].Answer()),
+			},
+		}
+	case "Intermediate certificate issued by Venafi":
+		err = q["subca_policy"].Ask()
+		if err != nil {
+			return err
+		}
+
+		certificateRequest, err := venafi.GenerateCertRequestConfig(questioner)
+		if err != nil {
+			return err
+		}
+
+		c.CA = &CA{
+			VenafiIntermediate: &IntermediateCertRequest{
+				Zone:               string(q["subca_policy"].Answer()),
+				CertificateRequest: *certificateRequest,
+			},
+		}
+	default:
+		certificateRequest, err := venafi.GenerateCertRequestConfig(questioner)
+		if err != nil {
+			return err
+		}
+
+		c.CA = &CA{
+			Root: certificateRequest,
+		}
+	}
 
 	return nil
 }
@@ -208,13 +429,6 @@ func askForRole(questioner questions.Questioner) (*Role, error) {
 		"import_policy_name": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
 			Question: "Which Venafi policy should be used for importing certificates into?",
 		}),
-		"issuing_cert_type": questioner.NewClosedQuestion(&questions.ClosedQuestion{
-			Question: "What type of certificate should Vault use to issue certificates?",
-			Items:    []string{"Self-signed root certificate", "Intermediate certificate issued by Venafi"},
-		}),
-		"subca_policy": questioner.NewOpenEndedQuestion(&questions.OpenEndedQuestion{
-			Question: "Which policy should be used to issue the subordinate CA certificate?",
-		}),
 	}
 
 	err := questions.AskQuestions([]questions.Question{
@@ -248,14 +462,6 @@ func askForRole(questioner questions.Questioner) (*Role, error) {
 				q["import_policy_name"],
 			},
 		},
-		&questions.QuestionBranch{
-			ConditionQuestion: q["issuing_cert_type"],
-			ConditionAnswer:   "Self-signed root certificate",
-			BranchA:           nil,
-			BranchB: []questions.Question{
-				q["subca_policy"],
-			},
-		},
 	})
 	if err != nil {
 		return nil, err
@@ -297,28 +503,13 @@ func askForRole(questioner questions.Questioner) (*Role, error) {
 		}
 	}
 
-	if q["issuing_cert_type"].Answer() == "Self-signed root certificate" {
-		certificateRequest, err := venafi.GenerateCertRequestConfig(questioner)
-		if err != nil {
-			return nil, err
-		}
-		role.RootCert = certificateRequest
-	} else {
-		certificateRequest, err := venafi.GenerateCertRequestConfig(questioner)
-		if err != nil {
-			return nil, err
-		}
-		role.IntermediateCert = &IntermediateCertRequest{
-			Zone:               string(q["subca_policy"].Answer()),
-			CertificateRequest: *certificateRequest,
-		}
-	}
-
 	optionalConfig, err := venafi.GenerateOptionalQuestions(questioner)
 	if err != nil {
 		return nil, err
 	}
-	role.OptionalConfig = optionalConfig
+	if optionalConfig != nil {
+		role.OptionalConfig = &OptionalConfig{OptionalConfig: *optionalConfig}
+	}
 
 	testCertificates, err := venafi.AskForTestCertificates(questioner)
 	if err != nil {
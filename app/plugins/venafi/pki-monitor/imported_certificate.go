@@ -0,0 +1,26 @@
+package pki_monitor
+
+import (
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+func ConfigureImportedCertificate(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	mountPath string,
+	importedCA *ImportedCA,
+) error {
+	check := reportSection.AddCheck("Importing CA certificate for the plugin to use...")
+
+	_, err := vaultClient.WriteValue(mountPath+"/config/ca", map[string]interface{}{
+		"pem_bundle": importedCA.Certificate + "\n" + importedCA.PrivateKey,
+	})
+	if err != nil {
+		check.Errorf("Error importing CA certificate: %s", err)
+		return err
+	}
+
+	check.Success("CA certificate imported into Vault")
+	return nil
+}
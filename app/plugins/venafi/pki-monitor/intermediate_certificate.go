@@ -1,30 +1,199 @@
 package pki_monitor
 
 import (
-	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	configErrors "github.com/opencredo/venafi-vault-wizard/app/config/errors"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
+// defaultRetrieveTimeout is how long to wait for Venafi to issue an intermediate certificate if retrieve_timeout isn't
+// given
+const defaultRetrieveTimeout = 180 * time.Second
+
+func (i *IntermediateCertRequest) Validate() error {
+	if i.Zone == "" {
+		return fmt.Errorf("error, venafi_intermediate zone cannot be an empty string: %w", configErrors.ErrBlankParam)
+	}
+
+	if i.RetrieveTimeout != "" {
+		_, err := time.ParseDuration(i.RetrieveTimeout)
+		if err != nil {
+			return fmt.Errorf("error, venafi_intermediate retrieve_timeout must be a duration such as 10m: %w", err)
+		}
+	}
+
+	if i.PendingRequestsFile != "" && !i.PendingApproval {
+		return fmt.Errorf("error, venafi_intermediate pending_requests_file can only be given with pending_approval")
+	}
+
+	return nil
+}
+
+// validateSecret checks that VenafiSecret, if given, is the name of one of secretNames
+func (i *IntermediateCertRequest) validateSecret(secretNames map[string]bool) error {
+	if i.VenafiSecret != "" && !secretNames[i.VenafiSecret] {
+		return fmt.Errorf("error, venafi_intermediate venafi_secret must be the name of the secret of one of the roles, got %s", i.VenafiSecret)
+	}
+
+	return nil
+}
+
+func (i *IntermediateCertRequest) WriteHCL(hclBody *hclwrite.Body) {
+	hclBody.SetAttributeValue("zone", cty.StringVal(i.Zone))
+	if i.VenafiSecret != "" {
+		hclBody.SetAttributeValue("venafi_secret", cty.StringVal(i.VenafiSecret))
+	}
+	if i.RetrieveTimeout != "" {
+		hclBody.SetAttributeValue("retrieve_timeout", cty.StringVal(i.RetrieveTimeout))
+	}
+	if i.PendingApproval {
+		hclBody.SetAttributeValue("pending_approval", cty.True)
+	}
+	if i.PendingRequestsFile != "" {
+		hclBody.SetAttributeValue("pending_requests_file", cty.StringVal(i.PendingRequestsFile))
+	}
+
+	i.CertificateRequest.WriteHCL(hclBody)
+}
+
+// getRetrieveTimeout returns RetrieveTimeout as a duration, defaulting to defaultRetrieveTimeout
+func (i *IntermediateCertRequest) getRetrieveTimeout() time.Duration {
+	if i.RetrieveTimeout == "" {
+		return defaultRetrieveTimeout
+	}
+
+	// Already validated by Validate
+	retrieveTimeout, _ := time.ParseDuration(i.RetrieveTimeout)
+	return retrieveTimeout
+}
+
+// getPendingRequests returns where pickup IDs of requests pending approval are saved, resolving relative paths from
+// the directory of the config file rather than wherever vvw is run from
+func (i *IntermediateCertRequest) getPendingRequests() *PendingRequests {
+	filename := i.PendingRequestsFile
+	if filename == "" {
+		filename = DefaultPendingRequestsFile
+	}
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(i.ConfigDir, filename)
+	}
+
+	return &PendingRequests{Filename: filename, VaultAddress: i.VaultAddress}
+}
+
+// ConfigureIntermediateCertificate generates a CSR for request in the plugin mounted at mountPath, has it signed by
+// Venafi in the zone of intermediate, and sets the certificate in the plugin. If intermediate allows the request to be
+// pending approval, and it isn't issued in time, then its pickup ID is saved and plugins.ErrPendingApproval returned.
+// The next call then picks up the certificate using the saved pickup ID rather than generating another CSR.
 func ConfigureIntermediateCertificate(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
 	mountPath string,
 	request *venafi.CertificateRequest,
 	venafiClient venafi_wrapper.VenafiWrapper,
-	zone string,
+	intermediate *IntermediateCertRequest,
 ) error {
 	check := reportSection.AddCheck("Generating a CSR for an intermediate certificate from Venafi...")
 
+	var pendingRequests *PendingRequests
+	var pickupID string
+	if intermediate.PendingApproval {
+		var err error
+		pendingRequests = intermediate.getPendingRequests()
+		pickupID, err = pendingRequests.Get(mountPath)
+		if err != nil {
+			check.Errorf("Error reading pending intermediate certificate requests: %s", err)
+			return err
+		}
+	}
+
+	if pickupID != "" {
+		// The CSR, and its private key, from the earlier run are still in Vault waiting to be signed
+		check.UpdateStatus(fmt.Sprintf(
+			"Resuming intermediate certificate request with pickup ID %s saved in %s...",
+			pickupID, pendingRequests.Filename,
+		))
+	} else {
+		var err error
+		pickupID, err = requestIntermediateCertificate(check, vaultClient, mountPath, request, venafiClient, intermediate.Zone)
+		if err != nil {
+			return err
+		}
+	}
+
+	retrieveTimeout := intermediate.getRetrieveTimeout()
+	check.UpdateStatus(fmt.Sprintf("Waiting up to %s for Venafi to issue the intermediate certificate...", retrieveTimeout))
+
+	venafiPEMs, err := venafiClient.RetrieveCertificate(&certificate.Request{
+		PickupID: pickupID,
+		Timeout:  retrieveTimeout,
+	}, intermediate.Zone)
+	if err != nil {
+		if intermediate.PendingApproval && isPending(err) {
+			err = pendingRequests.Save(mountPath, pickupID)
+			if err != nil {
+				check.Errorf("Error saving pickup ID %s of the pending intermediate certificate request: %s", pickupID, err)
+				return err
+			}
+
+			check.Warningf(
+				"Intermediate certificate is pending approval in Venafi, so its pickup ID %s has been saved in %s. Run again once it's approved to finish configuring it",
+				pickupID, pendingRequests.Filename,
+			)
+			return plugins.ErrPendingApproval
+		}
+
+		check.Errorf("Error retrieving intermediate certificate from Venafi: %s", err)
+		return err
+	}
+
+	_, err = vaultClient.WriteValue(mountPath+"/intermediate/set-signed", map[string]interface{}{
+		"certificate": venafiPEMs.Certificate,
+	})
+	if err != nil {
+		check.Errorf("Error setting intermediate certificate in Vault: %s", err)
+		return err
+	}
+
+	if pendingRequests != nil {
+		err = pendingRequests.Remove(mountPath)
+		if err != nil {
+			check.Errorf("Error removing the pickup ID of the issued intermediate certificate: %s", err)
+			return err
+		}
+	}
+
+	check.Success("Intermediate certificate set in Vault")
+	return nil
+}
+
+// requestIntermediateCertificate generates a CSR in Vault and submits it to Venafi, returning the request's pickup ID
+func requestIntermediateCertificate(
+	check reporter.Check,
+	vaultClient api.VaultAPIClient,
+	mountPath string,
+	request *venafi.CertificateRequest,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	zone string,
+) (string, error) {
 	// Get intermediate CSR from plugin
 	data, err := vaultClient.WriteValue(mountPath+"/intermediate/generate/internal", request.ToMap())
 	if err != nil {
 		check.Errorf("Error generating subordinate CSR for an intermediate CA: %s", err)
-		return err
+		return "", err
 	}
 	pluginCSR := data["csr"].(string)
 
@@ -35,7 +204,7 @@ func ConfigureIntermediateCertificate(
 	err = enrollReq.SetCSR([]byte(pluginCSR))
 	if err != nil {
 		check.Errorf("Error parsing intermediate CSR provided by Vault: %s", err)
-		return err
+		return "", err
 	}
 
 	check.UpdateStatus("CSR generated, requesting intermediate certificate from Venafi...")
@@ -44,29 +213,18 @@ func ConfigureIntermediateCertificate(
 	requestID, err := venafiClient.RequestCertificate(enrollReq, zone)
 	if err != nil {
 		check.Errorf("Error requesting intermediate certificate from Venafi: %s", err)
-		return err
+		return "", err
 	}
 
-	// Wait up to 3 minutes for request to complete and get the certificate back
-	venafiPEMs, err := venafiClient.RetrieveCertificate(&certificate.Request{
-		PickupID: requestID,
-		Timeout:  180 * time.Second,
-	}, zone)
-	if err != nil {
-		check.Errorf("Error retrieving intermediate certificate from Venafi: %s", err)
-		return err
-	}
+	return requestID, nil
+}
 
-	_, err = vaultClient.WriteValue(mountPath+"/intermediate/set-signed", map[string]interface{}{
-		"certificate": venafiPEMs.Certificate,
-	})
-	if err != nil {
-		check.Errorf("Error setting intermediate certificate in Vault: %s", err)
-		return err
-	}
+// isPending returns whether err from retrieving a certificate is because it hasn't been issued yet
+func isPending(err error) bool {
+	var pendingErr endpoint.ErrCertificatePending
+	var timeoutErr endpoint.ErrRetrieveCertificateTimeout
 
-	check.Success("Intermediate certificate set in Vault")
-	return nil
+	return errors.As(err, &pendingErr) || errors.As(err, &timeoutErr)
 }
 
 func VerifyIntermediateCertificate(
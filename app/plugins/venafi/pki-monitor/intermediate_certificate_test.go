@@ -0,0 +1,209 @@
+package pki_monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	mockVenafiWrapper "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestConfigureIntermediateCertificate(t *testing.T) {
+	const mountPath = "pki"
+	const zone = "zone"
+	const pickupID = "requestID"
+
+	tests := map[string]struct {
+		retrieveTimeout   string
+		pendingApproval   bool
+		savedPickupID     string
+		retrieveErr       error
+		wantTimeout       time.Duration
+		wantRequest       bool
+		wantErr           error
+		wantSavedPickupID string
+	}{
+		"issued": {
+			wantTimeout: defaultRetrieveTimeout,
+			wantRequest: true,
+		},
+		"issued with retrieve timeout": {
+			retrieveTimeout: "2h",
+			wantTimeout:     2 * time.Hour,
+			wantRequest:     true,
+		},
+		"pending approval": {
+			pendingApproval:   true,
+			retrieveErr:       endpoint.ErrRetrieveCertificateTimeout{CertificateID: pickupID},
+			wantTimeout:       defaultRetrieveTimeout,
+			wantRequest:       true,
+			wantErr:           plugins.ErrPendingApproval,
+			wantSavedPickupID: pickupID,
+		},
+		"still pending approval": {
+			pendingApproval:   true,
+			savedPickupID:     pickupID,
+			retrieveErr:       endpoint.ErrCertificatePending{CertificateID: pickupID, Status: "Pending Approval"},
+			wantTimeout:       defaultRetrieveTimeout,
+			wantErr:           plugins.ErrPendingApproval,
+			wantSavedPickupID: pickupID,
+		},
+		"approved": {
+			pendingApproval: true,
+			savedPickupID:   pickupID,
+			wantTimeout:     defaultRetrieveTimeout,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+			defer venafiClient.AssertExpectations(t)
+
+			reportExpectations(report, section, check)
+			check.On("Warningf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+
+			pendingRequests := &PendingRequests{Filename: filepath.Join(t.TempDir(), "pending.json")}
+			if tt.savedPickupID != "" {
+				require.NoError(t, pendingRequests.Save(mountPath, tt.savedPickupID))
+			}
+
+			intermediate := &IntermediateCertRequest{
+				Zone:                zone,
+				RetrieveTimeout:     tt.retrieveTimeout,
+				PendingApproval:     tt.pendingApproval,
+				PendingRequestsFile: pendingRequests.Filename,
+				CertificateRequest:  testCARequest,
+			}
+
+			if tt.wantRequest {
+				vaultAPIClient.On("WriteValue", mountPath+"/intermediate/generate/internal", testCARequest.ToMap()).
+					Return(map[string]interface{}{"csr": testCsr}, nil)
+				venafiClient.On("RequestCertificate", mock.Anything, zone).Return(pickupID, nil)
+			}
+
+			retrieveRequest := mock.MatchedBy(func(request *certificate.Request) bool {
+				return request.PickupID == pickupID && request.Timeout == tt.wantTimeout
+			})
+			if tt.retrieveErr != nil {
+				venafiClient.On("RetrieveCertificate", retrieveRequest, zone).Return(nil, tt.retrieveErr)
+			} else {
+				venafiClient.On("RetrieveCertificate", retrieveRequest, zone).
+					Return(&certificate.PEMCollection{Certificate: testCert}, nil)
+				vaultAPIClient.On("WriteValue", mountPath+"/intermediate/set-signed", map[string]interface{}{
+					"certificate": testCert,
+				}).Return(nil, nil)
+			}
+
+			err := ConfigureIntermediateCertificate(
+				section,
+				vaultAPIClient,
+				mountPath,
+				&intermediate.CertificateRequest,
+				venafiClient,
+				intermediate,
+			)
+			require.ErrorIs(t, err, tt.wantErr)
+
+			savedPickupID, err := pendingRequests.Get(mountPath)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSavedPickupID, savedPickupID)
+		})
+	}
+}
+
+func TestConfigureIntermediateCertificate_TimeoutWithoutPendingApproval(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+
+	reportExpectations(report, section, check)
+	check.On("Errorf", mock.AnythingOfType("string"), mock.Anything)
+
+	vaultAPIClient.On("WriteValue", "pki/intermediate/generate/internal", mock.Anything).
+		Return(map[string]interface{}{"csr": testCsr}, nil)
+	venafiClient.On("RequestCertificate", mock.Anything, "zone").Return("requestID", nil)
+	venafiClient.On("RetrieveCertificate", mock.Anything, "zone").
+		Return(nil, endpoint.ErrRetrieveCertificateTimeout{CertificateID: "requestID"})
+
+	intermediate := &IntermediateCertRequest{Zone: "zone", CertificateRequest: testCARequest}
+	err := ConfigureIntermediateCertificate(
+		section,
+		vaultAPIClient,
+		"pki",
+		&intermediate.CertificateRequest,
+		venafiClient,
+		intermediate,
+	)
+	require.Error(t, err)
+	require.NotErrorIs(t, err, plugins.ErrPendingApproval)
+}
+
+func TestIntermediateCertRequest_getPendingRequests(t *testing.T) {
+	tests := map[string]struct {
+		pendingRequestsFile string
+		want                string
+	}{
+		"default": {
+			want: filepath.Join("configs", DefaultPendingRequestsFile),
+		},
+		"relative to config file": {
+			pendingRequestsFile: "state/pending.json",
+			want:                filepath.Join("configs", "state", "pending.json"),
+		},
+		"absolute": {
+			pendingRequestsFile: "/var/lib/vvw/pending.json",
+			want:                "/var/lib/vvw/pending.json",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			intermediate := &IntermediateCertRequest{
+				PendingRequestsFile: tt.pendingRequestsFile,
+				VaultAddress:        "https://vault:8200",
+				ConfigDir:           "configs",
+			}
+
+			pendingRequests := intermediate.getPendingRequests()
+			require.Equal(t, tt.want, pendingRequests.Filename)
+			require.Equal(t, "https://vault:8200", pendingRequests.VaultAddress)
+		})
+	}
+}
+
+func TestPendingRequests_separateVaultClusters(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "pending.json")
+	cluster1 := &PendingRequests{Filename: filename, VaultAddress: "https://vault1:8200"}
+	cluster2 := &PendingRequests{Filename: filename, VaultAddress: "https://vault2:8200/"}
+
+	require.NoError(t, cluster1.Save("pki", "requestID1"))
+	require.NoError(t, cluster2.Save("pki", "requestID2"))
+
+	pickupID, err := cluster1.Get("pki")
+	require.NoError(t, err)
+	require.Equal(t, "requestID1", pickupID)
+
+	require.NoError(t, cluster2.Remove("pki"))
+
+	pickupID, err = cluster1.Get("pki")
+	require.NoError(t, err)
+	require.Equal(t, "requestID1", pickupID)
+
+	pickupID, err = cluster2.Get("pki")
+	require.NoError(t, err)
+	require.Empty(t, pickupID)
+}
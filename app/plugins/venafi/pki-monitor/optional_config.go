@@ -0,0 +1,251 @@
+package pki_monitor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+)
+
+var (
+	validKeyType  = []string{venafi.KeyTypeRSA, venafi.KeyTypeEC, "ed25519", "any"}
+	validKeyUsage = []string{
+		"DigitalSignature", "ContentCommitment", "KeyEncipherment", "DataEncipherment", "KeyAgreement", "CertSign",
+		"CRLSign", "EncipherOnly", "DecipherOnly",
+	}
+	validExtKeyUsage = []string{
+		"Any", "ServerAuth", "ClientAuth", "CodeSigning", "EmailProtection", "IPSECEndSystem", "IPSECTunnel",
+		"IPSECUser", "TimeStamping", "OCSPSigning", "MicrosoftServerGatedCrypto", "NetscapeServerGatedCrypto",
+		"MicrosoftCommercialCodeSigning", "MicrosoftKernelCodeSigning",
+	}
+	oidRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)+$`)
+)
+
+// OptionalConfig is the optional_config block of a venafi-pki-monitor role. As well as the parameters shared with the
+// venafi-pki-backend plugin, it has those of Vault's PKI roles. Booleans that default to true in the plugin are
+// pointers, so that they're only written if given.
+type OptionalConfig struct {
+	AllowedDomains      []string `hcl:"allowed_domains,optional"`
+	AllowBareDomains    bool     `hcl:"allow_bare_domains,optional"`
+	AllowSubdomains     bool     `hcl:"allow_subdomains,optional"`
+	AllowGlobDomains    bool     `hcl:"allow_glob_domains,optional"`
+	AllowLocalhost      *bool    `hcl:"allow_localhost,optional"`
+	EnforceHostnames    *bool    `hcl:"enforce_hostnames,optional"`
+	AllowIPSANs         *bool    `hcl:"allow_ip_sans,optional"`
+	AllowedURISANs      []string `hcl:"allowed_uri_sans,optional"`
+	AllowedOtherSANs    []string `hcl:"allowed_other_sans,optional"`
+	ServerFlag          *bool    `hcl:"server_flag,optional"`
+	ClientFlag          *bool    `hcl:"client_flag,optional"`
+	CodeSigningFlag     bool     `hcl:"code_signing_flag,optional"`
+	EmailProtectionFlag bool     `hcl:"email_protection_flag,optional"`
+	KeyType             string   `hcl:"key_type,optional"`
+	KeyBits             int      `hcl:"key_bits,optional"`
+	KeyUsage            []string `hcl:"key_usage,optional"`
+	ExtKeyUsage         []string `hcl:"ext_key_usage,optional"`
+	ExtKeyUsageOIDs     []string `hcl:"ext_key_usage_oids,optional"`
+	UseCSRCommonName    *bool    `hcl:"use_csr_common_name,optional"`
+	UseCSRSANs          *bool    `hcl:"use_csr_sans,optional"`
+	RequireCN           *bool    `hcl:"require_cn,optional"`
+	NoStore             bool     `hcl:"no_store,optional"`
+	NotBeforeDuration   string   `hcl:"not_before_duration,optional"`
+
+	venafi.OptionalConfig `hcl:",remain"` // gohcl currently ignores any field without hcl tags, even in an embedded struct with nested tagged fields
+}
+
+func (oc *OptionalConfig) Validate() error {
+	err := oc.OptionalConfig.Validate()
+	if err != nil {
+		return err
+	}
+
+	for _, domain := range oc.AllowedDomains {
+		if domain == "" {
+			return fmt.Errorf("allowed_domains cannot contain an empty domain")
+		}
+	}
+
+	err = oc.validateKey()
+	if err != nil {
+		return err
+	}
+
+	for _, usage := range oc.KeyUsage {
+		if !venafi.ContainsString(validKeyUsage, usage) {
+			return fmt.Errorf("key_usage must only contain %s, got %s", strings.Join(validKeyUsage, ", "), usage)
+		}
+	}
+	for _, usage := range oc.ExtKeyUsage {
+		if !venafi.ContainsString(validExtKeyUsage, usage) {
+			return fmt.Errorf("ext_key_usage must only contain %s, got %s", strings.Join(validExtKeyUsage, ", "), usage)
+		}
+	}
+	for _, oid := range oc.ExtKeyUsageOIDs {
+		if !oidRegexp.MatchString(oid) {
+			return fmt.Errorf("ext_key_usage_oids must only contain OIDs such as 1.3.6.1.5.5.7.3.1, got %s", oid)
+		}
+	}
+
+	if oc.NotBeforeDuration != "" {
+		_, err = time.ParseDuration(oc.NotBeforeDuration)
+		if err != nil {
+			return fmt.Errorf("cannot parse not_before_duration: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func (oc *OptionalConfig) validateKey() error {
+	// The plugin defaults to 2048 bit RSA keys
+	keyType := venafi.KeyTypeRSA
+	if oc.KeyType != "" {
+		if !venafi.ContainsString(validKeyType, oc.KeyType) {
+			return fmt.Errorf("key_type must be one of %s, got %s", strings.Join(validKeyType, ", "), oc.KeyType)
+		}
+		keyType = oc.KeyType
+	}
+
+	return venafi.ValidateKeyBits(keyType, oc.KeyBits)
+}
+
+func (oc *OptionalConfig) WriteHCL(hclBody *hclwrite.Body) {
+	oc.OptionalConfig.WriteHCL(hclBody)
+
+	for _, parameter := range oc.getMonitorParameters() {
+		hclBody.SetAttributeValue(parameter.name, parameter.ctyValue())
+	}
+}
+
+// GetAsMap returns the parameters to write to the role. Those specific to the monitor are only included if they're
+// given, so that the plugin's defaults are used otherwise.
+func (oc *OptionalConfig) GetAsMap() map[string]interface{} {
+	if oc == nil {
+		return map[string]interface{}{}
+	}
+
+	parameters := oc.OptionalConfig.GetAsMap()
+	for _, parameter := range oc.getMonitorParameters() {
+		parameters[parameter.name] = parameter.value
+	}
+
+	return parameters
+}
+
+// getMaxTTL returns the role's max_ttl, or an empty string if it isn't given
+func (oc *OptionalConfig) getMaxTTL() string {
+	if oc == nil {
+		return ""
+	}
+
+	return oc.MaxTTL
+}
+
+// GetExpectedRoleData returns the parameters that reading the role back should return. Vault returns durations as a
+// number of seconds, so they are converted to match.
+func (oc *OptionalConfig) GetExpectedRoleData() map[string]interface{} {
+	if oc == nil {
+		return map[string]interface{}{}
+	}
+
+	expected := map[string]interface{}{}
+	for _, parameter := range oc.getMonitorParameters() {
+		expected[parameter.name] = parameter.value
+	}
+	if oc.GenerateLease {
+		expected["generate_lease"] = true
+	}
+	if oc.AllowAnyName {
+		expected["allow_any_name"] = true
+	}
+	for name, duration := range map[string]string{
+		"ttl":                 oc.TTL,
+		"max_ttl":             oc.MaxTTL,
+		"not_before_duration": oc.NotBeforeDuration,
+	} {
+		if duration == "" {
+			continue
+		}
+		// Already validated by Validate
+		parsed, _ := time.ParseDuration(duration)
+		expected[name] = int64(parsed.Seconds())
+	}
+
+	return expected
+}
+
+type roleParameter struct {
+	name  string
+	value interface{}
+}
+
+func (p roleParameter) ctyValue() cty.Value {
+	switch value := p.value.(type) {
+	case bool:
+		return cty.BoolVal(value)
+	case int:
+		return cty.NumberIntVal(int64(value))
+	case []string:
+		return cty.ListVal(stringValues(value))
+	default:
+		return cty.StringVal(fmt.Sprint(value))
+	}
+}
+
+// getMonitorParameters returns the parameters specific to venafi-pki-monitor roles that are given, in the order
+// they're documented
+func (oc *OptionalConfig) getMonitorParameters() []roleParameter {
+	var parameters []roleParameter
+	addString := func(name, value string) {
+		if value != "" {
+			parameters = append(parameters, roleParameter{name, value})
+		}
+	}
+	addList := func(name string, value []string) {
+		if len(value) != 0 {
+			parameters = append(parameters, roleParameter{name, value})
+		}
+	}
+	addBool := func(name string, value bool) {
+		if value {
+			parameters = append(parameters, roleParameter{name, true})
+		}
+	}
+	addOptionalBool := func(name string, value *bool) {
+		if value != nil {
+			parameters = append(parameters, roleParameter{name, *value})
+		}
+	}
+
+	addList("allowed_domains", oc.AllowedDomains)
+	addBool("allow_bare_domains", oc.AllowBareDomains)
+	addBool("allow_subdomains", oc.AllowSubdomains)
+	addBool("allow_glob_domains", oc.AllowGlobDomains)
+	addOptionalBool("allow_localhost", oc.AllowLocalhost)
+	addOptionalBool("enforce_hostnames", oc.EnforceHostnames)
+	addOptionalBool("allow_ip_sans", oc.AllowIPSANs)
+	addList("allowed_uri_sans", oc.AllowedURISANs)
+	addList("allowed_other_sans", oc.AllowedOtherSANs)
+	addOptionalBool("server_flag", oc.ServerFlag)
+	addOptionalBool("client_flag", oc.ClientFlag)
+	addBool("code_signing_flag", oc.CodeSigningFlag)
+	addBool("email_protection_flag", oc.EmailProtectionFlag)
+	addString("key_type", oc.KeyType)
+	if oc.KeyBits != 0 {
+		parameters = append(parameters, roleParameter{"key_bits", oc.KeyBits})
+	}
+	addList("key_usage", oc.KeyUsage)
+	addList("ext_key_usage", oc.ExtKeyUsage)
+	addList("ext_key_usage_oids", oc.ExtKeyUsageOIDs)
+	addOptionalBool("use_csr_common_name", oc.UseCSRCommonName)
+	addOptionalBool("use_csr_sans", oc.UseCSRSANs)
+	addOptionalBool("require_cn", oc.RequireCN)
+	addBool("no_store", oc.NoStore)
+	addString("not_before_duration", oc.NotBeforeDuration)
+
+	return parameters
+}
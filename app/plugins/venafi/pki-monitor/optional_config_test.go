@@ -0,0 +1,123 @@
+package pki_monitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+)
+
+func TestOptionalConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		config  OptionalConfig
+		wantErr bool
+	}{
+		"all parameters": {
+			config: OptionalConfig{
+				AllowedDomains:      []string{"example.com"},
+				AllowSubdomains:     true,
+				AllowLocalhost:      boolPtr(false),
+				AllowedURISANs:      []string{"spiffe://example.com/*"},
+				ClientFlag:          boolPtr(false),
+				CodeSigningFlag:     true,
+				KeyType:             "ec",
+				KeyBits:             384,
+				KeyUsage:            []string{"DigitalSignature"},
+				ExtKeyUsage:         []string{"ServerAuth", "CodeSigning"},
+				ExtKeyUsageOIDs:     []string{"1.3.6.1.5.5.7.3.1"},
+				NotBeforeDuration:   "30s",
+				OptionalConfig:      venafi.OptionalConfig{TTL: "1h", MaxTTL: "2h"},
+				EmailProtectionFlag: true,
+			},
+		},
+		"rsa key bits without key type": {
+			config: OptionalConfig{KeyBits: 4096},
+		},
+		"invalid key type": {
+			config:  OptionalConfig{KeyType: "dsa"},
+			wantErr: true,
+		},
+		"rsa key bits for ec key": {
+			config:  OptionalConfig{KeyType: "ec", KeyBits: 2048},
+			wantErr: true,
+		},
+		"P224 ec key unsupported by Venafi": {
+			config:  OptionalConfig{KeyType: "ec", KeyBits: 224},
+			wantErr: true,
+		},
+		"key bits for ed25519 key": {
+			config:  OptionalConfig{KeyType: "ed25519", KeyBits: 256},
+			wantErr: true,
+		},
+		"invalid key usage": {
+			config:  OptionalConfig{KeyUsage: []string{"Signing"}},
+			wantErr: true,
+		},
+		"invalid extended key usage": {
+			config:  OptionalConfig{ExtKeyUsage: []string{"serverAuth"}},
+			wantErr: true,
+		},
+		"invalid extended key usage OID": {
+			config:  OptionalConfig{ExtKeyUsageOIDs: []string{"serverAuth"}},
+			wantErr: true,
+		},
+		"invalid not_before_duration": {
+			config:  OptionalConfig{NotBeforeDuration: "30 seconds"},
+			wantErr: true,
+		},
+		"empty allowed domain": {
+			config:  OptionalConfig{AllowedDomains: []string{""}},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOptionalConfig_GetAsMap(t *testing.T) {
+	config := &OptionalConfig{
+		AllowedDomains:    []string{"example.com"},
+		EnforceHostnames:  boolPtr(false),
+		KeyType:           "rsa",
+		KeyBits:           4096,
+		ExtKeyUsage:       []string{"ServerAuth"},
+		NotBeforeDuration: "1m",
+		OptionalConfig:    venafi.OptionalConfig{TTL: "1h"},
+	}
+
+	require.Equal(t, map[string]interface{}{
+		"ttl":                 "1h",
+		"max_ttl":             "",
+		"allow_any_name":      false,
+		"generate_lease":      false,
+		"allowed_domains":     []string{"example.com"},
+		"enforce_hostnames":   false,
+		"key_type":            "rsa",
+		"key_bits":            4096,
+		"ext_key_usage":       []string{"ServerAuth"},
+		"not_before_duration": "1m",
+	}, config.GetAsMap())
+
+	require.Equal(t, map[string]interface{}{
+		"ttl":                 int64(3600),
+		"allowed_domains":     []string{"example.com"},
+		"enforce_hostnames":   false,
+		"key_type":            "rsa",
+		"key_bits":            4096,
+		"ext_key_usage":       []string{"ServerAuth"},
+		"not_before_duration": int64(60),
+	}, config.GetExpectedRoleData())
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
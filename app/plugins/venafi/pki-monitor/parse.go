@@ -17,5 +17,15 @@ func (c *VenafiPKIMonitorConfig) ParseConfig(config *plugins.PluginConfig, evalC
 		return diagnostics
 	}
 
+	err := c.moveRoleCAToMount()
+	if err != nil {
+		return err
+	}
+
+	if c.CA != nil && c.CA.VenafiIntermediate != nil {
+		c.CA.VenafiIntermediate.VaultAddress = config.VaultAddress
+		c.CA.VenafiIntermediate.ConfigDir = config.ConfigDir
+	}
+
 	return nil
 }
@@ -0,0 +1,102 @@
+package pki_monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DefaultPendingRequestsFile is where the pickup IDs of intermediate certificate requests pending approval are saved,
+// next to the config file, if pending_requests_file isn't given
+const DefaultPendingRequestsFile = "vvw_pending_requests.json"
+
+// PendingRequests is a JSON file recording the pickup IDs of intermediate certificate requests that are pending
+// approval in Venafi, keyed by Vault address and mount path, so that a later run can pick up the certificate rather than
+// request another
+type PendingRequests struct {
+	Filename string
+	// VaultAddress is the api_address of the Vault cluster the mounts are in, so that clusters sharing the file with
+	// the same mount paths don't pick up each other's requests
+	VaultAddress string
+}
+
+// Get returns the pickup ID saved for mountPath, or an empty string if there isn't one
+func (p *PendingRequests) Get(mountPath string) (string, error) {
+	pickupIDs, err := p.read()
+	if err != nil {
+		return "", err
+	}
+
+	return pickupIDs[p.key(mountPath)], nil
+}
+
+// Save records pickupID as pending for mountPath
+func (p *PendingRequests) Save(mountPath, pickupID string) error {
+	pickupIDs, err := p.read()
+	if err != nil {
+		return err
+	}
+
+	pickupIDs[p.key(mountPath)] = pickupID
+	return p.write(pickupIDs)
+}
+
+// Remove removes any pickup ID saved for mountPath, deleting the file if there are none left
+func (p *PendingRequests) Remove(mountPath string) error {
+	pickupIDs, err := p.read()
+	if err != nil {
+		return err
+	}
+	key := p.key(mountPath)
+	if _, ok := pickupIDs[key]; !ok {
+		return nil
+	}
+
+	delete(pickupIDs, key)
+	if len(pickupIDs) == 0 {
+		return os.Remove(p.Filename)
+	}
+
+	return p.write(pickupIDs)
+}
+
+// key returns the key of mountPath in the file, which is its URL in the Vault cluster such as
+// https://vault:8200/pki
+func (p *PendingRequests) key(mountPath string) string {
+	return strings.TrimSuffix(p.VaultAddress, "/") + "/" + strings.Trim(mountPath, "/")
+}
+
+func (p *PendingRequests) read() (map[string]string, error) {
+	pickupIDs := make(map[string]string)
+
+	contents, err := ioutil.ReadFile(p.Filename)
+	if os.IsNotExist(err) {
+		return pickupIDs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading pending requests from %s: %w", p.Filename, err)
+	}
+
+	err = json.Unmarshal(contents, &pickupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing pending requests in %s: %w", p.Filename, err)
+	}
+
+	return pickupIDs, nil
+}
+
+func (p *PendingRequests) write(pickupIDs map[string]string) error {
+	contents, err := json.MarshalIndent(pickupIDs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(p.Filename, contents, 0600)
+	if err != nil {
+		return fmt.Errorf("error saving pending requests to %s: %w", p.Filename, err)
+	}
+
+	return nil
+}
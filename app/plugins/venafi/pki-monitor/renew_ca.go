@@ -0,0 +1,221 @@
+package pki_monitor
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper/vcert_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// HasVenafiIntermediate returns whether the mount's CA is an intermediate certificate signed by Venafi
+func (c *VenafiPKIMonitorConfig) HasVenafiIntermediate() bool {
+	return c.CA != nil && c.CA.VenafiIntermediate != nil
+}
+
+// RenewIntermediateCA renews the mount's intermediate CA certificate if it expires within threshold, by generating a
+// new CSR and having it signed by Venafi. If threshold is nil then the CA's renewal_window is used, the same as apply.
+func (c *VenafiPKIMonitorConfig) RenewIntermediateCA(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	threshold *time.Duration,
+) error {
+	venafiClient, err := vcert_wrapper.NewVenafiClient(c.getIntermediateSecret().VenafiSecret)
+	if err != nil {
+		return err
+	}
+
+	return c.renewIntermediateCA(report, vaultClient, venafiClient, threshold)
+}
+
+func (c *VenafiPKIMonitorConfig) renewIntermediateCA(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	threshold *time.Duration,
+) error {
+	renewalWindow := c.CA.getRenewalWindow()
+	if threshold != nil {
+		renewalWindow = *threshold
+	}
+
+	renewSection := report.AddSection(fmt.Sprintf("Renewing intermediate CA certificate of %s", c.MountPath))
+	check := renewSection.AddCheck("Checking expiry of the intermediate CA certificate...")
+
+	currentCA, err := GetCurrentCA(vaultClient, c.MountPath)
+	if err != nil {
+		check.Errorf("Error reading the current CA certificate: %s", err)
+		return err
+	}
+	if currentCA == nil {
+		check.Error("No CA certificate configured yet, so there is nothing to renew. Run apply to configure it")
+		return fmt.Errorf("no CA certificate configured at %s", c.MountPath)
+	}
+
+	if time.Until(currentCA.NotAfter) > renewalWindow {
+		check.Successf(
+			"CA certificate %s is valid until %s, so doesn't need renewing yet",
+			currentCA.Subject.CommonName, currentCA.NotAfter.Format(time.RFC3339),
+		)
+		return nil
+	}
+	check.Successf(
+		"CA certificate %s expires at %s, within %s, so will be renewed",
+		currentCA.Subject.CommonName, currentCA.NotAfter.Format(time.RFC3339), renewalWindow,
+	)
+
+	c.warnKeyReplaced(renewSection)
+
+	request := renewalRequest(c.CA.VenafiIntermediate.CertificateRequest, currentCA)
+	err = ConfigureIntermediateCertificate(
+		renewSection,
+		vaultClient,
+		c.MountPath,
+		&request,
+		venafiClient,
+		c.CA.VenafiIntermediate,
+	)
+	if err != nil {
+		return err
+	}
+
+	return c.verifyRenewedCA(renewSection, vaultClient, currentCA)
+}
+
+// warnKeyReplaced warns that generating the CSR replaces the private key of the mount's CA straight away, but not its
+// certificate, so certificates issued in the meantime won't chain to the CA certificate served. With pending_approval
+// that lasts until the request is approved in Venafi, which could be days. When resuming a pending request the key has
+// already been replaced, so there's nothing to warn about.
+func (c *VenafiPKIMonitorConfig) warnKeyReplaced(renewSection reporter.Section) {
+	intermediate := c.CA.VenafiIntermediate
+	if intermediate.PendingApproval {
+		// An error reading the file is reported when the request is resumed, so it's treated as there being no request
+		pickupID, _ := intermediate.getPendingRequests().Get(c.MountPath)
+		if pickupID != "" {
+			return
+		}
+	}
+
+	until := "Venafi issues the renewed certificate"
+	if intermediate.PendingApproval {
+		until = "the request is approved in Venafi"
+	}
+	renewSection.AddCheck("Checking the CA key can be replaced...").Warningf(
+		"Generating the CSR replaces the private key of %s before its certificate, so certificates it issues won't chain to its CA certificate until %s",
+		c.MountPath, until,
+	)
+}
+
+// verifyRenewedCA checks that the mount's CA certificate has been replaced, and that the roles' test certificates can
+// be issued by it
+func (c *VenafiPKIMonitorConfig) verifyRenewedCA(
+	renewSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	previousCA *x509.Certificate,
+) error {
+	check := renewSection.AddCheck("Checking the renewed CA certificate...")
+
+	renewedCA, err := GetCurrentCA(vaultClient, c.MountPath)
+	if err != nil {
+		check.Errorf("Error reading the renewed CA certificate: %s", err)
+		return err
+	}
+	if renewedCA == nil || renewedCA.Equal(previousCA) {
+		check.Error("The CA certificate wasn't replaced by the renewed one")
+		return fmt.Errorf("CA certificate at %s wasn't renewed", c.MountPath)
+	}
+	check.Successf("Renewed CA certificate is valid until %s", renewedCA.NotAfter.Format(time.RFC3339))
+
+	testCertsRequested := false
+	for _, role := range c.Roles {
+		for _, certRequest := range role.TestCerts {
+			data, err := verifyIssuedByCA(renewSection, vaultClient, c.MountPath, role.Name, certRequest, renewedCA)
+			if err != nil {
+				return err
+			}
+
+			err = c.cleanupTestCertificate(renewSection, vaultClient, certRequest, data)
+			if err != nil {
+				return err
+			}
+			testCertsRequested = true
+		}
+	}
+
+	if !testCertsRequested {
+		renewSection.AddCheck("Checking certificates can be issued...").Warning(
+			"No test_certificate blocks are given for any role, so issuing certificates wasn't checked",
+		)
+	}
+
+	return nil
+}
+
+// verifyIssuedByCA requests a certificate from the role roleName and checks it was signed by ca, returning the data
+// Vault returned
+func verifyIssuedByCA(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	mountPath, roleName string,
+	certRequest venafi.CertificateRequest,
+	ca *x509.Certificate,
+) (map[string]interface{}, error) {
+	issuePath, parameters, err := certRequest.GetIssueRequest(mountPath, roleName)
+	if err != nil {
+		check := reportSection.AddCheck(fmt.Sprintf("Requesting test certificate with CN:%s", certRequest.CommonName))
+		check.Errorf("Error reading CSR for test certificate: %s", err)
+		return nil, err
+	}
+	check := reportSection.AddCheck(
+		fmt.Sprintf("Requesting test certificate from %s with CN:%s", issuePath, certRequest.CommonName),
+	)
+
+	data, err := vaultClient.WriteValue(issuePath, parameters)
+	if err != nil {
+		check.Errorf("Error retrieving certificate from Vault: %s", err)
+		return nil, err
+	}
+
+	certificatePEM, _ := data["certificate"].(string)
+	certificate, err := parseCertificatePEM(certificatePEM)
+	if err != nil {
+		check.Errorf("Error parsing returned certificate: %s", err)
+		return nil, err
+	}
+
+	err = certificate.CheckSignatureFrom(ca)
+	if err != nil {
+		check.Errorf("Certificate wasn't issued by the renewed CA certificate: %s", err)
+		return nil, err
+	}
+
+	check.Success("Test certificate issued by the renewed CA certificate")
+	return data, nil
+}
+
+// renewalRequest returns the configured request for the intermediate certificate, keeping its key and SANs, but with
+// the subject of the current CA certificate so that the renewed one replaces it like for like
+func renewalRequest(configured venafi.CertificateRequest, current *x509.Certificate) venafi.CertificateRequest {
+	request := configured
+	subject := current.Subject
+	request.CommonName = subject.CommonName
+	request.OU = firstOrEmpty(subject.OrganizationalUnit)
+	request.Organisation = firstOrEmpty(subject.Organization)
+	request.Locality = firstOrEmpty(subject.Locality)
+	request.Province = firstOrEmpty(subject.Province)
+	request.Country = firstOrEmpty(subject.Country)
+
+	return request
+}
+
+func firstOrEmpty(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,235 @@
+package pki_monitor
+
+import (
+	"crypto/x509/pkix"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/testcerts"
+	mockVenafiWrapper "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestRenewIntermediateCA(t *testing.T) {
+	const mountPath = "pki"
+	const caPath = mountPath + "/cert/ca"
+	const zone = "zone"
+
+	testCertRequest := venafi.CertificateRequest{
+		CommonName: "test.venafidemo.com",
+		TTL:        "1h",
+	}
+
+	// The subject has since been changed in the config, but renewing keeps the current CA's
+	configuredRequest := testCARequest
+	configuredRequest.CommonName = "Renamed Vault CA"
+	configuredRequest.KeyType = "ec"
+	configuredRequest.KeyBits = 384
+	configuredRequest.AltNames = []string{"ca.venafidemo.com"}
+	configuredRequest.TTL = "8760h"
+
+	zero := time.Duration(0)
+	day := 24 * time.Hour
+	threeDays := 72 * time.Hour
+
+	tests := map[string]struct {
+		threshold     *time.Duration
+		renewalWindow string
+		validity      time.Duration
+		wantRenewal   bool
+	}{
+		"valid for longer than threshold": {
+			threshold: &day,
+			validity:  48 * time.Hour,
+		},
+		"expiring within threshold": {
+			threshold:   &threeDays,
+			validity:    48 * time.Hour,
+			wantRenewal: true,
+		},
+		"zero threshold overriding renewal window": {
+			threshold:     &zero,
+			renewalWindow: "72h",
+			validity:      48 * time.Hour,
+		},
+		"expiring within renewal window": {
+			renewalWindow: "72h",
+			validity:      48 * time.Hour,
+			wantRenewal:   true,
+		},
+		"expiring within default renewal window": {
+			validity:    48 * time.Hour,
+			wantRenewal: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+			defer venafiClient.AssertExpectations(t)
+
+			reportExpectations(report, section, check)
+			check.On("Successf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+
+			config := &VenafiPKIMonitorConfig{
+				MountPath: mountPath,
+				CA: &CA{
+					VenafiIntermediate: &IntermediateCertRequest{
+						Zone:               zone,
+						CertificateRequest: configuredRequest,
+					},
+					RenewalWindow: tt.renewalWindow,
+				},
+				Roles: []Role{
+					{
+						Name:      "web",
+						TestCerts: []venafi.CertificateRequest{testCertRequest},
+					},
+				},
+			}
+
+			currentCA := generateTestCA(t, testCARequest, tt.validity)
+			vaultAPIClient.On("ReadValue", caPath).Return(map[string]interface{}{"certificate": currentCA}, nil).Once()
+
+			if tt.wantRenewal {
+				renewedCA := testcerts.NewCA(t, testCARequest.Subject(), 30*24*time.Hour, nil)
+				renewedCAPEM := renewedCA.PEM
+				issuedPEM, _ := renewedCA.Issue(
+					t,
+					testcerts.NewTemplate(t, pkix.Name{CommonName: testCertRequest.CommonName}, time.Hour),
+					&testcerts.NewKey(t).PublicKey,
+				)
+
+				// The new CSR should have the same subject as the current CA, and the configured key and SANs
+				renewalRequest := testCARequest
+				renewalRequest.KeyType = configuredRequest.KeyType
+				renewalRequest.KeyBits = configuredRequest.KeyBits
+				renewalRequest.AltNames = configuredRequest.AltNames
+				renewalRequest.TTL = configuredRequest.TTL
+				check.On("Warningf", mock.AnythingOfType("string"), mock.Anything).Once()
+				vaultAPIClient.On("WriteValue", mountPath+"/intermediate/generate/internal", renewalRequest.ToMap()).
+					Return(map[string]interface{}{"csr": testCsr}, nil)
+				venafiClient.On("RequestCertificate", mock.Anything, zone).Return("requestID", nil)
+				venafiClient.On("RetrieveCertificate", mock.Anything, zone).
+					Return(&certificate.PEMCollection{Certificate: renewedCAPEM}, nil)
+				vaultAPIClient.On("WriteValue", mountPath+"/intermediate/set-signed", map[string]interface{}{
+					"certificate": renewedCAPEM,
+				}).Return(nil, nil)
+				vaultAPIClient.On("ReadValue", caPath).
+					Return(map[string]interface{}{"certificate": renewedCAPEM}, nil).Once()
+				vaultAPIClient.On("WriteValue", mountPath+"/issue/web", testCertRequest.ToMap()).
+					Return(map[string]interface{}{
+						"certificate": issuedPEM,
+					}, nil)
+			}
+
+			err := config.renewIntermediateCA(report, vaultAPIClient, venafiClient, tt.threshold)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRenewIntermediateCA_ResumePendingRequest(t *testing.T) {
+	const pickupID = "pickupID"
+
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer venafiClient.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+	check.On("Successf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+
+	pendingRequests := &PendingRequests{Filename: filepath.Join(t.TempDir(), "pending.json")}
+	require.NoError(t, pendingRequests.Save("pki", pickupID))
+
+	config := &VenafiPKIMonitorConfig{
+		MountPath: "pki",
+		CA: &CA{
+			VenafiIntermediate: &IntermediateCertRequest{
+				Zone:                "zone",
+				PendingApproval:     true,
+				PendingRequestsFile: pendingRequests.Filename,
+				CertificateRequest:  testCARequest,
+			},
+		},
+		Roles: []Role{{Name: "web"}},
+	}
+
+	currentCA := generateTestCA(t, testCARequest, time.Hour)
+	renewedCA := testcerts.NewCA(t, testCARequest.Subject(), 30*24*time.Hour, nil).PEM
+	vaultAPIClient.On("ReadValue", "pki/cert/ca").Return(map[string]interface{}{"certificate": currentCA}, nil).Once()
+
+	// The CSR from the earlier run is still waiting in Vault, so only the saved request is picked up
+	venafiClient.On("RetrieveCertificate", mock.MatchedBy(func(request *certificate.Request) bool {
+		return request.PickupID == pickupID
+	}), "zone").Return(&certificate.PEMCollection{Certificate: renewedCA}, nil)
+	vaultAPIClient.On("WriteValue", "pki/intermediate/set-signed", map[string]interface{}{
+		"certificate": renewedCA,
+	}).Return(nil, nil)
+	vaultAPIClient.On("ReadValue", "pki/cert/ca").Return(map[string]interface{}{"certificate": renewedCA}, nil).Once()
+	check.On("Warning", mock.AnythingOfType("string"))
+
+	err := config.renewIntermediateCA(report, vaultAPIClient, venafiClient, nil)
+	require.NoError(t, err)
+	vaultAPIClient.AssertNotCalled(t, "WriteValue", "pki/intermediate/generate/internal", mock.Anything)
+	venafiClient.AssertNotCalled(t, "RequestCertificate", mock.Anything, mock.Anything)
+	check.AssertNotCalled(t, "Warningf", mock.Anything, mock.Anything)
+
+	savedPickupID, err := pendingRequests.Get("pki")
+	require.NoError(t, err)
+	require.Empty(t, savedPickupID)
+}
+
+func TestRenewIntermediateCA_NotRenewed(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	venafiClient := new(mockVenafiWrapper.VenafiWrapper)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+	check.On("Successf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+	check.On("Errorf", mock.AnythingOfType("string"), mock.Anything)
+	check.On("Error", mock.AnythingOfType("string"))
+
+	config := &VenafiPKIMonitorConfig{
+		MountPath: "pki",
+		CA: &CA{
+			VenafiIntermediate: &IntermediateCertRequest{
+				Zone:               "zone",
+				CertificateRequest: testCARequest,
+			},
+		},
+		Roles: []Role{{Name: "web"}},
+	}
+
+	// Set-signed succeeding without the CA changing should be caught
+	currentCA := generateTestCA(t, testCARequest, time.Hour)
+	vaultAPIClient.On("ReadValue", "pki/cert/ca").Return(map[string]interface{}{"certificate": currentCA}, nil)
+	check.On("Warningf", mock.AnythingOfType("string"), mock.Anything)
+	vaultAPIClient.On("WriteValue", "pki/intermediate/generate/internal", mock.Anything).
+		Return(map[string]interface{}{"csr": testCsr}, nil)
+	venafiClient.On("RequestCertificate", mock.Anything, "zone").Return("requestID", nil)
+	venafiClient.On("RetrieveCertificate", mock.Anything, "zone").
+		Return(&certificate.PEMCollection{Certificate: currentCA}, nil)
+	vaultAPIClient.On("WriteValue", "pki/intermediate/set-signed", mock.Anything).Return(nil, nil)
+
+	err := config.renewIntermediateCA(report, vaultAPIClient, venafiClient, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package pki_monitor
+
+import (
+	"fmt"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// RotateSecrets rewrites the Venafi secret called secretName, or every secret if secretName is blank, with fresh TPP
+// tokens or the API key given in the config. The rest of the mount is left alone, and the test certificates of the
+// roles using the secret are then requested to check it works.
+func (c *VenafiPKIMonitorConfig) RotateSecrets(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	secretName string,
+) (bool, error) {
+	if secretName != "" && !c.hasSecret(secretName) {
+		return false, nil
+	}
+
+	// Every secret is connected with, as a role's import policies may use the secrets of other roles
+	venafiClients, err := c.connectToVenafi(report.AddSection(fmt.Sprintf("Connecting to Venafi for %s", c.MountPath)))
+	if err != nil {
+		return false, err
+	}
+
+	return true, c.rotateSecrets(report, vaultClient, venafiClients, secretName)
+}
+
+func (c *VenafiPKIMonitorConfig) rotateSecrets(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	venafiClients map[string]venafi_wrapper.VenafiWrapper,
+	secretName string,
+) error {
+	roles := make([]venafi.RotatingRole, len(c.Roles))
+	for i, role := range c.Roles {
+		roles[i] = venafi.RotatingRole{
+			Name:         role.Name,
+			SecretName:   role.Secret.Name,
+			Secret:       role.Secret,
+			HasTestCerts: len(role.TestCerts) != 0,
+		}
+	}
+
+	return venafi.RotateVenafiSecrets(
+		report,
+		vaultClient,
+		venafiClients,
+		c.MountPath,
+		venafi.MonitorEngine,
+		roles,
+		secretName,
+		func(rotateSection reporter.Section, i int) error {
+			return c.requestRoleTestCertificates(rotateSection, vaultClient, venafiClients, c.Roles[i])
+		},
+	)
+}
+
+// hasSecret returns whether any role uses the secret called secretName
+func (c *VenafiPKIMonitorConfig) hasSecret(secretName string) bool {
+	for _, role := range c.Roles {
+		if role.Secret.Name == secretName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,113 @@
+package pki_monitor
+
+import (
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/venafi/tpp"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	mockVenafiWrapper "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestVenafiPKIMonitorConfig_rotateSecrets(t *testing.T) {
+	tppSecret := venafi.VenafiSecret{
+		TPP: &venafi.VenafiTPPConnection{
+			URL:      "https://tpp.example.com",
+			Username: "username",
+			Password: "password",
+		},
+	}
+	vaasSecret := venafi.VenafiSecret{
+		VaaS: &venafi.VenafiVaaSConnection{APIKey: "new API key"},
+	}
+
+	tests := map[string]struct {
+		secretName   string
+		wantRotated  []string
+		wantWarnings int
+	}{
+		"all secrets": {
+			wantRotated:  []string{"tpp", "vaas"},
+			wantWarnings: 3,
+		},
+		"secret shared by roles": {
+			secretName:   "tpp",
+			wantRotated:  []string{"tpp"},
+			wantWarnings: 2,
+		},
+		"secret of one role": {
+			secretName:   "vaas",
+			wantRotated:  []string{"vaas"},
+			wantWarnings: 1,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			tppClient := new(mockVenafiWrapper.VenafiWrapper)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+			defer tppClient.AssertExpectations(t)
+			defer check.AssertExpectations(t)
+
+			reportExpectations(report, section, check)
+			check.On("Warning", mock.AnythingOfType("string")).Times(tt.wantWarnings)
+
+			// Each secret should only be written once, however many roles use it
+			for _, secretName := range tt.wantRotated {
+				switch secretName {
+				case "tpp":
+					tppClient.On("GetRefreshToken", mock.Anything).Return(tpp.OauthGetRefreshTokenResponse{
+						Access_token:  "new access token",
+						Refresh_token: "new refresh token",
+					}, nil).Once()
+					vaultAPIClient.On("WriteValue", "pki/venafi/tpp", map[string]interface{}{
+						"access_token":  "new access token",
+						"refresh_token": "new refresh token",
+						"url":           "https://tpp.example.com",
+					}).Return(nil, nil).Once()
+				case "vaas":
+					vaultAPIClient.On("WriteValue", "pki/venafi/vaas", map[string]interface{}{
+						"apikey": "new API key",
+					}).Return(nil, nil).Once()
+				}
+			}
+
+			config := &VenafiPKIMonitorConfig{
+				MountPath: "pki",
+				Roles: []Role{
+					{Name: "web", Secret: UnZonedSecret{Name: "tpp", VenafiSecret: tppSecret}},
+					{Name: "api", Secret: UnZonedSecret{Name: "tpp", VenafiSecret: tppSecret}},
+					{Name: "mail", Secret: UnZonedSecret{Name: "vaas", VenafiSecret: vaasSecret}},
+				},
+			}
+			err := config.rotateSecrets(report, vaultAPIClient, map[string]venafi_wrapper.VenafiWrapper{
+				"tpp":  tppClient,
+				"vaas": new(mockVenafiWrapper.VenafiWrapper),
+			}, tt.secretName)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVenafiPKIMonitorConfig_RotateSecrets_NoMatchingSecret(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+
+	config := &VenafiPKIMonitorConfig{
+		MountPath: "pki",
+		Roles:     []Role{{Name: "web", Secret: UnZonedSecret{Name: "tpp"}}},
+	}
+	rotated, err := config.RotateSecrets(report, vaultAPIClient, "vaas")
+	require.NoError(t, err)
+	require.False(t, rotated)
+}
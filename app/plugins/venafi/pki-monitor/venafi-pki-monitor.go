@@ -1,7 +1,11 @@
 package pki_monitor
 
 import (
+	"crypto/x509"
 	"fmt"
+	"time"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper/vcert_wrapper"
@@ -26,37 +30,206 @@ func (c *VenafiPKIMonitorConfig) GetDownloadURL() (string, error) {
 	)
 }
 
+const (
+	defaultEnforcementPolicyName = "default"
+	defaultImportPolicyName      = "visibility"
+)
+
+// GetKind returns PluginKindSecret, as the plugin is a secrets engine
+func (c *VenafiPKIMonitorConfig) GetKind() api.PluginKind {
+	return api.PluginKindSecret
+}
+
 func (c *VenafiPKIMonitorConfig) Configure(report reporter.Report, vaultClient api.VaultAPIClient) error {
 	configurePluginSection := report.AddSection("Setting up venafi-pki-monitor")
 
-	venafiClient, err := vcert_wrapper.NewVenafiClient(c.Role.Secret.VenafiSecret)
+	for i, role := range c.Roles {
+		venafiClient, err := vcert_wrapper.NewVenafiClient(role.Secret.VenafiSecret)
+		if err != nil {
+			return err
+		}
+
+		err = role.Configure(
+			configurePluginSection,
+			c.MountPath,
+			getPolicyName(defaultEnforcementPolicyName, i, role.Name),
+			getPolicyName(defaultImportPolicyName, i, role.Name),
+			vaultClient,
+			venafiClient,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.ConfigurePolicies(configurePluginSection, vaultClient)
 	if err != nil {
 		return err
 	}
 
-	err = c.Role.Configure(configurePluginSection, c.MountPath, vaultClient, venafiClient)
+	var venafiClient venafi_wrapper.VenafiWrapper
+	if c.HasVenafiIntermediate() {
+		venafiClient, err = vcert_wrapper.NewVenafiClient(c.getIntermediateSecret().VenafiSecret)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.ConfigureCA(configurePluginSection, vaultClient, venafiClient)
+}
+
+// Preflight authenticates to Venafi with each role's secret and reads the zones of its policies, the mount's policies
+// and the intermediate CA, before anything is installed
+func (c *VenafiPKIMonitorConfig) Preflight(report reporter.Report) error {
+	preflightSection := report.AddSection("Checking Venafi for venafi-pki-monitor")
+
+	venafiClients, err := c.connectToVenafi(preflightSection)
 	if err != nil {
 		return err
 	}
 
+	return c.preflightZones(preflightSection, venafiClients)
+}
+
+// connectToVenafi authenticates to Venafi with the secret of each role, returning the clients keyed by secret name
+func (c *VenafiPKIMonitorConfig) connectToVenafi(reportSection reporter.Section) (map[string]venafi_wrapper.VenafiWrapper, error) {
+	venafiClients := make(map[string]venafi_wrapper.VenafiWrapper, len(c.Roles))
+	for _, role := range c.Roles {
+		if venafiClients[role.Secret.Name] != nil {
+			continue
+		}
+
+		check := reportSection.AddCheck(fmt.Sprintf("Connecting to Venafi with secret %s...", role.Secret.Name))
+		venafiClient, err := vcert_wrapper.NewVenafiClient(role.Secret.VenafiSecret)
+		if err != nil {
+			check.Errorf("Error connecting to Venafi with secret %s: %s", role.Secret.Name, err)
+			return nil, err
+		}
+		check.Successf("Connected to Venafi with secret %s", role.Secret.Name)
+
+		venafiClients[role.Secret.Name] = venafiClient
+	}
+
+	return venafiClients, nil
+}
+
+func (c *VenafiPKIMonitorConfig) preflightZones(
+	preflightSection reporter.Section,
+	venafiClients map[string]venafi_wrapper.VenafiWrapper,
+) error {
+	roles := make(map[string]Role, len(c.Roles))
+	for _, role := range c.Roles {
+		roles[role.Name] = role
+		venafiClient := venafiClients[role.Secret.Name]
+
+		if role.EnforcementPolicy != nil {
+			zoneConfig, err := venafi.CheckVenafiZone(
+				preflightSection,
+				venafiClient,
+				fmt.Sprintf("role %s enforcement policy", role.Name),
+				role.EnforcementPolicy.Zone,
+			)
+			if err != nil {
+				return err
+			}
+
+			err = role.checkTestCertificates(preflightSection, role.EnforcementPolicy.Zone, zoneConfig)
+			if err != nil {
+				return err
+			}
+		}
+
+		if role.ImportPolicy != nil {
+			_, err := venafi.CheckVenafiZone(
+				preflightSection,
+				venafiClient,
+				fmt.Sprintf("role %s import policy", role.Name),
+				role.ImportPolicy.Zone,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, policy := range c.Policies {
+		venafiClient := venafiClients[policy.VenafiSecret]
+		zoneConfig, err := venafi.CheckVenafiZone(
+			preflightSection,
+			venafiClient,
+			fmt.Sprintf("policy %s", policy.Name),
+			policy.Zone,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, roleName := range policy.EnforcementRoles {
+			role := roles[roleName]
+			err = role.checkTestCertificates(preflightSection, policy.Zone, zoneConfig)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.HasVenafiIntermediate() {
+		_, err := venafi.CheckVenafiZone(
+			preflightSection,
+			venafiClients[c.getIntermediateSecret().Name],
+			"intermediate CA",
+			c.CA.VenafiIntermediate.Zone,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// checkTestCertificates checks the role's test certificates meet the policy of a zone enforced on it. They're issued
+// by the mount's CA rather than enrolled through the zone, so are only validated against its policy.
+func (r *Role) checkTestCertificates(
+	preflightSection reporter.Section,
+	zone string,
+	zoneConfig *endpoint.ZoneConfiguration,
+) error {
+	return venafi.ValidateTestCertificates(
+		preflightSection,
+		zone,
+		zoneConfig,
+		r.TestCerts,
+		r.OptionalConfig.getMaxTTL(),
+	)
+}
+
+// getPolicyName returns the name of one of the Venafi policies for the role at roleIndex. The first role uses the
+// default names, as it did before multiple roles were supported, and the rest have their role name appended.
+func getPolicyName(defaultName string, roleIndex int, roleName string) string {
+	if roleIndex == 0 {
+		return defaultName
+	}
+
+	return fmt.Sprintf("%s-%s", defaultName, roleName)
+}
+
+// ConfigureCA configures the certificate the mount issues certificates with, which is shared by all of its roles
+func (c *VenafiPKIMonitorConfig) ConfigureCA(
+	configurePluginSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	venafiClient venafi_wrapper.VenafiWrapper,
+) error {
+	return c.CA.Configure(configurePluginSection, vaultClient, c.MountPath, venafiClient)
+}
+
 func (r *Role) Configure(
 	configurePluginSection reporter.Section,
-	mountPath string,
+	mountPath, enforcementPolicyName, importPolicyName string,
 	vaultClient api.VaultAPIClient,
 	venafiClient venafi_wrapper.VenafiWrapper,
 ) error {
-	err := venafi.ConfigureVenafiSecret(
-		configurePluginSection,
-		vaultClient,
-		venafiClient,
-		fmt.Sprintf("%s/venafi/%s", mountPath, r.Secret.Name),
-		r.Secret,
-		venafi.MonitorEngine,
-		nil,
-	)
+	err := r.configureSecret(configurePluginSection, mountPath, vaultClient, venafiClient)
 	if err != nil {
 		return err
 	}
@@ -66,13 +239,8 @@ func (r *Role) Configure(
 			configurePluginSection,
 			vaultClient,
 			mountPath,
-			"default",
-			map[string]interface{}{
-				"venafi_secret":     r.Secret.Name,
-				"zone":              r.EnforcementPolicy.Zone,
-				"enforcement_roles": r.Name,
-				"defaults_roles":    r.Name,
-			},
+			r.EnforcementPolicy.getName(enforcementPolicyName),
+			r.getEnforcementPolicyParameters(),
 		)
 		if err != nil {
 			return err
@@ -83,73 +251,305 @@ func (r *Role) Configure(
 			configurePluginSection,
 			vaultClient,
 			mountPath,
-			"visibility",
-			map[string]interface{}{
-				"venafi_secret": r.Secret.Name,
-				"zone":          r.ImportPolicy.Zone,
-				"import_roles":  r.Name,
-			},
+			r.ImportPolicy.getName(importPolicyName),
+			r.getImportPolicyParameters(),
 		)
 		if err != nil {
 			return err
 		}
 	}
 
-	if r.IntermediateCert != nil {
-		err = ConfigureIntermediateCertificate(
+	err = ConfigureVenafiRole(
+		configurePluginSection,
+		vaultClient,
+		fmt.Sprintf("%s/roles/%s", mountPath, r.Name),
+		r.OptionalConfig.GetAsMap(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// configureSecret writes the role's Venafi secret, requesting fresh TPP tokens if needed
+func (r *Role) configureSecret(
+	reportSection reporter.Section,
+	mountPath string,
+	vaultClient api.VaultAPIClient,
+	venafiClient venafi_wrapper.VenafiWrapper,
+) error {
+	return venafi.ConfigureVenafiSecret(
+		reportSection,
+		vaultClient,
+		venafiClient,
+		fmt.Sprintf("%s/venafi/%s", mountPath, r.Secret.Name),
+		r.Secret,
+		venafi.MonitorEngine,
+		nil,
+		false,
+	)
+}
+
+func (r *Role) getEnforcementPolicyParameters() map[string]interface{} {
+	parameters := r.EnforcementPolicy.getParameters(r.Secret.Name)
+	parameters["enforcement_roles"] = r.Name
+	parameters["defaults_roles"] = r.Name
+
+	return parameters
+}
+
+func (r *Role) getImportPolicyParameters() map[string]interface{} {
+	parameters := r.ImportPolicy.getParameters(r.Secret.Name)
+	parameters["import_roles"] = r.Name
+
+	return parameters
+}
+
+// ConfigurePolicies configures the Venafi policies given for the whole mount, once the roles and their secrets exist
+func (c *VenafiPKIMonitorConfig) ConfigurePolicies(
+	configurePluginSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+) error {
+	for _, policy := range c.Policies {
+		err := ConfigureVenafiPolicy(
 			configurePluginSection,
 			vaultClient,
-			mountPath,
-			&r.IntermediateCert.CertificateRequest,
-			venafiClient,
-			r.IntermediateCert.Zone,
+			c.MountPath,
+			policy.Name,
+			policy.getParameters(),
 		)
 		if err != nil {
 			return err
 		}
-	} else {
-		err := ConfigureSelfsignedCertificate(
-			configurePluginSection,
+	}
+
+	return nil
+}
+
+// CheckPolicies checks the Venafi policies and roles of the mount are configured as given
+func (c *VenafiPKIMonitorConfig) CheckPolicies(report reporter.Report, vaultClient api.VaultAPIClient) error {
+	section := report.AddSection(fmt.Sprintf("Checking the Venafi policies and roles of %s", c.MountPath))
+
+	for i, role := range c.Roles {
+		if role.EnforcementPolicy != nil {
+			policyName := role.EnforcementPolicy.getName(getPolicyName(defaultEnforcementPolicyName, i, role.Name))
+			err := VerifyVenafiPolicy(section, vaultClient, c.MountPath, policyName, role.getEnforcementPolicyParameters())
+			if err != nil {
+				return err
+			}
+		}
+		if role.ImportPolicy != nil {
+			policyName := role.ImportPolicy.getName(getPolicyName(defaultImportPolicyName, i, role.Name))
+			err := VerifyVenafiPolicy(section, vaultClient, c.MountPath, policyName, role.getImportPolicyParameters())
+			if err != nil {
+				return err
+			}
+		}
+
+		err := VerifyVenafiRole(
+			section,
 			vaultClient,
-			mountPath,
-			r.RootCert,
+			fmt.Sprintf("%s/roles/%s", c.MountPath, role.Name),
+			role.OptionalConfig.GetExpectedRoleData(),
 		)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = ConfigureVenafiRole(
-		configurePluginSection,
-		vaultClient,
-		fmt.Sprintf("%s/roles/%s", mountPath, r.Name),
-		r.OptionalConfig.GetAsMap(),
-	)
+	for _, policy := range c.Policies {
+		err := VerifyVenafiPolicy(section, vaultClient, c.MountPath, policy.Name, policy.getParameters())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *VenafiPKIMonitorConfig) Check(report reporter.Report, vaultClient api.VaultAPIClient) error {
+	err := c.CheckPolicies(report, vaultClient)
 	if err != nil {
 		return err
 	}
 
+	// Venafi is only needed to check test certificates are imported by the roles' import policies
+	var venafiClients map[string]venafi_wrapper.VenafiWrapper
+	for _, role := range c.Roles {
+		if len(role.TestCerts) != 0 && len(c.getImportZones(role)) != 0 {
+			venafiClients, err = c.connectToVenafi(report.AddSection("Connecting to Venafi to check certificates are imported"))
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return c.requestTestCertificates(report, vaultClient, venafiClients)
+}
+
+// requestTestCertificates requests each role's test certificates from Vault, checks they're imported into the zones of
+// the role's import policies, and then cleans them up
+func (c *VenafiPKIMonitorConfig) requestTestCertificates(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	venafiClients map[string]venafi_wrapper.VenafiWrapper,
+) error {
+	for _, role := range c.Roles {
+		roleIssuePath := fmt.Sprintf("%s/issue/%s", c.MountPath, role.Name)
+
+		fetchCertSection := report.AddSection(
+			fmt.Sprintf("Requesting test certificates from %s", roleIssuePath),
+		)
+		err := c.requestRoleTestCertificates(fetchCertSection, vaultClient, venafiClients, role)
+		if err != nil {
+			return err
+		}
+
+		fetchCertSection.Info(fmt.Sprintf("Certificates can be requested using:\nvault write %s common_name=\"test.example.com\"", roleIssuePath))
+	}
 	return nil
 }
 
-func (c *VenafiPKIMonitorConfig) Check(report reporter.Report, vaultClient api.VaultAPIClient) error {
-	roleIssuePath := fmt.Sprintf("%s/issue/%s", c.MountPath, c.Role.Name)
+// requestRoleTestCertificates requests the test certificates of role, checking they chain to the mount's CA, checks
+// they're imported into the zones of its import policies using venafiClients, and then cleans them up
+func (c *VenafiPKIMonitorConfig) requestRoleTestCertificates(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	venafiClients map[string]venafi_wrapper.VenafiWrapper,
+	role Role,
+) error {
+	if len(role.TestCerts) == 0 {
+		return nil
+	}
 
-	fetchCertSection := report.AddSection(
-		fmt.Sprintf("Requesting test certificates from %s", roleIssuePath),
-	)
-	for _, cert := range c.Role.TestCerts {
-		err := venafi.RequestVenafiCertificate(
-			fetchCertSection,
+	roots, err := c.getCAPool(reportSection, vaultClient)
+	if err != nil {
+		return err
+	}
+
+	importZones := c.getImportZones(role)
+	for _, cert := range role.TestCerts {
+		// Certificates are issued by the mount's CA rather than through a Venafi zone, so no subject fields are overridden
+		data, err := venafi.RequestVenafiCertificate(
+			reportSection,
 			vaultClient,
-			roleIssuePath,
+			c.MountPath,
+			role.Name,
 			cert,
+			roots,
+			nil,
 		)
+		if err != nil && data == nil {
+			return err
+		}
+
+		if err == nil {
+			err = c.waitForImports(reportSection, venafiClients, importZones, data)
+		}
+
+		// A certificate failing its checks was still issued, so is cleaned up before their error is returned
+		cleanupErr := c.cleanupTestCertificate(reportSection, vaultClient, cert, data)
 		if err != nil {
 			return err
 		}
+		if cleanupErr != nil {
+			return cleanupErr
+		}
+	}
 
-		fetchCertSection.Info(fmt.Sprintf("Certificates can be requested using:\nvault write %s common_name=\"test.example.com\"", roleIssuePath))
+	return nil
+}
+
+// waitForImports waits for the test certificate issued in data to appear in each of importZones
+func (c *VenafiPKIMonitorConfig) waitForImports(
+	reportSection reporter.Section,
+	venafiClients map[string]venafi_wrapper.VenafiWrapper,
+	importZones []importZone,
+	data map[string]interface{},
+) error {
+	for _, importZone := range importZones {
+		err := venafi.WaitForCertificateInZone(
+			reportSection,
+			venafiClients[importZone.secretName],
+			importZone.zone,
+			data,
+			c.getTestCertificateImportTimeout(),
+		)
+		if err != nil {
+			return err
+		}
 	}
+
 	return nil
 }
+
+// getCAPool returns a pool of the mount's CA certificate, which test certificates must chain to rather than whatever
+// root Vault returns in their chain
+func (c *VenafiPKIMonitorConfig) getCAPool(reportSection reporter.Section, vaultClient api.VaultAPIClient) (*x509.CertPool, error) {
+	currentCA, err := GetCurrentCA(vaultClient, c.MountPath)
+	if err == nil && currentCA == nil {
+		err = fmt.Errorf("no CA certificate is set at %s", c.MountPath)
+	}
+	if err != nil {
+		check := reportSection.AddCheck("Reading the CA certificate to verify test certificates against...")
+		check.Errorf("Error reading the CA certificate: %s", err)
+		return nil, err
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(currentCA)
+	return roots, nil
+}
+
+// importZone is a Venafi zone that a role's certificates are imported into, along with the name of the secret used to
+// connect to it
+type importZone struct {
+	zone       string
+	secretName string
+}
+
+// getImportZones returns the zones of the role's import_policy and of the policy blocks listing it in import_roles
+func (c *VenafiPKIMonitorConfig) getImportZones(role Role) []importZone {
+	var importZones []importZone
+	if role.ImportPolicy != nil {
+		importZones = append(importZones, importZone{zone: role.ImportPolicy.Zone, secretName: role.Secret.Name})
+	}
+
+	for _, policy := range c.Policies {
+		for _, roleName := range policy.ImportRoles {
+			if roleName == role.Name {
+				importZones = append(importZones, importZone{zone: policy.Zone, secretName: policy.VenafiSecret})
+			}
+		}
+	}
+
+	return importZones
+}
+
+// getTestCertificateImportTimeout returns TestCertificateImportTimeout as a duration, which is zero if not given
+func (c *VenafiPKIMonitorConfig) getTestCertificateImportTimeout() time.Duration {
+	if c.TestCertificateImportTimeout == "" {
+		return 0
+	}
+
+	// Already validated by ValidateConfig
+	timeout, _ := time.ParseDuration(c.TestCertificateImportTimeout)
+	return timeout
+}
+
+// cleanupTestCertificate revokes the test certificate issued in data through the mount's revoke endpoint, if cert's
+// cleanup, or the plugin's test_certificate_cleanup, is revoke
+func (c *VenafiPKIMonitorConfig) cleanupTestCertificate(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	cert venafi.CertificateRequest,
+	data map[string]interface{},
+) error {
+	if cert.GetCleanup(c.TestCertificateCleanup) != venafi.CleanupRevoke {
+		return nil
+	}
+
+	return venafi.RevokeVaultCertificate(reportSection, vaultClient, fmt.Sprintf("%s/revoke", c.MountPath), data)
+}
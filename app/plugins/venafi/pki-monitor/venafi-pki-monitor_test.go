@@ -3,10 +3,15 @@ package pki_monitor
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
 	"github.com/Venafi/vcert/v4/pkg/venafi/tpp"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/testcerts"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
 	mockVenafiWrapper "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
 	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
 	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
@@ -45,6 +50,7 @@ func TestConfigureVenafiPKIMonitor(t *testing.T) {
 	var username = "username"
 	var password = "password"
 
+	var caPath = fmt.Sprintf("%s/cert/ca", pluginMountPath)
 	var intermediateCertGeneratePath = fmt.Sprintf("%s/intermediate/generate/internal", pluginMountPath)
 	var intermediateCertSignedPath = fmt.Sprintf("%s/intermediate/set-signed", pluginMountPath)
 	var policyDefaultPath = fmt.Sprintf("%s/venafi-policy/default", pluginMountPath)
@@ -94,6 +100,7 @@ func TestConfigureVenafiPKIMonitor(t *testing.T) {
 		"zone":          zone,
 	}
 
+	vaultAPIClient.On("ReadValue", caPath).Return(nil, vault.ErrNotFound)
 	vaultAPIClient.On("WriteValue", secretPath, venafiConnectionConfig).Return(nil, nil)
 	vaultAPIClient.On("WriteValue", policyDefaultPath, venafiPolicyConfig).Return(nil, nil)
 	venafiClient.On("GetRefreshToken", mock.Anything).Return(tokenResponse, nil)
@@ -112,88 +119,104 @@ func TestConfigureVenafiPKIMonitor(t *testing.T) {
 		"pki-monitor VaaS enforcement intermediate config": {
 			config: VenafiPKIMonitorConfig{
 				MountPath: pluginMountPath,
-				Role: Role{
-					Name: roleName,
-					Secret: UnZonedSecret{
-						Name: secretName,
-						VenafiSecret: venafi.VenafiSecret{
-							VaaS: &venafi.VenafiVaaSConnection{
-								APIKey: apiKey,
-							},
-						},
-					},
-					EnforcementPolicy: &Policy{
-						Zone: zone,
-					},
-					IntermediateCert: &IntermediateCertRequest{
+				CA: &CA{
+					VenafiIntermediate: &IntermediateCertRequest{
 						Zone:               zone,
 						CertificateRequest: certificateRequest,
 					},
 				},
+				Roles: []Role{
+					{
+						Name: roleName,
+						Secret: UnZonedSecret{
+							Name: secretName,
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: apiKey,
+								},
+							},
+						},
+						EnforcementPolicy: &Policy{
+							Zone: zone,
+						},
+					},
+				},
 			},
 		},
 		"pki-monitor VaaS enforcement root config": {
 			config: VenafiPKIMonitorConfig{
 				MountPath: pluginMountPath,
-				Role: Role{
-					Name: roleName,
-					Secret: UnZonedSecret{
-						Name: secretName,
-						VenafiSecret: venafi.VenafiSecret{
-							VaaS: &venafi.VenafiVaaSConnection{
-								APIKey: apiKey,
+				CA: &CA{
+					Root: &certificateRequest,
+				},
+				Roles: []Role{
+					{
+						Name: roleName,
+						Secret: UnZonedSecret{
+							Name: secretName,
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: apiKey,
+								},
 							},
 						},
+						EnforcementPolicy: &Policy{
+							Zone: zone,
+						},
 					},
-					EnforcementPolicy: &Policy{
-						Zone: zone,
-					},
-					RootCert: &certificateRequest,
 				},
 			},
 		},
 		"pki-monitor VaaS enforcement import root config": {
 			config: VenafiPKIMonitorConfig{
 				MountPath: pluginMountPath,
-				Role: Role{
-					Name: roleName,
-					Secret: UnZonedSecret{
-						Name: secretName,
-						VenafiSecret: venafi.VenafiSecret{
-							VaaS: &venafi.VenafiVaaSConnection{
-								APIKey: apiKey,
+				CA: &CA{
+					Root: &certificateRequest,
+				},
+				Roles: []Role{
+					{
+						Name: roleName,
+						Secret: UnZonedSecret{
+							Name: secretName,
+							VenafiSecret: venafi.VenafiSecret{
+								VaaS: &venafi.VenafiVaaSConnection{
+									APIKey: apiKey,
+								},
 							},
 						},
+						EnforcementPolicy: &Policy{
+							Zone: zone,
+						},
+						ImportPolicy: &Policy{
+							Zone: zone,
+						},
 					},
-					EnforcementPolicy: &Policy{
-						Zone: zone,
-					},
-					ImportPolicy: &Policy{
-						Zone: zone,
-					},
-					RootCert: &certificateRequest,
 				},
 			},
 		},
 		"pki-monitor tpp enforcement root config": {
 			config: VenafiPKIMonitorConfig{
 				MountPath: pluginMountPath,
-				Role: Role{
-					Name: roleName,
-					Secret: UnZonedSecret{
-						Name: secretName,
-						VenafiSecret: venafi.VenafiSecret{
-							TPP: &venafi.VenafiTPPConnection{
-								URL:      url,
-								Username: username,
-								Password: password,
+				CA: &CA{
+					Root: &certificateRequest,
+				},
+				Roles: []Role{
+					{
+						Name: roleName,
+						Secret: UnZonedSecret{
+							Name: secretName,
+							VenafiSecret: venafi.VenafiSecret{
+								TPP: &venafi.VenafiTPPConnection{
+									URL:      url,
+									Username: username,
+									Password: password,
+								},
 							},
 						},
+						EnforcementPolicy: &Policy{
+							Zone: zone,
+						},
 					},
-					EnforcementPolicy: &Policy{
-						Zone: zone,
-					},
-					RootCert: &certificateRequest,
 				},
 			},
 		},
@@ -201,7 +224,17 @@ func TestConfigureVenafiPKIMonitor(t *testing.T) {
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			err := tc.config.Role.Configure(section, tc.config.MountPath, vaultAPIClient, venafiClient)
+			err := tc.config.Roles[0].Configure(
+				section,
+				tc.config.MountPath,
+				defaultEnforcementPolicyName,
+				defaultImportPolicyName,
+				vaultAPIClient,
+				venafiClient,
+			)
+			require.NoError(t, err)
+
+			err = tc.config.ConfigureCA(section, vaultAPIClient, venafiClient)
 			require.NoError(t, err)
 		})
 	}
@@ -214,3 +247,184 @@ func reportExpectations(report *mockReport.Report, section *mockReport.Section,
 	check.On("UpdateStatus", mock.AnythingOfType("string")).Maybe()
 	check.On("Success", mock.AnythingOfType("string"))
 }
+
+func TestVenafiPKIMonitorConfig_preflightZones(t *testing.T) {
+	tppClient := new(mockVenafiWrapper.VenafiWrapper)
+	vaasClient := new(mockVenafiWrapper.VenafiWrapper)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer tppClient.AssertExpectations(t)
+	defer vaasClient.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+	check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+
+	config := &VenafiPKIMonitorConfig{
+		MountPath: "pki",
+		CA: &CA{
+			VenafiIntermediate: &IntermediateCertRequest{Zone: "intermediate zone", VenafiSecret: "vaas"},
+		},
+		Roles: []Role{
+			{
+				Name:              "web",
+				Secret:            UnZonedSecret{Name: "tpp"},
+				EnforcementPolicy: &Policy{Zone: "enforcement zone"},
+				ImportPolicy:      &Policy{Zone: "import zone"},
+				TestCerts:         []venafi.CertificateRequest{{CommonName: "test.example.com", TTL: "1h"}},
+			},
+			{
+				Name:              "internal",
+				Secret:            UnZonedSecret{Name: "vaas"},
+				EnforcementPolicy: &Policy{Zone: "internal zone"},
+			},
+		},
+		Policies: []MountPolicy{
+			{
+				Name:             "shared",
+				VenafiSecret:     "vaas",
+				EnforcementRoles: []string{"web"},
+				Policy:           Policy{Zone: "shared zone"},
+			},
+		},
+	}
+
+	zoneConfig := &endpoint.ZoneConfiguration{}
+	for _, zone := range []string{"enforcement zone", "import zone"} {
+		tppClient.On("ReadZoneConfiguration", zone).Return(zoneConfig, nil).Once()
+	}
+	for _, zone := range []string{"internal zone", "shared zone", "intermediate zone"} {
+		vaasClient.On("ReadZoneConfiguration", zone).Return(zoneConfig, nil).Once()
+	}
+
+	err := config.preflightZones(section, map[string]venafi_wrapper.VenafiWrapper{
+		"tpp":  tppClient,
+		"vaas": vaasClient,
+	})
+	require.NoError(t, err)
+
+	// The web role's test certificate is only validated against its enforcement and shared policies, as it's issued by
+	// the mount's CA rather than enrolled through either zone
+	tppClient.AssertNotCalled(t, "GenerateRequest", mock.Anything, mock.Anything, mock.Anything)
+	vaasClient.AssertNotCalled(t, "GenerateRequest", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVenafiPKIMonitorConfig_getIntermediateSecret(t *testing.T) {
+	tests := map[string]struct {
+		venafiSecret string
+		want         string
+	}{
+		"defaults to first role's": {
+			want: "tpp",
+		},
+		"named secret": {
+			venafiSecret: "vaas",
+			want:         "vaas",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := &VenafiPKIMonitorConfig{
+				CA: &CA{
+					VenafiIntermediate: &IntermediateCertRequest{Zone: "zone", VenafiSecret: tt.venafiSecret},
+				},
+				Roles: []Role{
+					{Name: "web", Secret: UnZonedSecret{Name: "tpp"}},
+					{Name: "internal", Secret: UnZonedSecret{Name: "vaas"}},
+				},
+			}
+
+			require.Equal(t, tt.want, config.getIntermediateSecret().Name)
+		})
+	}
+}
+
+func TestVenafiPKIMonitorConfig_cleanupTestCertificate(t *testing.T) {
+	data := map[string]interface{}{"serial_number": "1a:2b"}
+
+	tests := map[string]struct {
+		pluginCleanup string
+		certCleanup   string
+		wantRevoke    bool
+	}{
+		"no cleanup":                    {},
+		"revoked by plugin":             {pluginCleanup: venafi.CleanupRevoke, wantRevoke: true},
+		"revoked by test certificate":   {certCleanup: venafi.CleanupRevoke, wantRevoke: true},
+		"test certificate kept instead": {pluginCleanup: venafi.CleanupRevoke, certCleanup: venafi.CleanupNone},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+
+			if tt.wantRevoke {
+				section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+				check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+				vaultAPIClient.On("WriteValue", "pki/revoke", map[string]interface{}{"serial_number": "1a:2b"}).Return(nil, nil)
+			}
+
+			config := &VenafiPKIMonitorConfig{MountPath: "pki", TestCertificateCleanup: tt.pluginCleanup}
+			err := config.cleanupTestCertificate(section, vaultAPIClient, venafi.CertificateRequest{Cleanup: tt.certCleanup}, data)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVenafiPKIMonitorConfig_requestRoleTestCertificates(t *testing.T) {
+	certRequest := venafi.CertificateRequest{CommonName: "test.example.com", TTL: "1h"}
+	mountCA := testcerts.NewCA(t, testCARequest.Subject(), 24*time.Hour, nil)
+	otherCA := testcerts.NewCA(t, testCARequest.Subject(), 24*time.Hour, nil)
+
+	tests := map[string]struct {
+		issuer     *testcerts.CA
+		cleanup    string
+		wantRevoke bool
+		wantErr    bool
+	}{
+		"issued by the mount's CA": {issuer: mountCA},
+		// The returned chain is valid, but isn't trusted as it doesn't end at the mount's CA
+		"issued by another CA": {issuer: otherCA, wantErr: true},
+		"issued by another CA is still revoked": {
+			issuer:     otherCA,
+			cleanup:    venafi.CleanupRevoke,
+			wantRevoke: true,
+			wantErr:    true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+
+			reportExpectations(report, section, check)
+			check.On("Errorf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+
+			vaultAPIClient.On("ReadValue", "pki/cert/ca").Return(map[string]interface{}{"certificate": mountCA.PEM}, nil)
+			template := testcerts.NewTemplate(t, certRequest.Subject(), time.Hour)
+			data := testcerts.IssuedData(t, template, testcerts.NewKey(t), tt.issuer)
+			vaultAPIClient.On("WriteValue", "pki/issue/web", certRequest.ToMap()).Return(data, nil)
+			if tt.wantRevoke {
+				check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+				vaultAPIClient.On("WriteValue", "pki/revoke", map[string]interface{}{
+					"serial_number": data["serial_number"],
+				}).Return(nil, nil)
+			}
+
+			config := &VenafiPKIMonitorConfig{MountPath: "pki", TestCertificateCleanup: tt.cleanup}
+			err := config.requestRoleTestCertificates(section, vaultAPIClient, nil, Role{
+				Name:      "web",
+				TestCerts: []venafi.CertificateRequest{certRequest},
+			})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
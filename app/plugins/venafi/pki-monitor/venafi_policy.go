@@ -2,11 +2,201 @@ package pki_monitor
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/opencredo/venafi-vault-wizard/app/config/errors"
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
+var policyNameRegexp = regexp.MustCompile(`^[\w-]+$`)
+
+// Validate checks the policy given in a block called blockName
+func (p *Policy) Validate(blockName string) error {
+	if p.Zone == "" {
+		return fmt.Errorf("error, %s zone cannot be an empty string: %w", blockName, errors.ErrBlankParam)
+	}
+	if p.Name != "" && !policyNameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("error, %s name can only contain letters, numbers, underscores and hyphens, got %s", blockName, p.Name)
+	}
+	if p.AutoRefreshInterval < 0 || p.ImportTimeout < 0 || p.ImportWorkers < 0 {
+		return fmt.Errorf("error, %s auto_refresh_interval, import_timeout and import_workers cannot be negative", blockName)
+	}
+
+	return nil
+}
+
+func (p *Policy) WriteHCL(hclBody *hclwrite.Body) {
+	if p.Name != "" {
+		hclBody.SetAttributeValue("name", cty.StringVal(p.Name))
+	}
+	hclBody.SetAttributeValue("zone", cty.StringVal(p.Zone))
+	if p.AutoRefreshInterval != 0 {
+		hclBody.SetAttributeValue("auto_refresh_interval", cty.NumberIntVal(int64(p.AutoRefreshInterval)))
+	}
+	if p.ImportTimeout != 0 {
+		hclBody.SetAttributeValue("import_timeout", cty.NumberIntVal(int64(p.ImportTimeout)))
+	}
+	if p.ImportWorkers != 0 {
+		hclBody.SetAttributeValue("import_workers", cty.NumberIntVal(int64(p.ImportWorkers)))
+	}
+	if p.CreateRole {
+		hclBody.SetAttributeValue("create_role", cty.True)
+	}
+}
+
+// getName returns the name of the policy in the plugin, or defaultName if it isn't given
+func (p *Policy) getName(defaultName string) string {
+	if p.Name == "" {
+		return defaultName
+	}
+
+	return p.Name
+}
+
+// getParameters returns the policy's parameters to write to the plugin, along with secretName. Those that aren't
+// given are left out, so that the plugin's defaults are used.
+func (p *Policy) getParameters(secretName string) map[string]interface{} {
+	parameters := map[string]interface{}{
+		"venafi_secret": secretName,
+		"zone":          p.Zone,
+	}
+	if p.AutoRefreshInterval != 0 {
+		parameters["auto_refresh_interval"] = p.AutoRefreshInterval
+	}
+	if p.ImportTimeout != 0 {
+		parameters["import_timeout"] = p.ImportTimeout
+	}
+	if p.ImportWorkers != 0 {
+		parameters["import_workers"] = p.ImportWorkers
+	}
+	if p.CreateRole {
+		parameters["create_role"] = true
+	}
+
+	return parameters
+}
+
+func (p *MountPolicy) Validate(roleNames map[string]bool, secretNames map[string]bool) error {
+	blockName := fmt.Sprintf("policy %s", p.Name)
+	if p.Policy.Name != "" {
+		return fmt.Errorf("error, %s is named by its label so cannot also give name", blockName)
+	}
+	if !policyNameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("error, %s can only contain letters, numbers, underscores and hyphens", blockName)
+	}
+
+	err := p.Policy.Validate(blockName)
+	if err != nil {
+		return err
+	}
+
+	if !secretNames[p.VenafiSecret] {
+		return fmt.Errorf("error, %s venafi_secret must be the name of the secret of one of the roles, got %s", blockName, p.VenafiSecret)
+	}
+
+	if len(p.EnforcementRoles)+len(p.DefaultsRoles)+len(p.ImportRoles) == 0 {
+		return fmt.Errorf("error, %s must give at least one of enforcement_roles, defaults_roles or import_roles: %w", blockName, errors.ErrBlankParam)
+	}
+	for _, roles := range [][]string{p.EnforcementRoles, p.DefaultsRoles, p.ImportRoles} {
+		for _, role := range roles {
+			if !roleNames[role] {
+				return fmt.Errorf("error, %s refers to role %s which isn't defined", blockName, role)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *MountPolicy) WriteHCL(hclBody *hclwrite.Body) {
+	policyBody := hclBody.AppendNewBlock("policy", []string{p.Name}).Body()
+	policyBody.SetAttributeValue("venafi_secret", cty.StringVal(p.VenafiSecret))
+	if len(p.EnforcementRoles) != 0 {
+		policyBody.SetAttributeValue("enforcement_roles", cty.ListVal(stringValues(p.EnforcementRoles)))
+	}
+	if len(p.DefaultsRoles) != 0 {
+		policyBody.SetAttributeValue("defaults_roles", cty.ListVal(stringValues(p.DefaultsRoles)))
+	}
+	if len(p.ImportRoles) != 0 {
+		policyBody.SetAttributeValue("import_roles", cty.ListVal(stringValues(p.ImportRoles)))
+	}
+	p.Policy.WriteHCL(policyBody)
+}
+
+// getParameters returns the parameters of the policy to write to the plugin, including the roles it applies to
+func (p *MountPolicy) getParameters() map[string]interface{} {
+	parameters := p.Policy.getParameters(p.VenafiSecret)
+	for name, roles := range map[string][]string{
+		"enforcement_roles": p.EnforcementRoles,
+		"defaults_roles":    p.DefaultsRoles,
+		"import_roles":      p.ImportRoles,
+	} {
+		if len(roles) != 0 {
+			parameters[name] = strings.Join(roles, ",")
+		}
+	}
+
+	return parameters
+}
+
+// validatePolicies checks the mount's policies, and that no two policies, including those of the roles, have the same
+// name
+func (c *VenafiPKIMonitorConfig) validatePolicies(roleNames map[string]bool) error {
+	secretNames := c.getSecretNames()
+
+	policyNames := make(map[string]bool)
+	addPolicyName := func(name string) error {
+		if policyNames[name] {
+			return fmt.Errorf("error, policy %s is defined more than once", name)
+		}
+		policyNames[name] = true
+		return nil
+	}
+
+	for i, role := range c.Roles {
+		if role.EnforcementPolicy != nil {
+			err := addPolicyName(role.EnforcementPolicy.getName(getPolicyName(defaultEnforcementPolicyName, i, role.Name)))
+			if err != nil {
+				return err
+			}
+		}
+		if role.ImportPolicy != nil {
+			err := addPolicyName(role.ImportPolicy.getName(getPolicyName(defaultImportPolicyName, i, role.Name)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, policy := range c.Policies {
+		err := policy.Validate(roleNames, secretNames)
+		if err != nil {
+			return err
+		}
+
+		err = addPolicyName(policy.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func stringValues(values []string) []cty.Value {
+	ctyValues := make([]cty.Value, len(values))
+	for i, value := range values {
+		ctyValues[i] = cty.StringVal(value)
+	}
+
+	return ctyValues
+}
+
 func ConfigureVenafiPolicy(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
@@ -26,10 +216,13 @@ func ConfigureVenafiPolicy(
 	return nil
 }
 
+// VerifyVenafiPolicy checks the policy called policyName has the parameters in expected, which must include its
+// venafi_secret and zone. Other parameters that the plugin version doesn't return aren't checked.
 func VerifyVenafiPolicy(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
-	mountPath, policyName, secretName, zone string,
+	mountPath, policyName string,
+	expected map[string]interface{},
 ) error {
 	check := reportSection.AddCheck("Checking Venafi policy...")
 
@@ -40,16 +233,51 @@ func VerifyVenafiPolicy(
 		return err
 	}
 
-	if data["venafi_secret"] != secretName {
-		check.Errorf("The Venafi policy's venafi_secret field was not as expected: expected %s got %s", secretName, data["venafi_secret"])
-		return fmt.Errorf("venafi policy incorrect")
+	for _, name := range []string{"venafi_secret", "zone"} {
+		if data[name] != expected[name] {
+			check.Errorf("The Venafi policy's %s field was not as expected: expected %s got %s", name, expected[name], data[name])
+			return fmt.Errorf("venafi policy incorrect")
+		}
 	}
 
-	if data["zone"] != zone {
-		check.Errorf("The Venafi policy's zone field was not as expected: expected %s got %s", zone, data["zone"])
+	if !dataMatches(check, "Venafi policy", data, expected) {
 		return fmt.Errorf("venafi policy incorrect")
 	}
 
 	check.Success("Venafi policy correctly configured at " + policyPath)
 	return nil
 }
+
+// dataMatches checks the values in expected are the same as those read from Vault in data, ignoring any missing from
+// data, and reports the first that isn't to check
+func dataMatches(check reporter.Check, description string, data, expected map[string]interface{}) bool {
+	for name, expectedValue := range expected {
+		actualValue, ok := data[name]
+		if !ok {
+			continue
+		}
+
+		// Vault returns numbers as json.Number and lists as []interface{}, so compare how they're formatted instead.
+		// Role lists are written comma separated, so are compared the same way.
+		if list, isList := actualValue.([]interface{}); isList {
+			if _, expectedString := expectedValue.(string); expectedString {
+				actualValue = joinValues(list)
+			}
+		}
+		if fmt.Sprint(actualValue) != fmt.Sprint(expectedValue) {
+			check.Errorf("The %s's %s field was not as expected: expected %v got %v", description, name, expectedValue, actualValue)
+			return false
+		}
+	}
+
+	return true
+}
+
+func joinValues(values []interface{}) string {
+	strs := make([]string, len(values))
+	for i, value := range values {
+		strs[i] = fmt.Sprint(value)
+	}
+
+	return strings.Join(strs, ",")
+}
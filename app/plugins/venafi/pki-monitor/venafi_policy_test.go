@@ -0,0 +1,273 @@
+package pki_monitor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestVenafiPKIMonitorConfig_validatePolicies(t *testing.T) {
+	newConfig := func(policies ...MountPolicy) *VenafiPKIMonitorConfig {
+		return &VenafiPKIMonitorConfig{
+			Roles: []Role{
+				{
+					Name:              "web",
+					Secret:            UnZonedSecret{Name: "tpp"},
+					EnforcementPolicy: &Policy{Zone: "zone"},
+				},
+				{
+					Name:         "internal",
+					Secret:       UnZonedSecret{Name: "tpp"},
+					ImportPolicy: &Policy{Name: "internal-import", Zone: "zone"},
+				},
+			},
+			Policies: policies,
+		}
+	}
+	roleNames := map[string]bool{"web": true, "internal": true}
+
+	tests := map[string]struct {
+		config  *VenafiPKIMonitorConfig
+		wantErr bool
+	}{
+		"no mount policies": {
+			config: newConfig(),
+		},
+		"mount policy for several roles": {
+			config: newConfig(MountPolicy{
+				Name:         "shared",
+				ImportRoles:  []string{"web", "internal"},
+				VenafiSecret: "tpp",
+				Policy:       Policy{Zone: "zone", ImportWorkers: 5},
+			}),
+		},
+		"mount policy with the same name as a role's": {
+			config: newConfig(MountPolicy{
+				Name:         "internal-import",
+				ImportRoles:  []string{"web"},
+				VenafiSecret: "tpp",
+				Policy:       Policy{Zone: "zone"},
+			}),
+			wantErr: true,
+		},
+		"mount policy with the default name of a role's": {
+			config: newConfig(MountPolicy{
+				Name:         "default",
+				ImportRoles:  []string{"web"},
+				VenafiSecret: "tpp",
+				Policy:       Policy{Zone: "zone"},
+			}),
+			wantErr: true,
+		},
+		"mount policy for undefined role": {
+			config: newConfig(MountPolicy{
+				Name:         "shared",
+				ImportRoles:  []string{"api"},
+				VenafiSecret: "tpp",
+				Policy:       Policy{Zone: "zone"},
+			}),
+			wantErr: true,
+		},
+		"mount policy with undefined secret": {
+			config: newConfig(MountPolicy{
+				Name:         "shared",
+				ImportRoles:  []string{"web"},
+				VenafiSecret: "vaas",
+				Policy:       Policy{Zone: "zone"},
+			}),
+			wantErr: true,
+		},
+		"mount policy without roles": {
+			config: newConfig(MountPolicy{
+				Name:         "shared",
+				VenafiSecret: "tpp",
+				Policy:       Policy{Zone: "zone"},
+			}),
+			wantErr: true,
+		},
+		"mount policy with negative import timeout": {
+			config: newConfig(MountPolicy{
+				Name:         "shared",
+				ImportRoles:  []string{"web"},
+				VenafiSecret: "tpp",
+				Policy:       Policy{Zone: "zone", ImportTimeout: -1},
+			}),
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.config.validatePolicies(roleNames)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVenafiPKIMonitorConfig_ConfigurePolicies(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	config := &VenafiPKIMonitorConfig{
+		MountPath: "pki",
+		Policies: []MountPolicy{
+			{
+				Name:             "shared",
+				VenafiSecret:     "tpp",
+				EnforcementRoles: []string{"web", "internal"},
+				DefaultsRoles:    []string{"web"},
+				Policy: Policy{
+					Zone:                "zone",
+					AutoRefreshInterval: 300,
+					CreateRole:          true,
+				},
+			},
+		},
+	}
+
+	vaultAPIClient.On("WriteValue", "pki/venafi-policy/shared", map[string]interface{}{
+		"venafi_secret":         "tpp",
+		"zone":                  "zone",
+		"enforcement_roles":     "web,internal",
+		"defaults_roles":        "web",
+		"auto_refresh_interval": 300,
+		"create_role":           true,
+	}).Return(nil, nil)
+
+	err := config.ConfigurePolicies(section, vaultAPIClient)
+	require.NoError(t, err)
+}
+
+func TestVenafiPKIMonitorConfig_CheckPolicies(t *testing.T) {
+	config := &VenafiPKIMonitorConfig{
+		MountPath: "pki",
+		Roles: []Role{
+			{
+				Name:   "web",
+				Secret: UnZonedSecret{Name: "tpp"},
+				EnforcementPolicy: &Policy{
+					Name:          "web-policy",
+					Zone:          "zone",
+					ImportWorkers: 5,
+				},
+				OptionalConfig: &OptionalConfig{
+					KeyBits:        4096,
+					OptionalConfig: venafi.OptionalConfig{TTL: "1h"},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		policyData map[string]interface{}
+		roleData   map[string]interface{}
+		wantErr    bool
+	}{
+		"matching policy and role": {
+			policyData: map[string]interface{}{
+				"venafi_secret":     "tpp",
+				"zone":              "zone",
+				"import_workers":    json.Number("5"),
+				"enforcement_roles": []interface{}{"web"},
+			},
+			roleData: map[string]interface{}{
+				"key_bits": json.Number("4096"),
+				"ttl":      json.Number("3600"),
+			},
+		},
+		"different policy zone": {
+			policyData: map[string]interface{}{
+				"venafi_secret": "tpp",
+				"zone":          "other zone",
+			},
+			wantErr: true,
+		},
+		"different policy parameter": {
+			policyData: map[string]interface{}{
+				"venafi_secret":  "tpp",
+				"zone":           "zone",
+				"import_workers": json.Number("2"),
+			},
+			wantErr: true,
+		},
+		"different role parameter": {
+			policyData: map[string]interface{}{
+				"venafi_secret": "tpp",
+				"zone":          "zone",
+			},
+			roleData: map[string]interface{}{
+				"key_bits": json.Number("2048"),
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+
+			reportExpectations(report, section, check)
+			check.On("Errorf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+
+			vaultAPIClient.On("ReadValue", "pki/venafi-policy/web-policy").Return(tt.policyData, nil)
+			vaultAPIClient.On("ReadValue", "pki/roles/web").Return(tt.roleData, nil).Maybe()
+
+			err := config.CheckPolicies(report, vaultAPIClient)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVenafiPKIMonitorConfig_getImportZones(t *testing.T) {
+	config := &VenafiPKIMonitorConfig{
+		Roles: []Role{
+			{
+				Name:         "web",
+				Secret:       UnZonedSecret{Name: "tpp"},
+				ImportPolicy: &Policy{Zone: "web zone"},
+			},
+			{
+				Name:              "internal",
+				Secret:            UnZonedSecret{Name: "tpp"},
+				EnforcementPolicy: &Policy{Zone: "internal zone"},
+			},
+		},
+		Policies: []MountPolicy{
+			{
+				Name:             "shared",
+				VenafiSecret:     "vaas",
+				ImportRoles:      []string{"web", "internal"},
+				EnforcementRoles: []string{"web"},
+				Policy:           Policy{Zone: "shared zone"},
+			},
+		},
+	}
+
+	require.Equal(t, []importZone{
+		{zone: "web zone", secretName: "tpp"},
+		{zone: "shared zone", secretName: "vaas"},
+	}, config.getImportZones(config.Roles[0]))
+	require.Equal(t, []importZone{
+		{zone: "shared zone", secretName: "vaas"},
+	}, config.getImportZones(config.Roles[1]))
+}
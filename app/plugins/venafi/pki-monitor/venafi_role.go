@@ -1,6 +1,8 @@
 package pki_monitor
 
 import (
+	"fmt"
+
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
@@ -23,10 +25,26 @@ func ConfigureVenafiRole(
 	return nil
 }
 
+// VerifyVenafiRole checks the role at rolePath has the parameters in expected. Parameters that the plugin version
+// doesn't return aren't checked.
 func VerifyVenafiRole(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
-	rolePath, secretName string,
+	rolePath string,
+	expected map[string]interface{},
 ) error {
+	check := reportSection.AddCheck("Checking Venafi role...")
+
+	data, err := vaultClient.ReadValue(rolePath)
+	if err != nil {
+		check.Errorf("Error retrieving Venafi role: %s", err)
+		return err
+	}
+
+	if !dataMatches(check, "Venafi role", data, expected) {
+		return fmt.Errorf("venafi role incorrect")
+	}
+
+	check.Success("Venafi role correctly configured at " + rolePath)
 	return nil
 }
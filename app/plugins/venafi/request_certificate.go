@@ -2,43 +2,48 @@ package venafi
 
 import (
 	"crypto/x509"
-	"encoding/pem"
 	"fmt"
 
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
+// RequestVenafiCertificate requests certRequest from the role roleName of the plugin mounted at mountPath and verifies
+// the certificate issued chains to one of roots, or the system's roots if nil, and has the requested subject other than
+// the fields set by zoneConfig, if it's given, returning the data Vault returned so
+// that the certificate can be cleaned up afterwards. The data is returned along with the error if the certificate was
+// issued but failed verification, as it still needs cleaning up.
 func RequestVenafiCertificate(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
-	rolePath string, certRequest CertificateRequest,
-) error {
-	check := reportSection.AddCheck(fmt.Sprintf("Requesting test certificate from %s with CN:%s", rolePath, certRequest.CommonName))
+	mountPath, roleName string,
+	certRequest CertificateRequest,
+	roots *x509.CertPool,
+	zoneConfig *endpoint.ZoneConfiguration,
+) (map[string]interface{}, error) {
+	issuePath, parameters, err := certRequest.GetIssueRequest(mountPath, roleName)
+	if err != nil {
+		check := reportSection.AddCheck(fmt.Sprintf("Requesting test certificate with CN:%s", certRequest.CommonName))
+		check.Errorf("Error reading CSR for test certificate: %s", err)
+		return nil, err
+	}
+	check := reportSection.AddCheck(fmt.Sprintf("Requesting test certificate from %s with CN:%s", issuePath, certRequest.CommonName))
 
 	// Get certificate from Vault
-	data, err := vaultClient.WriteValue(rolePath, certRequest.ToMap())
+	data, err := vaultClient.WriteValue(issuePath, parameters)
 	if err != nil {
 		check.Errorf("Error retrieving certificate from Vault: %s", err)
-		return err
+		return nil, err
 	}
 
-	// Decode the returned PEM block and check it's a certificate with a matching common name
-	pemBlock, _ := pem.Decode([]byte(data["certificate"].(string)))
-	if pemBlock.Type != "CERTIFICATE" {
-		check.Error("Expected a certificate to be returned in PEM format")
-		return fmt.Errorf("pem type incorrect")
-	}
-	certificate, err := x509.ParseCertificate(pemBlock.Bytes)
+	err = VerifyIssuedCertificate(data, certRequest, roots, zoneConfig)
 	if err != nil {
-		check.Errorf("Error parsing returned certificate: %s", err)
-		return err
-	}
-	if certificate.Subject.CommonName != certRequest.CommonName {
-		check.Errorf("Certificate's common name was not as expected: expected %s got %s", certRequest.CommonName, certificate.Subject.CommonName)
-		return fmt.Errorf("common_name incorrect")
+		check.Errorf("Error verifying the issued certificate: %s", err)
+		return data, err
 	}
 
 	check.Success("Successfully requested test certificate from Vault")
-	return nil
+	return data, nil
 }
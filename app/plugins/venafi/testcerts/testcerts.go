@@ -0,0 +1,120 @@
+// Package testcerts creates CAs, certificates and keys for tests, along with the data Vault returns when issuing
+// certificates, so that they don't need to be created by hand in each package's tests
+package testcerts
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// CA is a certificate authority that can issue test certificates
+type CA struct {
+	Certificate *x509.Certificate
+	Key         *ecdsa.PrivateKey
+	PEM         string
+}
+
+// NewKey returns a new P-256 ECDSA key
+func NewKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return key
+}
+
+// EncodeKey returns key PEM encoded, as Vault returns private keys
+func EncodeKey(t *testing.T, key *ecdsa.PrivateKey) string {
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+// NewTemplate returns a template for a certificate with subject and a random serial number, which is valid from a
+// minute ago until validity from now
+func NewTemplate(t *testing.T, subject pkix.Name, validity time.Duration) *x509.Certificate {
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(validity),
+	}
+}
+
+// NewCA returns a CA with subject, which is valid for validity from now. It is issued by parent, or self-signed if
+// parent is nil.
+func NewCA(t *testing.T, subject pkix.Name, validity time.Duration, parent *CA) *CA {
+	key := NewKey(t)
+
+	template := NewTemplate(t, subject, validity)
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign
+
+	ca := &CA{Key: key}
+	if parent == nil {
+		parent = &CA{Certificate: template, Key: key}
+	}
+	ca.PEM, ca.Certificate = parent.Issue(t, template, &key.PublicKey)
+
+	return ca
+}
+
+// Issue returns the certificate of template for publicKey signed by the CA, both PEM encoded and parsed
+func (ca *CA) Issue(t *testing.T, template *x509.Certificate, publicKey crypto.PublicKey) (string, *x509.Certificate) {
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, publicKey, ca.Key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), certificate
+}
+
+// IssuedData returns the data Vault returns when issuing the certificate of template for key. The certificate is issued
+// by the first CA in chain, which is returned as the issuing_ca, and the whole chain as the ca_chain.
+func IssuedData(t *testing.T, template *x509.Certificate, key *ecdsa.PrivateKey, chain ...*CA) map[string]interface{} {
+	certificatePEM, certificate := chain[0].Issue(t, template, &key.PublicKey)
+
+	caChain := make([]interface{}, len(chain))
+	for i, ca := range chain {
+		caChain[i] = ca.PEM
+	}
+
+	return map[string]interface{}{
+		"certificate":   certificatePEM,
+		"issuing_ca":    chain[0].PEM,
+		"ca_chain":      caChain,
+		"private_key":   EncodeKey(t, key),
+		"serial_number": SerialNumber(certificate),
+	}
+}
+
+// SerialNumber returns the serial number of certificate in Vault's colon separated format
+func SerialNumber(certificate *x509.Certificate) string {
+	serialNumber := fmt.Sprintf("%x", certificate.SerialNumber)
+	if len(serialNumber)%2 != 0 {
+		serialNumber = "0" + serialNumber
+	}
+
+	serialBytes := make([]string, 0, len(serialNumber)/2)
+	for i := 0; i < len(serialNumber); i += 2 {
+		serialBytes = append(serialBytes, serialNumber[i:i+2])
+	}
+
+	return strings.Join(serialBytes, ":")
+}
@@ -1,14 +1,19 @@
 package venafi
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/Venafi/vcert/v4/pkg/endpoint"
 	"github.com/hashicorp/hcl/v2/hclwrite"
-	"github.com/opencredo/venafi-vault-wizard/app/config/errors"
+	configErrors "github.com/opencredo/venafi-vault-wizard/app/config/errors"
 	"github.com/opencredo/venafi-vault-wizard/app/config/generate"
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
@@ -19,6 +24,9 @@ const (
 	MonitorEngine
 )
 
+// ConfigureVenafiSecret writes the Venafi secret at secretPath. A TPP access token given along with a refresh token is
+// only used the first time, as the tokens already in the secret may have since been refreshed, which spends the given
+// refresh token. When rotating, the refresh token in the secret is used to get a new pair.
 func ConfigureVenafiSecret(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
@@ -27,10 +35,14 @@ func ConfigureVenafiSecret(
 	secretValue VenafiConnectionConfig,
 	pluginType PluginType,
 	zone *string,
+	rotate bool,
 ) error {
 	check := reportSection.AddCheck("Adding Venafi secret...")
 
-	secretParameters, err := secretValue.GetAsMap(pluginType, venafiClient)
+	readStoredTokens := func() (*TPPTokens, error) {
+		return readTPPTokens(vaultClient, secretPath)
+	}
+	secretParameters, err := secretValue.GetAsMap(pluginType, venafiClient, readStoredTokens, rotate)
 	if err != nil {
 		check.Errorf("Error getting values for the Venafi secret: %s", err)
 		return err
@@ -50,6 +62,73 @@ func ConfigureVenafiSecret(
 	return nil
 }
 
+// RotatingRole is a role of a plugin, along with the Venafi secret it uses, whose secret is rotated by
+// RotateVenafiSecrets
+type RotatingRole struct {
+	Name       string
+	SecretName string
+	Secret     VenafiConnectionConfig
+	// Zone is written to the secret along with the credentials, for plugins whose secrets have one
+	Zone *string
+	// HasTestCerts is whether the role has test certificates to check the rotated secret with
+	HasTestCerts bool
+}
+
+// RotateVenafiSecrets rewrites the Venafi secret at <mountPath>/venafi/<name> of each of roles using secretName, or of
+// every role if secretName is blank, refreshing any TPP tokens. Roles can share a secret, which is only written once.
+// requestTestCertificates is then called with the index in roles of each role with test certificates, to check the
+// rotated secret works.
+func RotateVenafiSecrets(
+	report reporter.Report,
+	vaultClient api.VaultAPIClient,
+	venafiClients map[string]venafi_wrapper.VenafiWrapper,
+	mountPath string,
+	pluginType PluginType,
+	roles []RotatingRole,
+	secretName string,
+	requestTestCertificates func(rotateSection reporter.Section, role int) error,
+) error {
+	rotated := make(map[string]bool, len(roles))
+	for i, role := range roles {
+		if secretName != "" && role.SecretName != secretName {
+			continue
+		}
+
+		secretPath := fmt.Sprintf("%s/venafi/%s", mountPath, role.SecretName)
+		rotateSection := report.AddSection(fmt.Sprintf("Rotating Venafi secret %s for role %s", secretPath, role.Name))
+		if !rotated[role.SecretName] {
+			err := ConfigureVenafiSecret(
+				rotateSection,
+				vaultClient,
+				venafiClients[role.SecretName],
+				secretPath,
+				role.Secret,
+				pluginType,
+				role.Zone,
+				true,
+			)
+			if err != nil {
+				return err
+			}
+			rotated[role.SecretName] = true
+		}
+
+		if !role.HasTestCerts {
+			rotateSection.AddCheck("Checking certificates can be issued...").Warning(
+				fmt.Sprintf("No test_certificate blocks are given for role %s, so issuing certificates with the rotated secret wasn't checked", role.Name),
+			)
+			continue
+		}
+
+		err := requestTestCertificates(rotateSection, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func VerifyVenafiSecret(reportSection reporter.Section, vaultClient api.VaultAPIClient, secretPath string, secretValue VenafiConnectionConfig) error {
 	check := reportSection.AddCheck("Checking Venafi secret...")
 
@@ -70,7 +149,44 @@ type VenafiSecret struct {
 }
 
 type VenafiConnectionConfig interface {
-	GetAsMap(pluginType PluginType, venafiClient venafi_wrapper.VenafiWrapper) (map[string]interface{}, error)
+	GetAsMap(
+		pluginType PluginType,
+		venafiClient venafi_wrapper.VenafiWrapper,
+		readStoredTokens func() (*TPPTokens, error),
+		rotate bool,
+	) (map[string]interface{}, error)
+}
+
+// TPPTokens are a TPP access token and the refresh token to get the next one with
+type TPPTokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// readTPPTokens returns the TPP tokens in the Venafi secret at secretPath, or nil if there isn't a secret yet or it
+// doesn't hold both tokens
+func readTPPTokens(vaultClient api.VaultAPIClient, secretPath string) (*TPPTokens, error) {
+	data, err := vaultClient.ReadValue(secretPath)
+	if err != nil {
+		if errors.Is(err, vault.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	accessToken, _ := data["access_token"].(string)
+	refreshToken, _ := data["refresh_token"].(string)
+	// Plugins that mask credentials when their secret is read give nothing usable, the same as no tokens
+	if isMasked(accessToken) || isMasked(refreshToken) {
+		return nil, nil
+	}
+
+	return &TPPTokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// isMasked returns whether value is blank or only asterisks
+func isMasked(value string) bool {
+	return strings.Trim(value, "*") == ""
 }
 
 func (v *VenafiSecret) Validate() error {
@@ -79,7 +195,7 @@ func (v *VenafiSecret) Validate() error {
 
 	// Ensure only one of VaaS or TPP is defined
 	if (vaasConnectionProvided && tppConnectionProvided) || (!vaasConnectionProvided && !tppConnectionProvided) {
-		return fmt.Errorf("error, must provide exactly one of VaaS or TPP connection details: %w", errors.ErrConflictingBlocks)
+		return fmt.Errorf("error, must provide exactly one of VaaS or TPP connection details: %w", configErrors.ErrConflictingBlocks)
 	}
 
 	if vaasConnectionProvided {
@@ -93,7 +209,12 @@ func (v *VenafiSecret) Validate() error {
 	return nil
 }
 
-func (v VenafiSecret) GetAsMap(pluginType PluginType, venafiClient venafi_wrapper.VenafiWrapper) (map[string]interface{}, error) {
+func (v VenafiSecret) GetAsMap(
+	pluginType PluginType,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	readStoredTokens func() (*TPPTokens, error),
+	rotate bool,
+) (map[string]interface{}, error) {
 	if v.VaaS != nil {
 		m := map[string]interface{}{
 			"apikey": v.VaaS.APIKey,
@@ -102,7 +223,7 @@ func (v VenafiSecret) GetAsMap(pluginType PluginType, venafiClient venafi_wrappe
 	}
 
 	if v.TPP != nil {
-		m, err := v.TPP.getAccessToken(pluginType, venafiClient)
+		m, err := v.TPP.getAccessToken(pluginType, venafiClient, readStoredTokens, rotate)
 		if err != nil {
 			return nil, err
 		}
@@ -128,15 +249,27 @@ type VenafiVaaSConnection struct {
 	APIKey string `hcl:"apikey"`
 }
 
+// VenafiTPPConnection authenticates to TPP with exactly one of a username and password, an existing access token
+// (optionally with its refresh token), or a client certificate and key
 type VenafiTPPConnection struct {
-	URL      string `hcl:"url"`
-	Username string `hcl:"username"`
-	Password string `hcl:"password"`
+	URL               string `hcl:"url"`
+	Username          string `hcl:"username,optional"`
+	Password          string `hcl:"password,optional"`
+	AccessToken       string `hcl:"access_token,optional"`
+	RefreshToken      string `hcl:"refresh_token,optional"`
+	ClientCertificate string `hcl:"client_certificate,optional"`
+	ClientKey         string `hcl:"client_key,optional"`
+	ClientID          string `hcl:"client_id,optional"`
+	Scope             string `hcl:"scope,optional"`
+	TrustBundle       string `hcl:"trust_bundle,optional"`
+	// TrustBundleFile is the path of the trust bundle on the Vault servers, which is written to the secret for the
+	// plugin to trust when connecting to TPP. TrustBundle is only used by vvw itself.
+	TrustBundleFile string `hcl:"trust_bundle_file,optional"`
 }
 
 func (c *VenafiVaaSConnection) Validate() error {
 	if c.APIKey == "" {
-		return fmt.Errorf("error with Venafi API key: %w", errors.ErrBlankParam)
+		return fmt.Errorf("error with Venafi API key: %w", configErrors.ErrBlankParam)
 	}
 	return nil
 }
@@ -149,14 +282,51 @@ func (c *VenafiVaaSConnection) WriteHCL(hclBody *hclwrite.Body) {
 
 func (c *VenafiTPPConnection) Validate() error {
 	if c.URL == "" {
-		return fmt.Errorf("error with TPP URL: %w", errors.ErrBlankParam)
+		return fmt.Errorf("error with TPP URL: %w", configErrors.ErrBlankParam)
 	}
-	if c.Username == "" {
-		return fmt.Errorf("error with TPP Username: %w", errors.ErrBlankParam)
+
+	passwordProvided := c.Username != "" || c.Password != ""
+	tokenProvided := c.AccessToken != "" || c.RefreshToken != ""
+	certificateProvided := c.ClientCertificate != "" || c.ClientKey != ""
+
+	methods := 0
+	for _, provided := range []bool{passwordProvided, tokenProvided, certificateProvided} {
+		if provided {
+			methods++
+		}
+	}
+	if methods != 1 {
+		return fmt.Errorf("error, must provide exactly one of a TPP username and password, access token or client certificate: %w", configErrors.ErrConflictingBlocks)
+	}
+
+	if passwordProvided {
+		if c.Username == "" {
+			return fmt.Errorf("error with TPP Username: %w", configErrors.ErrBlankParam)
+		}
+		if c.Password == "" {
+			return fmt.Errorf("error with TPP Password: %w", configErrors.ErrBlankParam)
+		}
+	}
+	if tokenProvided {
+		if c.AccessToken == "" {
+			return fmt.Errorf("error with TPP access token, which must be given along with the refresh token: %w", configErrors.ErrBlankParam)
+		}
+		if c.Scope != "" {
+			return fmt.Errorf("error, TPP scope cannot be given along with an access token as no token is requested")
+		}
+		if c.ClientID != "" && c.RefreshToken == "" {
+			return fmt.Errorf("error, TPP client_id can only be given along with an access token to refresh it with the refresh token")
+		}
 	}
-	if c.Password == "" {
-		return fmt.Errorf("error with TPP Password: %w", errors.ErrBlankParam)
+	if certificateProvided {
+		if c.ClientCertificate == "" {
+			return fmt.Errorf("error with TPP client certificate: %w", configErrors.ErrBlankParam)
+		}
+		if c.ClientKey == "" {
+			return fmt.Errorf("error with TPP client key: %w", configErrors.ErrBlankParam)
+		}
 	}
+
 	return nil
 }
 
@@ -164,11 +334,88 @@ func (c *VenafiTPPConnection) WriteHCL(hclBody *hclwrite.Body) {
 	tppBlock := hclBody.AppendNewBlock("venafi_tpp", nil)
 	tppBody := tppBlock.Body()
 	generate.WriteStringAttributeToHCL("url", c.URL, tppBody)
-	generate.WriteStringAttributeToHCL("username", c.Username, tppBody)
-	generate.WriteStringAttributeToHCL("password", c.Password, tppBody)
+	for _, attribute := range []struct {
+		name, value string
+	}{
+		{"username", c.Username},
+		{"password", c.Password},
+		{"access_token", c.AccessToken},
+		{"refresh_token", c.RefreshToken},
+		{"client_certificate", c.ClientCertificate},
+		{"client_key", c.ClientKey},
+		{"client_id", c.ClientID},
+		{"scope", c.Scope},
+		{"trust_bundle", c.TrustBundle},
+		{"trust_bundle_file", c.TrustBundleFile},
+	} {
+		if attribute.value != "" {
+			generate.WriteStringAttributeToHCL(attribute.name, attribute.value, tppBody)
+		}
+	}
+}
+
+// GetTrustBundle returns the PEM encoded CA certificates in the trust_bundle file, or an empty string if it isn't
+// given
+func (c *VenafiTPPConnection) GetTrustBundle() (string, error) {
+	if c.TrustBundle == "" {
+		return "", nil
+	}
+
+	trustBundle, err := os.ReadFile(c.TrustBundle)
+	if err != nil {
+		return "", fmt.Errorf("error reading TPP trust bundle: %w", err)
+	}
+
+	return string(trustBundle), nil
 }
 
-func (c *VenafiTPPConnection) getAccessToken(pluginType PluginType, venafiClient venafi_wrapper.VenafiWrapper) (map[string]interface{}, error) {
+// GetClientCertificate returns the certificate to authenticate to TPP with, or nil if a client certificate isn't
+// given
+func (c *VenafiTPPConnection) GetClientCertificate() (*tls.Certificate, error) {
+	if c.ClientCertificate == "" {
+		return nil, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(c.ClientCertificate, c.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("error loading TPP client certificate: %w", err)
+	}
+
+	return &certificate, nil
+}
+
+// getAccessToken returns the parameters of the plugin's secret, requesting a new access and refresh token unless an
+// access token is given. A given access token is written as it is, unless it's given along with a refresh token, in
+// which case the tokens from readStoredTokens take precedence, and when rotating their refresh token is used to get a
+// new pair.
+func (c *VenafiTPPConnection) getAccessToken(
+	pluginType PluginType,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	readStoredTokens func() (*TPPTokens, error),
+	rotate bool,
+) (map[string]interface{}, error) {
+	if c.AccessToken != "" && c.RefreshToken == "" {
+		return c.getSecretData(c.AccessToken, ""), nil
+	}
+
+	refreshToken := c.RefreshToken
+	if c.AccessToken != "" {
+		storedTokens, err := readStoredTokens()
+		if err != nil {
+			return nil, fmt.Errorf("error reading the TPP tokens already in the Venafi secret: %w", err)
+		}
+
+		if storedTokens != nil {
+			// Refreshing spends the refresh token, so once rotated the config's tokens are older than those stored
+			if !rotate {
+				return c.getSecretData(storedTokens.AccessToken, storedTokens.RefreshToken), nil
+			}
+			refreshToken = storedTokens.RefreshToken
+		} else if !rotate {
+			return c.getSecretData(c.AccessToken, c.RefreshToken), nil
+		}
+	}
+
 	var scope string
 	var clientID string
 
@@ -182,19 +429,52 @@ func (c *VenafiTPPConnection) getAccessToken(pluginType PluginType, venafiClient
 		return nil, fmt.Errorf("unrecognised plugin type")
 	}
 
+	if c.Scope != "" {
+		scope = c.Scope
+	}
+	if c.ClientID != "" {
+		clientID = c.ClientID
+	}
+
+	if refreshToken != "" {
+		tokens, err := venafiClient.RefreshAccessToken(&endpoint.Authentication{
+			RefreshToken: refreshToken,
+			ClientId:     clientID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error trying to refresh the TPP access token: %w", err)
+		}
+
+		return c.getSecretData(tokens.Access_token, tokens.Refresh_token), nil
+	}
+
 	tokens, err := venafiClient.GetRefreshToken(&endpoint.Authentication{
-		User:     c.Username,
-		Password: c.Password,
-		Scope:    scope,
-		ClientId: clientID,
+		User:         c.Username,
+		Password:     c.Password,
+		Scope:        scope,
+		ClientId:     clientID,
+		ClientPKCS12: c.ClientCertificate != "",
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error trying to request an access and refresh token for TPP: %w", err)
 	}
 
-	return map[string]interface{}{
-		"url":           c.URL,
-		"access_token":  tokens.Access_token,
-		"refresh_token": tokens.Refresh_token,
-	}, nil
+	return c.getSecretData(tokens.Access_token, tokens.Refresh_token), nil
+}
+
+// getSecretData returns the parameters of the plugin's secret authenticating to TPP with accessToken, and refreshToken
+// if it's given
+func (c *VenafiTPPConnection) getSecretData(accessToken, refreshToken string) map[string]interface{} {
+	data := map[string]interface{}{
+		"url":          c.URL,
+		"access_token": accessToken,
+	}
+	if refreshToken != "" {
+		data["refresh_token"] = refreshToken
+	}
+	if c.TrustBundleFile != "" {
+		data["trust_bundle_file"] = c.TrustBundleFile
+	}
+
+	return data
 }
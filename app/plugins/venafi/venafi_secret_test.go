@@ -0,0 +1,341 @@
+package venafi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/Venafi/vcert/v4/pkg/venafi/tpp"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	configErrors "github.com/opencredo/venafi-vault-wizard/app/config/errors"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	mockVenafi "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestVenafiTPPConnection_Validate(t *testing.T) {
+	tests := map[string]struct {
+		connection VenafiTPPConnection
+		wantErr    error
+	}{
+		"username and password": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", Username: "admin", Password: "password"},
+		},
+		"access and refresh token": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access", RefreshToken: "refresh"},
+		},
+		"tokens with client id to refresh with": {
+			connection: VenafiTPPConnection{
+				URL:          "tpp.example.com",
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+				ClientID:     "vault",
+			},
+		},
+		"client certificate": {
+			connection: VenafiTPPConnection{
+				URL:               "tpp.example.com",
+				ClientCertificate: "client.pem",
+				ClientKey:         "client.key",
+				ClientID:          "vault",
+				Scope:             "certificate:manage",
+				TrustBundle:       "ca.pem",
+			},
+		},
+		"no URL": {
+			connection: VenafiTPPConnection{Username: "admin", Password: "password"},
+			wantErr:    configErrors.ErrBlankParam,
+		},
+		"no credentials": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com"},
+			wantErr:    configErrors.ErrConflictingBlocks,
+		},
+		"password and access token": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", Username: "admin", Password: "password", AccessToken: "access"},
+			wantErr:    configErrors.ErrConflictingBlocks,
+		},
+		"no password": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", Username: "admin"},
+			wantErr:    configErrors.ErrBlankParam,
+		},
+		"refresh token without access token": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", RefreshToken: "refresh"},
+			wantErr:    configErrors.ErrBlankParam,
+		},
+		"client certificate without key": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", ClientCertificate: "client.pem"},
+			wantErr:    configErrors.ErrBlankParam,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.connection.Validate()
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, tt.wantErr), "expected %s, got %v", tt.wantErr, err)
+		})
+	}
+
+	// scope is only used when requesting a token, so can't be given with one, and client_id only to refresh one
+	connection := VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access", Scope: "certificate:manage"}
+	require.Error(t, connection.Validate())
+	connection = VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access", ClientID: "vault"}
+	require.Error(t, connection.Validate())
+}
+
+func TestVenafiTPPConnection_getAccessToken(t *testing.T) {
+	tokens := tpp.OauthGetRefreshTokenResponse{Access_token: "new access", Refresh_token: "new refresh"}
+	refreshedTokens := tpp.OauthRefreshAccessTokenResponse{Access_token: "refreshed access", Refresh_token: "refreshed refresh"}
+	tests := map[string]struct {
+		connection          VenafiTPPConnection
+		pluginType          PluginType
+		storedTokens        *TPPTokens
+		rotate              bool
+		expectedAuth        *endpoint.Authentication
+		expectedRefreshAuth *endpoint.Authentication
+		expected            map[string]interface{}
+	}{
+		"username and password": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", Username: "admin", Password: "password"},
+			pluginType: SecretsEngine,
+			expectedAuth: &endpoint.Authentication{
+				User:     "admin",
+				Password: "password",
+				Scope:    "certificate:manage,revoke",
+				ClientId: "hashicorp-vault-by-venafi",
+			},
+			expected: map[string]interface{}{
+				"url":           "tpp.example.com",
+				"access_token":  "new access",
+				"refresh_token": "new refresh",
+			},
+		},
+		"client certificate with overrides": {
+			connection: VenafiTPPConnection{
+				URL:               "tpp.example.com",
+				ClientCertificate: "client.pem",
+				ClientKey:         "client.key",
+				ClientID:          "vault",
+				Scope:             "certificate:discover",
+			},
+			pluginType: MonitorEngine,
+			expectedAuth: &endpoint.Authentication{
+				Scope:        "certificate:discover",
+				ClientId:     "vault",
+				ClientPKCS12: true,
+			},
+			expected: map[string]interface{}{
+				"url":           "tpp.example.com",
+				"access_token":  "new access",
+				"refresh_token": "new refresh",
+			},
+		},
+		"existing tokens": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access", RefreshToken: "refresh"},
+			pluginType: MonitorEngine,
+			expected: map[string]interface{}{
+				"url":           "tpp.example.com",
+				"access_token":  "access",
+				"refresh_token": "refresh",
+			},
+		},
+		"existing tokens refreshed when rotating": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access", RefreshToken: "refresh"},
+			pluginType: MonitorEngine,
+			rotate:     true,
+			expectedRefreshAuth: &endpoint.Authentication{
+				RefreshToken: "refresh",
+				ClientId:     "hashicorp-vault-monitor-by-venafi",
+			},
+			expected: map[string]interface{}{
+				"url":           "tpp.example.com",
+				"access_token":  "refreshed access",
+				"refresh_token": "refreshed refresh",
+			},
+		},
+		"stored tokens kept over existing tokens": {
+			connection:   VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access", RefreshToken: "refresh"},
+			pluginType:   MonitorEngine,
+			storedTokens: &TPPTokens{AccessToken: "stored access", RefreshToken: "stored refresh"},
+			expected: map[string]interface{}{
+				"url":           "tpp.example.com",
+				"access_token":  "stored access",
+				"refresh_token": "stored refresh",
+			},
+		},
+		"stored tokens refreshed when rotating": {
+			connection:   VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access", RefreshToken: "refresh"},
+			pluginType:   MonitorEngine,
+			storedTokens: &TPPTokens{AccessToken: "stored access", RefreshToken: "stored refresh"},
+			rotate:       true,
+			expectedRefreshAuth: &endpoint.Authentication{
+				RefreshToken: "stored refresh",
+				ClientId:     "hashicorp-vault-monitor-by-venafi",
+			},
+			expected: map[string]interface{}{
+				"url":           "tpp.example.com",
+				"access_token":  "refreshed access",
+				"refresh_token": "refreshed refresh",
+			},
+		},
+		"existing tokens refreshed with client id when rotating": {
+			connection: VenafiTPPConnection{
+				URL:          "tpp.example.com",
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+				ClientID:     "vault",
+			},
+			pluginType: SecretsEngine,
+			rotate:     true,
+			expectedRefreshAuth: &endpoint.Authentication{
+				RefreshToken: "refresh",
+				ClientId:     "vault",
+			},
+			expected: map[string]interface{}{
+				"url":           "tpp.example.com",
+				"access_token":  "refreshed access",
+				"refresh_token": "refreshed refresh",
+			},
+		},
+		"existing access token only when rotating": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access"},
+			pluginType: SecretsEngine,
+			rotate:     true,
+			expected: map[string]interface{}{
+				"url":          "tpp.example.com",
+				"access_token": "access",
+			},
+		},
+		"trust bundle file": {
+			connection: VenafiTPPConnection{
+				URL:             "tpp.example.com",
+				AccessToken:     "access",
+				TrustBundle:     "ca.pem",
+				TrustBundleFile: "/etc/vault/tpp-ca.pem",
+			},
+			pluginType: SecretsEngine,
+			expected: map[string]interface{}{
+				"url":               "tpp.example.com",
+				"access_token":      "access",
+				"trust_bundle_file": "/etc/vault/tpp-ca.pem",
+			},
+		},
+		"existing access token only": {
+			connection: VenafiTPPConnection{URL: "tpp.example.com", AccessToken: "access"},
+			pluginType: SecretsEngine,
+			expected: map[string]interface{}{
+				"url":          "tpp.example.com",
+				"access_token": "access",
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			venafiClient := new(mockVenafi.VenafiWrapper)
+			if tt.expectedAuth != nil {
+				venafiClient.On("GetRefreshToken", tt.expectedAuth).Return(tokens, nil)
+			}
+			if tt.expectedRefreshAuth != nil {
+				venafiClient.On("RefreshAccessToken", tt.expectedRefreshAuth).Return(refreshedTokens, nil)
+			}
+
+			readStoredTokens := func() (*TPPTokens, error) {
+				return tt.storedTokens, nil
+			}
+
+			parameters, err := tt.connection.getAccessToken(tt.pluginType, venafiClient, readStoredTokens, tt.rotate)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, parameters)
+
+			venafiClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReadTPPTokens(t *testing.T) {
+	tests := map[string]struct {
+		data     map[string]interface{}
+		err      error
+		expected *TPPTokens
+	}{
+		"no secret": {
+			err: vault.ErrNotFound,
+		},
+		"tokens": {
+			data: map[string]interface{}{"access_token": "access", "refresh_token": "refresh"},
+			expected: &TPPTokens{
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+			},
+		},
+		"no refresh token": {
+			data: map[string]interface{}{"access_token": "access"},
+		},
+		"masked tokens": {
+			data: map[string]interface{}{"access_token": "********", "refresh_token": "********"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			vaultAPIClient.On("ReadValue", "pki/venafi/tpp").Return(tt.data, tt.err)
+
+			tokens, err := readTPPTokens(vaultAPIClient, "pki/venafi/tpp")
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, tokens)
+		})
+	}
+}
+
+func TestRotateVenafiSecrets(t *testing.T) {
+	zone := "zone"
+	vaasSecret := VenafiSecret{VaaS: &VenafiVaaSConnection{APIKey: "new API key"}}
+	roles := []RotatingRole{
+		{Name: "web", SecretName: "vaas", Secret: vaasSecret, Zone: &zone, HasTestCerts: true},
+		{Name: "api", SecretName: "vaas", Secret: vaasSecret, Zone: &zone},
+		{Name: "mail", SecretName: "other", Secret: vaasSecret, Zone: &zone, HasTestCerts: true},
+	}
+
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	report.On("AddSection", mock.AnythingOfType("string")).Return(section)
+	section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+	check.On("Success", mock.AnythingOfType("string"))
+	// The api role has no test certificates to check the secret with
+	check.On("Warning", mock.AnythingOfType("string")).Once()
+
+	// The secret shared by the web and api roles is only written once, and the mail role's secret isn't rotated
+	vaultAPIClient.On("WriteValue", "pki/venafi/vaas", map[string]interface{}{
+		"apikey": "new API key",
+		"zone":   "zone",
+	}).Return(nil, nil).Once()
+
+	var testedRoles []int
+	err := RotateVenafiSecrets(
+		report,
+		vaultAPIClient,
+		map[string]venafi_wrapper.VenafiWrapper{"vaas": new(mockVenafi.VenafiWrapper)},
+		"pki",
+		SecretsEngine,
+		roles,
+		"vaas",
+		func(rotateSection reporter.Section, role int) error {
+			testedRoles = append(testedRoles, role)
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, testedRoles)
+}
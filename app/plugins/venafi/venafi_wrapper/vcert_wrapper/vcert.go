@@ -1,8 +1,13 @@
 package vcert_wrapper
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/Venafi/vcert/v4"
 	"github.com/Venafi/vcert/v4/pkg/certificate"
@@ -13,6 +18,11 @@ import (
 	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
 )
 
+// certificateLister lists the certificates of the zone set on a connector
+type certificateLister interface {
+	ListCertificates(filter endpoint.Filter) ([]certificate.CertificateInfo, error)
+}
+
 type venafiClient struct {
 	vaasConnector *cloud.Connector
 	tppConnector  *tpp.Connector
@@ -34,18 +44,32 @@ func NewVenafiClient(secret venafi.VenafiSecret) (venafi_wrapper.VenafiWrapper,
 			Client: http.DefaultClient,
 		}
 	} else {
+		trustBundle, err := secret.TPP.GetTrustBundle()
+		if err != nil {
+			return nil, err
+		}
+		httpClient, err := newTPPHTTPClient(secret.TPP, trustBundle)
+		if err != nil {
+			return nil, err
+		}
+
 		config = vcert.Config{
 			ConnectorType: endpoint.ConnectorTypeTPP,
 			BaseUrl:       secret.TPP.URL,
 			Credentials: &endpoint.Authentication{
-				User:     secret.TPP.Username,
-				Password: secret.TPP.Password,
+				User:        secret.TPP.Username,
+				Password:    secret.TPP.Password,
+				AccessToken: secret.TPP.AccessToken,
 			},
-			Zone: "",
+			Zone:            "",
+			ConnectionTrust: trustBundle,
+			Client:          httpClient,
 		}
 	}
 
-	client, err := vcert.NewClient(&config)
+	// With a client certificate there are no credentials to authenticate with until a token has been requested
+	authenticate := secret.TPP == nil || secret.TPP.ClientCertificate == ""
+	client, err := vcert.NewClient(&config, authenticate)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +89,13 @@ func NewVenafiClient(secret venafi.VenafiSecret) (venafi_wrapper.VenafiWrapper,
 			return nil, fmt.Errorf("cannot cast client to TPP Connector")
 		}
 
+		if !authenticate {
+			err = authenticateWithClientCertificate(tppConnector, secret.TPP)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		return &venafiClient{
 			tppConnector: tppConnector,
 		}, nil
@@ -73,6 +104,52 @@ func NewVenafiClient(secret venafi.VenafiSecret) (venafi_wrapper.VenafiWrapper,
 	}
 }
 
+// newTPPHTTPClient returns a client that presents the TPP client certificate, trusting trustBundle if it's given, or
+// nil if there isn't a client certificate so that vcert creates its own
+func newTPPHTTPClient(tppConnection *venafi.VenafiTPPConnection, trustBundle string) (*http.Client, error) {
+	clientCertificate, err := tppConnection.GetClientCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if clientCertificate == nil {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{*clientCertificate},
+	}
+	if trustBundle != "" {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM([]byte(trustBundle)) {
+			return nil, fmt.Errorf("error parsing TPP trust bundle")
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
+	}, nil
+}
+
+// authenticateWithClientCertificate requests a token for the wizard itself using the client certificate, and
+// authenticates the connector with it
+func authenticateWithClientCertificate(tppConnector *tpp.Connector, tppConnection *venafi.VenafiTPPConnection) error {
+	tokens, err := tppConnector.GetRefreshToken(&endpoint.Authentication{
+		ClientPKCS12: true,
+		ClientId:     tppConnection.ClientID,
+	})
+	if err != nil {
+		return fmt.Errorf("error requesting a TPP access token with the client certificate: %w", err)
+	}
+
+	return tppConnector.Authenticate(&endpoint.Authentication{
+		AccessToken: tokens.Access_token,
+	})
+}
+
 func (v *venafiClient) setZone(zone string) {
 	if v.vaasConnector != nil {
 		v.vaasConnector.SetZone(zone)
@@ -116,6 +193,84 @@ func (v *venafiClient) RetrieveCertificate(req *certificate.Request, zone string
 	}
 }
 
+func (v *venafiClient) ReadZoneConfiguration(zone string) (config *endpoint.ZoneConfiguration, err error) {
+	v.setZone(zone)
+	if v.vaasConnector != nil {
+		return v.vaasConnector.ReadZoneConfiguration()
+	} else if v.tppConnector != nil {
+		return v.tppConnector.ReadZoneConfiguration()
+	} else {
+		panic("expected venafiClient to have either vaasConnector or tppConnector specified")
+	}
+}
+
+func (v *venafiClient) RevokeCertificate(req *certificate.RevocationRequest) (err error) {
+	if v.vaasConnector != nil {
+		return v.vaasConnector.RevokeCertificate(req)
+	} else if v.tppConnector != nil {
+		return v.tppConnector.RevokeCertificate(req)
+	} else {
+		panic("expected venafiClient to have either vaasConnector or tppConnector specified")
+	}
+}
+
+func (v *venafiClient) ListCertificates(zone string, filter endpoint.Filter) (certificates []certificate.CertificateInfo, err error) {
+	v.setZone(zone)
+	if v.vaasConnector != nil {
+		return v.vaasConnector.ListCertificates(filter)
+	} else if v.tppConnector != nil {
+		return v.tppConnector.ListCertificates(filter)
+	} else {
+		panic("expected venafiClient to have either vaasConnector or tppConnector specified")
+	}
+}
+
+func (v *venafiClient) FindCertificateInZone(zone string, thumbprint string) (found bool, err error) {
+	if v.vaasConnector != nil {
+		v.setZone(zone)
+		return findCertificate(v.vaasConnector, thumbprint)
+	} else if v.tppConnector != nil {
+		result, err := v.tppConnector.SearchCertificates(&certificate.SearchRequest{
+			"Thumbprint=" + url.QueryEscape(thumbprint),
+			"ParentDnRecursive=" + url.QueryEscape(getPolicyDN(zone)),
+		})
+		if err != nil {
+			return false, err
+		}
+		return len(result.Certificates) > 0, nil
+	} else {
+		panic("expected venafiClient to have either vaasConnector or tppConnector specified")
+	}
+}
+
+// findCertificate returns whether the zone set on lister has a certificate with thumbprint. The VaaS connector has no
+// search, and lists the zone's certificates in batches in no particular order, so every batch is checked rather than
+// only the first.
+func findCertificate(lister certificateLister, thumbprint string) (bool, error) {
+	// Without a limit, the connector pages through all of the zone's certificates
+	certificates, err := lister.ListCertificates(endpoint.Filter{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, info := range certificates {
+		if strings.EqualFold(info.Thumbprint, thumbprint) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// getPolicyDN returns the full DN of the TPP policy folder for zone, which can be given with or without the
+// \VED\Policy prefix
+func getPolicyDN(zone string) string {
+	if strings.HasPrefix(zone, `\VED\Policy`) {
+		return zone
+	}
+	return `\VED\Policy\` + strings.TrimPrefix(zone, `\`)
+}
+
 func (v *venafiClient) GetRefreshToken(auth *endpoint.Authentication) (resp tpp.OauthGetRefreshTokenResponse, err error) {
 	if v.tppConnector != nil {
 		return v.tppConnector.GetRefreshToken(auth)
@@ -123,3 +278,11 @@ func (v *venafiClient) GetRefreshToken(auth *endpoint.Authentication) (resp tpp.
 		panic("expected venafiClient to have either vaasConnector or tppConnector specified")
 	}
 }
+
+func (v *venafiClient) RefreshAccessToken(auth *endpoint.Authentication) (resp tpp.OauthRefreshAccessTokenResponse, err error) {
+	if v.tppConnector != nil {
+		return v.tppConnector.RefreshAccessToken(auth)
+	} else {
+		panic("expected venafiClient to have either vaasConnector or tppConnector specified")
+	}
+}
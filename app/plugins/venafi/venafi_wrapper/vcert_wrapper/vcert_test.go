@@ -0,0 +1,70 @@
+package vcert_wrapper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedLister lists certificates in batches of 50 until filter's limit is reached, like the VaaS connector
+type pagedLister struct {
+	certificates []certificate.CertificateInfo
+	pagesListed  int
+}
+
+func (l *pagedLister) ListCertificates(filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
+	const batchSize = 50
+	limit := len(l.certificates)
+	if filter.Limit != nil && *filter.Limit < limit {
+		limit = *filter.Limit
+	}
+
+	var listed []certificate.CertificateInfo
+	for len(listed) < limit {
+		end := len(listed) + batchSize
+		if end > limit {
+			end = limit
+		}
+		listed = append(listed, l.certificates[len(listed):end]...)
+		l.pagesListed++
+	}
+
+	return listed, nil
+}
+
+func TestFindCertificate(t *testing.T) {
+	certificates := make([]certificate.CertificateInfo, 120)
+	for i := range certificates {
+		certificates[i] = certificate.CertificateInfo{Thumbprint: fmt.Sprintf("%040X", i)}
+	}
+
+	tests := map[string]struct {
+		thumbprint string
+		want       bool
+	}{
+		"on the first page": {
+			thumbprint: fmt.Sprintf("%040X", 10),
+			want:       true,
+		},
+		"after the first page": {
+			thumbprint: fmt.Sprintf("%040x", 110),
+			want:       true,
+		},
+		"not in the zone": {
+			thumbprint: fmt.Sprintf("%040X", 500),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			lister := &pagedLister{certificates: certificates}
+
+			found, err := findCertificate(lister, tt.thumbprint)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, found)
+			require.Equal(t, 3, lister.pagesListed)
+		})
+	}
+}
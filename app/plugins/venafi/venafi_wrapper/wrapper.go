@@ -10,6 +10,13 @@ type VenafiWrapper interface {
 	GenerateRequest(config *endpoint.ZoneConfiguration, req *certificate.Request, zone string) (err error)
 	RequestCertificate(req *certificate.Request, zone string) (requestID string, err error)
 	RetrieveCertificate(req *certificate.Request, zone string) (certificates *certificate.PEMCollection, err error)
+	ReadZoneConfiguration(zone string) (config *endpoint.ZoneConfiguration, err error)
+	RevokeCertificate(req *certificate.RevocationRequest) (err error)
+	ListCertificates(zone string, filter endpoint.Filter) (certificates []certificate.CertificateInfo, err error)
+	// FindCertificateInZone returns whether the certificate with the SHA-1 thumbprint is in zone, without listing it
+	FindCertificateInZone(zone string, thumbprint string) (found bool, err error)
 	// GetRefreshToken TPP implementation only
 	GetRefreshToken(auth *endpoint.Authentication) (resp tpp.OauthGetRefreshTokenResponse, err error)
+	// RefreshAccessToken TPP implementation only
+	RefreshAccessToken(auth *endpoint.Authentication) (resp tpp.OauthRefreshAccessTokenResponse, err error)
 }
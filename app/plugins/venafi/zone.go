@@ -0,0 +1,162 @@
+package venafi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/venafi/venafi_wrapper"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+)
+
+// CheckVenafiZone reads the configuration of zone from Venafi, reporting its key and subject constraints, so that a
+// wrong zone or credentials are found before anything is installed into Vault
+func CheckVenafiZone(
+	reportSection reporter.Section,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	description, zone string,
+) (*endpoint.ZoneConfiguration, error) {
+	check := reportSection.AddCheck(fmt.Sprintf("Reading %s zone %s...", description, zone))
+
+	zoneConfig, err := venafiClient.ReadZoneConfiguration(zone)
+	if err != nil {
+		check.Errorf("Error reading %s zone %s from Venafi: %s", description, zone, err)
+		return nil, err
+	}
+
+	check.Successf("Read %s zone %s from Venafi", description, zone)
+	reportSection.Info(fmt.Sprintf("Zone %s allows keys %s\n", zone, describeAllowedKeys(zoneConfig)))
+	reportSection.Info(fmt.Sprintf("Zone %s requires subject %s\n", zone, describeSubjectConstraints(zoneConfig)))
+
+	return zoneConfig, nil
+}
+
+// describeAllowedKeys summarises the key types, sizes and curves the zone allows
+func describeAllowedKeys(zoneConfig *endpoint.ZoneConfiguration) string {
+	if len(zoneConfig.AllowedKeyConfigurations) == 0 {
+		return "of any type"
+	}
+
+	keys := make([]string, len(zoneConfig.AllowedKeyConfigurations))
+	for i, keyConfig := range zoneConfig.AllowedKeyConfigurations {
+		var options []string
+		for _, size := range keyConfig.KeySizes {
+			options = append(options, fmt.Sprint(size))
+		}
+		for _, curve := range keyConfig.KeyCurves {
+			options = append(options, curve.String())
+		}
+
+		keys[i] = keyConfig.KeyType.String()
+		if len(options) != 0 {
+			keys[i] += fmt.Sprintf(" (%s)", strings.Join(options, ", "))
+		}
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+// describeSubjectConstraints summarises the patterns the zone requires each subject field to match, and the values
+// it sets
+func describeSubjectConstraints(zoneConfig *endpoint.ZoneConfiguration) string {
+	var constraints []string
+	for _, field := range []struct {
+		name    string
+		regexes []string
+		value   string
+	}{
+		{"CN", zoneConfig.SubjectCNRegexes, ""},
+		{"O", zoneConfig.SubjectORegexes, zoneConfig.Organization},
+		{"OU", zoneConfig.SubjectOURegexes, strings.Join(zoneConfig.OrganizationalUnit, ", ")},
+		{"L", zoneConfig.SubjectLRegexes, zoneConfig.Locality},
+		{"ST", zoneConfig.SubjectSTRegexes, zoneConfig.Province},
+		{"C", zoneConfig.SubjectCRegexes, zoneConfig.Country},
+	} {
+		if field.value != "" {
+			constraints = append(constraints, fmt.Sprintf("%s = %s", field.name, field.value))
+		} else if len(field.regexes) != 0 && !(len(field.regexes) == 1 && field.regexes[0] == ".*") {
+			constraints = append(constraints, fmt.Sprintf("%s matching %s", field.name, strings.Join(field.regexes, " or ")))
+		}
+	}
+
+	if len(constraints) == 0 {
+		return "fields to have any value"
+	}
+
+	return strings.Join(constraints, ", ")
+}
+
+// ValidateTestCertificates checks each of the test certificates would be accepted by zone, by validating them against
+// its policy in zoneConfig. This is all that can be checked when certificates aren't enrolled through the zone, such
+// as those issued by Vault's own CA and only imported into Venafi.
+func ValidateTestCertificates(
+	reportSection reporter.Section,
+	zone string,
+	zoneConfig *endpoint.ZoneConfiguration,
+	certRequests []CertificateRequest,
+	maxTTL string,
+) error {
+	for _, certRequest := range certRequests {
+		check := reportSection.AddCheck(fmt.Sprintf("Validating test certificate with CN:%s against zone %s...", certRequest.CommonName, zone))
+
+		err := validateTestCertificate(check, zone, zoneConfig, certRequest, maxTTL)
+		if err != nil {
+			return err
+		}
+
+		check.Successf("Test certificate with CN:%s meets the policy of zone %s", certRequest.CommonName, zone)
+	}
+
+	return nil
+}
+
+// CheckTestCertificates checks each of the test certificates would be accepted by zone, by validating them against
+// its policy in zoneConfig and generating the request Venafi would be sent, with the CSR generated as csrOrigin says,
+// before they're requested through Vault
+func CheckTestCertificates(
+	reportSection reporter.Section,
+	venafiClient venafi_wrapper.VenafiWrapper,
+	zone string,
+	zoneConfig *endpoint.ZoneConfiguration,
+	certRequests []CertificateRequest,
+	maxTTL string,
+	csrOrigin certificate.CSrOriginOption,
+) error {
+	for _, certRequest := range certRequests {
+		check := reportSection.AddCheck(fmt.Sprintf("Validating test certificate with CN:%s against zone %s...", certRequest.CommonName, zone))
+
+		err := validateTestCertificate(check, zone, zoneConfig, certRequest, maxTTL)
+		if err != nil {
+			return err
+		}
+
+		err = venafiClient.GenerateRequest(zoneConfig, certRequest.ToVCertRequest(csrOrigin), zone)
+		if err != nil {
+			check.Errorf("Error generating request for test certificate with CN:%s in zone %s: %s", certRequest.CommonName, zone, err)
+			return err
+		}
+
+		check.Successf("Test certificate with CN:%s meets the policy of zone %s", certRequest.CommonName, zone)
+	}
+
+	return nil
+}
+
+// validateTestCertificate validates certRequest against the policy of zone in zoneConfig, reporting any error on check
+func validateTestCertificate(
+	check reporter.Check,
+	zone string,
+	zoneConfig *endpoint.ZoneConfiguration,
+	certRequest CertificateRequest,
+	maxTTL string,
+) error {
+	err := certRequest.ValidateAgainstZone(zoneConfig, maxTTL)
+	if err != nil {
+		check.Errorf("Test certificate with CN:%s breaks the policy of zone %s: %s", certRequest.CommonName, zone, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package venafi
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Venafi/vcert/v4/pkg/certificate"
+	"github.com/Venafi/vcert/v4/pkg/endpoint"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mockVenafi "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins/venafi/venafi_wrapper"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+)
+
+func TestCheckVenafiZone(t *testing.T) {
+	zoneConfig := &endpoint.ZoneConfiguration{
+		Organization: "OpenCredo",
+		Policy: endpoint.Policy{
+			SubjectCNRegexes: []string{`.*\.example\.com`},
+			SubjectCRegexes:  []string{".*"},
+			AllowedKeyConfigurations: []endpoint.AllowedKeyConfiguration{
+				{KeyType: certificate.KeyTypeRSA, KeySizes: []int{2048, 4096}},
+				{KeyType: certificate.KeyTypeECDSA, KeyCurves: []certificate.EllipticCurve{certificate.EllipticCurveP256}},
+			},
+		},
+	}
+
+	t.Run("zone read", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		venafiClient := new(mockVenafi.VenafiWrapper)
+		defer section.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+		section.On("Info", "Zone zone allows keys RSA (2048, 4096), ECDSA (P256)\n")
+		section.On("Info", `Zone zone requires subject CN matching .*\.example\.com, O = OpenCredo`+"\n")
+		venafiClient.On("ReadZoneConfiguration", "zone").Return(zoneConfig, nil)
+
+		actual, err := CheckVenafiZone(section, venafiClient, "role web", "zone")
+		require.NoError(t, err)
+		require.Equal(t, zoneConfig, actual)
+	})
+
+	t.Run("zone not found", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		venafiClient := new(mockVenafi.VenafiWrapper)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Errorf", mock.AnythingOfType("string"), mock.Anything)
+		venafiClient.On("ReadZoneConfiguration", "missing").Return(nil, fmt.Errorf("zone not found"))
+
+		_, err := CheckVenafiZone(section, venafiClient, "role web", "missing")
+		require.Error(t, err)
+	})
+}
+
+func TestDescribeZone_NoConstraints(t *testing.T) {
+	zoneConfig := &endpoint.ZoneConfiguration{}
+
+	require.Equal(t, "of any type", describeAllowedKeys(zoneConfig))
+	require.Equal(t, "fields to have any value", describeSubjectConstraints(zoneConfig))
+}
+
+func TestCheckTestCertificates(t *testing.T) {
+	zoneConfig := &endpoint.ZoneConfiguration{
+		Policy: endpoint.Policy{
+			SubjectCNRegexes: []string{`^.*\.example\.com$`},
+		},
+	}
+	certRequest := CertificateRequest{
+		CommonName:   "test.example.com",
+		OU:           "VVW",
+		Organisation: "OpenCredo",
+		Locality:     "London",
+		Province:     "London",
+		Country:      "GB",
+		TTL:          "1h",
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		venafiClient := new(mockVenafi.VenafiWrapper)
+		defer venafiClient.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+		venafiClient.On("GenerateRequest", zoneConfig, certRequest.ToVCertRequest(certificate.LocalGeneratedCSR), "zone").Return(nil)
+
+		err := CheckTestCertificates(section, venafiClient, "zone", zoneConfig, []CertificateRequest{certRequest}, "", certificate.LocalGeneratedCSR)
+		require.NoError(t, err)
+	})
+
+	t.Run("service generated", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		venafiClient := new(mockVenafi.VenafiWrapper)
+		defer venafiClient.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+		venafiClient.On("GenerateRequest", zoneConfig, certRequest.ToVCertRequest(certificate.ServiceGeneratedCSR), "zone").Return(nil)
+
+		err := CheckTestCertificates(section, venafiClient, "zone", zoneConfig, []CertificateRequest{certRequest}, "", certificate.ServiceGeneratedCSR)
+		require.NoError(t, err)
+	})
+
+	t.Run("breaks policy", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		venafiClient := new(mockVenafi.VenafiWrapper)
+		defer check.AssertExpectations(t)
+
+		invalidRequest := certRequest
+		invalidRequest.CommonName = "test.example.org"
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Errorf", mock.AnythingOfType("string"), mock.Anything)
+
+		err := CheckTestCertificates(section, venafiClient, "zone", zoneConfig, []CertificateRequest{invalidRequest}, "", certificate.LocalGeneratedCSR)
+		require.Error(t, err)
+		venafiClient.AssertNotCalled(t, "GenerateRequest", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestValidateTestCertificates(t *testing.T) {
+	zoneConfig := &endpoint.ZoneConfiguration{
+		Policy: endpoint.Policy{
+			SubjectCNRegexes: []string{`^.*\.example\.com$`},
+		},
+	}
+	certRequest := CertificateRequest{
+		CommonName: "test.example.com",
+		TTL:        "1h",
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		defer check.AssertExpectations(t)
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Successf", mock.AnythingOfType("string"), mock.Anything)
+
+		err := ValidateTestCertificates(section, "zone", zoneConfig, []CertificateRequest{certRequest}, "")
+		require.NoError(t, err)
+	})
+
+	t.Run("breaks policy", func(t *testing.T) {
+		section := new(mockReport.Section)
+		check := new(mockReport.Check)
+		defer check.AssertExpectations(t)
+
+		invalidRequest := certRequest
+		invalidRequest.CommonName = "test.example.org"
+
+		section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
+		check.On("Errorf", mock.AnythingOfType("string"), mock.Anything)
+
+		err := ValidateTestCertificates(section, "zone", zoneConfig, []CertificateRequest{invalidRequest}, "")
+		require.Error(t, err)
+	})
+}
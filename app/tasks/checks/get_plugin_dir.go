@@ -2,24 +2,52 @@ package checks
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
 	"github.com/opencredo/venafi-vault-wizard/app/vault"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
-func GetPluginDir(reportSection reporter.Section, vaultClient api.VaultAPIClient) (string, error) {
+// GetPluginDir reads the plugin_directory from the Vault API, and if that isn't possible falls back to the override
+// provided in the config file, and then to reading the Vault server config file over SSH
+func GetPluginDir(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	fallback *VaultServerConfigFallback,
+) (string, error) {
 	pluginDirCheck := reportSection.AddCheck("Checking plugin_directory is configured...")
+
 	pluginDir, err := vaultClient.GetPluginDir()
+	if err == nil {
+		pluginDirCheck.Success("Vault Plugin Directory found using the Vault API")
+		return pluginDir, nil
+	}
+	if errors.Is(err, vault.ErrPluginDirNotConfigured) {
+		pluginDirCheck.Error("The plugin_directory hasn't been configured correctly in the Vault Server Config")
+		return "", err
+	}
+
+	pluginDirCheck.UpdateStatusf("Can't read plugin_directory from the Vault API (%s), trying alternatives...", err)
+
+	if fallback.PluginDirectory != "" {
+		pluginDirCheck.Success("Vault Plugin Directory taken from the plugin_directory override in the vault block")
+		return fallback.PluginDirectory, nil
+	}
+
+	serverConfig, err := fallback.getServerConfigOverSSH()
 	if err != nil {
-		if errors.Is(err, vault.ErrPluginDirNotConfigured) {
-			pluginDirCheck.Error("The plugin_directory hasn't been configured correctly in the Vault Server Config")
-		} else {
-			pluginDirCheck.Errorf("Error while trying to read plugin_directory: %s", err)
-		}
+		pluginDirCheck.Errorf(
+			"Error while trying to read plugin_directory from the Vault API or the Vault config file over SSH, consider setting plugin_directory in the vault block: %s",
+			err,
+		)
 		return "", err
 	}
-	pluginDirCheck.Success("Vault Plugin Directory found")
+	if serverConfig.PluginDirectory == "" {
+		pluginDirCheck.Errorf("The plugin_directory hasn't been configured in the Vault config at %s", strings.Join(serverConfig.ConfigPaths, ", "))
+		return "", vault.ErrPluginDirNotConfigured
+	}
 
-	return pluginDir, nil
+	pluginDirCheck.Successf("Vault Plugin Directory found in the Vault config at %s over SSH", strings.Join(serverConfig.ConfigPaths, ", "))
+	return serverConfig.PluginDirectory, nil
 }
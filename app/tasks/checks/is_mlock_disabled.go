@@ -1,21 +1,51 @@
 package checks
 
 import (
+	"strings"
+
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
-func IsMlockDisabled(checkConfigSection reporter.Section, vaultClient api.VaultAPIClient) (bool, error) {
+// IsMlockDisabled reads the disable_mlock option from the Vault API, and if that isn't possible falls back to the
+// override provided in the config file, and then to reading the Vault server config file over SSH
+func IsMlockDisabled(
+	checkConfigSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	fallback *VaultServerConfigFallback,
+) (bool, error) {
 	mlockDisabledCheck := checkConfigSection.AddCheck("Checking if mlock is disabled...")
+
+	var source string
 	mlockDisabled, err := vaultClient.IsMLockDisabled()
-	if err != nil {
-		mlockDisabledCheck.Errorf("Error checking whether mlock is disabled: %s", err)
-		return false, err
+	if err == nil {
+		source = "according to the Vault API"
+	} else {
+		mlockDisabledCheck.UpdateStatusf("Can't read disable_mlock from the Vault API (%s), trying alternatives...", err)
+
+		if fallback.DisableMlock != nil {
+			mlockDisabled = *fallback.DisableMlock
+			source = "according to the disable_mlock override in the vault block"
+		} else {
+			serverConfig, err := fallback.getServerConfigOverSSH()
+			if err != nil {
+				mlockDisabledCheck.Errorf(
+					"Error checking whether mlock is disabled using the Vault API or the Vault config file over SSH, consider setting disable_mlock in the vault block: %s",
+					err,
+				)
+				return false, err
+			}
+
+			mlockDisabled = serverConfig.DisableMlock
+			source = "according to the Vault config at " + strings.Join(serverConfig.ConfigPaths, ", ")
+		}
 	}
+
 	if mlockDisabled {
-		mlockDisabledCheck.Warning("mlock is disabled in the Vault server config, should be enabled for production")
+		mlockDisabledCheck.Warningf("mlock is disabled %s, should be enabled for production", source)
 	} else {
-		mlockDisabledCheck.Success("mlock is enabled in the Vault server config")
+		mlockDisabledCheck.Successf("mlock is enabled %s", source)
 	}
+
 	return mlockDisabled, nil
 }
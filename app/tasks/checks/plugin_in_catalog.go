@@ -1,15 +1,17 @@
 package checks
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
 
-func InstallPluginInCatalog(reportSection reporter.Section, vaultClient api.VaultAPIClient, pluginName, command, sha string) error {
+func InstallPluginInCatalog(reportSection reporter.Section, vaultClient api.VaultAPIClient, plugin *api.PluginRegistration) error {
 	check := reportSection.AddCheck("Enabling plugin in Vault plugin catalog...")
-	err := vaultClient.RegisterPlugin(pluginName, command, sha)
+	err := vaultClient.RegisterPlugin(plugin)
 	if err != nil {
 		check.Errorf("Error registering plugin in Vault catalog: %s", err)
 		return err
@@ -19,17 +21,52 @@ func InstallPluginInCatalog(reportSection reporter.Section, vaultClient api.Vaul
 	return nil
 }
 
-func VerifyPluginInCatalog(reportSection reporter.Section, vaultClient api.VaultAPIClient, pluginName, command string) error {
+func VerifyPluginInCatalog(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	kind api.PluginKind,
+	pluginName, version, command string,
+) error {
 	check := reportSection.AddCheck("Checking whether plugin is enabled in Vault plugin catalog...")
-	plugin, err := vaultClient.GetPlugin(pluginName)
+	plugin, err := vaultClient.GetPlugin(kind, pluginName, version)
 	if err != nil {
 		check.Errorf("Can't look up plugin in Vault plugin catalog: %s", err)
 		return err
 	}
-	if plugin["command"] != command {
+	if plugin.Command != command {
 		check.Error("Plugin enabled, but the currently configured command is incorrect")
 		return fmt.Errorf("wrong plugin command configured")
 	}
 	check.Success("Plugin is enabled in the Vault plugin catalog")
 	return nil
 }
+
+// RemoveUnversionedPluginFromCatalog deregisters the unversioned catalog entry for a plugin, if there is one, once it
+// has been replaced by versioned entries. The removed entry is returned, or nil if there wasn't one.
+func RemoveUnversionedPluginFromCatalog(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	kind api.PluginKind,
+	pluginName string,
+) (*api.PluginRegistration, error) {
+	check := reportSection.AddCheck("Checking for unversioned plugin catalog entry...")
+	plugin, err := vaultClient.GetPlugin(kind, pluginName, "")
+	if err != nil {
+		if errors.Is(err, vault.ErrNotFound) {
+			check.Success("No unversioned entry left in plugin catalog")
+			return nil, nil
+		}
+
+		check.Errorf("Can't look up unversioned entry in Vault plugin catalog: %s", err)
+		return nil, err
+	}
+
+	err = vaultClient.DeregisterPlugin(kind, pluginName, "")
+	if err != nil {
+		check.Errorf("Error removing unversioned entry from Vault plugin catalog: %s", err)
+		return nil, err
+	}
+
+	check.Successf("Removed unversioned entry for %s from Vault plugin catalog, as it has been migrated to versioned entries", pluginName)
+	return plugin, nil
+}
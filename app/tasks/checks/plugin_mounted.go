@@ -12,10 +12,11 @@ import (
 func InstallPluginMount(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
-	pluginName, pluginMountPath string,
+	kind api.PluginKind,
+	pluginName, pluginMountPath, pluginVersion string,
 ) error {
 	check := reportSection.AddCheck("Mounting plugin...")
-	err := vaultClient.MountPlugin(pluginName, pluginMountPath)
+	err := vaultClient.MountPlugin(kind, pluginName, pluginMountPath, pluginVersion)
 	if err != nil {
 		check.Errorf("Error mounting plugin: %s", err)
 		return err
@@ -28,10 +29,11 @@ func InstallPluginMount(
 func VerifyPluginMount(
 	reportSection reporter.Section,
 	vaultClient api.VaultAPIClient,
+	kind api.PluginKind,
 	pluginName, pluginMountPath string,
 ) error {
 	pluginMountCheck := reportSection.AddCheck("Checking plugin is mounted...")
-	actualPluginName, err := vaultClient.GetMountPluginName(pluginMountPath)
+	actualPluginName, err := vaultClient.GetMountPluginName(kind, pluginMountPath)
 	if err != nil {
 		if errors.Is(err, vault.ErrPluginNotMounted) {
 			pluginMountCheck.Errorf("Plugin is not mounted at %s", pluginMountPath)
@@ -48,3 +50,21 @@ func VerifyPluginMount(
 	pluginMountCheck.Success("Plugin is mounted")
 	return nil
 }
+
+// TunePluginMountVersion changes the plugin version used by a mount. The plugin needs to be reloaded afterwards.
+func TunePluginMountVersion(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	kind api.PluginKind,
+	pluginMountPath, pluginVersion string,
+) error {
+	check := reportSection.AddCheck("Changing plugin version used by mount...")
+	err := vaultClient.TuneMountPluginVersion(kind, pluginMountPath, pluginVersion)
+	if err != nil {
+		check.Errorf("Error changing plugin version of mount %s: %s", pluginMountPath, err)
+		return err
+	}
+
+	check.Successf("Mount %s now uses plugin version %s", pluginMountPath, pluginVersion)
+	return nil
+}
@@ -2,6 +2,7 @@ package checks
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 
@@ -32,6 +33,46 @@ func InstallPluginOnServer(
 	return nil
 }
 
+// BackupPluginOnServer copies the plugin binary already at filepath to backupPath if it differs from pluginBytes, so
+// that the previous version isn't lost when it's overwritten and the plugin can be rolled back to it. The binaries are
+// compared by their SHA256, so that the previous one doesn't have to be read back over SSH.
+func BackupPluginOnServer(
+	reportSection reporter.Section,
+	sshClient ssh.VaultSSHClient,
+	filepath, backupPath string,
+	pluginBytes []byte,
+) (bool, error) {
+	check := reportSection.AddCheck("Checking for previous plugin binary...")
+	exists, err := sshClient.FileExists(filepath)
+	if err != nil {
+		check.Errorf("Error checking for previous plugin binary: %s", err)
+		return false, err
+	}
+	if !exists {
+		check.Success("No previous plugin binary to keep")
+		return false, nil
+	}
+
+	previousSHA, err := sshClient.FileSHA256(filepath)
+	if err != nil {
+		check.Errorf("Error getting the SHA256 of the previous plugin binary: %s", err)
+		return false, err
+	}
+	if previousSHA == fmt.Sprintf("%x", sha256.Sum256(pluginBytes)) {
+		check.Success("Plugin binary is already the right version")
+		return false, nil
+	}
+
+	err = sshClient.CopyFile(filepath, backupPath)
+	if err != nil {
+		check.Errorf("Error keeping a copy of the previous plugin binary: %s", err)
+		return false, err
+	}
+
+	check.Successf("Previous plugin binary kept at %s so that it can be rolled back to", backupPath)
+	return true, nil
+}
+
 func VerifyPluginOnServer(reportSection reporter.Section, sshClient ssh.VaultSSHClient, filepath string) error {
 	pluginExistsCheck := reportSection.AddCheck("Checking plugin binary exists...")
 	exists, err := sshClient.FileExists(filepath)
@@ -1,6 +1,8 @@
 package checks
 
 import (
+	"fmt"
+
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 )
@@ -8,12 +10,67 @@ import (
 func ReloadPlugin(reportSection reporter.Section, vaultClient api.VaultAPIClient, pluginName string) error {
 	pluginReloadCheck := reportSection.AddCheck("Reloading plugin...")
 
-	err := vaultClient.ReloadPlugin(pluginName)
+	nodeStatuses, err := vaultClient.ReloadPlugin(pluginName)
+	reportReloadStatuses(reportSection, pluginReloadCheck, nodeStatuses, err)
+
+	return err
+}
+
+// ReloadPluginMount is the same as ReloadPlugin, except that only the mount at pluginMountPath is reloaded
+func ReloadPluginMount(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	kind api.PluginKind,
+	pluginMountPath string,
+) error {
+	pluginReloadCheck := reportSection.AddCheck(fmt.Sprintf("Reloading plugin mounted at %s...", pluginMountPath))
+
+	nodeStatuses, err := vaultClient.ReloadMount(kind, pluginMountPath)
+	reportReloadStatuses(reportSection, pluginReloadCheck, nodeStatuses, err)
+
+	return err
+}
+
+// ReloadDatabasePlugin reloads the connections of the database secrets engine at pluginMountPath that use the database
+// plugin, as database plugins aren't reloaded by ReloadPlugin
+func ReloadDatabasePlugin(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	pluginMountPath, pluginName string,
+) error {
+	pluginReloadCheck := reportSection.AddCheck("Reloading database plugin connections...")
+
+	err := vaultClient.ReloadDatabasePlugin(pluginMountPath, pluginName)
 	if err != nil {
-		pluginReloadCheck.Errorf("Error reloading plugin: %s", err)
+		pluginReloadCheck.Errorf("Error reloading database plugin: %s", err)
 		return err
 	}
 
-	pluginReloadCheck.Success("Plugin reloaded")
+	pluginReloadCheck.Successf("Connections using database plugin %s reloaded", pluginName)
 	return nil
 }
+
+// reportReloadStatuses updates the check for a reload with its overall outcome, and adds a check for each node's status
+func reportReloadStatuses(
+	reportSection reporter.Section,
+	pluginReloadCheck reporter.Check,
+	nodeStatuses []api.PluginReloadStatus,
+	err error,
+) {
+	if err != nil {
+		pluginReloadCheck.Errorf("Error reloading plugin: %s", err)
+	} else if len(nodeStatuses) == 0 {
+		pluginReloadCheck.Success("Plugin reloaded")
+	} else {
+		pluginReloadCheck.Successf("Plugin reloaded on all %d nodes in the cluster", len(nodeStatuses))
+	}
+
+	for _, nodeStatus := range nodeStatuses {
+		nodeCheck := reportSection.AddCheck(fmt.Sprintf("Checking plugin reload on node %s...", nodeStatus.Node))
+		if nodeStatus.Error != "" {
+			nodeCheck.Errorf("Plugin failed to reload on node %s: %s", nodeStatus.Node, nodeStatus.Error)
+		} else {
+			nodeCheck.Successf("Plugin reloaded on node %s", nodeStatus.Node)
+		}
+	}
+}
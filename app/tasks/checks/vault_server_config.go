@@ -0,0 +1,41 @@
+package checks
+
+import (
+	"errors"
+
+	"github.com/opencredo/venafi-vault-wizard/app/vault/ssh"
+)
+
+var errNoSSHConnections = errors.New("no SSH connections configured")
+
+// VaultServerConfigFallback holds the alternative sources for the Vault server's config, which are used if it can't
+// be read from sys/config/state/sanitized, either because the token isn't privileged enough or the endpoint isn't
+// available, as is the case on HCP Vault
+type VaultServerConfigFallback struct {
+	// PluginDirectory is used as the plugin_directory if set, rather than looking over SSH
+	PluginDirectory string
+	// DisableMlock is used as the disable_mlock value if set, rather than looking over SSH
+	DisableMlock *bool
+	// SSHClients are used to read the Vault server config file if there is no override
+	SSHClients []ssh.VaultSSHClient
+	// VaultConfigPath is the path of the config file on the Vault servers, blank to look for it automatically
+	VaultConfigPath string
+}
+
+// getServerConfigOverSSH reads the Vault server's config file over SSH, using the first server that it can be read from
+func (f *VaultServerConfigFallback) getServerConfigOverSSH() (*ssh.VaultServerConfig, error) {
+	if len(f.SSHClients) == 0 {
+		return nil, errNoSSHConnections
+	}
+
+	var err error
+	for _, sshClient := range f.SSHClients {
+		var serverConfig *ssh.VaultServerConfig
+		serverConfig, err = sshClient.GetVaultServerConfig(f.VaultConfigPath)
+		if err == nil {
+			return serverConfig, nil
+		}
+	}
+
+	return nil, err
+}
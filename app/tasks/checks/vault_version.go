@@ -0,0 +1,43 @@
+package checks
+
+import (
+	"fmt"
+
+	goversion "github.com/hashicorp/go-version"
+
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// VerifyVaultVersion checks that the Vault server is at least minimumVersion, for features that need newer versions
+// of Vault, with feature describing what needs it
+func VerifyVaultVersion(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	minimumVersion, feature string,
+) error {
+	check := reportSection.AddCheck("Checking Vault server version...")
+
+	serverVersion, err := vaultClient.GetVaultVersion()
+	if err != nil {
+		check.Errorf("Can't determine the Vault server version: %s", err)
+		return err
+	}
+
+	actual, err := goversion.NewVersion(serverVersion)
+	if err != nil {
+		check.Errorf("Can't parse the Vault server version %s: %s", serverVersion, err)
+		return err
+	}
+
+	// Only compare the numeric part of the version, so that release candidates count as that release
+	segments := actual.Segments()
+	core, _ := goversion.NewVersion(fmt.Sprintf("%d.%d.%d", segments[0], segments[1], segments[2]))
+	if core.LessThan(goversion.Must(goversion.NewVersion(minimumVersion))) {
+		check.Errorf("Vault %s is running, but %s requires Vault %s or later", serverVersion, feature, minimumVersion)
+		return fmt.Errorf("vault version %s is too old for %s", serverVersion, feature)
+	}
+
+	check.Successf("Vault %s supports %s", serverVersion, feature)
+	return nil
+}
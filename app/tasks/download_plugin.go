@@ -13,7 +13,8 @@ type DownloadPluginInput struct {
 }
 
 // DownloadPlugin gets the plugin's download URL from its Impl.GetDownloadURL(), then downloads and unzips it, returning
-// the plugin binary itself as a byte slice, and the SHA as a string
+// the plugin binary itself as a byte slice, and the SHA as a string. If the plugin is a ChecksummedPlugin, the download
+// is also checked against its checksums.
 func DownloadPlugin(i *DownloadPluginInput) ([]byte, string, error) {
 	pluginDownloadSection := i.Reporter.AddSection("Downloading plugin")
 
@@ -25,7 +26,16 @@ func DownloadPlugin(i *DownloadPluginInput) ([]byte, string, error) {
 		return nil, "", err
 	}
 
-	pluginBytes, sha, err := i.Downloader.DownloadPluginAndUnzip(pluginURL)
+	var checksumsURL string
+	if checksummedPlugin, ok := i.Plugin.Impl.(plugins.ChecksummedPlugin); ok {
+		checksumsURL, err = checksummedPlugin.GetChecksumsURL()
+		if err != nil {
+			downloadCheck.Errorf("Error getting plugin checksums URL: %s", err)
+			return nil, "", err
+		}
+	}
+
+	pluginBytes, sha, err := i.Downloader.DownloadPluginWithChecksums(pluginURL, checksumsURL)
 	if err != nil {
 		downloadCheck.Errorf("Could not download plugin from %s: %s", pluginURL, err)
 		return nil, "", err
@@ -3,6 +3,9 @@ package tasks
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins"
 	"github.com/opencredo/venafi-vault-wizard/app/reporter"
@@ -16,6 +19,8 @@ type EnablePluginInput struct {
 	Reporter    reporter.Report
 	Plugin      plugins.PluginConfig
 	SHA         string
+	// Upgrade is optional, and if given records the catalog entry that was replaced so that it can be rolled back
+	Upgrade *PluginUpgrade
 }
 
 func EnablePlugin(i *EnablePluginInput) error {
@@ -25,7 +30,19 @@ func EnablePlugin(i *EnablePluginInput) error {
 
 	var pluginNeverInstalled = false
 
-	pluginInfo, err := i.VaultClient.GetPlugin(i.Plugin.GetCatalogName())
+	// The plugin's semantic version is only registered when using the versioned catalog, as on Vault 1.12+ a versioned
+	// registration is a separate catalog entry from the unversioned one that mounts use by default
+	registration := &api.PluginRegistration{
+		Kind:    i.Plugin.GetKind(),
+		Name:    i.Plugin.GetCatalogName(),
+		Command: i.Plugin.GetFileName(),
+		SHA256:  i.SHA,
+		Args:    i.Plugin.Args,
+		Env:     i.Plugin.GetEnv(),
+		Version: i.Plugin.GetCatalogVersion(),
+	}
+
+	pluginInfo, err := i.VaultClient.GetPlugin(registration.Kind, registration.Name, registration.Version)
 	if err != nil {
 		if !errors.Is(err, vault.ErrNotFound) {
 			pluginVersionCheck.Errorf("Error checking if plugin is present in catalog: %s", err)
@@ -35,39 +52,98 @@ func EnablePlugin(i *EnablePluginInput) error {
 		pluginVersionCheck.Success("Plugin not yet added to catalog")
 		pluginNeverInstalled = true
 	} else {
-		correctPluginVersionInstalled := (pluginInfo["command"] == i.Plugin.GetFileName()) &&
-			(pluginInfo["sha"] == i.SHA)
-		if correctPluginVersionInstalled {
+		differences := getRegistrationDifferences(pluginInfo, registration)
+		if len(differences) == 0 {
 			pluginVersionCheck.Success(
-				fmt.Sprintf("Version %s of plugin %s already in catalog", i.Plugin.Version, i.Plugin.GetCatalogName()),
+				fmt.Sprintf("Version %s of plugin %s already in catalog", i.Plugin.Version, registration.Name),
 			)
 			return nil
 		}
 
-		pluginVersionCheck.Successf("Plugin command in catalog is currently %s", pluginInfo["command"])
+		pluginVersionCheck.Successf(
+			"Plugin catalog entry needs updating, as its %s changed", strings.Join(differences, ", "),
+		)
+
+		if i.Upgrade != nil {
+			i.Upgrade.setPreviousRegistration(pluginInfo, i.Plugin)
+		}
 	}
 
-	err = checks.InstallPluginInCatalog(
-		enablePluginSection,
-		i.VaultClient,
-		i.Plugin.GetCatalogName(),
-		i.Plugin.GetFileName(),
-		i.SHA,
-	)
+	err = checks.InstallPluginInCatalog(enablePluginSection, i.VaultClient, registration)
 	if err != nil {
 		return err
 	}
 
 	if !pluginNeverInstalled {
-		err := checks.ReloadPlugin(enablePluginSection, i.VaultClient, i.Plugin.GetCatalogName())
+		err := reloadPlugin(enablePluginSection, i.VaultClient, i.Plugin, registration.Name)
 		if err != nil {
 			return err
 		}
 	}
 
 	enablePluginSection.Info(
-		fmt.Sprintf("Version %s of plugin %s installed in catalog\n", i.Plugin.Version, i.Plugin.GetCatalogName()),
+		fmt.Sprintf("Version %s of plugin %s installed in catalog\n", i.Plugin.Version, registration.Name),
 	)
 
 	return nil
 }
+
+// reloadPlugin reloads the backends using a plugin after its catalog entry has changed. Database plugins are reloaded
+// through the database secrets engine instead, as they aren't backends themselves.
+func reloadPlugin(
+	reportSection reporter.Section,
+	vaultClient api.VaultAPIClient,
+	plugin plugins.PluginConfig,
+	catalogName string,
+) error {
+	if plugin.GetKind() == api.PluginKindDatabase {
+		return checks.ReloadDatabasePlugin(reportSection, vaultClient, plugin.MountPath, catalogName)
+	}
+
+	return checks.ReloadPlugin(reportSection, vaultClient, catalogName)
+}
+
+// getRegistrationDifferences compares the catalog entry currently in Vault with the one that should be registered,
+// and returns the names of any fields that differ. As Vault doesn't usually return a plugin's environment variables,
+// an entry that should have some but whose env wasn't returned is always treated as different, so that changes to them
+// are still registered.
+func getRegistrationDifferences(current, wanted *api.PluginRegistration) []string {
+	var differences []string
+	if current.Command != wanted.Command {
+		differences = append(differences, "command")
+	}
+	if current.SHA256 != wanted.SHA256 {
+		differences = append(differences, "SHA256")
+	}
+	if !reflect.DeepEqual(normaliseList(current.Args), normaliseList(wanted.Args)) {
+		differences = append(differences, "args")
+	}
+	if current.Env == nil {
+		if len(wanted.Env) != 0 {
+			differences = append(differences, "env (which Vault doesn't return)")
+		}
+	} else if !reflect.DeepEqual(normaliseEnv(current.Env), normaliseEnv(wanted.Env)) {
+		differences = append(differences, "env")
+	}
+	if current.Version != wanted.Version {
+		differences = append(differences, "version")
+	}
+
+	return differences
+}
+
+// normaliseList treats empty and nil lists as equal, so that they can be compared with reflect.DeepEqual
+func normaliseList(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+
+	return list
+}
+
+// normaliseEnv sorts a copy of a list of environment variables, as Vault doesn't necessarily preserve their order
+func normaliseEnv(env []string) []string {
+	sorted := append([]string(nil), env...)
+	sort.Strings(sorted)
+	return normaliseList(sorted)
+}
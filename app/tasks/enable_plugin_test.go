@@ -7,6 +7,7 @@ import (
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins"
 	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 	mockPlugin "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins"
 	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
 	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
@@ -35,11 +36,15 @@ func TestEnablePlugin_first_install(t *testing.T) {
 	var sha = "shashashasha"
 
 	// Check for registered plugin, it isn't in catalog for this mount point
-	vaultAPIClient.On("GetPlugin", pluginMock.GetCatalogName()).
+	vaultAPIClient.On("GetPlugin", api.PluginKindSecret, pluginMock.GetCatalogName(), "").
 		Return(nil, vault.ErrNotFound)
 	// Should try to register it
-	vaultAPIClient.On("RegisterPlugin", pluginMock.GetCatalogName(), pluginMock.GetFileName(), sha).
-		Return(nil)
+	vaultAPIClient.On("RegisterPlugin", &api.PluginRegistration{
+		Kind:    api.PluginKindSecret,
+		Name:    pluginMock.GetCatalogName(),
+		Command: pluginMock.GetFileName(),
+		SHA256:  sha,
+	}).Return(nil)
 
 	err := EnablePlugin(&EnablePluginInput{
 		VaultClient: vaultAPIClient,
@@ -73,11 +78,12 @@ func TestEnablePlugin_already_installed_correct_version(t *testing.T) {
 	var sha = "shashashasha"
 
 	// Check for registered plugin, it's already there
-	vaultAPIClient.On("GetPlugin", pluginMock.GetCatalogName()).
+	vaultAPIClient.On("GetPlugin", api.PluginKindSecret, pluginMock.GetCatalogName(), "").
 		Return(
-			map[string]interface{}{
-				"command": pluginMock.GetFileName(),
-				"sha":     sha,
+			&api.PluginRegistration{
+				Name:    pluginMock.GetCatalogName(),
+				Command: pluginMock.GetFileName(),
+				SHA256:  sha,
 			},
 			nil,
 		)
@@ -114,25 +120,224 @@ func TestEnablePlugin_already_installed_wrong_version(t *testing.T) {
 	var sha = "shashashasha"
 
 	// Check for registered plugin, it isn't in catalog for this mount point
-	vaultAPIClient.On("GetPlugin", pluginMock.GetCatalogName()).
+	vaultAPIClient.On("GetPlugin", api.PluginKindSecret, pluginMock.GetCatalogName(), "").
 		Return(
-			map[string]interface{}{
-				"command": pluginMock.Type + "_v0.8.3",
-				"sha":     "wrongsha",
+			&api.PluginRegistration{
+				Name:    pluginMock.GetCatalogName(),
+				Command: pluginMock.Type + "_v0.8.3",
+				SHA256:  "wrongsha",
 			},
 			nil,
 		)
 	// Should try to register it
-	vaultAPIClient.On("RegisterPlugin", pluginMock.GetCatalogName(), pluginMock.GetFileName(), sha).
-		Return(nil)
+	vaultAPIClient.On("RegisterPlugin", &api.PluginRegistration{
+		Kind:    api.PluginKindSecret,
+		Name:    pluginMock.GetCatalogName(),
+		Command: pluginMock.GetFileName(),
+		SHA256:  sha,
+	}).Return(nil)
 	// Then should try to reload it as it's probably already in use
-	vaultAPIClient.On("ReloadPlugin", pluginMock.GetCatalogName()).Return(nil)
+	vaultAPIClient.On("ReloadPlugin", pluginMock.GetCatalogName()).Return(nil, nil)
 
+	upgrade := new(PluginUpgrade)
 	err := EnablePlugin(&EnablePluginInput{
 		VaultClient: vaultAPIClient,
 		Reporter:    report,
 		Plugin:      pluginMock,
 		SHA:         sha,
+		Upgrade:     upgrade,
 	})
 	require.NoError(t, err)
+
+	// The replaced entry should be recorded so that it can be rolled back to
+	require.True(t, upgrade.IsUpgrade())
+	require.Equal(t, pluginMock.Type+"_v0.8.3", upgrade.PreviousRegistration.Command)
+	require.Equal(t, "wrongsha", upgrade.PreviousRegistration.SHA256)
+}
+
+func TestEnablePlugin_already_installed_args_changed(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:      "venafi-pki-monitor",
+		MountPath: "venafi-pki",
+		Version:   "v0.9.0",
+		Args:      []string{"-tls-skip-verify"},
+		Env: map[string]string{
+			"VAULT_API_ADDR": "https://vault:8200",
+			"HTTPS_PROXY":    "http://proxy:3128",
+		},
+		Impl: pluginImpl,
+	}
+	var sha = "shashashasha"
+
+	// Same binary is registered, but without the args. Vault doesn't return env so that can't be compared.
+	vaultAPIClient.On("GetPlugin", api.PluginKindSecret, pluginMock.GetCatalogName(), "").
+		Return(
+			&api.PluginRegistration{
+				Name:    pluginMock.GetCatalogName(),
+				Command: pluginMock.GetFileName(),
+				SHA256:  sha,
+				Args:    []string{},
+			},
+			nil,
+		)
+	// Should register it again with the args and env, sorted by key
+	vaultAPIClient.On("RegisterPlugin", &api.PluginRegistration{
+		Kind:    api.PluginKindSecret,
+		Name:    pluginMock.GetCatalogName(),
+		Command: pluginMock.GetFileName(),
+		SHA256:  sha,
+		Args:    []string{"-tls-skip-verify"},
+		Env:     []string{"HTTPS_PROXY=http://proxy:3128", "VAULT_API_ADDR=https://vault:8200"},
+	}).Return(nil)
+	vaultAPIClient.On("ReloadPlugin", pluginMock.GetCatalogName()).Return(nil, nil)
+
+	err := EnablePlugin(&EnablePluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+		SHA:         sha,
+	})
+	require.NoError(t, err)
+}
+
+func TestEnablePlugin_versioned_catalog_new_version(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:             "venafi-pki-monitor",
+		MountPath:        "venafi-pki",
+		Version:          "v0.9.1",
+		VersionedCatalog: true,
+		Impl:             pluginImpl,
+	}
+	var sha = "shashashasha"
+
+	// The catalog name doesn't include the mount path, and this version isn't registered yet
+	vaultAPIClient.On("GetPlugin", api.PluginKindSecret, "venafi-pki-monitor", "v0.9.1").
+		Return(nil, vault.ErrNotFound)
+	// Should register it with the version, and no reload is needed as no mounts can be using it yet
+	vaultAPIClient.On("RegisterPlugin", &api.PluginRegistration{
+		Kind:    api.PluginKindSecret,
+		Name:    "venafi-pki-monitor",
+		Command: pluginMock.GetFileName(),
+		SHA256:  sha,
+		Version: "v0.9.1",
+	}).Return(nil)
+
+	err := EnablePlugin(&EnablePluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+		SHA:         sha,
+	})
+	require.NoError(t, err)
+}
+
+func TestEnablePlugin_database_plugin_upgrade(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:      "database-plugin",
+		MountPath: "database",
+		Version:   "v0.9.0",
+		Kind:      api.PluginKindDatabase,
+		Impl:      pluginImpl,
+	}
+	var sha = "shashashasha"
+
+	vaultAPIClient.On("GetPlugin", api.PluginKindDatabase, pluginMock.GetCatalogName(), "").
+		Return(
+			&api.PluginRegistration{
+				Kind:    api.PluginKindDatabase,
+				Name:    pluginMock.GetCatalogName(),
+				Command: pluginMock.Type + "_v0.8.3",
+				SHA256:  "wrongsha",
+			},
+			nil,
+		)
+	vaultAPIClient.On("RegisterPlugin", &api.PluginRegistration{
+		Kind:    api.PluginKindDatabase,
+		Name:    pluginMock.GetCatalogName(),
+		Command: pluginMock.GetFileName(),
+		SHA256:  sha,
+	}).Return(nil)
+	// Database plugins are reloaded through the database secrets engine rather than sys/plugins/reload/backend
+	vaultAPIClient.On("ReloadDatabasePlugin", pluginMock.MountPath, pluginMock.GetCatalogName()).Return(nil)
+
+	err := EnablePlugin(&EnablePluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+		SHA:         sha,
+	})
+	require.NoError(t, err)
+}
+
+func TestGetRegistrationDifferences_env(t *testing.T) {
+	env := []string{"HTTPS_PROXY=http://proxy:3128", "VAULT_API_ADDR=https://vault:8200"}
+
+	tests := map[string]struct {
+		currentEnv      []string
+		wantedEnv       []string
+		wantDifferences []string
+	}{
+		"no env": {},
+		"env not returned by Vault": {
+			wantedEnv:       env,
+			wantDifferences: []string{"env (which Vault doesn't return)"},
+		},
+		"env returned in a different order": {
+			currentEnv: []string{env[1], env[0]},
+			wantedEnv:  env,
+		},
+		"env changed": {
+			currentEnv:      []string{"HTTPS_PROXY=http://old-proxy:3128"},
+			wantedEnv:       env,
+			wantDifferences: []string{"env"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			differences := getRegistrationDifferences(
+				&api.PluginRegistration{Command: "plugin", SHA256: "sha", Env: tt.currentEnv},
+				&api.PluginRegistration{Command: "plugin", SHA256: "sha", Env: tt.wantedEnv},
+			)
+			require.Equal(t, tt.wantDifferences, differences)
+		})
+	}
 }
@@ -14,21 +14,8 @@ func GetClients(cfg *config.VaultConfig, report reporter.Report) ([]ssh.VaultSSH
 	checkConnectionSection := report.AddSection("Checking connection to Vault")
 	check := checkConnectionSection.AddCheck("Checking Vault connection parameters...")
 
-	vaultClient, err := api.NewClient(
-		&api.Config{
-			APIAddress: cfg.VaultAddress,
-			Token:      cfg.VaultToken,
-		},
-		lib.NewVaultAPI(),
-	)
+	vaultClient, err := newVaultAPIClient(cfg, check)
 	if err != nil {
-		check.Errorf("Error setting the Vault address for the Vault API client: %s", err)
-		return nil, nil, nil, err
-	}
-
-	_, err = vaultClient.GetVaultConfig()
-	if err != nil {
-		check.Errorf("Error connecting to Vault API at %s and reading config: %s", cfg.VaultAddress, err)
 		return nil, nil, nil, err
 	}
 
@@ -60,3 +47,41 @@ func GetClients(cfg *config.VaultConfig, report reporter.Report) ([]ssh.VaultSSH
 
 	return sshClients, vaultClient, closeFunc, nil
 }
+
+// GetVaultAPIClient connects to Vault's API only, for commands that don't need to access the Vault servers over SSH
+func GetVaultAPIClient(cfg *config.VaultConfig, report reporter.Report) (api.VaultAPIClient, error) {
+	checkConnectionSection := report.AddSection("Checking connection to Vault")
+	check := checkConnectionSection.AddCheck("Checking Vault connection parameters...")
+
+	vaultClient, err := newVaultAPIClient(cfg, check)
+	if err != nil {
+		return nil, err
+	}
+
+	check.Success("Connected to Vault via its API")
+
+	return vaultClient, nil
+}
+
+func newVaultAPIClient(cfg *config.VaultConfig, check reporter.Check) (api.VaultAPIClient, error) {
+	vaultClient, err := api.NewClient(
+		&api.Config{
+			APIAddress:          cfg.VaultAddress,
+			Token:               cfg.VaultToken,
+			PluginReloadTimeout: cfg.GetPluginReloadTimeout(),
+		},
+		lib.NewVaultAPI(),
+	)
+	if err != nil {
+		check.Errorf("Error setting the Vault address for the Vault API client: %s", err)
+		return nil, err
+	}
+
+	err = vaultClient.CheckConnection()
+	if err != nil {
+		check.Errorf("Error connecting to Vault API at %s: %s", cfg.VaultAddress, err)
+		return nil, err
+	}
+
+	return vaultClient, nil
+}
@@ -18,6 +18,10 @@ type InstallPluginToServersInput struct {
 	MlockDisabled bool
 }
 
+// previousPluginSuffix is added to the filename of the previous version of a plugin when it is kept, for plugins whose
+// filename doesn't include their version
+const previousPluginSuffix = ".previous"
+
 // InstallPluginToServers connects to the Vault servers over SSH and ensures the correct version of the plugin is
 // present in the plugin_dir
 func InstallPluginToServers(input *InstallPluginToServersInput) error {
@@ -39,6 +43,25 @@ func InstallPluginToServers(input *InstallPluginToServersInput) error {
 	pluginPath := fmt.Sprintf("%s/%s", input.PluginDir, input.Plugin.GetFileName())
 
 	for i, sshClient := range input.SSHClients {
+		// The filename normally includes the version, so upgrades don't overwrite the previous binary. If it has been
+		// overridden though, a copy needs to be kept so that the upgrade can be rolled back.
+		if input.Plugin.Filename != "" {
+			backupPath := pluginPath + previousPluginSuffix
+			backedUp, err := checks.BackupPluginOnServer(
+				checkFilesystemSection, sshClient, pluginPath, backupPath, input.PluginFile,
+			)
+			if err != nil {
+				return err
+			}
+
+			if backedUp && !input.MlockDisabled {
+				err := checks.InstallPluginMlock(checkFilesystemSection, sshClient, backupPath)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 		err := checks.InstallPluginOnServer(checkFilesystemSection, sshClient, pluginPath, input.PluginFile)
 		if err != nil {
 			return err
@@ -1,6 +1,7 @@
 package tasks
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"testing"
 
@@ -53,6 +54,90 @@ func TestInstallPlugin(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestInstallPlugin_overridden_filename_keeps_previous(t *testing.T) {
+	vaultSSHClient := new(mockSSH.VaultSSHClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultSSHClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:      "venafi-pki-backend",
+		Version:   "v0.9.1",
+		MountPath: "pki",
+		Filename:  "venafi-pki-backend",
+		Impl:      pluginImpl,
+	}
+	var pluginDir = "/etc/plugins"
+	var pluginPath = pluginDir + "/venafi-pki-backend"
+	var backupPath = pluginPath + ".previous"
+
+	// The previous version is there and differs, so should be copied before being overwritten
+	vaultSSHClient.On("FileExists", pluginPath).Return(true, nil)
+	vaultSSHClient.On("FileSHA256", pluginPath).Return(fmt.Sprintf("%x", sha256.Sum256([]byte("old plugin"))), nil)
+	vaultSSHClient.On("CopyFile", pluginPath, backupPath).Return(nil)
+	vaultSSHClient.On("AddIPCLockCapabilityToFile", backupPath).Return(nil)
+	vaultSSHClient.On("WriteFile", mock.Anything, pluginPath).Return(nil)
+	vaultSSHClient.On("AddIPCLockCapabilityToFile", pluginPath).Return(nil)
+
+	err := InstallPluginToServers(&InstallPluginToServersInput{
+		SSHClients:    []ssh.VaultSSHClient{vaultSSHClient},
+		Reporter:      report,
+		Plugin:        pluginMock,
+		PluginFile:    []byte("new plugin"),
+		PluginDir:     pluginDir,
+		MlockDisabled: false,
+	})
+	require.NoError(t, err)
+}
+
+func TestInstallPlugin_overridden_filename_unchanged(t *testing.T) {
+	vaultSSHClient := new(mockSSH.VaultSSHClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultSSHClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:      "venafi-pki-backend",
+		Version:   "v0.9.1",
+		MountPath: "pki",
+		Filename:  "venafi-pki-backend",
+		Impl:      pluginImpl,
+	}
+	var pluginPath = "/etc/plugins/venafi-pki-backend"
+	var pluginFile = []byte("plugin")
+
+	// The binary already there has the same SHA256, so no copy of it should be kept
+	vaultSSHClient.On("FileExists", pluginPath).Return(true, nil)
+	vaultSSHClient.On("FileSHA256", pluginPath).Return(fmt.Sprintf("%x", sha256.Sum256(pluginFile)), nil)
+	vaultSSHClient.On("WriteFile", mock.Anything, pluginPath).Return(nil)
+
+	err := InstallPluginToServers(&InstallPluginToServersInput{
+		SSHClients:    []ssh.VaultSSHClient{vaultSSHClient},
+		Reporter:      report,
+		Plugin:        pluginMock,
+		PluginFile:    pluginFile,
+		PluginDir:     "/etc/plugins",
+		MlockDisabled: true,
+	})
+	require.NoError(t, err)
+}
+
 func reportExpectations(report *mockReport.Report, section *mockReport.Section, check *mockReport.Check) {
 	report.On("AddSection", mock.AnythingOfType("string")).Return(section)
 	section.On("AddCheck", mock.AnythingOfType("string")).Return(check)
@@ -15,6 +15,8 @@ type MountPluginInput struct {
 	VaultClient api.VaultAPIClient
 	Reporter    reporter.Report
 	Plugin      plugins.PluginConfig
+	// Upgrade is optional, and if given records any changes made to an existing mount so that they can be rolled back
+	Upgrade *PluginUpgrade
 }
 
 func MountPlugin(i *MountPluginInput) error {
@@ -22,7 +24,7 @@ func MountPlugin(i *MountPluginInput) error {
 
 	pluginMountCheck := mountPluginSection.AddCheck("Checking if plugin is already mounted...")
 
-	pluginName, err := i.VaultClient.GetMountPluginName(i.Plugin.MountPath)
+	pluginName, err := i.VaultClient.GetMountPluginName(i.Plugin.GetMountKind(), i.Plugin.MountPath)
 	if err != nil {
 		if !errors.Is(err, vault.ErrPluginNotMounted) {
 			pluginMountCheck.Errorf("Error checking plugin mount: %s", err)
@@ -32,22 +34,97 @@ func MountPlugin(i *MountPluginInput) error {
 		err = checks.InstallPluginMount(
 			mountPluginSection,
 			i.VaultClient,
-			i.Plugin.GetCatalogName(),
+			i.Plugin.GetMountKind(),
+			i.Plugin.GetMountType(),
 			i.Plugin.MountPath,
+			i.Plugin.GetCatalogVersion(),
 		)
 		if err != nil {
 			return err
 		}
 
-		mountPluginSection.Info(fmt.Sprintf("Plugin %s mounted at %s/\n", i.Plugin.GetCatalogName(), i.Plugin.MountPath))
+		mountPluginSection.Info(fmt.Sprintf("Plugin %s mounted at %s/\n", i.Plugin.GetMountType(), i.Plugin.MountPath))
 		return nil
 	}
 
-	if pluginName != i.Plugin.GetCatalogName() {
+	if pluginName != i.Plugin.GetMountType() {
 		pluginMountCheck.Errorf("Mount path %s is using plugin %s", i.Plugin.MountPath, pluginName)
 		return vault.ErrMountPathInUse
 	}
 
-	pluginMountCheck.Success("Plugin already mounted")
+	// The version only needs checking when using the versioned catalog, which database plugins don't use
+	if i.Plugin.GetCatalogVersion() == "" {
+		pluginMountCheck.Success("Plugin already mounted")
+		return nil
+	}
+
+	return upgradePluginMount(i, mountPluginSection, pluginMountCheck)
+}
+
+// upgradePluginMount makes sure that an existing mount is running the right version of the plugin when using the
+// versioned plugin catalog, and completes the migration from an unversioned catalog entry if necessary
+func upgradePluginMount(i *MountPluginInput, mountPluginSection reporter.Section, pluginMountCheck reporter.Check) error {
+	wantedVersion := i.Plugin.GetCatalogVersion()
+
+	version, runningVersion, err := i.VaultClient.GetMountPluginVersion(i.Plugin.GetMountKind(), i.Plugin.MountPath)
+	if err != nil {
+		pluginMountCheck.Errorf("Error checking plugin version of mount: %s", err)
+		return err
+	}
+
+	if version == wantedVersion && runningVersion == wantedVersion {
+		pluginMountCheck.Successf("Plugin already mounted using version %s", wantedVersion)
+	} else {
+		pluginMountCheck.Successf(
+			"Plugin already mounted, but using version %s rather than %s",
+			describePluginVersion(runningVersion),
+			wantedVersion,
+		)
+
+		if version != wantedVersion {
+			if i.Upgrade != nil {
+				i.Upgrade.MountVersionChanged = true
+				i.Upgrade.PreviousMountVersion = version
+			}
+
+			err = checks.TunePluginMountVersion(
+				mountPluginSection, i.VaultClient, i.Plugin.GetMountKind(), i.Plugin.MountPath, wantedVersion,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = checks.ReloadPluginMount(mountPluginSection, i.VaultClient, i.Plugin.GetMountKind(), i.Plugin.MountPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if i.Plugin.LegacyCatalogName {
+		removed, err := checks.RemoveUnversionedPluginFromCatalog(
+			mountPluginSection, i.VaultClient, i.Plugin.GetKind(), i.Plugin.GetCatalogName(),
+		)
+		if err != nil {
+			return err
+		}
+
+		if removed != nil && i.Upgrade != nil {
+			i.Upgrade.setPreviousRegistration(removed, i.Plugin)
+		}
+	}
+
+	mountPluginSection.Info(
+		fmt.Sprintf("Version %s of plugin %s mounted at %s/\n", wantedVersion, i.Plugin.GetCatalogName(), i.Plugin.MountPath),
+	)
 	return nil
 }
+
+// describePluginVersion makes a blank version returned by Vault more readable in messages
+func describePluginVersion(version string) string {
+	if version == "" {
+		return "the unversioned catalog entry"
+	}
+
+	return version
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins"
 	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 	mockPlugin "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins"
 	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
 	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
@@ -34,7 +35,7 @@ func TestMountPlugin_already_mounted_correctly(t *testing.T) {
 		Impl:      pluginImpl,
 	}
 
-	vaultAPIClient.On("GetMountPluginName", pluginMock.MountPath).Return(pluginMock.GetCatalogName(), nil)
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, pluginMock.MountPath).Return(pluginMock.GetCatalogName(), nil)
 
 	err := MountPlugin(&MountPluginInput{
 		VaultClient: vaultAPIClient,
@@ -66,7 +67,7 @@ func TestMountPlugin_already_mounted_wrong_plugin(t *testing.T) {
 		Impl:      pluginImpl,
 	}
 
-	vaultAPIClient.On("GetMountPluginName", pluginMock.MountPath).Return("some wrong plugin", nil)
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, pluginMock.MountPath).Return("some wrong plugin", nil)
 
 	err := MountPlugin(&MountPluginInput{
 		VaultClient: vaultAPIClient,
@@ -97,9 +98,9 @@ func TestMountPlugin_first_install(t *testing.T) {
 		Impl:      pluginImpl,
 	}
 
-	vaultAPIClient.On("GetMountPluginName", pluginMock.MountPath).
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, pluginMock.MountPath).
 		Return("", vault.ErrPluginNotMounted)
-	vaultAPIClient.On("MountPlugin", pluginMock.GetCatalogName(), pluginMock.MountPath).
+	vaultAPIClient.On("MountPlugin", api.PluginKindSecret, pluginMock.GetCatalogName(), pluginMock.MountPath, "").
 		Return(nil)
 
 	err := MountPlugin(&MountPluginInput{
@@ -109,3 +110,183 @@ func TestMountPlugin_first_install(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+func TestMountPlugin_versioned_catalog_first_install(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:             "venafi-pki-monitor",
+		MountPath:        "venafi-pki",
+		Version:          "v0.9.0",
+		VersionedCatalog: true,
+		Impl:             pluginImpl,
+	}
+
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, pluginMock.MountPath).
+		Return("", vault.ErrPluginNotMounted)
+	vaultAPIClient.On("MountPlugin", api.PluginKindSecret, "venafi-pki-monitor", pluginMock.MountPath, "v0.9.0").
+		Return(nil)
+
+	err := MountPlugin(&MountPluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+	})
+	require.NoError(t, err)
+}
+
+func TestMountPlugin_versioned_catalog_upgrade(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:             "venafi-pki-monitor",
+		MountPath:        "venafi-pki",
+		Version:          "v0.9.1",
+		VersionedCatalog: true,
+		Impl:             pluginImpl,
+	}
+
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, pluginMock.MountPath).Return("venafi-pki-monitor", nil)
+	vaultAPIClient.On("GetMountPluginVersion", api.PluginKindSecret, pluginMock.MountPath).Return("v0.9.0", "v0.9.0", nil)
+	// Should switch the mount to the new version and reload it
+	vaultAPIClient.On("TuneMountPluginVersion", api.PluginKindSecret, pluginMock.MountPath, "v0.9.1").Return(nil)
+	vaultAPIClient.On("ReloadMount", api.PluginKindSecret, pluginMock.MountPath).Return(nil, nil)
+
+	err := MountPlugin(&MountPluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+	})
+	require.NoError(t, err)
+}
+
+func TestMountPlugin_versioned_catalog_migrate_legacy_name(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:              "venafi-pki-monitor",
+		MountPath:         "venafi-pki",
+		Version:           "v0.9.0",
+		VersionedCatalog:  true,
+		LegacyCatalogName: true,
+		Impl:              pluginImpl,
+	}
+
+	// The mount is using the unversioned per-mount catalog entry
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, pluginMock.MountPath).Return("venafi-pki-monitor-venafi-pki", nil)
+	vaultAPIClient.On("GetMountPluginVersion", api.PluginKindSecret, pluginMock.MountPath).Return("", "", nil)
+	// Should pin it to the versioned entry registered under the same name, reload, then remove the unversioned entry
+	vaultAPIClient.On("TuneMountPluginVersion", api.PluginKindSecret, pluginMock.MountPath, "v0.9.0").Return(nil)
+	vaultAPIClient.On("ReloadMount", api.PluginKindSecret, pluginMock.MountPath).Return(nil, nil)
+	vaultAPIClient.On("GetPlugin", api.PluginKindSecret, "venafi-pki-monitor-venafi-pki", "").Return(&api.PluginRegistration{}, nil)
+	vaultAPIClient.On("DeregisterPlugin", api.PluginKindSecret, "venafi-pki-monitor-venafi-pki", "").Return(nil)
+
+	err := MountPlugin(&MountPluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+	})
+	require.NoError(t, err)
+}
+
+func TestMountPlugin_database_plugin_first_install(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:             "database-plugin",
+		MountPath:        "database",
+		Version:          "v0.9.0",
+		VersionedCatalog: true,
+		Kind:             api.PluginKindDatabase,
+		Impl:             pluginImpl,
+	}
+
+	// Database plugins are used through the built-in database secrets engine, which isn't versioned
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, pluginMock.MountPath).
+		Return("", vault.ErrPluginNotMounted)
+	vaultAPIClient.On("MountPlugin", api.PluginKindSecret, plugins.DatabaseSecretsEngine, pluginMock.MountPath, "").
+		Return(nil)
+
+	err := MountPlugin(&MountPluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+	})
+	require.NoError(t, err)
+}
+
+func TestMountPlugin_auth_plugin_already_mounted(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	pluginImpl := new(mockPlugin.Plugin)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer pluginImpl.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:      "auth-plugin",
+		MountPath: "auth-path",
+		Version:   "v0.9.0",
+		Kind:      api.PluginKindAuth,
+		Impl:      pluginImpl,
+	}
+
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindAuth, pluginMock.MountPath).Return(pluginMock.GetCatalogName(), nil)
+
+	err := MountPlugin(&MountPluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+	})
+	require.NoError(t, err)
+}
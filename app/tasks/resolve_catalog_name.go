@@ -0,0 +1,53 @@
+package tasks
+
+import (
+	"errors"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+type ResolveCatalogNameInput struct {
+	VaultClient api.VaultAPIClient
+	Reporter    reporter.Report
+	Plugin      plugins.PluginConfig
+}
+
+// ResolveCatalogName works out whether a plugin using the versioned plugin catalog is already mounted using its
+// per-mount catalog name from before it was enabled. As Vault doesn't allow the plugin used by a mount to be changed,
+// such mounts are migrated by registering the versioned catalog entries under the per-mount name instead. It returns
+// the value that should be used for PluginConfig.LegacyCatalogName.
+func ResolveCatalogName(i *ResolveCatalogNameInput) (bool, error) {
+	// Database plugins don't use the versioned catalog, and aren't mounted under their catalog name anyway
+	if !i.Plugin.VersionedCatalog || i.Plugin.GetKind() == api.PluginKindDatabase {
+		return false, nil
+	}
+
+	resolveCatalogNameSection := i.Reporter.AddSection("Resolving plugin catalog name")
+	check := resolveCatalogNameSection.AddCheck("Checking which catalog name the plugin is mounted with...")
+
+	mountPluginName, err := i.VaultClient.GetMountPluginName(i.Plugin.GetMountKind(), i.Plugin.MountPath)
+	if err != nil {
+		if errors.Is(err, vault.ErrPluginNotMounted) {
+			check.Successf("Plugin not yet mounted, so will be registered as %s", i.Plugin.Type)
+			return false, nil
+		}
+
+		check.Errorf("Error checking plugin mount: %s", err)
+		return false, err
+	}
+
+	if mountPluginName == i.Plugin.GetPerMountCatalogName() {
+		check.Warningf(
+			"Plugin is mounted using the per-mount catalog name %s, which can't be changed without remounting, "+
+				"so versions of the plugin will be registered under that name",
+			mountPluginName,
+		)
+		return true, nil
+	}
+
+	check.Successf("Plugin is mounted using catalog name %s", mountPluginName)
+	return false, nil
+}
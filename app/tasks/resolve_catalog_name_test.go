@@ -0,0 +1,74 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestResolveCatalogName(t *testing.T) {
+	tests := map[string]struct {
+		versionedCatalog bool
+		mountPluginName  string
+		mountErr         error
+		wantLegacy       bool
+	}{
+		"versioned catalog not in use": {
+			versionedCatalog: false,
+		},
+		"not mounted yet": {
+			versionedCatalog: true,
+			mountErr:         vault.ErrPluginNotMounted,
+		},
+		"mounted with plugin type": {
+			versionedCatalog: true,
+			mountPluginName:  "venafi-pki-backend",
+		},
+		"mounted with per-mount catalog name": {
+			versionedCatalog: true,
+			mountPluginName:  "venafi-pki-backend-pki",
+			wantLegacy:       true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+			defer report.AssertExpectations(t)
+			defer section.AssertExpectations(t)
+			defer check.AssertExpectations(t)
+
+			plugin := plugins.PluginConfig{
+				Type:             "venafi-pki-backend",
+				MountPath:        "pki",
+				Version:          "v0.9.0",
+				VersionedCatalog: tc.versionedCatalog,
+			}
+
+			// Nothing should be checked or reported unless the versioned catalog is in use
+			if tc.versionedCatalog {
+				reportExpectations(report, section, check)
+				check.On("Warningf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+				vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, "pki").Return(tc.mountPluginName, tc.mountErr)
+			}
+
+			legacy, err := ResolveCatalogName(&ResolveCatalogNameInput{
+				VaultClient: vaultAPIClient,
+				Reporter:    report,
+				Plugin:      plugin,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantLegacy, legacy)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/tasks/checks"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// PluginUpgrade records what was replaced when upgrading a plugin, so that the upgrade can be rolled back if the new
+// version of the plugin doesn't work
+type PluginUpgrade struct {
+	// PreviousRegistration is the plugin catalog entry that was replaced, or removed when migrating to the versioned
+	// catalog
+	PreviousRegistration *api.PluginRegistration
+	// MountVersionChanged is whether the mount was tuned to use a different plugin version from the versioned catalog
+	MountVersionChanged bool
+	// PreviousMountVersion is the plugin version the mount used before, which is blank for the unversioned entry
+	PreviousMountVersion string
+}
+
+// IsUpgrade returns whether anything was changed that would need to be rolled back
+func (u *PluginUpgrade) IsUpgrade() bool {
+	return u.PreviousRegistration != nil || u.MountVersionChanged
+}
+
+// setPreviousRegistration records the catalog entry being replaced. Vault doesn't return a plugin's environment
+// variables, so the configured ones are assumed to be unchanged. If the plugin's filename is overridden, the previous
+// binary will have been kept alongside the new one by InstallPluginToServers, so the command is changed to point at it.
+func (u *PluginUpgrade) setPreviousRegistration(previous *api.PluginRegistration, plugin plugins.PluginConfig) {
+	registration := *previous
+	if registration.Env == nil {
+		registration.Env = plugin.GetEnv()
+	}
+	if plugin.Filename != "" && registration.Command == plugin.GetFileName() {
+		registration.Command += previousPluginSuffix
+	}
+
+	u.PreviousRegistration = &registration
+}
+
+type RollbackPluginInput struct {
+	VaultClient api.VaultAPIClient
+	Reporter    reporter.Report
+	Plugin      plugins.PluginConfig
+	Upgrade     *PluginUpgrade
+	// Cause is the error that caused the upgrade to be rolled back
+	Cause error
+}
+
+// RollbackPlugin puts back the plugin catalog entry and mount version that were in place before an upgrade, and
+// reloads the plugin so that the previous version is running again
+func RollbackPlugin(i *RollbackPluginInput) error {
+	if i.Upgrade == nil || !i.Upgrade.IsUpgrade() {
+		return nil
+	}
+
+	rollbackSection := i.Reporter.AddSection("Rolling back plugin upgrade")
+	rollbackCheck := rollbackSection.AddCheck("Checking what needs to be rolled back...")
+	rollbackCheck.Warningf(
+		"Upgrading plugin %s to version %s failed, so rolling back: %s",
+		i.Plugin.GetCatalogName(), i.Plugin.Version, i.Cause,
+	)
+
+	if i.Upgrade.PreviousRegistration != nil {
+		err := checks.InstallPluginInCatalog(rollbackSection, i.VaultClient, i.Upgrade.PreviousRegistration)
+		if err != nil {
+			return err
+		}
+	}
+
+	if i.Upgrade.MountVersionChanged {
+		err := checks.TunePluginMountVersion(
+			rollbackSection, i.VaultClient, i.Plugin.GetMountKind(), i.Plugin.MountPath, i.Upgrade.PreviousMountVersion,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = checks.ReloadPluginMount(rollbackSection, i.VaultClient, i.Plugin.GetMountKind(), i.Plugin.MountPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := reloadPlugin(rollbackSection, i.VaultClient, i.Plugin, i.Upgrade.PreviousRegistration.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	rollbackSection.Info(fmt.Sprintf("Plugin %s rolled back to its previous version\n", i.Plugin.GetCatalogName()))
+	return nil
+}
@@ -0,0 +1,130 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestRollbackPlugin_replaced_catalog_entry(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+	check.On("Warningf", mock.AnythingOfType("string"), mock.Anything)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:      "venafi-pki-backend",
+		MountPath: "pki",
+		Version:   "v0.9.1",
+	}
+	var previous = &api.PluginRegistration{
+		Name:    pluginMock.GetCatalogName(),
+		Command: "venafi-pki-backend_v0.9.0",
+		SHA256:  "oldsha",
+	}
+
+	// Should put back the previous catalog entry and reload the plugin
+	vaultAPIClient.On("RegisterPlugin", previous).Return(nil)
+	vaultAPIClient.On("ReloadPlugin", pluginMock.GetCatalogName()).Return(nil, nil)
+
+	err := RollbackPlugin(&RollbackPluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+		Upgrade:     &PluginUpgrade{PreviousRegistration: previous},
+		Cause:       errors.New("test certificate couldn't be issued"),
+	})
+	require.NoError(t, err)
+}
+
+func TestRollbackPlugin_versioned_mount(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	section := new(mockReport.Section)
+	check := new(mockReport.Check)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+	defer section.AssertExpectations(t)
+	defer check.AssertExpectations(t)
+
+	reportExpectations(report, section, check)
+	check.On("Warningf", mock.AnythingOfType("string"), mock.Anything)
+
+	var pluginMock = plugins.PluginConfig{
+		Type:             "venafi-pki-backend",
+		MountPath:        "pki",
+		Version:          "v0.9.1",
+		VersionedCatalog: true,
+	}
+
+	// Should tune the mount back to the previous version and reload it
+	vaultAPIClient.On("TuneMountPluginVersion", api.PluginKindSecret, "pki", "v0.9.0").Return(nil)
+	vaultAPIClient.On("ReloadMount", api.PluginKindSecret, "pki").Return(nil, nil)
+
+	err := RollbackPlugin(&RollbackPluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      pluginMock,
+		Upgrade: &PluginUpgrade{
+			MountVersionChanged:  true,
+			PreviousMountVersion: "v0.9.0",
+		},
+		Cause: errors.New("test certificate couldn't be issued"),
+	})
+	require.NoError(t, err)
+}
+
+func TestRollbackPlugin_nothing_to_roll_back(t *testing.T) {
+	vaultAPIClient := new(mockAPI.VaultAPIClient)
+	report := new(mockReport.Report)
+	defer vaultAPIClient.AssertExpectations(t)
+	defer report.AssertExpectations(t)
+
+	err := RollbackPlugin(&RollbackPluginInput{
+		VaultClient: vaultAPIClient,
+		Reporter:    report,
+		Plugin:      plugins.PluginConfig{Type: "venafi-pki-backend", MountPath: "pki", Version: "v0.9.0"},
+		Upgrade:     new(PluginUpgrade),
+		Cause:       errors.New("first install failed"),
+	})
+	require.NoError(t, err)
+}
+
+func TestPluginUpgrade_overridden_filename(t *testing.T) {
+	var pluginMock = plugins.PluginConfig{
+		Type:      "venafi-pki-backend",
+		MountPath: "pki",
+		Version:   "v0.9.1",
+		Filename:  "venafi-pki-backend",
+		Env:       map[string]string{"HTTPS_PROXY": "http://proxy:3128"},
+	}
+
+	upgrade := new(PluginUpgrade)
+	upgrade.setPreviousRegistration(&api.PluginRegistration{
+		Name:    pluginMock.GetCatalogName(),
+		Command: "venafi-pki-backend",
+		SHA256:  "oldsha",
+	}, pluginMock)
+
+	// The previous binary is kept with a suffix, and the env is assumed to be unchanged as Vault doesn't return it
+	require.Equal(t, &api.PluginRegistration{
+		Name:    pluginMock.GetCatalogName(),
+		Command: "venafi-pki-backend.previous",
+		SHA256:  "oldsha",
+		Env:     []string{"HTTPS_PROXY=http://proxy:3128"},
+	}, upgrade.PreviousRegistration)
+}
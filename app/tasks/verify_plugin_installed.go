@@ -46,12 +46,25 @@ func VerifyPluginInstalled(input *VerifyPluginInstalledInput) error {
 
 	pluginConfSection := input.Reporter.AddSection("Checking plugin configuration in Vault")
 
-	err := checks.VerifyPluginInCatalog(pluginConfSection, input.VaultClient, pluginName, pluginFileName)
+	err := checks.VerifyPluginInCatalog(
+		pluginConfSection,
+		input.VaultClient,
+		input.Plugin.GetKind(),
+		pluginName,
+		input.Plugin.GetCatalogVersion(),
+		pluginFileName,
+	)
 	if err != nil {
 		return err
 	}
 
-	err = checks.VerifyPluginMount(pluginConfSection, input.VaultClient, pluginName, input.Plugin.MountPath)
+	err = checks.VerifyPluginMount(
+		pluginConfSection,
+		input.VaultClient,
+		input.Plugin.GetMountKind(),
+		input.Plugin.GetMountType(),
+		input.Plugin.MountPath,
+	)
 	if err != nil {
 		return err
 	}
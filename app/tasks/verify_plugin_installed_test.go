@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/opencredo/venafi-vault-wizard/app/plugins"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/ssh"
 	mockPlugin "github.com/opencredo/venafi-vault-wizard/mocks/app/plugins"
 	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
@@ -40,13 +41,14 @@ func TestVerifyPluginInstalled(t *testing.T) {
 
 	vaultSSHClient.On("FileExists", pluginPath).Return(true, nil)
 	vaultSSHClient.On("IsIPCLockCapabilityOnFile", pluginPath).Return(true, nil)
-	vaultAPIClient.On("GetPlugin", pluginName).Return(
-		map[string]interface{}{
-			"command": pluginFileName,
+	vaultAPIClient.On("GetPlugin", api.PluginKindSecret, pluginName, "").Return(
+		&api.PluginRegistration{
+			Name:    pluginName,
+			Command: pluginFileName,
 		},
 		nil,
 	)
-	vaultAPIClient.On("GetMountPluginName", pluginMountPath).Return(pluginName, nil)
+	vaultAPIClient.On("GetMountPluginName", api.PluginKindSecret, pluginMountPath).Return(pluginName, nil)
 
 	err := VerifyPluginInstalled(&VerifyPluginInstalledInput{
 		VaultClient: vaultAPIClient,
@@ -0,0 +1,156 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/opencredo/venafi-vault-wizard/app/config"
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+)
+
+// hostLookupTimeout limits how long is spent resolving each hostname when matching cluster members to SSH hosts
+const hostLookupTimeout = 2 * time.Second
+
+type VerifySSHCoverageInput struct {
+	VaultClient api.VaultAPIClient
+	SSHConfig   []config.SSH
+	Reporter    reporter.Report
+	// AllowMissing reports cluster members without SSH access as a warning rather than an error
+	AllowMissing bool
+}
+
+// VerifySSHCoverage lists the members of the Vault cluster and checks that each of them corresponds to one of the ssh
+// blocks in the config. Otherwise the plugin won't be installed on some of the replicas, and will break when one of
+// them becomes the active node after a failover. If there are no ssh blocks at all then it is assumed the plugin is
+// installed by other means, so there is nothing to check.
+func VerifySSHCoverage(input *VerifySSHCoverageInput) error {
+	if len(input.SSHConfig) == 0 {
+		return nil
+	}
+
+	section := input.Reporter.AddSection("Checking SSH access to every Vault cluster member")
+	listCheck := section.AddCheck("Listing Vault cluster members...")
+
+	members, err := input.VaultClient.GetClusterMembers()
+	if err != nil {
+		listCheck.Warningf("Unable to list Vault cluster members, so can't check that every replica has an ssh block: %s", err)
+		return nil
+	}
+	listCheck.Successf("Found %d Vault cluster members", len(members))
+
+	sshHosts := make([]map[string]bool, len(input.SSHConfig))
+	for i, sshConfig := range input.SSHConfig {
+		sshHosts[i] = resolveHosts([]string{sshConfig.Hostname})
+	}
+	sshHostsUsed := make([]bool, len(input.SSHConfig))
+
+	var missing []string
+	for _, member := range members {
+		memberName := member.Name
+		if member.Leader {
+			memberName += " (active)"
+		}
+		check := section.AddCheck(fmt.Sprintf("Checking for SSH access to %s...", memberName))
+
+		memberHosts := resolveHosts(append([]string{member.Name}, hostsFromAddresses(member.Addresses)...))
+
+		sshIndex := -1
+		for i, hosts := range sshHosts {
+			if hostsOverlap(hosts, memberHosts) {
+				sshIndex = i
+				break
+			}
+		}
+
+		if sshIndex == -1 {
+			missing = append(missing, member.Name)
+			message := fmt.Sprintf(
+				"No ssh block for cluster member %s at %s, so the plugin won't be installed on it and will break if it becomes active",
+				memberName, strings.Join(member.Addresses, ", "),
+			)
+			if input.AllowMissing {
+				check.Warning(message)
+			} else {
+				check.Error(message)
+			}
+			continue
+		}
+
+		sshHostsUsed[sshIndex] = true
+		check.Successf("Cluster member %s will be accessed over SSH at %s", memberName, input.SSHConfig[sshIndex].Hostname)
+	}
+
+	for i, used := range sshHostsUsed {
+		if !used {
+			check := section.AddCheck(fmt.Sprintf("Checking ssh block for %s...", input.SSHConfig[i].Hostname))
+			check.Warningf("The ssh block for %s doesn't correspond to any of the Vault cluster members", input.SSHConfig[i].Hostname)
+		}
+	}
+
+	if len(missing) > 0 && !input.AllowMissing {
+		return fmt.Errorf(
+			"no SSH access to Vault cluster members %s, add ssh blocks for them or set allow_missing_ssh to continue anyway",
+			strings.Join(missing, ", "),
+		)
+	}
+
+	return nil
+}
+
+// hostsFromAddresses extracts the hostnames or IPs from addresses which could be URLs or host:port pairs
+func hostsFromAddresses(addresses []string) []string {
+	var hosts []string
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+
+		if u, err := url.Parse(address); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Hostname())
+		} else if host, _, err := net.SplitHostPort(address); err == nil {
+			hosts = append(hosts, host)
+		} else {
+			hosts = append(hosts, address)
+		}
+	}
+
+	return hosts
+}
+
+// resolveHosts returns a set of the given hosts along with any IP addresses they resolve to, so that a hostname in an
+// ssh block can be matched with an IP address reported by Vault and vice versa
+func resolveHosts(hosts []string) map[string]bool {
+	resolved := make(map[string]bool)
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+		resolved[strings.ToLower(host)] = true
+
+		ctx, cancel := context.WithTimeout(context.Background(), hostLookupTimeout)
+		addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+		cancel()
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			resolved[address] = true
+		}
+	}
+
+	return resolved
+}
+
+func hostsOverlap(a, b map[string]bool) bool {
+	for host := range a {
+		if b[host] {
+			return true
+		}
+	}
+	return false
+}
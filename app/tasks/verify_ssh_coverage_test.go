@@ -0,0 +1,90 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/opencredo/venafi-vault-wizard/app/config"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/api"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockAPI "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/api"
+)
+
+func TestVerifySSHCoverage(t *testing.T) {
+	var members = []api.ClusterMember{
+		{Name: "192.168.33.10", Addresses: []string{"http://192.168.33.10:8200", "https://192.168.33.10:8201"}, Leader: true},
+		{Name: "192.168.33.11", Addresses: []string{"192.168.33.11:8201"}},
+	}
+
+	tests := map[string]struct {
+		sshConfig    []config.SSH
+		allowMissing bool
+		wantErr      bool
+		wantWarning  bool
+	}{
+		"every member has ssh": {
+			sshConfig: []config.SSH{
+				{Hostname: "192.168.33.10"},
+				{Hostname: "192.168.33.11"},
+			},
+		},
+		"replica missing ssh": {
+			sshConfig: []config.SSH{
+				{Hostname: "192.168.33.10"},
+			},
+			wantErr: true,
+		},
+		"replica missing ssh allowed": {
+			sshConfig: []config.SSH{
+				{Hostname: "192.168.33.10"},
+			},
+			allowMissing: true,
+			wantWarning:  true,
+		},
+		"ssh block for unknown host": {
+			sshConfig: []config.SSH{
+				{Hostname: "192.168.33.10"},
+				{Hostname: "192.168.33.11"},
+				{Hostname: "192.168.33.12"},
+			},
+			wantWarning: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockAPI.VaultAPIClient)
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer vaultAPIClient.AssertExpectations(t)
+			defer report.AssertExpectations(t)
+			defer section.AssertExpectations(t)
+			defer check.AssertExpectations(t)
+
+			reportExpectations(report, section, check)
+			if tt.wantErr {
+				check.On("Error", mock.AnythingOfType("string")).Once()
+			}
+			if tt.wantWarning {
+				check.On("Warning", mock.AnythingOfType("string")).Maybe()
+				check.On("Warningf", mock.AnythingOfType("string"), mock.Anything).Maybe()
+			}
+
+			vaultAPIClient.On("GetClusterMembers").Return(members, nil)
+
+			err := VerifySSHCoverage(&VerifySSHCoverageInput{
+				VaultClient:  vaultAPIClient,
+				SSHConfig:    tt.sshConfig,
+				Reporter:     report,
+				AllowMissing: tt.allowMissing,
+			})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package tasks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opencredo/venafi-vault-wizard/app/reporter"
+	"github.com/opencredo/venafi-vault-wizard/app/vault/ssh"
+)
+
+type VerifyVaultServerConfigsInput struct {
+	SSHClients      []ssh.VaultSSHClient
+	Reporter        reporter.Report
+	VaultConfigPath string
+	PluginDir       string
+	MlockDisabled   bool
+}
+
+// VerifyVaultServerConfigs reads the Vault config from each server over SSH and checks that the settings relevant to
+// plugins are consistent across all the replicas, and match the plugin_directory and disable_mlock values that are
+// being used to install the plugins. As the config files might not be readable by the SSH user, and a mismatch doesn't
+// necessarily mean the install will fail, any problems are reported as warnings.
+func VerifyVaultServerConfigs(input *VerifyVaultServerConfigsInput) {
+	if len(input.SSHClients) == 0 {
+		return
+	}
+
+	section := input.Reporter.AddSection("Checking Vault server config files are consistent")
+
+	var referenceListeners string
+	var referenceServer int
+	for i, sshClient := range input.SSHClients {
+		check := section.AddCheck(fmt.Sprintf("Checking config file of Vault server %d...", i+1))
+
+		serverConfig, err := sshClient.GetVaultServerConfig(input.VaultConfigPath)
+		if err != nil {
+			check.Warningf("Unable to read the config of Vault server %d: %s", i+1, err)
+			continue
+		}
+
+		var problems []string
+		if serverConfig.PluginDirectory != input.PluginDir {
+			problems = append(problems, fmt.Sprintf("plugin_directory is %q rather than %q", serverConfig.PluginDirectory, input.PluginDir))
+		}
+		if serverConfig.DisableMlock != input.MlockDisabled {
+			problems = append(problems, fmt.Sprintf("disable_mlock is %t rather than %t", serverConfig.DisableMlock, input.MlockDisabled))
+		}
+		if serverConfig.APIAddr == "" {
+			problems = append(problems, "api_addr isn't set, which plugins may need in order to communicate with Vault")
+		}
+
+		listeners := summariseListeners(serverConfig.Listeners)
+		if referenceServer == 0 {
+			referenceListeners, referenceServer = listeners, i+1
+		} else if listeners != referenceListeners {
+			problems = append(problems, fmt.Sprintf("listeners [%s] differ from those of Vault server %d [%s]", listeners, referenceServer, referenceListeners))
+		}
+
+		if len(problems) > 0 {
+			check.Warningf(
+				"Config of Vault server %d at %s is inconsistent: %s",
+				i+1, strings.Join(serverConfig.ConfigPaths, ", "), strings.Join(problems, "; "),
+			)
+			continue
+		}
+
+		check.Successf("Config of Vault server %d is consistent, with api_addr %s and listeners [%s]", i+1, serverConfig.APIAddr, listeners)
+	}
+}
+
+func summariseListeners(listeners []ssh.Listener) string {
+	var summaries []string
+	for _, listener := range listeners {
+		summaries = append(summaries, listener.String())
+	}
+	sort.Strings(summaries)
+
+	return strings.Join(summaries, ", ")
+}
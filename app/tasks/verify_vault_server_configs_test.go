@@ -0,0 +1,81 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/opencredo/venafi-vault-wizard/app/vault/ssh"
+	mockReport "github.com/opencredo/venafi-vault-wizard/mocks/app/reporter"
+	mockSSH "github.com/opencredo/venafi-vault-wizard/mocks/app/vault/ssh"
+)
+
+func TestVerifyVaultServerConfigs(t *testing.T) {
+	var pluginDir = "/etc/vault.d/plugins"
+	var listeners = []ssh.Listener{
+		{Type: "tcp", Address: "0.0.0.0:8200", TLSDisable: "1"},
+	}
+
+	tests := map[string]struct {
+		serverConfigs []*ssh.VaultServerConfig
+		wantWarning   bool
+	}{
+		"consistent": {
+			serverConfigs: []*ssh.VaultServerConfig{
+				{PluginDirectory: pluginDir, APIAddr: "http://192.168.33.10:8200", Listeners: listeners},
+				{PluginDirectory: pluginDir, APIAddr: "http://192.168.33.11:8200", Listeners: listeners},
+			},
+			wantWarning: false,
+		},
+		"different plugin directory": {
+			serverConfigs: []*ssh.VaultServerConfig{
+				{PluginDirectory: pluginDir, APIAddr: "http://192.168.33.10:8200", Listeners: listeners},
+				{PluginDirectory: "/opt/plugins", APIAddr: "http://192.168.33.11:8200", Listeners: listeners},
+			},
+			wantWarning: true,
+		},
+		"different listeners": {
+			serverConfigs: []*ssh.VaultServerConfig{
+				{PluginDirectory: pluginDir, APIAddr: "http://192.168.33.10:8200", Listeners: listeners},
+				{PluginDirectory: pluginDir, APIAddr: "http://192.168.33.11:8200"},
+			},
+			wantWarning: true,
+		},
+		"no api_addr": {
+			serverConfigs: []*ssh.VaultServerConfig{
+				{PluginDirectory: pluginDir, Listeners: listeners},
+			},
+			wantWarning: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			report := new(mockReport.Report)
+			section := new(mockReport.Section)
+			check := new(mockReport.Check)
+			defer report.AssertExpectations(t)
+			defer section.AssertExpectations(t)
+			defer check.AssertExpectations(t)
+
+			reportExpectations(report, section, check)
+			if tt.wantWarning {
+				check.On("Warningf", mock.AnythingOfType("string"), mock.Anything).Once()
+			}
+
+			var sshClients []ssh.VaultSSHClient
+			for _, serverConfig := range tt.serverConfigs {
+				sshClient := new(mockSSH.VaultSSHClient)
+				defer sshClient.AssertExpectations(t)
+
+				sshClient.On("GetVaultServerConfig", "").Return(serverConfig, nil)
+				sshClients = append(sshClients, sshClient)
+			}
+
+			VerifyVaultServerConfigs(&VerifyVaultServerConfigsInput{
+				SSHClients: sshClients,
+				Reporter:   report,
+				PluginDir:  pluginDir,
+			})
+		})
+	}
+}
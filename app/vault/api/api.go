@@ -1,10 +1,12 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	vaultAPI "github.com/hashicorp/vault/api"
-	vaultConsts "github.com/hashicorp/vault/sdk/helper/consts"
 
 	"github.com/opencredo/venafi-vault-wizard/app/vault"
 	"github.com/opencredo/venafi-vault-wizard/app/vault/lib"
@@ -13,19 +15,40 @@ import (
 // VaultAPIClient represents a HashiCorp Vault instance and the operations available on it via the Vault API. For
 // operations involving SSH, see the vault/ssh/VaultSSHClient interface instead.
 type VaultAPIClient interface {
+	// CheckConnection verifies that Vault can be reached and that the token is valid, without requiring any particular
+	// permissions, by looking up the token's own details
+	CheckConnection() error
 	// GetPluginDir queries the server for the local plugin directory
 	GetPluginDir() (directory string, err error)
-	// RegisterPlugin adds the plugin to the VaultPlugin Catalog
-	RegisterPlugin(name, command, sha string) error
-	// GetPlugin returns information about a registered plugin (command, sha, args etc)
-	GetPlugin(name string) (map[string]interface{}, error)
+	// RegisterPlugin adds the plugin to the Vault plugin catalog, or updates its existing entry
+	RegisterPlugin(plugin *PluginRegistration) error
+	// GetPlugin returns information about a registered plugin (command, sha, args etc). If version is blank then the
+	// unversioned entry is returned, otherwise the entry for that version in the versioned catalog of Vault 1.12+
+	GetPlugin(kind PluginKind, name, version string) (*PluginRegistration, error)
+	// DeregisterPlugin removes a plugin from the catalog. If version is blank then only the unversioned entry is removed
+	DeregisterPlugin(kind PluginKind, name, version string) error
 	// ReloadPlugin reloads a plugin (globally across a cluster if Vault is clustered) and waits for the number of
-	// completed reloads to equal the number of replicas
-	ReloadPlugin(name string) error
-	// MountPlugin mounts a secret engine at the specified path. Equivalent to vault secrets enable -plugin-name=name -path=path
-	MountPlugin(name, path string) error
+	// completed reloads to equal the number of replicas, returning the status of the reload on each node
+	ReloadPlugin(name string) ([]PluginReloadStatus, error)
+	// ReloadMount is the same as ReloadPlugin, except that only the plugin backend mounted at path is reloaded
+	ReloadMount(kind PluginKind, path string) ([]PluginReloadStatus, error)
+	// ReloadDatabasePlugin reloads all the connections of the database secrets engine at mountPath that use the
+	// database plugin called name
+	ReloadDatabasePlugin(mountPath, name string) error
+	// MountPlugin mounts a secret engine or auth method at the specified path. Equivalent to vault secrets enable (or
+	// vault auth enable) -plugin-name=name -plugin-version=version -path=path, where version can be blank to use the
+	// unversioned catalog entry
+	MountPlugin(kind PluginKind, name, path, version string) error
 	// GetMountPluginName checks which backend is used for particular mount
-	GetMountPluginName(path string) (string, error)
+	GetMountPluginName(kind PluginKind, path string) (string, error)
+	// GetMountPluginVersion returns the plugin version a mount is configured to use, and the version that is actually
+	// running, which can differ until the plugin is reloaded. Requires Vault 1.12 or later
+	GetMountPluginVersion(kind PluginKind, path string) (version, runningVersion string, err error)
+	// TuneMountPluginVersion changes the plugin version used by a mount. The plugin must be reloaded for it to take
+	// effect. Equivalent to vault secrets tune (or vault auth tune) -plugin-version=version path
+	TuneMountPluginVersion(kind PluginKind, path, version string) error
+	// GetVaultVersion returns the version of Vault running on the server, from sys/seal-status
+	GetVaultVersion() (string, error)
 	// WriteValue writes to the specified path. Equivalent to `$ vault write path value1=v1 value2=v2`
 	WriteValue(path string, value map[string]interface{}) (map[string]interface{}, error)
 	// ReadValue reads from the specified path. Equivalent to `$ vault read path`
@@ -34,6 +57,47 @@ type VaultAPIClient interface {
 	GetVaultConfig() (map[string]interface{}, error)
 	// IsMLockDisabled checks to see if the server was run with the disable_mlock option
 	IsMLockDisabled() (bool, error)
+	// GetClusterMembers lists the nodes in the Vault cluster using sys/ha-status, falling back to the Raft
+	// configuration if using integrated storage
+	GetClusterMembers() ([]ClusterMember, error)
+}
+
+// ClusterMember represents a node in a Vault cluster
+type ClusterMember struct {
+	// Name is the hostname of the node, or its node ID when found from the Raft configuration
+	Name string
+	// Addresses are the API and cluster addresses the node is known by, in whatever form Vault reports them
+	Addresses []string
+	// Leader is whether the node is currently the active node
+	Leader bool
+}
+
+// PluginReloadStatus is the outcome of a plugin reload on one node of a clustered Vault
+type PluginReloadStatus struct {
+	// Node is the ID of the node that reported the status
+	Node string
+	// Error is blank if the reload succeeded on the node
+	Error string
+}
+
+// PluginRegistration represents an entry in the Vault plugin catalog
+type PluginRegistration struct {
+	// Kind is the type of plugin, which defaults to PluginKindSecret if blank
+	Kind PluginKind
+	// Name is the name the plugin is registered under in the catalog
+	Name string
+	// Command is the filename of the plugin binary in the plugin directory
+	Command string
+	// SHA256 is the hex-encoded SHA256 sum of the plugin binary
+	SHA256 string
+	// Args are passed to the plugin process when Vault starts it
+	Args []string
+	// Env holds environment variables, in KEY=VALUE form, that are set for the plugin process. Vault doesn't return
+	// these when reading the catalog, so when read back with GetPlugin it will be nil unless the server includes them.
+	Env []string
+	// Version is the semantic version of the plugin, only supported by Vault 1.12 and later. Older versions of Vault
+	// ignore it when registering and don't return it.
+	Version string
 }
 
 type vaultAPIClient struct {
@@ -47,8 +111,17 @@ type Config struct {
 	APIAddress string
 	// Authentication token to perform Vault operations. Must have sufficient permissions
 	Token string
+	// PluginReloadTimeout is how long to wait for every node in a cluster to report that it has reloaded a plugin.
+	// Defaults to DefaultPluginReloadTimeout if not set
+	PluginReloadTimeout time.Duration
 }
 
+// DefaultPluginReloadTimeout is used if no Config.PluginReloadTimeout is specified
+const DefaultPluginReloadTimeout = time.Minute
+
+// reloadStatusPollInterval is how often to check on the progress of a global plugin reload
+var reloadStatusPollInterval = 2 * time.Second
+
 // NewClient returns an instance of the Vault API client
 func NewClient(config *Config, apiClient lib.VaultAPIWrapper) (VaultAPIClient, error) {
 	err := apiClient.SetAddress(config.APIAddress)
@@ -60,6 +133,11 @@ func NewClient(config *Config, apiClient lib.VaultAPIWrapper) (VaultAPIClient, e
 	return &vaultAPIClient{config, apiClient}, nil
 }
 
+func (v *vaultAPIClient) CheckConnection() error {
+	_, err := v.ReadValue("auth/token/lookup-self")
+	return err
+}
+
 func (v *vaultAPIClient) GetPluginDir() (string, error) {
 	config, err := v.GetVaultConfig()
 	if err != nil {
@@ -74,55 +152,206 @@ func (v *vaultAPIClient) GetPluginDir() (string, error) {
 	return dir, nil
 }
 
-func (v *vaultAPIClient) RegisterPlugin(name, command, sha string) error {
-	err := v.VaultClient.RegisterPlugin(&vaultAPI.RegisterPluginInput{
-		Name:    name,
-		Type:    vaultConsts.PluginTypeSecrets,
-		Command: command,
-		SHA256:  sha,
-	})
+func (v *vaultAPIClient) RegisterPlugin(plugin *PluginRegistration) error {
+	// This uses the logical API rather than the client's RegisterPlugin method, as the version of the client in use
+	// doesn't support the env and version fields
+	data := map[string]interface{}{
+		"command": plugin.Command,
+		"sha256":  plugin.SHA256,
+	}
+	if len(plugin.Args) > 0 {
+		data["args"] = plugin.Args
+	}
+	if len(plugin.Env) > 0 {
+		data["env"] = plugin.Env
+	}
+	if plugin.Version != "" {
+		data["version"] = plugin.Version
+	}
+
+	catalogPath := getCatalogPath(plugin.Kind, plugin.Name)
+	_, err := v.VaultClient.Write(catalogPath, data)
 	if err != nil {
-		return fmt.Errorf("error writing sys/plugins/catalog/secret: %w", err)
+		return fmt.Errorf("error writing %s: %w", catalogPath, err)
 	}
 
 	return nil
 }
 
-func (v *vaultAPIClient) GetPlugin(name string) (map[string]interface{}, error) {
-	plugin, err := v.VaultClient.GetPlugin(&vaultAPI.GetPluginInput{
-		Name: name,
-		Type: vaultConsts.PluginTypeSecrets,
-	})
+func (v *vaultAPIClient) GetPlugin(kind PluginKind, name, version string) (*PluginRegistration, error) {
+	data, err := v.VaultClient.ReadWithData(getCatalogPath(kind, name), getVersionParams(version))
 	if err != nil {
 		return nil, fmt.Errorf("error getting details for plugin %s: %w", name, err)
 	}
 
-	return map[string]interface{}{
-		"command": plugin.Command,
-		"args":    plugin.Args,
-		"sha":     plugin.SHA256,
-	}, nil
+	plugin := &PluginRegistration{Kind: kind.orDefault(), Name: name}
+	plugin.Command, _ = data["command"].(string)
+	plugin.SHA256, _ = data["sha256"].(string)
+	plugin.Version, _ = data["version"].(string)
+	plugin.Args = getStringSlice(data["args"])
+	plugin.Env = getStringSlice(data["env"])
+
+	return plugin, nil
 }
 
-func (v *vaultAPIClient) ReloadPlugin(name string) error {
-	reloadID, err := v.VaultClient.ReloadPlugin(&vaultAPI.ReloadPluginInput{
-		Plugin: name,
-	})
+func (v *vaultAPIClient) DeregisterPlugin(kind PluginKind, name, version string) error {
+	err := v.VaultClient.Delete(getCatalogPath(kind, name), getVersionParams(version))
 	if err != nil {
-		return fmt.Errorf("error reloading plugin %s: %w", name, err)
+		return fmt.Errorf("error deregistering plugin %s: %w", name, err)
 	}
 
-	if reloadID == "" {
+	return nil
+}
+
+// getVersionParams returns the query parameters needed to refer to a versioned plugin catalog entry, or nil for the
+// unversioned entry
+func getVersionParams(version string) map[string][]string {
+	if version == "" {
+		return nil
+	}
+
+	return map[string][]string{"version": {version}}
+}
+
+// getCatalogPath returns the path of a plugin's entry in the plugin catalog
+func getCatalogPath(kind PluginKind, name string) string {
+	return fmt.Sprintf("sys/plugins/catalog/%s/%s", kind.orDefault(), name)
+}
+
+// getStringSlice converts a list decoded from a JSON response into a slice of strings, ignoring any values that aren't
+// strings. Returns nil if the value isn't a list.
+func getStringSlice(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
 		return nil
 	}
 
-	return fmt.Errorf("wasn't expecting a reload ID from the plugin reload, are there multiple clusters?")
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+
+	return strs
+}
+
+func (v *vaultAPIClient) ReloadPlugin(name string) ([]PluginReloadStatus, error) {
+	return v.reload(&vaultAPI.ReloadPluginInput{Plugin: name}, "plugin "+name)
+}
+
+func (v *vaultAPIClient) ReloadMount(kind PluginKind, path string) ([]PluginReloadStatus, error) {
+	return v.reload(
+		&vaultAPI.ReloadPluginInput{Mounts: []string{kind.getRouterPath(path)}},
+		"plugin mounted at "+kind.getRouterPath(path),
+	)
+}
+
+func (v *vaultAPIClient) ReloadDatabasePlugin(mountPath, name string) error {
+	_, err := v.WriteValue(fmt.Sprintf("%s/reload/%s", mountPath, name), nil)
+	return err
 }
 
-func (v *vaultAPIClient) MountPlugin(name, path string) error {
-	err := v.VaultClient.Mount(path, &vaultAPI.MountInput{
-		Type: name,
+// reload performs a plugin reload specified by input, which is described in any errors by description
+func (v *vaultAPIClient) reload(input *vaultAPI.ReloadPluginInput, description string) ([]PluginReloadStatus, error) {
+	// Only reload globally if there is more than one node, as there's nothing to wait for otherwise
+	members, err := v.GetClusterMembers()
+	if err != nil || len(members) <= 1 {
+		_, err := v.VaultClient.ReloadPlugin(input)
+		if err != nil {
+			return nil, fmt.Errorf("error reloading %s: %w", description, err)
+		}
+
+		return nil, nil
+	}
+
+	globalInput := *input
+	globalInput.Scope = "global"
+	reloadID, err := v.VaultClient.ReloadPlugin(&globalInput)
+	if err != nil {
+		return nil, fmt.Errorf("error reloading %s across the cluster: %w", description, err)
+	}
+	if reloadID == "" {
+		return nil, fmt.Errorf("error reloading %s across the cluster: no reload ID returned", description)
+	}
+
+	timeout := v.Config.PluginReloadTimeout
+	if timeout == 0 {
+		timeout = DefaultPluginReloadTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	var statuses []PluginReloadStatus
+	for {
+		status, err := v.VaultClient.ReloadPluginStatus(&vaultAPI.ReloadPluginStatusInput{
+			ReloadID: reloadID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error checking status of reload %s of %s: %w", reloadID, description, err)
+		}
+
+		statuses = reloadStatuses(status)
+		if len(statuses) >= len(members) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return statuses, fmt.Errorf(
+				"timed out after %s waiting for %s to reload, only %d of %d nodes reported back: %w",
+				timeout, description, len(statuses), len(members), vault.ErrPluginReloadIncomplete,
+			)
+		}
+		time.Sleep(reloadStatusPollInterval)
+	}
+
+	for _, nodeStatus := range statuses {
+		if nodeStatus.Error != "" {
+			return statuses, fmt.Errorf("error reloading %s on node %s: %s", description, nodeStatus.Node, nodeStatus.Error)
+		}
+	}
+
+	return statuses, nil
+}
+
+// reloadStatuses converts the results of a reload into a list of statuses, sorted by node so they're reported in a
+// consistent order
+func reloadStatuses(response *vaultAPI.ReloadStatusResponse) []PluginReloadStatus {
+	var statuses []PluginReloadStatus
+	if response == nil {
+		return statuses
+	}
+
+	for node, result := range response.Results {
+		nodeStatus := PluginReloadStatus{Node: node}
+		if result != nil {
+			nodeStatus.Error = result.Error
+		}
+		statuses = append(statuses, nodeStatus)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Node < statuses[j].Node
 	})
+
+	return statuses
+}
+
+func (v *vaultAPIClient) MountPlugin(kind PluginKind, name, path, version string) error {
+	var err error
+	if version == "" && kind.orDefault() != PluginKindAuth {
+		err = v.VaultClient.Mount(path, &vaultAPI.MountInput{
+			Type: name,
+		})
+	} else {
+		// The version of the client in use doesn't support plugin_version in its MountInput, and the wrapper doesn't
+		// expose enabling auth methods, so the API is written to directly
+		data := map[string]interface{}{
+			"type": name,
+		}
+		if version != "" {
+			data["plugin_version"] = version
+		}
+		_, err = v.VaultClient.Write(fmt.Sprintf("%s/%s", kind.getMountsPath(), path), data)
+	}
 	if err != nil {
 		// TODO: check for "Unrecognized remote plugin message" and see whether it's mlock or api_addr
 		return fmt.Errorf("error mounting plugin %s at path %s: %w", name, path, err)
@@ -131,7 +360,11 @@ func (v *vaultAPIClient) MountPlugin(name, path string) error {
 	return nil
 }
 
-func (v *vaultAPIClient) GetMountPluginName(path string) (string, error) {
+func (v *vaultAPIClient) GetMountPluginName(kind PluginKind, path string) (string, error) {
+	if kind.orDefault() == PluginKindAuth {
+		return v.getAuthMountPluginName(path)
+	}
+
 	mounts, err := v.VaultClient.ListMounts()
 	if err != nil {
 		return "", fmt.Errorf("error listing mounts: %w", err)
@@ -145,6 +378,58 @@ func (v *vaultAPIClient) GetMountPluginName(path string) (string, error) {
 	return mount.Type, nil
 }
 
+// getAuthMountPluginName is the equivalent of GetMountPluginName for auth methods, which are listed under sys/auth
+func (v *vaultAPIClient) getAuthMountPluginName(path string) (string, error) {
+	mounts, err := v.ReadValue("sys/auth")
+	if err != nil {
+		return "", fmt.Errorf("error listing auth methods: %w", err)
+	}
+
+	mount, ok := mounts[path+"/"].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("nothing mounted at path auth/%s: %w", path, vault.ErrPluginNotMounted)
+	}
+
+	mountType, _ := mount["type"].(string)
+	return mountType, nil
+}
+
+func (v *vaultAPIClient) GetMountPluginVersion(kind PluginKind, path string) (string, string, error) {
+	mount, err := v.ReadValue(fmt.Sprintf("%s/%s", kind.getMountsPath(), path))
+	if err != nil {
+		if errors.Is(err, vault.ErrNotFound) {
+			return "", "", fmt.Errorf("nothing mounted at path %s: %w", path, vault.ErrPluginNotMounted)
+		}
+		return "", "", err
+	}
+
+	version, _ := mount["plugin_version"].(string)
+	runningVersion, _ := mount["running_plugin_version"].(string)
+
+	return version, runningVersion, nil
+}
+
+func (v *vaultAPIClient) TuneMountPluginVersion(kind PluginKind, path, version string) error {
+	_, err := v.WriteValue(fmt.Sprintf("%s/%s/tune", kind.getMountsPath(), path), map[string]interface{}{
+		"plugin_version": version,
+	})
+	return err
+}
+
+func (v *vaultAPIClient) GetVaultVersion() (string, error) {
+	sealStatus, err := v.ReadValue("sys/seal-status")
+	if err != nil {
+		return "", err
+	}
+
+	version, ok := sealStatus["version"].(string)
+	if !ok || version == "" {
+		return "", fmt.Errorf("error, version not found in sys/seal-status")
+	}
+
+	return version, nil
+}
+
 func (v *vaultAPIClient) WriteValue(path string, value map[string]interface{}) (map[string]interface{}, error) {
 	secret, err := v.VaultClient.Write(path, value)
 	if err != nil {
@@ -180,3 +465,54 @@ func (v *vaultAPIClient) IsMLockDisabled() (bool, error) {
 
 	return disabled, nil
 }
+
+func (v *vaultAPIClient) GetClusterMembers() ([]ClusterMember, error) {
+	var members []ClusterMember
+
+	haStatus, haErr := v.VaultClient.HAStatus()
+	if haErr == nil {
+		for _, node := range haStatus.Nodes {
+			members = append(members, ClusterMember{
+				Name:      node.Hostname,
+				Addresses: []string{node.APIAddress, node.ClusterAddress},
+				Leader:    node.ActiveNode,
+			})
+		}
+		if len(members) > 0 {
+			return members, nil
+		}
+	}
+
+	// Older versions of Vault don't have sys/ha-status, but with integrated storage the Raft configuration has the nodes
+	raftConfig, err := v.ReadValue("sys/storage/raft/configuration")
+	if err != nil {
+		if haErr != nil {
+			return nil, fmt.Errorf("error reading sys/ha-status (%s) and %w", haErr, err)
+		}
+		return nil, err
+	}
+
+	config, _ := raftConfig["config"].(map[string]interface{})
+	servers, _ := config["servers"].([]interface{})
+	for _, s := range servers {
+		server, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		nodeID, _ := server["node_id"].(string)
+		address, _ := server["address"].(string)
+		leader, _ := server["leader"].(bool)
+		members = append(members, ClusterMember{
+			Name:      nodeID,
+			Addresses: []string{address},
+			Leader:    leader,
+		})
+	}
+
+	if len(members) == 0 {
+		return nil, vault.ErrNoClusterMembers
+	}
+
+	return members, nil
+}
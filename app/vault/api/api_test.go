@@ -1,10 +1,11 @@
 package api
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	vaultAPI "github.com/hashicorp/vault/api"
-	vaultConsts "github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
@@ -45,6 +46,19 @@ func Test_vault_GetPluginDir(t *testing.T) {
 	}
 }
 
+func Test_vault_CheckConnection(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	// Shouldn't need access to sys/config/state/sanitized just to check the connection
+	vaultAPIClient.On("Read", "auth/token/lookup-self").Return(map[string]interface{}{}, nil)
+
+	err := vaultClient.CheckConnection()
+	require.NoError(t, err)
+}
+
 func getTestVaultClient(apiClient *mockVaultLib.VaultAPIWrapper) VaultAPIClient {
 	apiClient.On("SetAddress", "apiaddr").Return(nil)
 	apiClient.On("SetToken", "tok").Return(nil)
@@ -77,17 +91,71 @@ func Test_vault_RegisterPlugin(t *testing.T) {
 	vaultClient := getTestVaultClient(vaultAPIClient)
 
 	vaultAPIClient.On(
-		"RegisterPlugin",
-		mock.MatchedBy(func(input *vaultAPI.RegisterPluginInput) bool {
-			return input.Type == vaultConsts.PluginTypeSecrets
-		}),
-	).Return(nil)
+		"Write",
+		"sys/plugins/catalog/secret/name",
+		map[string]interface{}{
+			"command": "command",
+			"sha256":  "sha",
+			"args":    []string{"-arg"},
+			"env":     []string{"KEY=value"},
+		},
+	).Return(nil, nil)
 
-	err := vaultClient.RegisterPlugin("name", "command", "sha")
+	err := vaultClient.RegisterPlugin(&PluginRegistration{
+		Name:    "name",
+		Command: "command",
+		SHA256:  "sha",
+		Args:    []string{"-arg"},
+		Env:     []string{"KEY=value"},
+	})
 
 	require.NoError(t, err)
 }
 
+func Test_vault_GetPlugin(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("ReadWithData", "sys/plugins/catalog/secret/name", map[string][]string{"version": {"v1.0.0"}}).Return(
+		map[string]interface{}{
+			"name":    "name",
+			"command": "command",
+			"sha256":  "sha",
+			"args":    []interface{}{"-arg"},
+			"builtin": false,
+			"version": "v1.0.0",
+		},
+		nil,
+	)
+
+	plugin, err := vaultClient.GetPlugin(PluginKindSecret, "name", "v1.0.0")
+	require.NoError(t, err)
+
+	require.Equal(t, &PluginRegistration{
+		Kind:    PluginKindSecret,
+		Name:    "name",
+		Command: "command",
+		SHA256:  "sha",
+		Args:    []string{"-arg"},
+		Version: "v1.0.0",
+	}, plugin)
+}
+
+func Test_vault_GetPlugin_not_found(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("ReadWithData", "sys/plugins/catalog/secret/name", map[string][]string(nil)).
+		Return(nil, vault.ErrNotFound)
+
+	_, err := vaultClient.GetPlugin(PluginKindSecret, "name", "")
+	require.ErrorIs(t, err, vault.ErrNotFound)
+}
+
 func Test_vault_MountPlugin(t *testing.T) {
 	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
 	defer vaultAPIClient.AssertExpectations(t)
@@ -105,10 +173,139 @@ func Test_vault_MountPlugin(t *testing.T) {
 		}),
 	).Return(nil)
 
-	err := vaultClient.MountPlugin(backendName, mountPath)
+	err := vaultClient.MountPlugin(PluginKindSecret, backendName, mountPath, "")
 	require.NoError(t, err)
 }
 
+func Test_vault_MountPlugin_versioned(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("Write", "sys/mounts/path", map[string]interface{}{
+		"type":           "backend",
+		"plugin_version": "v1.0.0",
+	}).Return(nil, nil)
+
+	err := vaultClient.MountPlugin(PluginKindSecret, "backend", "path", "v1.0.0")
+	require.NoError(t, err)
+}
+
+func Test_vault_MountPlugin_auth(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("Write", "sys/auth/path", map[string]interface{}{
+		"type": "backend",
+	}).Return(nil, nil)
+
+	err := vaultClient.MountPlugin(PluginKindAuth, "backend", "path", "")
+	require.NoError(t, err)
+}
+
+func Test_vault_GetMountPluginName_auth(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("Read", "sys/auth").Return(
+		map[string]interface{}{
+			"path/": map[string]interface{}{"type": "backend"},
+		},
+		nil,
+	)
+
+	name, err := vaultClient.GetMountPluginName(PluginKindAuth, "path")
+	require.NoError(t, err)
+	require.Equal(t, "backend", name)
+
+	_, err = vaultClient.GetMountPluginName(PluginKindAuth, "missing")
+	require.ErrorIs(t, err, vault.ErrPluginNotMounted)
+}
+
+func Test_vault_GetMountPluginVersion(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("Read", "sys/mounts/path").Return(
+		map[string]interface{}{
+			"type":                   "backend",
+			"plugin_version":         "v1.1.0",
+			"running_plugin_version": "v1.0.0",
+		},
+		nil,
+	)
+	vaultAPIClient.On("Read", "sys/mounts/missing").Return(nil, vault.ErrNotFound)
+
+	version, runningVersion, err := vaultClient.GetMountPluginVersion(PluginKindSecret, "path")
+	require.NoError(t, err)
+	require.Equal(t, "v1.1.0", version)
+	require.Equal(t, "v1.0.0", runningVersion)
+
+	_, _, err = vaultClient.GetMountPluginVersion(PluginKindSecret, "missing")
+	require.ErrorIs(t, err, vault.ErrPluginNotMounted)
+}
+
+func Test_vault_TuneMountPluginVersion(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("Write", "sys/mounts/path/tune", map[string]interface{}{
+		"plugin_version": "v1.1.0",
+	}).Return(nil, nil)
+
+	err := vaultClient.TuneMountPluginVersion(PluginKindSecret, "path", "v1.1.0")
+	require.NoError(t, err)
+}
+
+func Test_vault_ReloadDatabasePlugin(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("Write", "database/reload/plugin", map[string]interface{}(nil)).Return(nil, nil)
+
+	err := vaultClient.ReloadDatabasePlugin("database", "plugin")
+	require.NoError(t, err)
+}
+
+func TestParsePluginKind(t *testing.T) {
+	kind, err := ParsePluginKind("auth")
+	require.NoError(t, err)
+	require.Equal(t, PluginKindAuth, kind)
+
+	_, err = ParsePluginKind("unknown")
+	require.Error(t, err)
+}
+
+func Test_vault_GetVaultVersion(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("Read", "sys/seal-status").Return(
+		map[string]interface{}{
+			"sealed":  false,
+			"version": "1.12.1",
+		},
+		nil,
+	)
+
+	version, err := vaultClient.GetVaultVersion()
+	require.NoError(t, err)
+	require.Equal(t, "1.12.1", version)
+}
+
 func Test_vault_IsMLockDisabled(t *testing.T) {
 	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
 	defer vaultAPIClient.AssertExpectations(t)
@@ -122,3 +319,179 @@ func Test_vault_IsMLockDisabled(t *testing.T) {
 
 	require.False(t, mLockDisabled)
 }
+
+func Test_vault_GetClusterMembers(t *testing.T) {
+	tests := map[string]struct {
+		haStatus    *vaultAPI.HAStatusResponse
+		haStatusErr error
+		raftConfig  map[string]interface{}
+		want        []ClusterMember
+		wantErr     bool
+	}{
+		"ha-status": {
+			haStatus: &vaultAPI.HAStatusResponse{
+				Nodes: []vaultAPI.HANode{
+					{Hostname: "vault-1", APIAddress: "http://192.168.33.10:8200", ClusterAddress: "https://192.168.33.10:8201", ActiveNode: true},
+					{Hostname: "vault-2", APIAddress: "http://192.168.33.11:8200", ClusterAddress: "https://192.168.33.11:8201"},
+				},
+			},
+			want: []ClusterMember{
+				{Name: "vault-1", Addresses: []string{"http://192.168.33.10:8200", "https://192.168.33.10:8201"}, Leader: true},
+				{Name: "vault-2", Addresses: []string{"http://192.168.33.11:8200", "https://192.168.33.11:8201"}},
+			},
+		},
+		"raft configuration fallback": {
+			haStatusErr: vault.ErrNotFound,
+			raftConfig: map[string]interface{}{
+				"config": map[string]interface{}{
+					"servers": []interface{}{
+						map[string]interface{}{"node_id": "vault-1", "address": "192.168.33.10:8201", "leader": true},
+						map[string]interface{}{"node_id": "vault-2", "address": "192.168.33.11:8201", "leader": false},
+					},
+				},
+			},
+			want: []ClusterMember{
+				{Name: "vault-1", Addresses: []string{"192.168.33.10:8201"}, Leader: true},
+				{Name: "vault-2", Addresses: []string{"192.168.33.11:8201"}},
+			},
+		},
+		"no members": {
+			haStatus:   &vaultAPI.HAStatusResponse{},
+			raftConfig: map[string]interface{}{},
+			wantErr:    true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+			defer vaultAPIClient.AssertExpectations(t)
+
+			vaultClient := getTestVaultClient(vaultAPIClient)
+
+			vaultAPIClient.On("HAStatus").Return(tc.haStatus, tc.haStatusErr)
+			if tc.raftConfig != nil {
+				vaultAPIClient.On("Read", "sys/storage/raft/configuration").Return(tc.raftConfig, nil)
+			}
+
+			members, err := vaultClient.GetClusterMembers()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, members)
+		})
+	}
+}
+
+func Test_vault_ReloadPlugin(t *testing.T) {
+	reloadStatusPollInterval = time.Millisecond
+
+	var twoNodes = &vaultAPI.HAStatusResponse{
+		Nodes: []vaultAPI.HANode{
+			{Hostname: "vault-1", ActiveNode: true},
+			{Hostname: "vault-2"},
+		},
+	}
+
+	tests := map[string]struct {
+		haStatus     *vaultAPI.HAStatusResponse
+		reloadStatus *vaultAPI.ReloadStatusResponse
+		wantStatuses []PluginReloadStatus
+		wantErr      error
+	}{
+		"single node": {
+			haStatus: &vaultAPI.HAStatusResponse{
+				Nodes: []vaultAPI.HANode{{Hostname: "vault-1", ActiveNode: true}},
+			},
+		},
+		"cluster reloaded": {
+			haStatus: twoNodes,
+			reloadStatus: &vaultAPI.ReloadStatusResponse{
+				ReloadID: "reloadid",
+				Results: map[string]*vaultAPI.ReloadStatus{
+					"node-2": {},
+					"node-1": {},
+				},
+			},
+			wantStatuses: []PluginReloadStatus{{Node: "node-1"}, {Node: "node-2"}},
+		},
+		"cluster reload failed on a node": {
+			haStatus: twoNodes,
+			reloadStatus: &vaultAPI.ReloadStatusResponse{
+				ReloadID: "reloadid",
+				Results: map[string]*vaultAPI.ReloadStatus{
+					"node-1": {},
+					"node-2": {Error: "plugin exited"},
+				},
+			},
+			wantStatuses: []PluginReloadStatus{{Node: "node-1"}, {Node: "node-2", Error: "plugin exited"}},
+			wantErr:      errors.New("error reloading plugin plugin on node node-2: plugin exited"),
+		},
+		"cluster reload timed out": {
+			haStatus: twoNodes,
+			reloadStatus: &vaultAPI.ReloadStatusResponse{
+				ReloadID: "reloadid",
+				Results: map[string]*vaultAPI.ReloadStatus{
+					"node-1": {},
+				},
+			},
+			wantStatuses: []PluginReloadStatus{{Node: "node-1"}},
+			wantErr:      vault.ErrPluginReloadIncomplete,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+			defer vaultAPIClient.AssertExpectations(t)
+
+			vaultAPIClient.On("SetAddress", "apiaddr").Return(nil)
+			vaultAPIClient.On("SetToken", "tok").Return(nil)
+			vaultClient, _ := NewClient(
+				&Config{
+					APIAddress:          "apiaddr",
+					Token:               "tok",
+					PluginReloadTimeout: 10 * time.Millisecond,
+				},
+				vaultAPIClient,
+			)
+
+			vaultAPIClient.On("HAStatus").Return(tc.haStatus, nil)
+			if tc.reloadStatus == nil {
+				vaultAPIClient.On("ReloadPlugin", &vaultAPI.ReloadPluginInput{Plugin: "plugin"}).Return("", nil)
+			} else {
+				vaultAPIClient.On("ReloadPlugin", &vaultAPI.ReloadPluginInput{Plugin: "plugin", Scope: "global"}).
+					Return("reloadid", nil)
+				vaultAPIClient.On("ReloadPluginStatus", &vaultAPI.ReloadPluginStatusInput{ReloadID: "reloadid"}).
+					Return(tc.reloadStatus, nil)
+			}
+
+			statuses, err := vaultClient.ReloadPlugin("plugin")
+			require.Equal(t, tc.wantStatuses, statuses)
+			switch {
+			case tc.wantErr == nil:
+				require.NoError(t, err)
+			case errors.Is(tc.wantErr, vault.ErrPluginReloadIncomplete):
+				require.ErrorIs(t, err, tc.wantErr)
+			default:
+				require.EqualError(t, err, tc.wantErr.Error())
+			}
+		})
+	}
+}
+
+func Test_vault_ReloadMount(t *testing.T) {
+	vaultAPIClient := new(mockVaultLib.VaultAPIWrapper)
+	defer vaultAPIClient.AssertExpectations(t)
+
+	vaultClient := getTestVaultClient(vaultAPIClient)
+
+	vaultAPIClient.On("HAStatus").Return(&vaultAPI.HAStatusResponse{
+		Nodes: []vaultAPI.HANode{{Hostname: "vault-1", ActiveNode: true}},
+	}, nil)
+	vaultAPIClient.On("ReloadPlugin", &vaultAPI.ReloadPluginInput{Mounts: []string{"path/"}}).Return("", nil)
+
+	statuses, err := vaultClient.ReloadMount(PluginKindSecret, "path")
+	require.NoError(t, err)
+	require.Empty(t, statuses)
+}
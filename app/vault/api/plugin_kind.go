@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PluginKind is the type of a plugin in the Vault plugin catalog, which determines how it is registered and mounted
+type PluginKind string
+
+const (
+	// PluginKindSecret is a secrets engine, mounted under sys/mounts
+	PluginKindSecret PluginKind = "secret"
+	// PluginKindAuth is an auth method, mounted under sys/auth
+	PluginKindAuth PluginKind = "auth"
+	// PluginKindDatabase is a database plugin, which isn't mounted itself but used by the database secrets engine
+	PluginKindDatabase PluginKind = "database"
+)
+
+var pluginKinds = []PluginKind{PluginKindSecret, PluginKindAuth, PluginKindDatabase}
+
+// ParsePluginKind converts a string into a PluginKind, returning an error if it isn't one of the supported kinds
+func ParsePluginKind(kind string) (PluginKind, error) {
+	for _, pluginKind := range pluginKinds {
+		if kind == string(pluginKind) {
+			return pluginKind, nil
+		}
+	}
+
+	return "", fmt.Errorf("error %s is not a valid plugin kind, must be one of %v", kind, pluginKinds)
+}
+
+// orDefault returns the kind, or PluginKindSecret if it is blank, as secrets engines were the only kind supported
+// before the kind could be specified
+func (k PluginKind) orDefault() PluginKind {
+	if k == "" {
+		return PluginKindSecret
+	}
+
+	return k
+}
+
+// getMountsPath returns the API path under which mounts of this kind of plugin are managed
+func (k PluginKind) getMountsPath() string {
+	if k.orDefault() == PluginKindAuth {
+		return "sys/auth"
+	}
+
+	return "sys/mounts"
+}
+
+// getRouterPath returns the path Vault routes requests to the mount through, which is how mounts are identified when
+// reloading plugins
+func (k PluginKind) getRouterPath(path string) string {
+	path = strings.TrimSuffix(path, "/") + "/"
+	if k.orDefault() == PluginKindAuth {
+		return "auth/" + path
+	}
+
+	return path
+}
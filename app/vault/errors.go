@@ -11,4 +11,6 @@ var (
 	ErrTLSDisabled            = errors.New("attempted to access Vault using TLS but it returned an HTTP response, check whether the protocol in the Vault address matches what's configured")
 	ErrMountPathInUse         = errors.New("the mount path is in already in use")
 	ErrPluginNotMounted       = errors.New("nothing is mounted at path")
+	ErrPluginReloadIncomplete = errors.New("not every node in the cluster reported that the plugin was reloaded")
+	ErrNoClusterMembers       = errors.New("no cluster members found, Vault may not be running in HA mode")
 )
@@ -1,6 +1,10 @@
 package lib
 
-import vaultAPI "github.com/hashicorp/vault/api"
+import (
+	vaultAPI "github.com/hashicorp/vault/api"
+
+	"github.com/opencredo/venafi-vault-wizard/app/vault"
+)
 
 // VaultAPIWrapper encapsulates the dependency on the HashiCorp Go Vault package, both to allow it to be injected, but
 // also to make its interface slightly simpler to its clients
@@ -8,12 +12,14 @@ type VaultAPIWrapper interface {
 	SetAddress(address string) error
 	SetToken(token string)
 	Read(path string) (map[string]interface{}, error)
+	ReadWithData(path string, data map[string][]string) (map[string]interface{}, error)
 	Write(path string, data map[string]interface{}) (map[string]interface{}, error)
-	RegisterPlugin(input *vaultAPI.RegisterPluginInput) error
-	GetPlugin(input *vaultAPI.GetPluginInput) (*vaultAPI.GetPluginResponse, error)
+	Delete(path string, data map[string][]string) error
 	ReloadPlugin(input *vaultAPI.ReloadPluginInput) (string, error)
+	ReloadPluginStatus(input *vaultAPI.ReloadPluginStatusInput) (*vaultAPI.ReloadStatusResponse, error)
 	Mount(path string, input *vaultAPI.MountInput) error
 	ListMounts() (map[string]*vaultAPI.MountOutput, error)
+	HAStatus() (*vaultAPI.HAStatusResponse, error)
 }
 
 type vaultAPIClient struct {
@@ -26,11 +32,21 @@ func NewVaultAPI() VaultAPIWrapper {
 }
 
 func (v *vaultAPIClient) Read(path string) (map[string]interface{}, error) {
-	secret, err := v.Logical().Read(path)
+	return v.ReadWithData(path, nil)
+}
+
+func (v *vaultAPIClient) ReadWithData(path string, data map[string][]string) (map[string]interface{}, error) {
+	secret, err := v.Logical().ReadWithData(path, data)
 	if err != nil {
 		return nil, normaliseError(err)
 	}
 
+	// Vault responds with a 404 and no errors if nothing exists at the path, which the Vault client turns into a nil
+	// secret rather than an error
+	if secret == nil {
+		return nil, vault.ErrNotFound
+	}
+
 	return secret.Data, nil
 }
 
@@ -47,21 +63,21 @@ func (v *vaultAPIClient) Write(path string, data map[string]interface{}) (map[st
 	}
 }
 
-func (v *vaultAPIClient) RegisterPlugin(input *vaultAPI.RegisterPluginInput) error {
-	err := v.Sys().RegisterPlugin(input)
+func (v *vaultAPIClient) Delete(path string, data map[string][]string) error {
+	_, err := v.Logical().DeleteWithData(path, data)
 	return normaliseError(err)
 }
 
-func (v *vaultAPIClient) GetPlugin(input *vaultAPI.GetPluginInput) (*vaultAPI.GetPluginResponse, error) {
-	plugin, err := v.Sys().GetPlugin(input)
-	return plugin, normaliseError(err)
-}
-
 func (v *vaultAPIClient) ReloadPlugin(input *vaultAPI.ReloadPluginInput) (string, error) {
 	reloadID, err := v.Sys().ReloadPlugin(input)
 	return reloadID, normaliseError(err)
 }
 
+func (v *vaultAPIClient) ReloadPluginStatus(input *vaultAPI.ReloadPluginStatusInput) (*vaultAPI.ReloadStatusResponse, error) {
+	status, err := v.Sys().ReloadPluginStatus(input)
+	return status, normaliseError(err)
+}
+
 func (v *vaultAPIClient) Mount(path string, input *vaultAPI.MountInput) error {
 	err := v.Sys().Mount(path, input)
 	return normaliseError(err)
@@ -71,3 +87,8 @@ func (v *vaultAPIClient) ListMounts() (map[string]*vaultAPI.MountOutput, error)
 	mounts, err := v.Sys().ListMounts()
 	return mounts, normaliseError(err)
 }
+
+func (v *vaultAPIClient) HAStatus() (*vaultAPI.HAStatusResponse, error) {
+	status, err := v.Sys().HAStatus()
+	return status, normaliseError(err)
+}
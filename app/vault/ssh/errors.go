@@ -5,3 +5,6 @@ import "errors"
 var ErrNotFound = errors.New("directory not found, ensure it exists")
 var ErrFileBusy = errors.New("file already exists and is busy, try disabling plugin first")
 var ErrNoPermissions = errors.New("cannot write file into directory, SSH user has insufficient permissions")
+var ErrFileNotFound = errors.New("file not found")
+var ErrNoReadPermissions = errors.New("cannot read file, SSH user has insufficient permissions and can't use sudo")
+var ErrVaultConfigNotFound = errors.New("couldn't find a running Vault server process or systemd unit to get the -config flag from")
@@ -0,0 +1,130 @@
+package ssh
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// DefaultVaultConfigPath is where the official Vault packages put the server config file. It is used if the config
+// file can't be found by looking at the running Vault process or its systemd unit.
+const DefaultVaultConfigPath = "/etc/vault.d/vault.hcl"
+
+// VaultServerConfig represents the parts of a Vault server's config file that are relevant to installing plugins. Any
+// other attributes or blocks in the file are ignored.
+type VaultServerConfig struct {
+	PluginDirectory string     `hcl:"plugin_directory,optional"`
+	DisableMlock    bool       `hcl:"disable_mlock,optional"`
+	APIAddr         string     `hcl:"api_addr,optional"`
+	Listeners       []Listener `hcl:"listener,block"`
+
+	// Remain allows everything else in the Vault config file to be ignored
+	Remain hcl.Body `hcl:",remain"`
+
+	// ConfigPaths lists the files the config was read from. It is not decoded from HCL, and is populated when the
+	// config is read from a server.
+	ConfigPaths []string
+}
+
+// Listener represents a listener block in the Vault server config
+type Listener struct {
+	Type    string `hcl:"type,label"`
+	Address string `hcl:"address,optional"`
+	// TLSDisable is a string as Vault accepts either a bool or a number for it, both of which can be converted to a
+	// string when decoded
+	TLSDisable string `hcl:"tls_disable,optional"`
+
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// IsTLSDisabled interprets the tls_disable attribute in the same way as Vault does
+func (l *Listener) IsTLSDisabled() bool {
+	disabled, _ := strconv.ParseBool(l.TLSDisable)
+	return disabled
+}
+
+// String summarises the listener for use in comparisons and messages
+func (l *Listener) String() string {
+	if l.IsTLSDisabled() {
+		return fmt.Sprintf("%s %s (TLS disabled)", l.Type, l.Address)
+	}
+	return fmt.Sprintf("%s %s", l.Type, l.Address)
+}
+
+// ParseVaultServerConfig parses the contents of a Vault server config file, which can either be in HCL or JSON format
+// depending on the filename's extension, as with Vault itself.
+func ParseVaultServerConfig(filename string, src []byte) (*VaultServerConfig, error) {
+	parser := hclparse.NewParser()
+
+	var file *hcl.File
+	var diagnostics hcl.Diagnostics
+	if strings.HasSuffix(filename, ".json") {
+		file, diagnostics = parser.ParseJSON(src, filename)
+	} else {
+		file, diagnostics = parser.ParseHCL(src, filename)
+	}
+	if diagnostics.HasErrors() {
+		return nil, fmt.Errorf("error parsing Vault config file %s: %w", filename, diagnostics)
+	}
+
+	config := new(VaultServerConfig)
+	diagnostics = gohcl.DecodeBody(file.Body, nil, config)
+	if diagnostics.HasErrors() {
+		return nil, fmt.Errorf("error decoding Vault config file %s: %w", filename, diagnostics)
+	}
+
+	return config, nil
+}
+
+// merge combines another config file into this one, in the same way that Vault does when given multiple -config
+// flags or a directory of config files
+func (c *VaultServerConfig) merge(other *VaultServerConfig) {
+	if other.PluginDirectory != "" {
+		c.PluginDirectory = other.PluginDirectory
+	}
+	if other.APIAddr != "" {
+		c.APIAddr = other.APIAddr
+	}
+	c.DisableMlock = c.DisableMlock || other.DisableMlock
+	c.Listeners = append(c.Listeners, other.Listeners...)
+}
+
+// ParseVaultServerConfigFlags takes the command line of a process, and if it is a Vault server returns the values of
+// any -config flags. It also copes with the ExecStart property of a systemd unit, which includes the command line
+// among other fields separated by semicolons.
+func ParseVaultServerConfigFlags(commandLine string) []string {
+	args := strings.Fields(commandLine)
+
+	for i := 0; i < len(args)-1; i++ {
+		// Strip systemd's "argv[]=" prefix if present
+		executable := strings.TrimPrefix(args[i], "argv[]=")
+		if path.Base(executable) != "vault" || args[i+1] != "server" {
+			continue
+		}
+
+		var configPaths []string
+		for j := i + 2; j < len(args) && args[j] != ";"; j++ {
+			if !strings.HasPrefix(args[j], "-") {
+				continue
+			}
+			flag := strings.TrimPrefix(args[j], "-")
+			flag = strings.TrimPrefix(flag, "-")
+
+			if strings.HasPrefix(flag, "config=") {
+				configPaths = append(configPaths, strings.TrimPrefix(flag, "config="))
+			} else if flag == "config" && j+1 < len(args) {
+				configPaths = append(configPaths, args[j+1])
+				j++
+			}
+		}
+
+		return configPaths
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package ssh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVaultServerConfig(t *testing.T) {
+	tests := map[string]struct {
+		filename string
+		config   string
+		want     VaultServerConfig
+		wantErr  bool
+	}{
+		"hcl config": {
+			filename: "vault.hcl",
+			config: `
+storage "raft" {
+  path    = "/opt/vault/data"
+  node_id = "vault-1"
+}
+
+listener "tcp" {
+  address     = "0.0.0.0:8200"
+  tls_disable = 1
+}
+
+api_addr         = "http://192.168.33.10:8200"
+plugin_directory = "/etc/vault.d/plugins"
+disable_mlock    = true
+ui               = true
+`,
+			want: VaultServerConfig{
+				PluginDirectory: "/etc/vault.d/plugins",
+				DisableMlock:    true,
+				APIAddr:         "http://192.168.33.10:8200",
+				Listeners: []Listener{
+					{Type: "tcp", Address: "0.0.0.0:8200", TLSDisable: "1"},
+				},
+			},
+		},
+		"json config": {
+			filename: "vault.json",
+			config:   `{"plugin_directory": "/etc/vault.d/plugins", "ui": true}`,
+			want: VaultServerConfig{
+				PluginDirectory: "/etc/vault.d/plugins",
+				DisableMlock:    false,
+			},
+		},
+		"invalid config": {
+			filename: "vault.hcl",
+			config:   `plugin_directory = `,
+			wantErr:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseVaultServerConfig(tt.filename, []byte(tt.config))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, tt.want.PluginDirectory, got.PluginDirectory)
+			require.Equal(t, tt.want.DisableMlock, got.DisableMlock)
+			require.Equal(t, tt.want.APIAddr, got.APIAddr)
+			require.Len(t, got.Listeners, len(tt.want.Listeners))
+			for i, listener := range tt.want.Listeners {
+				require.Equal(t, listener.String(), got.Listeners[i].String())
+			}
+		})
+	}
+}
+
+func TestParseVaultServerConfigFlags(t *testing.T) {
+	tests := map[string]struct {
+		commandLine string
+		want        []string
+	}{
+		"ps output": {
+			commandLine: "/usr/bin/vault server -config=/etc/vault.d/vault.hcl",
+			want:        []string{"/etc/vault.d/vault.hcl"},
+		},
+		"multiple flags with spaces": {
+			commandLine: "vault server --config /etc/vault.d/vault.hcl -config /etc/vault.d/extra -log-level=debug",
+			want:        []string{"/etc/vault.d/vault.hcl", "/etc/vault.d/extra"},
+		},
+		"systemd ExecStart": {
+			commandLine: "{ path=/usr/bin/vault ; argv[]=/usr/bin/vault server -config=/etc/vault.d/vault.hcl ; ignore_errors=no ; start_time=[n/a] }",
+			want:        []string{"/etc/vault.d/vault.hcl"},
+		},
+		"not a vault server": {
+			commandLine: "/usr/bin/vault agent -config=/etc/vault.d/agent.hcl",
+			want:        nil,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.want, ParseVaultServerConfigFlags(tt.commandLine))
+		})
+	}
+}
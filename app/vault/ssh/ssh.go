@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"strings"
 
 	"github.com/pkg/sftp"
@@ -20,6 +21,18 @@ type VaultSSHClient interface {
 	WriteFile(sourceFile io.Reader, hostDestination string) error
 	// FileExists checks whether a file exists on a server over SSH
 	FileExists(filepath string) (bool, error)
+	// ReadFile reads the contents of a file from the SSH server, falling back to sudo if the SSH user can't read it
+	ReadFile(filepath string) ([]byte, error)
+	// FileSHA256 runs sha256sum over SSH to return the hex encoded SHA256 of a file, without having to copy it back
+	FileSHA256(filepath string) (string, error)
+	// CopyFile copies a file to another path on the SSH server, preserving its mode
+	CopyFile(sourcePath, destinationPath string) error
+	// FindVaultConfigPaths looks at the running Vault server process, or failing that its systemd unit, to find the
+	// paths given to it with -config, which can be files or directories
+	FindVaultConfigPaths() ([]string, error)
+	// GetVaultServerConfig reads the Vault server's config and parses the values relevant to installing plugins. If
+	// configPath is blank then it is found with FindVaultConfigPaths, falling back to DefaultVaultConfigPath.
+	GetVaultServerConfig(configPath string) (*VaultServerConfig, error)
 	// AddIPCLockCapabilityToFile attempts to call setcap over SSH to add IPC_LOCK capability to an executable. Requires
 	// sudo privileges
 	AddIPCLockCapabilityToFile(filename string) error
@@ -118,6 +131,171 @@ func (c *sshClient) FileExists(filepath string) (bool, error) {
 	return true, nil
 }
 
+func (c *sshClient) ReadFile(filepath string) ([]byte, error) {
+	sftpClient, closeFunc, err := newSFTPClient(c.Client)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFunc()
+
+	file, err := sftpClient.Open(filepath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrFileNotFound
+		} else if errors.Is(err, os.ErrPermission) {
+			// Files such as the Vault config are often only readable by the vault user, so try again using sudo
+			return c.readFileWithSudo(filepath)
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+func (c *sshClient) readFileWithSudo(filepath string) ([]byte, error) {
+	session, err := c.Client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	// -n stops sudo from prompting for a password, which would otherwise hang
+	output, err := session.Output(fmt.Sprintf("sudo -n cat %s", shellQuote(filepath)))
+	if err != nil {
+		return nil, ErrNoReadPermissions
+	}
+
+	return output, nil
+}
+
+func (c *sshClient) FileSHA256(filepath string) (string, error) {
+	output, err := c.runCommand(fmt.Sprintf("sha256sum %s", shellQuote(filepath)))
+	if err != nil {
+		return "", err
+	}
+
+	// The output is the hash followed by the filename
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected output from sha256sum: %q", output)
+	}
+
+	return fields[0], nil
+}
+
+func (c *sshClient) CopyFile(sourcePath, destinationPath string) error {
+	_, err := c.runCommand(fmt.Sprintf("cp -p %s %s", shellQuote(sourcePath), shellQuote(destinationPath)))
+	return err
+}
+
+func (c *sshClient) FindVaultConfigPaths() ([]string, error) {
+	output, err := c.runCommand("ps -eo args")
+	if err == nil {
+		for _, process := range strings.Split(string(output), "\n") {
+			configPaths := ParseVaultServerConfigFlags(process)
+			if len(configPaths) > 0 {
+				return configPaths, nil
+			}
+		}
+	}
+
+	output, err = c.runCommand("systemctl show vault --property=ExecStart --value")
+	if err == nil {
+		configPaths := ParseVaultServerConfigFlags(string(output))
+		if len(configPaths) > 0 {
+			return configPaths, nil
+		}
+	}
+
+	return nil, ErrVaultConfigNotFound
+}
+
+func (c *sshClient) GetVaultServerConfig(configPath string) (*VaultServerConfig, error) {
+	configPaths := []string{configPath}
+	if configPath == "" {
+		var err error
+		configPaths, err = c.FindVaultConfigPaths()
+		if err != nil {
+			configPaths = []string{DefaultVaultConfigPath}
+		}
+	}
+
+	config := new(VaultServerConfig)
+	for _, p := range configPaths {
+		files, err := c.expandConfigPath(p)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			contents, err := c.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading Vault config file %s: %w", file, err)
+			}
+
+			fileConfig, err := ParseVaultServerConfig(file, contents)
+			if err != nil {
+				return nil, err
+			}
+
+			config.merge(fileConfig)
+			config.ConfigPaths = append(config.ConfigPaths, file)
+		}
+	}
+
+	return config, nil
+}
+
+// expandConfigPath returns the config files that Vault would load for a -config flag. For a file this is just the file
+// itself, but for a directory it is all the .hcl and .json files within it.
+func (c *sshClient) expandConfigPath(configPath string) ([]string, error) {
+	sftpClient, closeFunc, err := newSFTPClient(c.Client)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFunc()
+
+	info, err := sftpClient.Stat(configPath)
+	if err != nil || !info.IsDir() {
+		// Let ReadFile deal with any errors, as it can fall back to sudo
+		return []string{configPath}, nil
+	}
+
+	entries, err := sftpClient.ReadDir(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error listing Vault config directory %s: %w", configPath, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".hcl") || strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, path.Join(configPath, entry.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+// shellQuote returns s quoted for the remote shell, so that paths with spaces or metacharacters are passed as one
+// argument rather than interpreted
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (c *sshClient) runCommand(command string) ([]byte, error) {
+	session, err := c.Client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	return session.Output(command)
+}
+
 func newSFTPClient(conn *ssh.Client) (*sftp.Client, func(), error) {
 	sftpClient, err := sftp.NewClient(conn)
 	if err != nil {
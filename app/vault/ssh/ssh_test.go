@@ -0,0 +1,14 @@
+package ssh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellQuote(t *testing.T) {
+	require.Equal(t, `'/etc/vault.d/vault.hcl'`, shellQuote("/etc/vault.d/vault.hcl"))
+	require.Equal(t, `'/opt/vault plugins/venafi'`, shellQuote("/opt/vault plugins/venafi"))
+	require.Equal(t, `'/tmp/a; rm -rf $HOME'`, shellQuote("/tmp/a; rm -rf $HOME"))
+	require.Equal(t, `'/tmp/it'\''s'`, shellQuote("/tmp/it's"))
+}
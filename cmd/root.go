@@ -2,16 +2,18 @@ package cmd
 
 import (
 	"os"
+	"time"
 
 	"github.com/opencredo/venafi-vault-wizard/app/questions/prompter"
 	"github.com/spf13/cobra"
 
 	"github.com/opencredo/venafi-vault-wizard/app/commands"
 	"github.com/opencredo/venafi-vault-wizard/app/config"
+	"github.com/opencredo/venafi-vault-wizard/app/plugins/lookup"
 )
 
 func NewRootCommand() *cobra.Command {
-	var configFile string
+	var configFile, pluginRegistryDir string
 
 	cobra.EnableCommandSorting = false
 
@@ -19,9 +21,16 @@ func NewRootCommand() *cobra.Command {
 		Use:   "vvw",
 		Short: "Venafi Vault Wizard",
 		Long:  "VVW is a wizard to automate the installation and verification of Venafi PKI plugins for HashiCorp Vault.",
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			if pluginRegistryDir == "" {
+				return nil
+			}
+
+			return lookup.LoadPluginRegistry(pluginRegistryDir)
+		},
 	}
 
-	setUpGlobalFlags(rootCmd, &configFile)
+	setUpGlobalFlags(rootCmd, &configFile, &pluginRegistryDir)
 
 	generateConfigCmd := &cobra.Command{
 		Use:   "generate-config",
@@ -49,13 +58,67 @@ func NewRootCommand() *cobra.Command {
 		},
 	}
 
+	var renewThreshold time.Duration
+	renewCACmd := &cobra.Command{
+		Use:   "renew-ca",
+		Short: "Renews intermediate CA certificates issued by Venafi",
+		Long:  "Reads the config file and renews the intermediate CA certificates of venafi-pki-monitor plugins that expire within the threshold, by having a new one signed by Venafi",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			configuration, err := config.NewConfigFromFile(configFile)
+			if err != nil {
+				return err
+			}
+
+			// Only override the renewal_window if given, so that a threshold of 0 can be used to only renew expired CAs
+			var threshold *time.Duration
+			if cmd.Flags().Changed("threshold") {
+				threshold = &renewThreshold
+			}
+
+			commands.RenewCA(configuration, threshold)
+			return nil
+		},
+	}
+	renewCACmd.Flags().DurationVarP(
+		&renewThreshold,
+		"threshold",
+		"t",
+		0,
+		"Renew CA certificates expiring within this duration, defaulting to the renewal_window of each ca block, which defaults to 720h",
+	)
+
+	var secretName string
+	rotateSecretCmd := &cobra.Command{
+		Use:   "rotate-secret",
+		Short: "Rotates the Venafi credentials stored in Vault",
+		Long:  "Reads the config file and rewrites the Venafi secrets of the plugins with fresh TPP tokens, or the API key given, without changing the rest of their mounts, and then requests the test certificates to check the new credentials work",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			configuration, err := config.NewConfigFromFile(configFile)
+			if err != nil {
+				return err
+			}
+
+			commands.RotateSecret(configuration, secretName)
+			return nil
+		},
+	}
+	rotateSecretCmd.Flags().StringVarP(
+		&secretName,
+		"secret",
+		"s",
+		"",
+		"Name of the secret block to rotate, defaulting to every Venafi secret in the config",
+	)
+
 	rootCmd.AddCommand(generateConfigCmd)
 	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(renewCACmd)
+	rootCmd.AddCommand(rotateSecretCmd)
 
 	return rootCmd
 }
 
-func setUpGlobalFlags(cmd *cobra.Command, configFile *string) {
+func setUpGlobalFlags(cmd *cobra.Command, configFile, pluginRegistryDir *string) {
 	flags := cmd.PersistentFlags()
 
 	flags.StringVarP(
@@ -65,6 +128,13 @@ func setUpGlobalFlags(cmd *cobra.Command, configFile *string) {
 		"vvw_config.hcl",
 		"Path to config file to use to configure Venafi Vault plugin",
 	)
+	flags.StringVarP(
+		pluginRegistryDir,
+		"pluginRegistry",
+		"r",
+		"",
+		"Path to a directory of plugin descriptor files, describing plugin types to support in addition to the built-in ones",
+	)
 }
 
 func Execute() {
@@ -5,9 +5,9 @@ go 1.18
 require (
 	github.com/Venafi/vcert/v4 v4.20.1
 	github.com/google/go-cmp v0.5.8
+	github.com/hashicorp/go-version v1.2.0
 	github.com/hashicorp/hcl/v2 v2.12.0
 	github.com/hashicorp/vault/api v1.6.0
-	github.com/hashicorp/vault/sdk v0.5.0
 	github.com/manifoldco/promptui v0.9.0
 	github.com/pkg/sftp v1.13.4
 	github.com/pterm/pterm v0.12.41
@@ -43,9 +43,9 @@ require (
 	github.com/hashicorp/go-secure-stdlib/strutil v0.1.2 // indirect
 	github.com/hashicorp/go-sockaddr v1.0.2 // indirect
 	github.com/hashicorp/go-uuid v1.0.2 // indirect
-	github.com/hashicorp/go-version v1.2.0 // indirect
 	github.com/hashicorp/golang-lru v0.5.4 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/hashicorp/vault/sdk v0.5.0 // indirect
 	github.com/hashicorp/yamux v0.0.0-20180604194846-3520598351bb // indirect
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/kr/fs v0.1.0 // indirect
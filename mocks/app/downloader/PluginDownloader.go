@@ -38,3 +38,33 @@ func (_m *PluginDownloader) DownloadPluginAndUnzip(url string) ([]byte, string,
 
 	return r0, r1, r2
 }
+
+// DownloadPluginWithChecksums provides a mock function with given fields: url, checksumsURL
+func (_m *PluginDownloader) DownloadPluginWithChecksums(url string, checksumsURL string) ([]byte, string, error) {
+	ret := _m.Called(url, checksumsURL)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(string, string) []byte); ok {
+		r0 = rf(url, checksumsURL)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(string, string) string); ok {
+		r1 = rf(url, checksumsURL)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string) error); ok {
+		r2 = rf(url, checksumsURL)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
@@ -85,6 +85,20 @@ func (_m *Plugin) GetDownloadURL() (string, error) {
 	return r0, r1
 }
 
+// GetKind provides a mock function with given fields:
+func (_m *Plugin) GetKind() api.PluginKind {
+	ret := _m.Called()
+
+	var r0 api.PluginKind
+	if rf, ok := ret.Get(0).(func() api.PluginKind); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(api.PluginKind)
+	}
+
+	return r0
+}
+
 // ParseConfig provides a mock function with given fields: config, evalContext
 func (_m *Plugin) ParseConfig(config *plugins.PluginConfig, evalContext *hcl.EvalContext) error {
 	ret := _m.Called(config, evalContext)
@@ -13,13 +13,13 @@ type VenafiConnectionConfig struct {
 	mock.Mock
 }
 
-// GetAsMap provides a mock function with given fields: pluginType, venafiClient
-func (_m *VenafiConnectionConfig) GetAsMap(pluginType venafi.PluginType, venafiClient venafi_wrapper.VenafiWrapper) (map[string]interface{}, error) {
-	ret := _m.Called(pluginType, venafiClient)
+// GetAsMap provides a mock function with given fields: pluginType, venafiClient, rotate
+func (_m *VenafiConnectionConfig) GetAsMap(pluginType venafi.PluginType, venafiClient venafi_wrapper.VenafiWrapper, rotate bool) (map[string]interface{}, error) {
+	ret := _m.Called(pluginType, venafiClient, rotate)
 
 	var r0 map[string]interface{}
-	if rf, ok := ret.Get(0).(func(venafi.PluginType, venafi_wrapper.VenafiWrapper) map[string]interface{}); ok {
-		r0 = rf(pluginType, venafiClient)
+	if rf, ok := ret.Get(0).(func(venafi.PluginType, venafi_wrapper.VenafiWrapper, bool) map[string]interface{}); ok {
+		r0 = rf(pluginType, venafiClient, rotate)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(map[string]interface{})
@@ -27,8 +27,8 @@ func (_m *VenafiConnectionConfig) GetAsMap(pluginType venafi.PluginType, venafiC
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(venafi.PluginType, venafi_wrapper.VenafiWrapper) error); ok {
-		r1 = rf(pluginType, venafiClient)
+	if rf, ok := ret.Get(1).(func(venafi.PluginType, venafi_wrapper.VenafiWrapper, bool) error); ok {
+		r1 = rf(pluginType, venafiClient, rotate)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -16,6 +16,27 @@ type VenafiWrapper struct {
 	mock.Mock
 }
 
+// FindCertificateInZone provides a mock function with given fields: zone, thumbprint
+func (_m *VenafiWrapper) FindCertificateInZone(zone string, thumbprint string) (bool, error) {
+	ret := _m.Called(zone, thumbprint)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string) bool); ok {
+		r0 = rf(zone, thumbprint)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(zone, thumbprint)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GenerateRequest provides a mock function with given fields: config, req, zone
 func (_m *VenafiWrapper) GenerateRequest(config *endpoint.ZoneConfiguration, req *certificate.Request, zone string) error {
 	ret := _m.Called(config, req, zone)
@@ -51,6 +72,73 @@ func (_m *VenafiWrapper) GetRefreshToken(auth *endpoint.Authentication) (tpp.Oau
 	return r0, r1
 }
 
+// ListCertificates provides a mock function with given fields: zone, filter
+func (_m *VenafiWrapper) ListCertificates(zone string, filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
+	ret := _m.Called(zone, filter)
+
+	var r0 []certificate.CertificateInfo
+	if rf, ok := ret.Get(0).(func(string, endpoint.Filter) []certificate.CertificateInfo); ok {
+		r0 = rf(zone, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]certificate.CertificateInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, endpoint.Filter) error); ok {
+		r1 = rf(zone, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReadZoneConfiguration provides a mock function with given fields: zone
+func (_m *VenafiWrapper) ReadZoneConfiguration(zone string) (*endpoint.ZoneConfiguration, error) {
+	ret := _m.Called(zone)
+
+	var r0 *endpoint.ZoneConfiguration
+	if rf, ok := ret.Get(0).(func(string) *endpoint.ZoneConfiguration); ok {
+		r0 = rf(zone)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*endpoint.ZoneConfiguration)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(zone)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefreshAccessToken provides a mock function with given fields: auth
+func (_m *VenafiWrapper) RefreshAccessToken(auth *endpoint.Authentication) (tpp.OauthRefreshAccessTokenResponse, error) {
+	ret := _m.Called(auth)
+
+	var r0 tpp.OauthRefreshAccessTokenResponse
+	if rf, ok := ret.Get(0).(func(*endpoint.Authentication) tpp.OauthRefreshAccessTokenResponse); ok {
+		r0 = rf(auth)
+	} else {
+		r0 = ret.Get(0).(tpp.OauthRefreshAccessTokenResponse)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*endpoint.Authentication) error); ok {
+		r1 = rf(auth)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RequestCertificate provides a mock function with given fields: req, zone
 func (_m *VenafiWrapper) RequestCertificate(req *certificate.Request, zone string) (string, error) {
 	ret := _m.Called(req, zone)
@@ -94,3 +182,17 @@ func (_m *VenafiWrapper) RetrieveCertificate(req *certificate.Request, zone stri
 
 	return r0, r1
 }
+
+// RevokeCertificate provides a mock function with given fields: req
+func (_m *VenafiWrapper) RevokeCertificate(req *certificate.RevocationRequest) error {
+	ret := _m.Called(req)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*certificate.RevocationRequest) error); ok {
+		r0 = rf(req)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -2,27 +2,81 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	api "github.com/opencredo/venafi-vault-wizard/app/vault/api"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // VaultAPIClient is an autogenerated mock type for the VaultAPIClient type
 type VaultAPIClient struct {
 	mock.Mock
 }
 
-// GetMountPluginName provides a mock function with given fields: path
-func (_m *VaultAPIClient) GetMountPluginName(path string) (string, error) {
-	ret := _m.Called(path)
+// CheckConnection provides a mock function with given fields:
+func (_m *VaultAPIClient) CheckConnection() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeregisterPlugin provides a mock function with given fields: kind, name, version
+func (_m *VaultAPIClient) DeregisterPlugin(kind api.PluginKind, name string, version string) error {
+	ret := _m.Called(kind, name, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(api.PluginKind, string, string) error); ok {
+		r0 = rf(kind, name, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetClusterMembers provides a mock function with given fields:
+func (_m *VaultAPIClient) GetClusterMembers() ([]api.ClusterMember, error) {
+	ret := _m.Called()
+
+	var r0 []api.ClusterMember
+	if rf, ok := ret.Get(0).(func() []api.ClusterMember); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]api.ClusterMember)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMountPluginName provides a mock function with given fields: kind, path
+func (_m *VaultAPIClient) GetMountPluginName(kind api.PluginKind, path string) (string, error) {
+	ret := _m.Called(kind, path)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(path)
+	if rf, ok := ret.Get(0).(func(api.PluginKind, string) string); ok {
+		r0 = rf(kind, path)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(path)
+	if rf, ok := ret.Get(1).(func(api.PluginKind, string) error); ok {
+		r1 = rf(kind, path)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -30,22 +84,50 @@ func (_m *VaultAPIClient) GetMountPluginName(path string) (string, error) {
 	return r0, r1
 }
 
-// GetPlugin provides a mock function with given fields: name
-func (_m *VaultAPIClient) GetPlugin(name string) (map[string]interface{}, error) {
-	ret := _m.Called(name)
+// GetMountPluginVersion provides a mock function with given fields: kind, path
+func (_m *VaultAPIClient) GetMountPluginVersion(kind api.PluginKind, path string) (string, string, error) {
+	ret := _m.Called(kind, path)
 
-	var r0 map[string]interface{}
-	if rf, ok := ret.Get(0).(func(string) map[string]interface{}); ok {
-		r0 = rf(name)
+	var r0 string
+	if rf, ok := ret.Get(0).(func(api.PluginKind, string) string); ok {
+		r0 = rf(kind, path)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(api.PluginKind, string) string); ok {
+		r1 = rf(kind, path)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(api.PluginKind, string) error); ok {
+		r2 = rf(kind, path)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetPlugin provides a mock function with given fields: kind, name, version
+func (_m *VaultAPIClient) GetPlugin(kind api.PluginKind, name string, version string) (*api.PluginRegistration, error) {
+	ret := _m.Called(kind, name, version)
+
+	var r0 *api.PluginRegistration
+	if rf, ok := ret.Get(0).(func(api.PluginKind, string, string) *api.PluginRegistration); ok {
+		r0 = rf(kind, name, version)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(map[string]interface{})
+			r0 = ret.Get(0).(*api.PluginRegistration)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(name)
+	if rf, ok := ret.Get(1).(func(api.PluginKind, string, string) error); ok {
+		r1 = rf(kind, name, version)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -97,6 +179,27 @@ func (_m *VaultAPIClient) GetVaultConfig() (map[string]interface{}, error) {
 	return r0, r1
 }
 
+// GetVaultVersion provides a mock function with given fields:
+func (_m *VaultAPIClient) GetVaultVersion() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // IsMLockDisabled provides a mock function with given fields:
 func (_m *VaultAPIClient) IsMLockDisabled() (bool, error) {
 	ret := _m.Called()
@@ -118,13 +221,13 @@ func (_m *VaultAPIClient) IsMLockDisabled() (bool, error) {
 	return r0, r1
 }
 
-// MountPlugin provides a mock function with given fields: name, path
-func (_m *VaultAPIClient) MountPlugin(name string, path string) error {
-	ret := _m.Called(name, path)
+// MountPlugin provides a mock function with given fields: kind, name, path, version
+func (_m *VaultAPIClient) MountPlugin(kind api.PluginKind, name string, path string, version string) error {
+	ret := _m.Called(kind, name, path, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(name, path)
+	if rf, ok := ret.Get(0).(func(api.PluginKind, string, string, string) error); ok {
+		r0 = rf(kind, name, path, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -155,13 +258,13 @@ func (_m *VaultAPIClient) ReadValue(path string) (map[string]interface{}, error)
 	return r0, r1
 }
 
-// RegisterPlugin provides a mock function with given fields: name, command, sha
-func (_m *VaultAPIClient) RegisterPlugin(name string, command string, sha string) error {
-	ret := _m.Called(name, command, sha)
+// RegisterPlugin provides a mock function with given fields: plugin
+func (_m *VaultAPIClient) RegisterPlugin(plugin *api.PluginRegistration) error {
+	ret := _m.Called(plugin)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
-		r0 = rf(name, command, sha)
+	if rf, ok := ret.Get(0).(func(*api.PluginRegistration) error); ok {
+		r0 = rf(plugin)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -169,13 +272,73 @@ func (_m *VaultAPIClient) RegisterPlugin(name string, command string, sha string
 	return r0
 }
 
+// ReloadDatabasePlugin provides a mock function with given fields: mountPath, name
+func (_m *VaultAPIClient) ReloadDatabasePlugin(mountPath string, name string) error {
+	ret := _m.Called(mountPath, name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(mountPath, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReloadMount provides a mock function with given fields: kind, path
+func (_m *VaultAPIClient) ReloadMount(kind api.PluginKind, path string) ([]api.PluginReloadStatus, error) {
+	ret := _m.Called(kind, path)
+
+	var r0 []api.PluginReloadStatus
+	if rf, ok := ret.Get(0).(func(api.PluginKind, string) []api.PluginReloadStatus); ok {
+		r0 = rf(kind, path)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]api.PluginReloadStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(api.PluginKind, string) error); ok {
+		r1 = rf(kind, path)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ReloadPlugin provides a mock function with given fields: name
-func (_m *VaultAPIClient) ReloadPlugin(name string) error {
+func (_m *VaultAPIClient) ReloadPlugin(name string) ([]api.PluginReloadStatus, error) {
 	ret := _m.Called(name)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
+	var r0 []api.PluginReloadStatus
+	if rf, ok := ret.Get(0).(func(string) []api.PluginReloadStatus); ok {
 		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]api.PluginReloadStatus)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TuneMountPluginVersion provides a mock function with given fields: kind, path, version
+func (_m *VaultAPIClient) TuneMountPluginVersion(kind api.PluginKind, path string, version string) error {
+	ret := _m.Called(kind, path, version)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(api.PluginKind, string, string) error); ok {
+		r0 = rf(kind, path, version)
 	} else {
 		r0 = ret.Error(0)
 	}
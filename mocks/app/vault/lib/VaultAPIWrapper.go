@@ -13,22 +13,36 @@ type VaultAPIWrapper struct {
 	mock.Mock
 }
 
-// GetPlugin provides a mock function with given fields: input
-func (_m *VaultAPIWrapper) GetPlugin(input *api.GetPluginInput) (*api.GetPluginResponse, error) {
-	ret := _m.Called(input)
+// Delete provides a mock function with given fields: path, data
+func (_m *VaultAPIWrapper) Delete(path string, data map[string][]string) error {
+	ret := _m.Called(path, data)
 
-	var r0 *api.GetPluginResponse
-	if rf, ok := ret.Get(0).(func(*api.GetPluginInput) *api.GetPluginResponse); ok {
-		r0 = rf(input)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, map[string][]string) error); ok {
+		r0 = rf(path, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// HAStatus provides a mock function with given fields:
+func (_m *VaultAPIWrapper) HAStatus() (*api.HAStatusResponse, error) {
+	ret := _m.Called()
+
+	var r0 *api.HAStatusResponse
+	if rf, ok := ret.Get(0).(func() *api.HAStatusResponse); ok {
+		r0 = rf()
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*api.GetPluginResponse)
+			r0 = ret.Get(0).(*api.HAStatusResponse)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*api.GetPluginInput) error); ok {
-		r1 = rf(input)
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -96,18 +110,27 @@ func (_m *VaultAPIWrapper) Read(path string) (map[string]interface{}, error) {
 	return r0, r1
 }
 
-// RegisterPlugin provides a mock function with given fields: input
-func (_m *VaultAPIWrapper) RegisterPlugin(input *api.RegisterPluginInput) error {
-	ret := _m.Called(input)
+// ReadWithData provides a mock function with given fields: path, data
+func (_m *VaultAPIWrapper) ReadWithData(path string, data map[string][]string) (map[string]interface{}, error) {
+	ret := _m.Called(path, data)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(*api.RegisterPluginInput) error); ok {
-		r0 = rf(input)
+	var r0 map[string]interface{}
+	if rf, ok := ret.Get(0).(func(string, map[string][]string) map[string]interface{}); ok {
+		r0 = rf(path, data)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]interface{})
+		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, map[string][]string) error); ok {
+		r1 = rf(path, data)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // ReloadPlugin provides a mock function with given fields: input
@@ -131,6 +154,29 @@ func (_m *VaultAPIWrapper) ReloadPlugin(input *api.ReloadPluginInput) (string, e
 	return r0, r1
 }
 
+// ReloadPluginStatus provides a mock function with given fields: input
+func (_m *VaultAPIWrapper) ReloadPluginStatus(input *api.ReloadPluginStatusInput) (*api.ReloadStatusResponse, error) {
+	ret := _m.Called(input)
+
+	var r0 *api.ReloadStatusResponse
+	if rf, ok := ret.Get(0).(func(*api.ReloadPluginStatusInput) *api.ReloadStatusResponse); ok {
+		r0 = rf(input)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.ReloadStatusResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*api.ReloadPluginStatusInput) error); ok {
+		r1 = rf(input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetAddress provides a mock function with given fields: address
 func (_m *VaultAPIWrapper) SetAddress(address string) error {
 	ret := _m.Called(address)
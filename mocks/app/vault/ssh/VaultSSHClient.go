@@ -5,6 +5,7 @@ package mocks
 import (
 	io "io"
 
+	ssh "github.com/opencredo/venafi-vault-wizard/app/vault/ssh"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -69,6 +70,20 @@ func (_m *VaultSSHClient) Close() error {
 	return r0
 }
 
+// CopyFile provides a mock function with given fields: sourcePath, destinationPath
+func (_m *VaultSSHClient) CopyFile(sourcePath string, destinationPath string) error {
+	ret := _m.Called(sourcePath, destinationPath)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(sourcePath, destinationPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // FileExists provides a mock function with given fields: filepath
 func (_m *VaultSSHClient) FileExists(filepath string) (bool, error) {
 	ret := _m.Called(filepath)
@@ -90,6 +105,73 @@ func (_m *VaultSSHClient) FileExists(filepath string) (bool, error) {
 	return r0, r1
 }
 
+// FileSHA256 provides a mock function with given fields: filepath
+func (_m *VaultSSHClient) FileSHA256(filepath string) (string, error) {
+	ret := _m.Called(filepath)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(filepath)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(filepath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindVaultConfigPaths provides a mock function with given fields:
+func (_m *VaultSSHClient) FindVaultConfigPaths() ([]string, error) {
+	ret := _m.Called()
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultServerConfig provides a mock function with given fields: configPath
+func (_m *VaultSSHClient) GetVaultServerConfig(configPath string) (*ssh.VaultServerConfig, error) {
+	ret := _m.Called(configPath)
+
+	var r0 *ssh.VaultServerConfig
+	if rf, ok := ret.Get(0).(func(string) *ssh.VaultServerConfig); ok {
+		r0 = rf(configPath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ssh.VaultServerConfig)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(configPath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // IsIPCLockCapabilityOnFile provides a mock function with given fields: filename
 func (_m *VaultSSHClient) IsIPCLockCapabilityOnFile(filename string) (bool, error) {
 	ret := _m.Called(filename)
@@ -111,6 +193,29 @@ func (_m *VaultSSHClient) IsIPCLockCapabilityOnFile(filename string) (bool, erro
 	return r0, r1
 }
 
+// ReadFile provides a mock function with given fields: filepath
+func (_m *VaultSSHClient) ReadFile(filepath string) ([]byte, error) {
+	ret := _m.Called(filepath)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(string) []byte); ok {
+		r0 = rf(filepath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(filepath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WriteFile provides a mock function with given fields: sourceFile, hostDestination
 func (_m *VaultSSHClient) WriteFile(sourceFile io.Reader, hostDestination string) error {
 	ret := _m.Called(sourceFile, hostDestination)